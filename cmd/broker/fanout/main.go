@@ -22,8 +22,11 @@ import (
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/idtoken"
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
@@ -46,6 +49,17 @@ type envConfig struct {
 	HandlerConcurrency     int    `envconfig:"HANDLER_CONCURRENCY"`
 	MaxConcurrencyPerEvent int    `envconfig:"MAX_CONCURRENCY_PER_EVENT"`
 
+	// Name of the GCS bucket the ingress offloads the payloads of large events to. The payloads
+	// are read back from this bucket only, and offloaded events can't be delivered if empty.
+	ClaimCheckBucket string `envconfig:"CLAIM_CHECK_BUCKET" default:""`
+
+	// TargetsConfigURL is the URL the targets config is streamed from, falling back to the
+	// targets config volume. The targets config is only loaded from the volume if empty.
+	TargetsConfigURL string `envconfig:"TARGETS_CONFIG_URL"`
+
+	// TargetsConfigTokenPath is the path of the token the targets config is streamed with.
+	TargetsConfigTokenPath string `envconfig:"TARGETS_CONFIG_TOKEN_PATH"`
+
 	// Environment variable containing the authType, which represents the authentication configuration mode the Pod is using.
 	AuthType authcheck.AuthType `envconfig:"K_GCP_AUTH_TYPE" default:""`
 
@@ -63,6 +77,25 @@ type envConfig struct {
 
 	// Max to 10m.
 	TimeoutPerEvent time.Duration `envconfig:"TIMEOUT_PER_EVENT"`
+
+	// DisableCircuitBreaker disables the circuit breakers that stop deliveries to unavailable
+	// subscribers.
+	DisableCircuitBreaker bool `envconfig:"DISABLE_CIRCUIT_BREAKER" default:"false"`
+
+	// The PEM files of the CA bundle trusted to verify the subscribers, in addition to the system
+	// roots, and of the client certificate and key presented to them. They are reloaded when they
+	// change.
+	DeliveryCAFile   string `envconfig:"DELIVERY_CA_FILE"`
+	DeliveryCertFile string `envconfig:"DELIVERY_CERT_FILE"`
+	DeliveryKeyFile  string `envconfig:"DELIVERY_KEY_FILE"`
+
+	// DrainTimeout is how long the handlers wait for the events being processed on shutdown and
+	// config change before nacking them. It should be shorter than the termination grace period.
+	DrainTimeout time.Duration `envconfig:"DRAIN_TIMEOUT"`
+
+	// AdminPort is the port of the admin server exposing the state of the handlers as JSON, to
+	// debug delivery issues. The admin server is disabled if zero.
+	AdminPort int `envconfig:"ADMIN_PORT"`
 }
 
 func main() {
@@ -88,17 +121,29 @@ func main() {
 		logger.Fatalf("failed to get default ProjectID: %v", err)
 	}
 
+	handlerOpts := buildHandlerOptions(ctx, env)
+	if env.DeliveryCAFile != "" || env.DeliveryCertFile != "" || env.DeliveryKeyFile != "" {
+		deliveryTLS, err := tlsconfig.NewReloader(ctx, env.DeliveryCAFile, env.DeliveryCertFile, env.DeliveryKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load the delivery TLS configuration", zap.Error(err))
+		}
+		handlerOpts = append(handlerOpts, handler.WithDeliveryTLS(deliveryTLS))
+	}
+
 	syncSignal := poolSyncSignal(ctx, targetsUpdateCh)
 	syncPool, err := InitializeSyncPool(
 		ctx,
 		clients.ProjectID(projectID),
 		metrics.PodName(env.PodName),
 		metrics.ContainerName(component),
-		[]volume.Option{
-			volume.WithPath(env.TargetsConfigPath),
-			volume.WithNotifyChan(targetsUpdateCh),
+		handler.ClaimCheckBucket(env.ClaimCheckBucket),
+		[]stream.Option{
+			stream.WithURL(env.TargetsConfigURL),
+			stream.WithVolumeOptions(volume.WithPath(env.TargetsConfigPath)),
+			stream.WithNotifyChan(targetsUpdateCh),
+			stream.WithTokenFile(env.TargetsConfigTokenPath),
 		},
-		buildHandlerOptions(env)...,
+		handlerOpts...,
 	)
 	if err != nil {
 		logger.Fatal("Failed to create fanout sync pool", zap.Error(err))
@@ -106,12 +151,16 @@ func main() {
 	if _, err := handler.StartSyncPool(ctx, syncPool, syncSignal, env.MaxStaleDuration, handler.DefaultProbeCheckPort, authcheck.NewDefault(env.AuthType)); err != nil {
 		logger.Fatalw("Failed to start fanout sync pool", zap.Error(err))
 	}
+	if env.AdminPort > 0 {
+		handler.StartAdminServer(ctx, syncPool, env.AdminPort)
+	}
 
 	// Context will be done if a TERM signal is issued.
 	<-ctx.Done()
-	// Wait a grace period for the handlers to shutdown.
-	time.Sleep(30 * time.Second)
-	logger.Info("Done waiting, exit.")
+	// Let the handlers finish the events being processed, for at most the drain timeout.
+	logger.Info("Draining the handlers...")
+	syncPool.Drain()
+	logger.Info("Done draining, exit.")
 }
 
 func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan struct{} {
@@ -134,7 +183,7 @@ func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan str
 	return ch
 }
 
-func buildHandlerOptions(env envConfig) []handler.Option {
+func buildHandlerOptions(ctx context.Context, env envConfig) []handler.Option {
 	rs := pubsub.DefaultReceiveSettings
 	var opts []handler.Option
 	if env.HandlerConcurrency > 0 {
@@ -154,6 +203,14 @@ func buildHandlerOptions(env envConfig) []handler.Option {
 	if env.MaxOutstandingMessages > 0 {
 		rs.MaxOutstandingMessages = env.MaxOutstandingMessages
 	}
+	if env.DisableCircuitBreaker {
+		opts = append(opts, handler.WithCircuitBreaker(nil))
+	}
+	// Tokens for the subscribers that require authentication are minted for the identity of the pod.
+	opts = append(opts, handler.WithIDTokens(idtoken.NewMinter(ctx, idtoken.Google)))
+	if env.DrainTimeout > 0 {
+		opts = append(opts, handler.WithDrainTimeout(env.DrainTimeout))
+	}
 	opts = append(opts, handler.WithPubsubReceiveSettings(rs))
 	// The default CeClient is good?
 	return opts
@@ -7,7 +7,7 @@ package main
 
 import (
 	"context"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/handler"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
@@ -15,8 +15,10 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, targetsVolumeOpts []volume.Option, opts ...handler.Option) (*handler.FanoutPool, error) {
-	readonlyTargets, err := volume.NewTargetsFromFile(targetsVolumeOpts...)
+// InitializeSyncPool initializes the fanout sync pool. Uses the given projectID to initialize the
+// retry pool's pubsub client and uses targetsOpts to initialize the targets config stream.
+func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, claimCheckBucket handler.ClaimCheckBucket, targetsOpts []stream.Option, opts ...handler.Option) (*handler.FanoutPool, error) {
+	readonlyTargets, err := stream.NewTargets(ctx, targetsOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +36,8 @@ func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podNam
 	if err != nil {
 		return nil, err
 	}
-	fanoutPool, err := handler.NewFanoutPool(readonlyTargets, client, httpClient, retryClient, deliveryReporter, opts...)
+	reader := handler.NewClaimCheckReader(claimCheckBucket)
+	fanoutPool, err := handler.NewFanoutPool(readonlyTargets, client, httpClient, retryClient, deliveryReporter, reader, opts...)
 	if err != nil {
 		return nil, err
 	}
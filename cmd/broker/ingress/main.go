@@ -17,6 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+	"github.com/google/knative-gcp/pkg/gclient/storage"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
@@ -35,6 +39,25 @@ type envConfig struct {
 	// Environment variable containing the authType, which represents the authentication configuration mode the Pod is using.
 	AuthType authcheck.AuthType `envconfig:"K_GCP_AUTH_TYPE" default:""`
 
+	// Comma-separated list of issuers whose tokens are accepted for brokers that require
+	// authentication. Each item is either an issuer or "issuer=jwksURL". Defaults to Google.
+	TrustedIssuers string `envconfig:"TRUSTED_TOKEN_ISSUERS" default:""`
+
+	// Name of the GCS bucket where the payloads of large events are offloaded. Payloads are not
+	// offloaded if empty. Objects should be deleted by a lifecycle rule of the bucket.
+	ClaimCheckBucket string `envconfig:"CLAIM_CHECK_BUCKET" default:""`
+
+	// Payloads larger than this are offloaded to the claim check bucket. Default is 8MB, which leaves
+	// room for the attributes under the Pub/Sub message size limit.
+	ClaimCheckThresholdBytes int `envconfig:"CLAIM_CHECK_THRESHOLD_BYTES" default:"8000000"`
+
+	// TargetsConfigURL is the URL the targets config is streamed from, falling back to the
+	// targets config volume. The targets config is only loaded from the volume if empty.
+	TargetsConfigURL string `envconfig:"TARGETS_CONFIG_URL"`
+
+	// TargetsConfigTokenPath is the path of the token the targets config is streamed with.
+	TargetsConfigTokenPath string `envconfig:"TARGETS_CONFIG_TOKEN_PATH"`
+
 	// Default 300Mi.
 	PublishBufferedByteLimit int `envconfig:"PUBLISH_BUFFERED_BYTES_LIMIT" default:"314572800"`
 }
@@ -61,9 +84,22 @@ func main() {
 	if err != nil {
 		logger.Desugar().Fatal("Failed to create project id", zap.Error(err))
 	}
+	issuers, err := ingress.ParseTrustedIssuers(env.TrustedIssuers)
+	if err != nil {
+		logger.Desugar().Fatal("Failed to parse trusted token issuers", zap.Error(err))
+	}
+	var claimCheck *claimcheck.Store
+	if env.ClaimCheckBucket != "" {
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			logger.Desugar().Fatal("Failed to create storage client", zap.Error(err))
+		}
+		defer client.Close()
+		claimCheck = claimcheck.NewStore(client, env.ClaimCheckBucket, env.ClaimCheckThresholdBytes)
+	}
 	logger.Desugar().Info("Starting ingress handler", zap.Any("envConfig", env), zap.Any("Project ID", projectID))
 
-	ingress, err := InitializeHandler(
+	handler, err := InitializeHandler(
 		ctx,
 		clients.Port(env.Port),
 		clients.ProjectID(projectID),
@@ -71,13 +107,19 @@ func main() {
 		metrics.ContainerName(component),
 		publishSetting(logger.Desugar(), env),
 		env.AuthType,
+		issuers,
+		claimCheck,
+		[]stream.Option{
+			stream.WithURL(env.TargetsConfigURL),
+			stream.WithTokenFile(env.TargetsConfigTokenPath),
+		},
 	)
 	if err != nil {
 		logger.Desugar().Fatal("Unable to create ingress handler: ", zap.Error(err))
 	}
 
-	logger.Desugar().Info("Starting ingress.", zap.Any("ingress", ingress))
-	if err := ingress.Start(ctx); err != nil {
+	logger.Desugar().Info("Starting ingress.", zap.Any("ingress", handler))
+	if err := handler.Start(ctx); err != nil {
 		logger.Desugar().Fatal("failed to start ingress: ", zap.Error(err))
 	}
 }
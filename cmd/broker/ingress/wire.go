@@ -22,7 +22,8 @@ import (
 	"context"
 
 	"cloud.google.com/go/pubsub"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
@@ -38,10 +39,12 @@ func InitializeHandler(
 	containerName metrics.ContainerName,
 	publishSettings pubsub.PublishSettings,
 	authType authcheck.AuthType,
+	issuers ingress.TrustedIssuers,
+	claimCheck *claimcheck.Store,
+	targetsOpts []stream.Option,
 ) (*ingress.Handler, error) {
 	panic(wire.Build(
 		ingress.HandlerSet,
-		wire.Value([]volume.Option(nil)),
-		volume.NewTargetsFromFile,
+		stream.NewTargets,
 	))
 }
@@ -8,7 +8,8 @@ package main
 import (
 	"cloud.google.com/go/pubsub"
 	"context"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
@@ -17,10 +18,9 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, publishSettings pubsub.PublishSettings, authType authcheck.AuthType) (*ingress.Handler, error) {
+func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, publishSettings pubsub.PublishSettings, authType authcheck.AuthType, issuers ingress.TrustedIssuers, claimCheck *claimcheck.Store, targetsOpts []stream.Option) (*ingress.Handler, error) {
 	httpMessageReceiver := clients.NewHTTPMessageReceiverWithChecker(port, authType)
-	v := _wireValue
-	readonlyTargets, err := volume.NewTargetsFromFile(v...)
+	readonlyTargets, err := stream.NewTargets(ctx, targetsOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,14 +29,12 @@ func InitializeHandler(ctx context.Context, port clients.Port, projectID clients
 		return nil, err
 	}
 	multiTopicDecoupleSink := ingress.NewMultiTopicDecoupleSink(ctx, readonlyTargets, client, publishSettings)
+	rateLimiter := ingress.NewRateLimiter(readonlyTargets)
+	authenticator := ingress.NewAuthenticator(readonlyTargets, issuers)
 	ingressReporter, err := metrics.NewIngressReporter(podName, containerName)
 	if err != nil {
 		return nil, err
 	}
-	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, ingressReporter, authType)
+	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, rateLimiter, authenticator, claimCheck, ingressReporter, authType)
 	return handler, nil
 }
-
-var (
-	_wireValue = []volume.Option(nil)
-)
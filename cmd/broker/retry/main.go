@@ -24,8 +24,12 @@ import (
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
 
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/broker/idtoken"
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
@@ -45,6 +49,17 @@ type envConfig struct {
 	TargetsConfigPath  string `envconfig:"TARGETS_CONFIG_PATH" default:"/var/run/events-system/broker/targets"`
 	HandlerConcurrency int    `envconfig:"HANDLER_CONCURRENCY"`
 
+	// Name of the GCS bucket the ingress offloads the payloads of large events to. The payloads
+	// are read back from this bucket only, and offloaded events can't be delivered if empty.
+	ClaimCheckBucket string `envconfig:"CLAIM_CHECK_BUCKET" default:""`
+
+	// TargetsConfigURL is the URL the targets config is streamed from, falling back to the
+	// targets config volume. The targets config is only loaded from the volume if empty.
+	TargetsConfigURL string `envconfig:"TARGETS_CONFIG_URL"`
+
+	// TargetsConfigTokenPath is the path of the token the targets config is streamed with.
+	TargetsConfigTokenPath string `envconfig:"TARGETS_CONFIG_TOKEN_PATH"`
+
 	// Environment variable containing the authType, which represents the authentication configuration mode the Pod is using.
 	AuthType authcheck.AuthType `envconfig:"K_GCP_AUTH_TYPE" default:""`
 
@@ -61,6 +76,32 @@ type envConfig struct {
 
 	// Max to 10m.
 	TimeoutPerEvent time.Duration `envconfig:"TIMEOUT_PER_EVENT"`
+
+	// MaxRetryDelay is the longest an event is held after a throttled delivery before it's nacked.
+	// Events are nacked right away if zero.
+	MaxRetryDelay time.Duration `envconfig:"MAX_RETRY_DELAY" default:"1m"`
+	// NonRetryableStatusCodes are the subscriber response status codes after which events are sent
+	// to the dead letter sink without further retries, e.g. "400,413".
+	NonRetryableStatusCodes []int `envconfig:"NON_RETRYABLE_STATUS_CODES"`
+
+	// DisableBackpressure disables the adaptation of the number of events pulled for each trigger
+	// to the health of its subscriber.
+	DisableBackpressure bool `envconfig:"DISABLE_BACKPRESSURE" default:"false"`
+
+	// The PEM files of the CA bundle trusted to verify the subscribers, in addition to the system
+	// roots, and of the client certificate and key presented to them. They are reloaded when they
+	// change.
+	DeliveryCAFile   string `envconfig:"DELIVERY_CA_FILE"`
+	DeliveryCertFile string `envconfig:"DELIVERY_CERT_FILE"`
+	DeliveryKeyFile  string `envconfig:"DELIVERY_KEY_FILE"`
+
+	// DrainTimeout is how long the handlers wait for the events being processed on shutdown and
+	// config change before nacking them. It should be shorter than the termination grace period.
+	DrainTimeout time.Duration `envconfig:"DRAIN_TIMEOUT"`
+
+	// AdminPort is the port of the admin server exposing the state of the handlers as JSON, to
+	// debug delivery issues. The admin server is disabled if zero.
+	AdminPort int `envconfig:"ADMIN_PORT"`
 }
 
 func main() {
@@ -86,17 +127,29 @@ func main() {
 		logger.Fatalf("failed to get default ProjectID: %v", err)
 	}
 
+	handlerOpts := buildHandlerOptions(ctx, env)
+	if env.DeliveryCAFile != "" || env.DeliveryCertFile != "" || env.DeliveryKeyFile != "" {
+		deliveryTLS, err := tlsconfig.NewReloader(ctx, env.DeliveryCAFile, env.DeliveryCertFile, env.DeliveryKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load the delivery TLS configuration", zap.Error(err))
+		}
+		handlerOpts = append(handlerOpts, handler.WithDeliveryTLS(deliveryTLS))
+	}
+
 	syncSignal := poolSyncSignal(ctx, targetsUpdateCh)
 	syncPool, err := InitializeSyncPool(
 		ctx,
 		clients.ProjectID(projectID),
 		metrics.PodName(env.PodName),
 		metrics.ContainerName(component),
-		[]volume.Option{
-			volume.WithPath(env.TargetsConfigPath),
-			volume.WithNotifyChan(targetsUpdateCh),
+		handler.ClaimCheckBucket(env.ClaimCheckBucket),
+		[]stream.Option{
+			stream.WithURL(env.TargetsConfigURL),
+			stream.WithVolumeOptions(volume.WithPath(env.TargetsConfigPath)),
+			stream.WithNotifyChan(targetsUpdateCh),
+			stream.WithTokenFile(env.TargetsConfigTokenPath),
 		},
-		buildHandlerOptions(env)...,
+		handlerOpts...,
 	)
 	if err != nil {
 		logger.Fatal("Failed to get retry sync pool", zap.Error(err))
@@ -104,10 +157,16 @@ func main() {
 	if _, err := handler.StartSyncPool(ctx, syncPool, syncSignal, env.MaxStaleDuration, handler.DefaultProbeCheckPort, authcheck.NewDefault(env.AuthType)); err != nil {
 		logger.Fatal("Failed to start retry sync pool", zap.Error(err))
 	}
+	if env.AdminPort > 0 {
+		handler.StartAdminServer(ctx, syncPool, env.AdminPort)
+	}
 
 	// Context will be done if a TERM signal is issued.
 	<-ctx.Done()
-	logger.Info("Exiting...")
+	// Let the handlers finish the events being processed, for at most the drain timeout.
+	logger.Info("Draining the handlers...")
+	syncPool.Drain()
+	logger.Info("Done draining, exit.")
 }
 
 func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan struct{} {
@@ -130,7 +189,7 @@ func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan str
 	return ch
 }
 
-func buildHandlerOptions(env envConfig) []handler.Option {
+func buildHandlerOptions(ctx context.Context, env envConfig) []handler.Option {
 	rs := pubsub.DefaultReceiveSettings
 	// If Synchronous is true, then no more than MaxOutstandingMessages will be in memory at one time.
 	// MaxOutstandingBytes still refers to the total bytes processed, rather than in memory.
@@ -147,6 +206,22 @@ func buildHandlerOptions(env envConfig) []handler.Option {
 	if env.TimeoutPerEvent > 0 {
 		opts = append(opts, handler.WithTimeoutPerEvent(env.TimeoutPerEvent))
 	}
+	if env.MaxRetryDelay > 0 {
+		backoff := deliver.DefaultRetryBackoff
+		backoff.MaxDelay = env.MaxRetryDelay
+		opts = append(opts, handler.WithRetryBackoff(&backoff))
+	} else {
+		opts = append(opts, handler.WithRetryBackoff(nil))
+	}
+	opts = append(opts, handler.WithNonRetryableStatusCodes(env.NonRetryableStatusCodes...))
+	if env.DisableBackpressure {
+		opts = append(opts, handler.WithBackpressure(nil))
+	}
+	// Tokens for the subscribers that require authentication are minted for the identity of the pod.
+	opts = append(opts, handler.WithIDTokens(idtoken.NewMinter(ctx, idtoken.Google)))
+	if env.DrainTimeout > 0 {
+		opts = append(opts, handler.WithDrainTimeout(env.DrainTimeout))
+	}
 	opts = append(opts, handler.WithPubsubReceiveSettings(rs))
 	// The default CeClient is good?
 	return opts
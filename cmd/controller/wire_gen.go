@@ -11,6 +11,7 @@ import (
 	"github.com/google/knative-gcp/pkg/apis/configs/brokerdelivery"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
 	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/reconciler/broker"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell"
 	"github.com/google/knative-gcp/pkg/reconciler/deployment"
@@ -55,11 +56,12 @@ func InitializeControllers(ctx context.Context) ([]injection.ControllerConstruct
 	dataresidencyStoreSingleton := &dataresidency.StoreSingleton{}
 	topicConstructor := topic.NewConstructor(iamPolicyManager, storeSingleton, dataresidencyStoreSingleton)
 	channelConstructor := channel.NewConstructor(iamPolicyManager, storeSingleton)
-	triggerConstructor := trigger.NewConstructor(dataresidencyStoreSingleton)
+	server := stream.NewServer()
+	triggerConstructor := trigger.NewConstructor(dataresidencyStoreSingleton, server)
 	brokerdeliveryStoreSingleton := &brokerdelivery.StoreSingleton{}
-	brokerConstructor := broker.NewConstructor(brokerdeliveryStoreSingleton, dataresidencyStoreSingleton)
+	brokerConstructor := broker.NewConstructor(brokerdeliveryStoreSingleton, dataresidencyStoreSingleton, server)
 	deploymentConstructor := deployment.NewConstructor()
-	brokercellConstructor := brokercell.NewConstructor()
+	brokercellConstructor := brokercell.NewConstructor(server)
 	v2 := Controllers(constructor, storageConstructor, schedulerConstructor, pubsubConstructor, buildConstructor, staticConstructor, kedaConstructor, topicConstructor, channelConstructor, triggerConstructor, brokerConstructor, deploymentConstructor, brokercellConstructor)
 	return v2, nil
 }
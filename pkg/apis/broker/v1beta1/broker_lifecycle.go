@@ -21,12 +21,16 @@ import (
 	"knative.dev/pkg/apis"
 )
 
-var brokerCondSet = apis.NewLivingConditionSet(
+// brokerControlPlaneConditions are the conditions of the resources that the data plane needs to
+// accept and fan out the events sent to the Broker.
+var brokerControlPlaneConditions = []apis.ConditionType{
 	eventingv1beta1.BrokerConditionAddressable,
 	BrokerConditionBrokerCell,
 	BrokerConditionTopic,
 	BrokerConditionSubscription,
-)
+}
+
+var brokerCondSet = apis.NewLivingConditionSet(append(brokerControlPlaneConditions, BrokerConditionDeadLetterSink, BrokerConditionDataPlane)...)
 
 const (
 	// BrokerConditionBrokerCell reports the availability of the Broker's BrokerCell.
@@ -37,6 +41,12 @@ const (
 	// BrokerConditionSubscription reports the status of the Broker's PubSub
 	// subscription. This condition is specific to the Google Cloud Broker.
 	BrokerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// BrokerConditionDeadLetterSink reports whether the dead letter sink the retry pool delivers the
+	// events to once their retries are exhausted is resolved.
+	BrokerConditionDeadLetterSink apis.ConditionType = "DeadLetterSinkResolved"
+	// BrokerConditionDataPlane reports whether the data plane applied the targets config with the
+	// latest spec of the Broker.
+	BrokerConditionDataPlane apis.ConditionType = "DataPlaneReady"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -54,6 +64,17 @@ func (bs *BrokerStatus) IsReady() bool {
 	return brokerCondSet.Manage(bs).IsHappy()
 }
 
+// IsControlPlaneReady returns true if all the conditions but DataPlaneReady are true, i.e. if the
+// data plane can accept the events sent to the Broker once it applies the Broker.
+func (bs *BrokerStatus) IsControlPlaneReady() bool {
+	for _, t := range brokerControlPlaneConditions {
+		if !bs.GetCondition(t).IsTrue() {
+			return false
+		}
+	}
+	return true
+}
+
 // InitializeConditions sets relevant unset conditions to Unknown state.
 func (bs *BrokerStatus) InitializeConditions() {
 	brokerCondSet.Manage(bs).InitializeConditions()
@@ -105,3 +126,33 @@ func (bs *BrokerStatus) MarkSubscriptionUnknown(reason, format string, args ...i
 func (bs *BrokerStatus) MarkSubscriptionReady() {
 	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionSubscription)
 }
+
+func (bs *BrokerStatus) MarkDeadLetterSinkResolved() {
+	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionDeadLetterSink)
+}
+
+// MarkDeadLetterSinkNotNeeded marks the dead letter sink as resolved when the retry pool doesn't
+// deliver to one, either because there is none or because Pub/Sub forwards the events to a topic.
+func (bs *BrokerStatus) MarkDeadLetterSinkNotNeeded() {
+	brokerCondSet.Manage(bs).MarkTrueWithReason(BrokerConditionDeadLetterSink, "NotNeeded",
+		"The retry pool doesn't deliver to a dead letter sink")
+}
+
+func (bs *BrokerStatus) MarkDeadLetterSinkFailed(reason, format string, args ...interface{}) {
+	brokerCondSet.Manage(bs).MarkFalse(BrokerConditionDeadLetterSink, reason, format, args...)
+}
+
+func (bs *BrokerStatus) MarkDataPlaneReady() {
+	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionDataPlane)
+}
+
+func (bs *BrokerStatus) MarkDataPlaneUnknown(reason, format string, args ...interface{}) {
+	brokerCondSet.Manage(bs).MarkUnknown(BrokerConditionDataPlane, reason, format, args...)
+}
+
+// MarkDataPlaneNotTracked marks the data plane as ready when it doesn't report the targets config
+// it applied, so that the readiness of the Broker doesn't wait for it.
+func (bs *BrokerStatus) MarkDataPlaneNotTracked() {
+	brokerCondSet.Manage(bs).MarkTrueWithReason(BrokerConditionDataPlane, "NotTracked",
+		"The data plane doesn't report the targets config it applied")
+}
@@ -150,6 +150,12 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDeadLetterSink,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -184,6 +190,12 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDeadLetterSink,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -218,6 +230,12 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDeadLetterSink,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -249,13 +267,15 @@ func TestBrokerInitializeConditions(t *testing.T) {
 
 func TestBrokerConditionStatus(t *testing.T) {
 	tests := []struct {
-		name                string
-		addressStatus       bool
-		brokerCellStatus    corev1.ConditionStatus
-		subscriptionStatus  corev1.ConditionStatus
-		topicStatus         corev1.ConditionStatus
-		configStatus        corev1.ConditionStatus
-		wantConditionStatus corev1.ConditionStatus
+		name                 string
+		addressStatus        bool
+		brokerCellStatus     corev1.ConditionStatus
+		subscriptionStatus   corev1.ConditionStatus
+		topicStatus          corev1.ConditionStatus
+		configStatus         corev1.ConditionStatus
+		dataPlaneNotApplied  bool
+		deadLetterSinkFailed bool
+		wantConditionStatus  corev1.ConditionStatus
 	}{{
 		name:                "all happy",
 		addressStatus:       true,
@@ -328,6 +348,24 @@ func TestBrokerConditionStatus(t *testing.T) {
 		topicStatus:         corev1.ConditionTrue,
 		configStatus:        corev1.ConditionTrue,
 		wantConditionStatus: corev1.ConditionUnknown,
+	}, {
+		name:                "data plane not applied",
+		addressStatus:       true,
+		brokerCellStatus:    corev1.ConditionTrue,
+		subscriptionStatus:  corev1.ConditionTrue,
+		topicStatus:         corev1.ConditionTrue,
+		configStatus:        corev1.ConditionTrue,
+		dataPlaneNotApplied: true,
+		wantConditionStatus: corev1.ConditionUnknown,
+	}, {
+		name:                 "dead letter sink sad",
+		addressStatus:        true,
+		brokerCellStatus:     corev1.ConditionTrue,
+		subscriptionStatus:   corev1.ConditionTrue,
+		topicStatus:          corev1.ConditionTrue,
+		configStatus:         corev1.ConditionTrue,
+		deadLetterSinkFailed: true,
+		wantConditionStatus:  corev1.ConditionFalse,
 	}, {
 		name:                "all sad",
 		addressStatus:       false,
@@ -366,6 +404,16 @@ func TestBrokerConditionStatus(t *testing.T) {
 			} else {
 				bs.MarkTopicUnknown("Unable to create PubSub topic", "induced unknown")
 			}
+			if test.dataPlaneNotApplied {
+				bs.MarkDataPlaneUnknown("ConfigNotApplied", "induced unknown")
+			} else {
+				bs.MarkDataPlaneReady()
+			}
+			if test.deadLetterSinkFailed {
+				bs.MarkDeadLetterSinkFailed("DeadLetterSinkNotResolved", "induced failure")
+			} else {
+				bs.MarkDeadLetterSinkResolved()
+			}
 
 			got := bs.GetTopLevelCondition().Status
 			if test.wantConditionStatus != got {
@@ -385,3 +433,52 @@ func TestBrokerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokerDataPlane(t *testing.T) {
+	bs := &BrokerStatus{}
+	bs.SetAddress(apis.HTTP("example.com"))
+	bs.MarkBrokerCellReady()
+	bs.MarkTopicReady()
+	bs.MarkSubscriptionReady()
+	bs.MarkDeadLetterSinkNotNeeded()
+
+	bs.MarkDataPlaneUnknown("ConfigNotApplied", "induced unknown")
+	if bs.IsReady() {
+		t.Error("expected the broker not to be ready before the data plane applied it")
+	}
+	if !bs.IsControlPlaneReady() {
+		t.Error("expected the control plane of the broker to be ready")
+	}
+
+	bs.MarkDataPlaneNotTracked()
+	if !bs.IsReady() {
+		t.Error("expected the broker to be ready when the data plane isn't tracked")
+	}
+
+	bs.MarkSubscriptionFailed("SubscriptionDeleted", "induced failure")
+	if bs.IsControlPlaneReady() {
+		t.Error("expected the control plane of the broker not to be ready")
+	}
+}
+
+func TestBrokerDeadLetterSink(t *testing.T) {
+	bs := &BrokerStatus{}
+	bs.SetAddress(apis.HTTP("example.com"))
+	bs.MarkBrokerCellReady()
+	bs.MarkTopicReady()
+	bs.MarkSubscriptionReady()
+	bs.MarkDataPlaneReady()
+
+	bs.MarkDeadLetterSinkFailed("DeadLetterSinkNotResolved", "induced failure")
+	if bs.IsReady() {
+		t.Error("expected the broker not to be ready while its dead letter sink isn't resolved")
+	}
+	if !bs.IsControlPlaneReady() {
+		t.Error("expected the control plane of the broker to be ready, so that events are still fanned out")
+	}
+
+	bs.MarkDeadLetterSinkResolved()
+	if !bs.IsReady() {
+		t.Error("expected the broker to be ready once its dead letter sink is resolved")
+	}
+}
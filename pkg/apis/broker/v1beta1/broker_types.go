@@ -17,6 +17,8 @@ limitations under the License.
 package v1beta1
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -24,14 +26,96 @@ import (
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/system"
 )
 
 const (
 	// BrokerClass is the annotation value to use when creating a
 	// Google Cloud Broker object.
 	BrokerClass = "googlecloud"
+
+	// IngressEventsPerSecondAnnotationKey is the annotation key for the number of events per second
+	// the ingress accepts for a Broker.
+	IngressEventsPerSecondAnnotationKey = "events.cloud.google.com/ingressEventsPerSecond"
+	// IngressEventsBurstAnnotationKey is the annotation key for the maximum number of events the
+	// ingress accepts in a burst for a Broker. It defaults to the events per second.
+	IngressEventsBurstAnnotationKey = "events.cloud.google.com/ingressEventsBurst"
+	// IngressBytesPerSecondAnnotationKey is the annotation key for the number of bytes per second
+	// the ingress accepts for a Broker.
+	IngressBytesPerSecondAnnotationKey = "events.cloud.google.com/ingressBytesPerSecond"
+	// IngressBytesBurstAnnotationKey is the annotation key for the maximum number of bytes the
+	// ingress accepts in a burst for a Broker. It defaults to the bytes per second.
+	IngressBytesBurstAnnotationKey = "events.cloud.google.com/ingressBytesBurst"
+
+	// OrderingKeyExtensionAnnotationKey is the annotation key for the name of the CloudEvent
+	// extension used as the ordering key of the events sent to a Broker. Events with the same
+	// ordering key are delivered to each Trigger one at a time and in order. Message ordering can
+	// only be enabled on Pub/Sub subscriptions at creation time, so the annotation should be set
+	// when the Broker is created.
+	OrderingKeyExtensionAnnotationKey = "events.cloud.google.com/orderingKeyExtension"
+
+	// IngressAllowedAudiencesAnnotationKey is the annotation key for the comma-separated list of
+	// token audiences accepted by the ingress for a Broker. If set, requests to the Broker must
+	// carry a bearer token, e.g. a Kubernetes projected service account token or a Google-signed ID
+	// token, whose audience contains any of them.
+	IngressAllowedAudiencesAnnotationKey = "events.cloud.google.com/ingressAllowedAudiences"
+	// IngressAllowedSubjectsAnnotationKey is the annotation key for the comma-separated list of
+	// token subjects accepted by the ingress for a Broker. Any subject is accepted if unset. It
+	// requires IngressAllowedAudiencesAnnotationKey to be set.
+	IngressAllowedSubjectsAnnotationKey = "events.cloud.google.com/ingressAllowedSubjects"
+
+	// RetentionAnnotationKey is the annotation key for how long the events sent to a Broker are
+	// retained so that Triggers can replay them, as a duration between 10m and 168h (7 days). The
+	// events are retained by a Pub/Sub subscription that is never pulled. Events are not retained if
+	// unset.
+	RetentionAnnotationKey = "events.cloud.google.com/retention"
+
+	// MaxEventAgeAnnotationKey is the annotation key for the maximum age of the events delivered by
+	// a Broker or a Trigger, as a duration such as "24h". The age of an event counts from its
+	// arrival at the Broker. Older events are sent to the dead letter sink of the Broker if it has
+	// one, or dropped otherwise. The annotation of a Trigger overrides the one of its Broker. Events
+	// don't expire if unset.
+	MaxEventAgeAnnotationKey = "events.cloud.google.com/maxEventAge"
+
+	// BrokerCellAnnotationKey is the annotation key for the BrokerCell whose data plane serves a
+	// Broker, either as "<name>" for a BrokerCell in the namespace of the Broker, or as
+	// "<namespace>/<name>" where the namespace is the namespace of the Broker or the system
+	// namespace. The BrokerCells of the namespace of the Broker are created if they don't exist,
+	// while those of the system namespace are set up by the operator. Brokers are served by the
+	// "default" BrokerCell of the system namespace if unset.
+	BrokerCellAnnotationKey = "events.cloud.google.com/brokerCell"
 )
 
+// BrokerCellRef returns the namespace and name of the BrokerCell selected by the brokerCell
+// annotation of the Broker, and false if the annotation is unset.
+func (b *Broker) BrokerCellRef() (namespace, name string, ok bool) {
+	v, ok := b.GetAnnotations()[BrokerCellAnnotationKey]
+	if !ok {
+		return "", "", false
+	}
+	if i := strings.Index(v, "/"); i >= 0 {
+		return v[:i], v[i+1:], true
+	}
+	return b.Namespace, v, true
+}
+
+// IsBrokerCellNamespaceAllowed reports whether a Broker in namespace can be bound to a BrokerCell
+// in cellNamespace: either its own namespace, or the system namespace.
+func IsBrokerCellNamespaceAllowed(namespace, cellNamespace string) bool {
+	return cellNamespace == namespace || cellNamespace == system.Namespace()
+}
+
+// SplitAnnotationList splits a comma-separated annotation value, dropping empty items.
+func SplitAnnotationList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // +genclient
 // +genreconciler:class=eventing.knative.dev/broker.class
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
@@ -64,3 +65,36 @@ func TestBroker_GetStatus(t *testing.T) {
 		t.Errorf("GetStatus=%v, want=%v", got, want)
 	}
 }
+
+func TestBroker_BrokerCellRef(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		wantNamespace string
+		wantName      string
+		wantOK        bool
+	}{{
+		name: "no annotation",
+	}, {
+		name:          "name only",
+		annotations:   map[string]string{BrokerCellAnnotationKey: "team-a"},
+		wantNamespace: "ns",
+		wantName:      "team-a",
+		wantOK:        true,
+	}, {
+		name:          "namespace and name",
+		annotations:   map[string]string{BrokerCellAnnotationKey: "events-system/team-a"},
+		wantNamespace: "events-system",
+		wantName:      "team-a",
+		wantOK:        true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "broker", Annotations: tc.annotations}}
+			namespace, name, ok := b.BrokerCellRef()
+			if namespace != tc.wantNamespace || name != tc.wantName || ok != tc.wantOK {
+				t.Errorf("BrokerCellRef() = (%q, %q, %v), want (%q, %q, %v)", namespace, name, ok, tc.wantNamespace, tc.wantName, tc.wantOK)
+			}
+		})
+	}
+}
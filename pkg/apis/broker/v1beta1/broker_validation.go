@@ -18,7 +18,12 @@ package v1beta1
 
 import (
 	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/validation"
 	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
@@ -26,15 +31,83 @@ import (
 
 // Validate verifies that the Broker is valid.
 func (b *Broker) Validate(ctx context.Context) *apis.FieldError {
-	// We validate the GCP Broker's delivery spec. The eventing webhook will run
+	// We validate the GCP Broker's delivery spec and annotations. The eventing webhook will run
 	// the other usual validations.
+	errs := validateRateLimitAnnotations(b.GetAnnotations()).
+		Also(validateOrderingKeyExtensionAnnotation(b.GetAnnotations())).
+		Also(validateIngressAuthAnnotations(b.GetAnnotations())).
+		Also(validateRetentionAnnotation(b.GetAnnotations())).
+		Also(validateMaxEventAgeAnnotation(b.GetAnnotations())).
+		Also(validateBrokerCellAnnotation(b.GetAnnotations(), b.Namespace)).
+		ViaField("metadata", "annotations")
 	if b.Spec.Delivery == nil {
-		return nil
+		return errs
 	}
 	withNS := apis.AllowDifferentNamespace(apis.WithinParent(ctx, b.ObjectMeta))
-	return ValidateDeliverySpec(withNS, b.Spec.Delivery).ViaField("spec", "delivery")
+	return errs.Also(ValidateDeliverySpec(withNS, b.Spec.Delivery).ViaField("spec", "delivery"))
+}
+
+const (
+	// minRetention and maxRetention are the bounds of the message retention duration of Pub/Sub
+	// subscriptions.
+	minRetention = 10 * time.Minute
+	maxRetention = 7 * 24 * time.Hour
+)
+
+// validateRateLimitAnnotations verifies that the ingress rate limit annotations, if present, are
+// finite positive numbers.
+func validateRateLimitAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	for _, key := range []string{IngressEventsPerSecondAnnotationKey, IngressBytesPerSecondAnnotationKey} {
+		if v, ok := annotations[key]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f <= 0 {
+				errs = errs.Also(apis.ErrInvalidValue(v, key))
+			}
+		}
+	}
+	for _, key := range []string{IngressEventsBurstAnnotationKey, IngressBytesBurstAnnotationKey} {
+		if v, ok := annotations[key]; ok {
+			if i, err := strconv.ParseInt(v, 10, 64); err != nil || i <= 0 {
+				errs = errs.Also(apis.ErrInvalidValue(v, key))
+			}
+		}
+	}
+	return errs
 }
 
+// validateOrderingKeyExtensionAnnotation verifies that the ordering key extension annotation, if
+// present, is a valid CloudEvents extension name.
+func validateOrderingKeyExtensionAnnotation(annotations map[string]string) *apis.FieldError {
+	ext, ok := annotations[OrderingKeyExtensionAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if !isValidAttributeName(ext) {
+		return apis.ErrInvalidValue(ext, OrderingKeyExtensionAnnotationKey)
+	}
+	return nil
+}
+
+// validateIngressAuthAnnotations verifies that the ingress authentication annotations, if present,
+// are non-empty lists, and that subjects are only restricted along with audiences.
+func validateIngressAuthAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	for _, key := range []string{IngressAllowedAudiencesAnnotationKey, IngressAllowedSubjectsAnnotationKey} {
+		if v, ok := annotations[key]; ok && len(SplitAnnotationList(v)) == 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, key))
+		}
+	}
+	_, hasAudiences := annotations[IngressAllowedAudiencesAnnotationKey]
+	if _, hasSubjects := annotations[IngressAllowedSubjectsAnnotationKey]; hasSubjects && !hasAudiences {
+		errs = errs.Also(apis.ErrMissingField(IngressAllowedAudiencesAnnotationKey))
+	}
+	return errs
+}
+
+// MaxRetry is the largest number of retries in a delivery spec. The retries are counted by the
+// dead letter policy of the Pub/Sub subscription, which supports at most 100 delivery attempts.
+const MaxRetry = 100
+
 func ValidateDeliverySpec(ctx context.Context, spec *eventingduckv1beta1.DeliverySpec) *apis.FieldError {
 	var errs *apis.FieldError
 	if spec.BackoffDelay == nil {
@@ -46,18 +119,26 @@ func ValidateDeliverySpec(ctx context.Context, spec *eventingduckv1beta1.Deliver
 	if spec.Retry != nil && spec.DeadLetterSink == nil {
 		errs = errs.Also(apis.ErrGeneric("need DeadLetterSink when retry is defined", "deadLetterSink"))
 	}
+	if spec.Retry != nil && *spec.Retry > MaxRetry {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(*spec.Retry, 0, MaxRetry, "retry"))
+	}
 	return errs.Also(ValidateDeadLetterSink(ctx, spec.DeadLetterSink).ViaField("deadLetterSink"))
 }
 
+// ValidateDeadLetterSink verifies that the dead letter sink is either a Pub/Sub topic, referred to
+// by a pubsub://<topic-id> URI, or an addressable destination.
 func ValidateDeadLetterSink(ctx context.Context, sink *duckv1.Destination) *apis.FieldError {
 	if sink == nil {
 		return nil
 	}
-	if sink.URI == nil {
-		return apis.ErrMissingField("uri")
-	}
-	if scheme := sink.URI.Scheme; scheme != "pubsub" {
-		return apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub", "uri")
+	if !IsPubsubDeadLetterSink(sink) {
+		if err := duckv1.ValidateDestination(ctx, *sink); err != nil {
+			return err
+		}
+		if sink.Ref == nil && sink.URI.Scheme != "http" && sink.URI.Scheme != "https" {
+			return apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub, http or https", "uri")
+		}
+		return nil
 	}
 	topicID := sink.URI.Host
 	if topicID == "" {
@@ -68,3 +149,60 @@ func ValidateDeadLetterSink(ctx context.Context, sink *duckv1.Destination) *apis
 	}
 	return nil
 }
+
+// IsPubsubDeadLetterSink reports whether the dead letter sink is a Pub/Sub topic, which Pub/Sub
+// forwards the undeliverable messages to. Other dead letter sinks are delivered to by the retry
+// pool.
+func IsPubsubDeadLetterSink(sink *duckv1.Destination) bool {
+	return sink != nil && sink.Ref == nil && sink.URI != nil && sink.URI.Scheme == "pubsub"
+}
+
+// validateRetentionAnnotation verifies that the retention annotation, if present, is a duration
+// supported by Pub/Sub.
+func validateRetentionAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[RetentionAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if d, err := time.ParseDuration(v); err != nil || d < minRetention || d > maxRetention {
+		return apis.ErrOutOfBoundsValue(v, minRetention.String(), maxRetention.String(), RetentionAnnotationKey)
+	}
+	return nil
+}
+
+// validateMaxEventAgeAnnotation verifies that the max event age annotation of a Broker or a Trigger,
+// if present, is a positive duration.
+func validateMaxEventAgeAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[MaxEventAgeAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+		return apis.ErrInvalidValue(v, MaxEventAgeAnnotationKey)
+	}
+	return nil
+}
+
+// validateBrokerCellAnnotation verifies that the brokerCell annotation, if present, is the name of
+// a BrokerCell, optionally prefixed by its namespace, which must be the namespace of the Broker or
+// the system namespace.
+func validateBrokerCellAnnotation(annotations map[string]string, namespace string) *apis.FieldError {
+	v, ok := annotations[BrokerCellAnnotationKey]
+	if !ok {
+		return nil
+	}
+	name := v
+	if i := strings.Index(v, "/"); i >= 0 {
+		if len(validation.IsDNS1123Label(v[:i])) > 0 {
+			return apis.ErrInvalidValue(v, BrokerCellAnnotationKey)
+		}
+		name = v[i+1:]
+	}
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return apis.ErrInvalidValue(v, BrokerCellAnnotationKey)
+	}
+	if i := strings.Index(v, "/"); i >= 0 && !IsBrokerCellNamespaceAllowed(namespace, v[:i]) {
+		return apis.ErrGeneric("BrokerCell must be in the namespace of the Broker or in the system namespace", BrokerCellAnnotationKey)
+	}
+	return nil
+}
@@ -22,16 +22,20 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 	"knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/system"
+	_ "knative.dev/pkg/system/testing"
 )
 
 func TestBroker_Validate(t *testing.T) {
 	bop := eventingduckv1beta1.BackoffPolicyExponential
 	bod := "PT1S"
 	retry := int32(4)
+	tooManyRetries := int32(MaxRetry + 1)
 	tests := []struct {
 		name   string
 		broker Broker
@@ -74,7 +78,20 @@ func TestBroker_Validate(t *testing.T) {
 		},
 		want: apis.ErrGeneric("need DeadLetterSink when retry is defined", "spec.delivery.deadLetterSink"),
 	}, {
-		name: "invalid dead letter sink missing uri",
+		name: "too many retries",
+		broker: Broker{
+			Spec: v1beta1.BrokerSpec{
+				Delivery: &eventingduckv1beta1.DeliverySpec{
+					BackoffDelay:   &bod,
+					BackoffPolicy:  &bop,
+					Retry:          &tooManyRetries,
+					DeadLetterSink: &duckv1.Destination{URI: &apis.URL{Scheme: "http", Host: "dls.example.com"}},
+				},
+			},
+		},
+		want: apis.ErrOutOfBoundsValue(101, 0, MaxRetry, "spec.delivery.retry"),
+	}, {
+		name: "invalid dead letter sink missing uri and ref",
 		broker: Broker{
 			Spec: v1beta1.BrokerSpec{
 				Delivery: &eventingduckv1beta1.DeliverySpec{
@@ -84,9 +101,42 @@ func TestBroker_Validate(t *testing.T) {
 				},
 			},
 		},
-		want: apis.ErrMissingField("spec.delivery.deadLetterSink.uri"),
+		want: apis.ErrGeneric("expected at least one, got none", "spec.delivery.deadLetterSink.ref", "spec.delivery.deadLetterSink.uri"),
 	}, {
 		name: "invalid dead letter sink uri scheme",
+		broker: Broker{
+			Spec: v1beta1.BrokerSpec{
+				Delivery: &eventingduckv1beta1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						URI: &apis.URL{
+							Scheme: "ftp",
+							Host:   "dls.example.com",
+						},
+					},
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub, http or https", "spec.delivery.deadLetterSink.uri"),
+	}, {
+		name: "invalid relative dead letter sink uri",
+		broker: Broker{
+			Spec: v1beta1.BrokerSpec{
+				Delivery: &eventingduckv1beta1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						URI: &apis.URL{
+							Path: "/dls",
+						},
+					},
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("Relative URI is not allowed when Ref and [apiVersion, kind, name] is absent", "spec.delivery.deadLetterSink.uri"),
+	}, {
+		name: "valid http dead letter sink",
 		broker: Broker{
 			Spec: v1beta1.BrokerSpec{
 				Delivery: &eventingduckv1beta1.DeliverySpec{
@@ -95,13 +145,32 @@ func TestBroker_Validate(t *testing.T) {
 					DeadLetterSink: &duckv1.Destination{
 						URI: &apis.URL{
 							Scheme: "http",
-							Host:   "test-topic-id",
+							Host:   "dls.example.com",
+						},
+					},
+				},
+			},
+		},
+	}, {
+		name: "valid addressable dead letter sink",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+			},
+			Spec: v1beta1.BrokerSpec{
+				Delivery: &eventingduckv1beta1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{
+							APIVersion: "serving.knative.dev/v1",
+							Kind:       "Service",
+							Name:       "dls",
 						},
 					},
 				},
 			},
 		},
-		want: apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub", "spec.delivery.deadLetterSink.uri"),
 	}, {
 		name: "invalid empty dead letter topic id",
 		broker: Broker{
@@ -152,6 +221,219 @@ func TestBroker_Validate(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "valid rate limit annotations",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressEventsPerSecondAnnotationKey: "100",
+					IngressEventsBurstAnnotationKey:     "200",
+					IngressBytesPerSecondAnnotationKey:  "1048576.5",
+					IngressBytesBurstAnnotationKey:      "2097152",
+				},
+			},
+		},
+	}, {
+		name: "invalid rate limit annotations",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressEventsPerSecondAnnotationKey: "-1",
+					IngressBytesBurstAnnotationKey:      "1.5",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("-1", "metadata.annotations."+IngressEventsPerSecondAnnotationKey).Also(
+			apis.ErrInvalidValue("1.5", "metadata.annotations."+IngressBytesBurstAnnotationKey)),
+	}, {
+		name: "non-finite rate limit annotations",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressEventsPerSecondAnnotationKey: "NaN",
+					IngressBytesPerSecondAnnotationKey:  "1e400",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("NaN", "metadata.annotations."+IngressEventsPerSecondAnnotationKey).Also(
+			apis.ErrInvalidValue("1e400", "metadata.annotations."+IngressBytesPerSecondAnnotationKey)),
+	}, {
+		name: "infinite rate limit annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressEventsPerSecondAnnotationKey: "+Inf",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("+Inf", "metadata.annotations."+IngressEventsPerSecondAnnotationKey),
+	}, {
+		name: "valid ordering key extension annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					OrderingKeyExtensionAnnotationKey: "partitionkey",
+				},
+			},
+		},
+	}, {
+		name: "invalid ordering key extension annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					OrderingKeyExtensionAnnotationKey: "partition-key",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("partition-key", "metadata.annotations."+OrderingKeyExtensionAnnotationKey),
+	}, {
+		name: "valid ingress auth annotations",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressAllowedAudiencesAnnotationKey: "broker.example.com, other",
+					IngressAllowedSubjectsAnnotationKey:  "system:serviceaccount:ns:sa",
+				},
+			},
+		},
+	}, {
+		name: "empty ingress allowed audiences annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressAllowedAudiencesAnnotationKey: " , ",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue(" , ", "metadata.annotations."+IngressAllowedAudiencesAnnotationKey),
+	}, {
+		name: "ingress allowed subjects annotation without audiences",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					IngressAllowedSubjectsAnnotationKey: "system:serviceaccount:ns:sa",
+				},
+			},
+		},
+		want: apis.ErrMissingField("metadata.annotations." + IngressAllowedAudiencesAnnotationKey),
+	}, {
+		name: "valid retention annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RetentionAnnotationKey: "24h",
+				},
+			},
+		},
+	}, {
+		name: "retention annotation too long",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RetentionAnnotationKey: "720h",
+				},
+			},
+		},
+		want: apis.ErrOutOfBoundsValue("720h", "10m0s", "168h0m0s", "metadata.annotations."+RetentionAnnotationKey),
+	}, {
+		name: "invalid retention annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					RetentionAnnotationKey: "1d",
+				},
+			},
+		},
+		want: apis.ErrOutOfBoundsValue("1d", "10m0s", "168h0m0s", "metadata.annotations."+RetentionAnnotationKey),
+	}, {
+		name: "valid max event age annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					MaxEventAgeAnnotationKey: "36h",
+				},
+			},
+		},
+	}, {
+		name: "negative max event age annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					MaxEventAgeAnnotationKey: "-1h",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("-1h", "metadata.annotations."+MaxEventAgeAnnotationKey),
+	}, {
+		name: "valid broker cell annotation",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "team-a",
+				},
+			},
+		},
+	}, {
+		name: "valid broker cell annotation with the system namespace",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: system.Namespace() + "/team-a",
+				},
+			},
+		},
+	}, {
+		name: "valid broker cell annotation with the broker namespace",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "ns/team-a",
+				},
+			},
+		},
+	}, {
+		name: "broker cell in another namespace",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "other-team/team-a",
+				},
+			},
+		},
+		want: apis.ErrGeneric("BrokerCell must be in the namespace of the Broker or in the system namespace", "metadata.annotations."+BrokerCellAnnotationKey),
+	}, {
+		name: "invalid broker cell name",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "Team_A",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("Team_A", "metadata.annotations."+BrokerCellAnnotationKey),
+	}, {
+		name: "invalid broker cell namespace",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "/team-a",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("/team-a", "metadata.annotations."+BrokerCellAnnotationKey),
+	}, {
+		name: "broker cell annotation with too many segments",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					BrokerCellAnnotationKey: "ns/team/a",
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("ns/team/a", "metadata.annotations."+BrokerCellAnnotationKey),
 	}}
 
 	for _, test := range tests {
@@ -34,6 +34,8 @@ func (t testHelper) ReadyBrokerStatus() *BrokerStatus {
 	bs.MarkSubscriptionReady()
 	bs.MarkTopicReady()
 	bs.MarkBrokerCellReady()
+	bs.MarkDataPlaneReady()
+	bs.MarkDeadLetterSinkResolved()
 	return bs
 }
 
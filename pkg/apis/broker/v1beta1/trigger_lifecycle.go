@@ -17,23 +17,44 @@ limitations under the License.
 package v1beta1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
-var triggerCondSet = apis.NewLivingConditionSet(
+// triggerControlPlaneConditions are the conditions of the resources that the data plane needs to
+// deliver events to the subscriber of the Trigger.
+var triggerControlPlaneConditions = []apis.ConditionType{
 	eventingv1beta1.TriggerConditionBroker,
 	eventingv1beta1.TriggerConditionDependency,
 	eventingv1beta1.TriggerConditionSubscriberResolved,
 	TriggerConditionTopic,
 	TriggerConditionSubscription,
-)
+	TriggerConditionFilters,
+}
+
+var triggerCondSet = apis.NewLivingConditionSet(append(triggerControlPlaneConditions, TriggerConditionDataPlane)...)
 
 const (
 	TriggerConditionTopic        apis.ConditionType = "TopicReady"
 	TriggerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// TriggerConditionFilters reports whether the filters of the Trigger are valid. Events are not
+	// delivered through filters that the data plane can't evaluate.
+	TriggerConditionFilters apis.ConditionType = "FiltersReady"
+	// TriggerConditionDataPlane reports whether the data plane applied the targets config with the
+	// latest spec of the Trigger.
+	TriggerConditionDataPlane apis.ConditionType = "DataPlaneReady"
+	// TriggerConditionReplay reports whether the events retained by the Broker are being replayed
+	// to the subscriber of the Trigger. It is only set while the replay annotation is, and it
+	// doesn't affect the readiness of the Trigger.
+	TriggerConditionReplay apis.ConditionType = "ReplayReady"
+	// TriggerConditionSuspended reports that the delivery to the subscriber of the Trigger is
+	// suspended. It is only set while the Trigger is suspended, and it doesn't affect the readiness
+	// of the Trigger.
+	TriggerConditionSuspended apis.ConditionType = "Suspended"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -51,6 +72,17 @@ func (ts *TriggerStatus) IsReady() bool {
 	return triggerCondSet.Manage(ts).IsHappy()
 }
 
+// IsControlPlaneReady returns true if all the conditions but DataPlaneReady are true, i.e. if the
+// data plane can deliver events to the subscriber once it applies the Trigger.
+func (ts *TriggerStatus) IsControlPlaneReady() bool {
+	for _, t := range triggerControlPlaneConditions {
+		if !ts.GetCondition(t).IsTrue() {
+			return false
+		}
+	}
+	return true
+}
+
 // InitializeConditions sets relevant unset conditions to Unknown state.
 func (ts *TriggerStatus) InitializeConditions() {
 	triggerCondSet.Manage(ts).InitializeConditions()
@@ -112,6 +144,84 @@ func (bs *TriggerStatus) MarkSubscriptionReady() {
 	triggerCondSet.Manage(bs).MarkTrue(TriggerConditionSubscription)
 }
 
+func (ts *TriggerStatus) MarkFiltersReady() {
+	triggerCondSet.Manage(ts).MarkTrue(TriggerConditionFilters)
+}
+
+func (ts *TriggerStatus) MarkFiltersFailed(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkFalse(TriggerConditionFilters, reason, format, args...)
+}
+
+func (ts *TriggerStatus) MarkDataPlaneReady() {
+	triggerCondSet.Manage(ts).MarkTrue(TriggerConditionDataPlane)
+}
+
+func (ts *TriggerStatus) MarkDataPlaneUnknown(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkUnknown(TriggerConditionDataPlane, reason, format, args...)
+}
+
+// MarkDataPlaneNotTracked marks the data plane as ready when it doesn't report the targets config
+// it applied, so that the readiness of the Trigger doesn't wait for it.
+func (ts *TriggerStatus) MarkDataPlaneNotTracked() {
+	triggerCondSet.Manage(ts).MarkTrueWithReason(TriggerConditionDataPlane, "NotTracked",
+		"The data plane doesn't report the targets config it applied")
+}
+
+// MarkReplayReady marks the replay of the events published from the given time until the replay
+// started as ready, and records its bounds in the status annotations.
+func (ts *TriggerStatus) MarkReplayReady(from, until time.Time) {
+	if ts.Annotations == nil {
+		ts.Annotations = make(map[string]string)
+	}
+	ts.Annotations[ReplayFromStatusAnnotationKey] = from.UTC().Format(time.RFC3339Nano)
+	ts.Annotations[ReplayUntilStatusAnnotationKey] = until.UTC().Format(time.RFC3339Nano)
+	triggerCondSet.Manage(ts).MarkTrueWithReason(TriggerConditionReplay, "Replaying",
+		"Replaying the events published from %s until %s", ts.Annotations[ReplayFromStatusAnnotationKey], ts.Annotations[ReplayUntilStatusAnnotationKey])
+}
+
+func (ts *TriggerStatus) MarkReplayFailed(reason, format string, args ...interface{}) {
+	ts.clearReplayAnnotations()
+	triggerCondSet.Manage(ts).MarkFalse(TriggerConditionReplay, reason, format, args...)
+}
+
+// ClearReplay removes the replay condition and bounds once the replay is stopped.
+func (ts *TriggerStatus) ClearReplay() {
+	ts.clearReplayAnnotations()
+	triggerCondSet.Manage(ts).ClearCondition(TriggerConditionReplay)
+}
+
+func (ts *TriggerStatus) clearReplayAnnotations() {
+	delete(ts.Annotations, ReplayFromStatusAnnotationKey)
+	delete(ts.Annotations, ReplayUntilStatusAnnotationKey)
+}
+
+// ReplayWindow returns the bounds of the events being replayed, if the replay is ready.
+func (ts *TriggerStatus) ReplayWindow() (from, until time.Time, ok bool) {
+	if !ts.GetCondition(TriggerConditionReplay).IsTrue() {
+		return time.Time{}, time.Time{}, false
+	}
+	from, err := time.Parse(time.RFC3339Nano, ts.Annotations[ReplayFromStatusAnnotationKey])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	until, err = time.Parse(time.RFC3339Nano, ts.Annotations[ReplayUntilStatusAnnotationKey])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, until, true
+}
+
+// MarkSuspended marks the delivery to the subscriber as suspended.
+func (ts *TriggerStatus) MarkSuspended() {
+	triggerCondSet.Manage(ts).MarkTrueWithReason(TriggerConditionSuspended, "Suspended",
+		"Delivery to the subscriber is suspended, the events are held in the retry topic until the Trigger is resumed")
+}
+
+// MarkResumed removes the suspended condition once the Trigger is resumed.
+func (ts *TriggerStatus) MarkResumed() {
+	triggerCondSet.Manage(ts).ClearCondition(TriggerConditionSuspended)
+}
+
 func (ts *TriggerStatus) MarkSubscriberResolvedSucceeded() {
 	triggerCondSet.Manage(ts).MarkTrue(eventingv1beta1.TriggerConditionSubscriberResolved)
 }
@@ -18,6 +18,7 @@ package v1beta1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -147,9 +148,15 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   TriggerConditionFilters,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -184,9 +191,15 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionFalse,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   TriggerConditionFilters,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -221,9 +234,15 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionTrue,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   TriggerConditionFilters,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -261,6 +280,8 @@ func TestTriggerConditionStatus(t *testing.T) {
 		name                     string
 		brokerStatus             *BrokerStatus
 		topicStatus              corev1.ConditionStatus
+		invalidFilters           bool
+		dataPlaneNotApplied      bool
 		subscriptionStatus       corev1.ConditionStatus
 		subscriberResolvedStatus corev1.ConditionStatus
 		dependencyStatus         *duckv1.Source
@@ -369,6 +390,22 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriberResolvedStatus: corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.FalseDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionFalse,
+	}, {
+		name:                     "invalid filters",
+		brokerStatus:             TestHelper.ReadyBrokerStatus(),
+		subscriptionStatus:       corev1.ConditionTrue,
+		topicStatus:              corev1.ConditionTrue,
+		invalidFilters:           true,
+		subscriberResolvedStatus: corev1.ConditionTrue,
+		wantConditionStatus:      corev1.ConditionFalse,
+	}, {
+		name:                     "data plane not applied",
+		brokerStatus:             TestHelper.ReadyBrokerStatus(),
+		subscriptionStatus:       corev1.ConditionTrue,
+		topicStatus:              corev1.ConditionTrue,
+		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneNotApplied:      true,
+		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
 		name:                     "all sad",
 		brokerStatus:             TestHelper.FalseBrokerStatus(),
@@ -405,6 +442,16 @@ func TestTriggerConditionStatus(t *testing.T) {
 			} else {
 				ts.MarkSubscriberResolvedUnknown("Status of Subscriber URI is unknown", "induced failure")
 			}
+			if test.invalidFilters {
+				ts.MarkFiltersFailed("InvalidFilters", "induced failure")
+			} else {
+				ts.MarkFiltersReady()
+			}
+			if test.dataPlaneNotApplied {
+				ts.MarkDataPlaneUnknown("ConfigNotApplied", "induced unknown")
+			} else {
+				ts.MarkDataPlaneReady()
+			}
 			if test.dependencyStatus == nil {
 				ts.MarkDependencySucceeded()
 			} else {
@@ -428,3 +475,95 @@ func TestTriggerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestTriggerReplay(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkTopicReady()
+	ts.MarkSubscriptionReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkFiltersReady()
+	ts.MarkDependencySucceeded()
+	ts.MarkDataPlaneReady()
+
+	if _, _, ok := ts.ReplayWindow(); ok {
+		t.Error("ReplayWindow() ok before the replay is ready")
+	}
+
+	from := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	until := from.Add(2 * time.Hour)
+	ts.MarkReplayReady(from, until)
+	gotFrom, gotUntil, ok := ts.ReplayWindow()
+	if !ok || !gotFrom.Equal(from) || !gotUntil.Equal(until) {
+		t.Errorf("ReplayWindow() = %v, %v, %v, want %v, %v, true", gotFrom, gotUntil, ok, from, until)
+	}
+
+	ts.MarkReplayFailed("RetentionDisabled", "induced failure")
+	if _, _, ok := ts.ReplayWindow(); ok {
+		t.Error("ReplayWindow() ok after the replay failed")
+	}
+	if !ts.IsReady() {
+		t.Error("failed replay made the trigger not ready")
+	}
+
+	ts.MarkReplayReady(from, until)
+	ts.ClearReplay()
+	if got := ts.GetCondition(TriggerConditionReplay); got != nil {
+		t.Errorf("replay condition got=%v, want cleared", got)
+	}
+	if len(ts.Annotations) != 0 {
+		t.Errorf("status annotations got=%v, want none", ts.Annotations)
+	}
+}
+
+func TestTriggerSuspended(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkTopicReady()
+	ts.MarkSubscriptionReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkFiltersReady()
+	ts.MarkDependencySucceeded()
+	ts.MarkDataPlaneReady()
+
+	ts.MarkSuspended()
+	if got := ts.GetCondition(TriggerConditionSuspended); !got.IsTrue() {
+		t.Errorf("suspended condition got=%v, want true", got)
+	}
+	if !ts.IsReady() {
+		t.Error("suspending made the trigger not ready")
+	}
+
+	ts.MarkResumed()
+	if got := ts.GetCondition(TriggerConditionSuspended); got != nil {
+		t.Errorf("suspended condition got=%v, want cleared", got)
+	}
+}
+
+func TestTriggerDataPlane(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkTopicReady()
+	ts.MarkSubscriptionReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkFiltersReady()
+	ts.MarkDependencySucceeded()
+
+	ts.MarkDataPlaneUnknown("ConfigNotApplied", "induced unknown")
+	if ts.IsReady() {
+		t.Error("expected the trigger not to be ready before the data plane applied it")
+	}
+	if !ts.IsControlPlaneReady() {
+		t.Error("expected the control plane of the trigger to be ready")
+	}
+
+	ts.MarkDataPlaneNotTracked()
+	if !ts.IsReady() {
+		t.Error("expected the trigger to be ready when the data plane isn't tracked")
+	}
+
+	ts.MarkTopicFailed("TopicDeleted", "induced failure")
+	if ts.IsControlPlaneReady() {
+		t.Error("expected the control plane of the trigger not to be ready")
+	}
+}
@@ -17,6 +17,13 @@ limitations under the License.
 package v1beta1
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -33,8 +40,134 @@ const (
 	// InjectionAnnotation is the annotation key used to enable knative eventing injection for a namespace and automatically create a default broker.
 	// This will be used when the client creates a trigger paired with default broker and the default broker doesn't exist in the namespace
 	InjectionAnnotation = "knative-eventing-injection"
+	// FiltersAnnotationKey is the annotation key for the filters of a Trigger in the dialects of the
+	// CloudEvents Subscriptions API, as a JSON array of SubscriptionsAPIFilter. An event must pass
+	// all of them, as well as the attributes filter of the spec. For example:
+	//   [{"prefix": {"type": "com.example.orders."}}, {"not": {"exact": {"source": "test"}}}]
+	// The "sql" dialect takes a CloudEvents SQL expression, e.g.
+	//   [{"sql": "type LIKE 'com.example.%' AND severity > 3"}]
+	FiltersAnnotationKey = "events.cloud.google.com/filters"
+	// MaxConcurrencyAnnotationKey is the annotation key for the maximum number of events delivered
+	// to the subscriber of a Trigger at the same time. Events above the limit are retried later.
+	MaxConcurrencyAnnotationKey = "events.cloud.google.com/maxConcurrency"
+	// MaxRequestsPerSecondAnnotationKey is the annotation key for the maximum number of requests
+	// per second sent to the subscriber of a Trigger. Events above the limit are retried later.
+	MaxRequestsPerSecondAnnotationKey = "events.cloud.google.com/maxRequestsPerSecond"
+	// SubscriberAudienceAnnotationKey is the annotation key for the audience of the Google-signed ID
+	// token sent to the subscriber of a Trigger, e.g. the URL of an authenticated Cloud Run service.
+	// The audience must be an HTTP(S) URL on the host of the subscriber, and an empty value stands
+	// for the origin of the subscriber URI. The token is minted for the identity of the BrokerCell.
+	// No token is sent if unset.
+	SubscriberAudienceAnnotationKey = "events.cloud.google.com/subscriberAudience"
+	// BatchMaxCountAnnotationKey is the annotation key for the maximum number of events delivered to
+	// the subscriber of a Trigger in a single request. Setting it enables batch delivery: the events
+	// are sent together as a JSON array in the application/cloudevents-batch+json format.
+	BatchMaxCountAnnotationKey = "events.cloud.google.com/batchMaxCount"
+	// BatchMaxBytesAnnotationKey is the annotation key for the maximum size in bytes of the batches
+	// of events delivered to the subscriber of a Trigger. Batches are not limited in size if unset.
+	BatchMaxBytesAnnotationKey = "events.cloud.google.com/batchMaxBytes"
+	// BatchMaxLatencyAnnotationKey is the annotation key for the longest an event waits for its batch
+	// to fill up before the batch is delivered, as a duration such as "500ms". Defaults to 1s.
+	BatchMaxLatencyAnnotationKey = "events.cloud.google.com/batchMaxLatency"
+	// ReplayFromAnnotationKey is the annotation key for the RFC 3339 timestamp from which the events
+	// retained by the Broker are replayed to the subscriber of a Trigger, e.g.
+	// "2021-03-01T12:00:00Z". It requires the Broker to retain events, see RetentionAnnotationKey.
+	// The events published from that time until the replay started are delivered again, alongside
+	// the new events. The replay is stopped by removing the annotation, and restarted by changing it.
+	ReplayFromAnnotationKey = "events.cloud.google.com/replayFrom"
+	// SuspendedAnnotationKey is the annotation key for suspending the delivery to the subscriber of
+	// a Trigger, e.g. during its maintenance. While it is "true", the events matching the Trigger
+	// are held in its retry topic, and they are delivered once it is removed or set to "false".
+	SuspendedAnnotationKey = "events.cloud.google.com/suspended"
+
+	// ReplayFromStatusAnnotationKey and ReplayUntilStatusAnnotationKey are the status annotation
+	// keys for the RFC 3339 bounds of the events being replayed to the subscriber of a Trigger.
+	ReplayFromStatusAnnotationKey  = "replayFrom"
+	ReplayUntilStatusAnnotationKey = "replayUntil"
 )
 
+// SubscriptionsAPIFilter is a filter in the dialects of the CloudEvents Subscriptions API. Exactly
+// one dialect must be set.
+// +k8s:deepcopy-gen=false
+type SubscriptionsAPIFilter struct {
+	// All passes if all of the nested filters pass.
+	All []SubscriptionsAPIFilter `json:"all,omitempty"`
+	// Any passes if any of the nested filters passes.
+	Any []SubscriptionsAPIFilter `json:"any,omitempty"`
+	// Not passes if the nested filter doesn't pass.
+	Not *SubscriptionsAPIFilter `json:"not,omitempty"`
+	// Exact passes if each attribute is equal to the given value.
+	Exact map[string]string `json:"exact,omitempty"`
+	// Prefix passes if each attribute starts with the given value.
+	Prefix map[string]string `json:"prefix,omitempty"`
+	// Suffix passes if each attribute ends with the given value.
+	Suffix map[string]string `json:"suffix,omitempty"`
+	// SQL passes if the CloudEvents SQL expression evaluates to true.
+	SQL string `json:"sql,omitempty"`
+}
+
+// Filters returns the Subscriptions API filters of the Trigger set through FiltersAnnotationKey.
+func (t *Trigger) Filters() ([]SubscriptionsAPIFilter, error) {
+	v, ok := t.GetAnnotations()[FiltersAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	var filters []SubscriptionsAPIFilter
+	d := json.NewDecoder(strings.NewReader(v))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// SubscriberAudience returns the audience of the ID tokens sent to the resolved subscriber of the
+// Trigger set through SubscriberAudienceAnnotationKey, or an empty string if no token is sent.
+// Audiences on another host than the subscriber's are rejected, so that a Trigger can't obtain
+// tokens for services it doesn't deliver to.
+func (t *Trigger) SubscriberAudience() (string, error) {
+	v, ok := t.GetAnnotations()[SubscriberAudienceAnnotationKey]
+	if !ok {
+		return "", nil
+	}
+	subscriber := t.Status.SubscriberURI
+	if subscriber == nil || subscriber.Host == "" {
+		return "", errors.New("the subscriber URI is not resolved")
+	}
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return subscriber.Scheme + "://" + subscriber.Host, nil
+	}
+	audience, err := parseSubscriberAudience(v)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(audience.Host, subscriber.Host) {
+		return "", fmt.Errorf("audience %q is not on the host %s of the subscriber", v, subscriber.Host)
+	}
+	return v, nil
+}
+
+// parseSubscriberAudience parses a non-empty subscriber audience, which must be an absolute HTTP(S)
+// URL.
+func parseSubscriberAudience(v string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("audience %q is not an absolute HTTP(S) URL", v)
+	}
+	return u, nil
+}
+
+// IsSuspended returns true if the delivery to the subscriber of the Trigger is suspended through
+// SuspendedAnnotationKey.
+func (t *Trigger) IsSuspended() bool {
+	suspended, _ := strconv.ParseBool(t.GetAnnotations()[SuspendedAnnotationKey])
+	return suspended
+}
+
 // +genclient
 // +genreconciler
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
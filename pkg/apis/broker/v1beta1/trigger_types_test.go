@@ -64,3 +64,57 @@ func TestTrigger_GetStatus(t *testing.T) {
 		t.Errorf("GetStatus=%v, want=%v", got, want)
 	}
 }
+
+func TestTrigger_SubscriberAudience(t *testing.T) {
+	subscriberURI := apis.HTTPS("subscriber.example.com")
+	subscriberURI.Path = "/events"
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		subscriber  *apis.URL
+		want        string
+		wantErr     bool
+	}{{
+		name:       "no annotation",
+		subscriber: subscriberURI,
+	}, {
+		name:        "audience on the subscriber host",
+		annotations: map[string]string{SubscriberAudienceAnnotationKey: "https://subscriber.example.com/events"},
+		subscriber:  subscriberURI,
+		want:        "https://subscriber.example.com/events",
+	}, {
+		name:        "empty audience defaults to the subscriber origin",
+		annotations: map[string]string{SubscriberAudienceAnnotationKey: ""},
+		subscriber:  subscriberURI,
+		want:        "https://subscriber.example.com",
+	}, {
+		name:        "audience on another host",
+		annotations: map[string]string{SubscriberAudienceAnnotationKey: "https://victim.example.com"},
+		subscriber:  subscriberURI,
+		wantErr:     true,
+	}, {
+		name:        "audience not a URL",
+		annotations: map[string]string{SubscriberAudienceAnnotationKey: "client-id"},
+		subscriber:  subscriberURI,
+		wantErr:     true,
+	}, {
+		name:        "subscriber not resolved",
+		annotations: map[string]string{SubscriberAudienceAnnotationKey: "https://subscriber.example.com"},
+		wantErr:     true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &Trigger{}
+			tr.Annotations = tc.annotations
+			tr.Status.SubscriberURI = tc.subscriber
+			got, err := tr.SubscriberAudience()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SubscriberAudience() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("SubscriberAudience() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -18,13 +18,229 @@ package v1beta1
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"knative.dev/pkg/apis"
+
+	"github.com/google/knative-gcp/pkg/utils/cesql"
 )
 
+// maxBatchLatency is the longest an event can wait for its batch. Batches must be delivered well
+// before the Pub/Sub messages of their events need to be acked.
+const maxBatchLatency = time.Minute
+
 // Validate the Trigger.
 func (t *Trigger) Validate(ctx context.Context) *apis.FieldError {
-	// The Google Cloud Broker doesn't have any custom validations. The
-	// eventing webhook will run the usual validations.
+	// We validate the GCP Trigger's annotations. The eventing webhook will run the usual
+	// validations.
+	if apis.IsInStatusUpdate(ctx) {
+		// Triggers with invalid filters created before they were validated must still be able to
+		// report them in their status.
+		return nil
+	}
+	return t.ValidateFilters().
+		Also(validateDeliveryLimitAnnotations(t.GetAnnotations()).
+			Also(validateSubscriberAudienceAnnotation(t)).
+			Also(validateBatchAnnotations(t.GetAnnotations())).
+			Also(validateReplayFromAnnotation(t.GetAnnotations())).
+			Also(validateSuspendedAnnotation(t.GetAnnotations())).
+			Also(validateMaxEventAgeAnnotation(t.GetAnnotations())).
+			ViaField("metadata", "annotations"))
+}
+
+// ValidateFilters verifies that the filters annotation, if present, is a valid list of
+// Subscriptions API filters. The trigger reconciler uses it to report filters that were not
+// rejected by the webhook.
+func (t *Trigger) ValidateFilters() *apis.FieldError {
+	return validateFiltersAnnotation(t).ViaField("metadata", "annotations")
+}
+
+func validateFiltersAnnotation(t *Trigger) *apis.FieldError {
+	filters, err := t.Filters()
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), FiltersAnnotationKey)
+	}
+	var errs *apis.FieldError
+	for i := range filters {
+		errs = errs.Also(filters[i].Validate().ViaIndex(i))
+	}
+	return errs.ViaField(FiltersAnnotationKey)
+}
+
+// validateDeliveryLimitAnnotations verifies that the delivery limit annotations, if present, are
+// positive numbers.
+func validateDeliveryLimitAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	if v, ok := annotations[MaxConcurrencyAnnotationKey]; ok {
+		if i, err := strconv.ParseInt(v, 10, 32); err != nil || i <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, MaxConcurrencyAnnotationKey))
+		}
+	}
+	if v, ok := annotations[MaxRequestsPerSecondAnnotationKey]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, MaxRequestsPerSecondAnnotationKey))
+		}
+	}
+	return errs
+}
+
+// Validate verifies that exactly one dialect of the filter is set, and that it is valid.
+func (f *SubscriptionsAPIFilter) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	dialects := 0
+	if f.All != nil {
+		dialects++
+		errs = errs.Also(validateNestedFilters(f.All).ViaField("all"))
+	}
+	if f.Any != nil {
+		dialects++
+		errs = errs.Also(validateNestedFilters(f.Any).ViaField("any"))
+	}
+	if f.Not != nil {
+		dialects++
+		errs = errs.Also(f.Not.Validate().ViaField("not"))
+	}
+	if f.Exact != nil {
+		dialects++
+		errs = errs.Also(validateFilterAttributes(f.Exact).ViaField("exact"))
+	}
+	if f.Prefix != nil {
+		dialects++
+		errs = errs.Also(validateFilterAttributes(f.Prefix).ViaField("prefix"))
+	}
+	if f.Suffix != nil {
+		dialects++
+		errs = errs.Also(validateFilterAttributes(f.Suffix).ViaField("suffix"))
+	}
+	if f.SQL != "" {
+		dialects++
+		if _, err := cesql.Parse(f.SQL); err != nil {
+			errs = errs.Also(&apis.FieldError{
+				Message: "invalid CESQL expression",
+				Paths:   []string{"sql"},
+				Details: err.Error(),
+			})
+		}
+	}
+	switch {
+	case dialects == 0:
+		errs = errs.Also(apis.ErrMissingOneOf("all", "any", "not", "exact", "prefix", "suffix", "sql"))
+	case dialects > 1:
+		errs = errs.Also(apis.ErrGeneric("expected exactly one dialect, got multiple", apis.CurrentField))
+	}
+	return errs
+}
+
+func validateNestedFilters(filters []SubscriptionsAPIFilter) *apis.FieldError {
+	if len(filters) == 0 {
+		return apis.ErrGeneric("expected at least one filter", apis.CurrentField)
+	}
+	var errs *apis.FieldError
+	for i := range filters {
+		errs = errs.Also(filters[i].Validate().ViaIndex(i))
+	}
+	return errs
+}
+
+// validateFilterAttributes verifies that the attribute names are valid CloudEvents attribute
+// names, and that the values are not empty.
+func validateFilterAttributes(attrs map[string]string) *apis.FieldError {
+	if len(attrs) == 0 {
+		return apis.ErrGeneric("expected at least one attribute", apis.CurrentField)
+	}
+	var errs *apis.FieldError
+	for k, v := range attrs {
+		if !isValidAttributeName(k) {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField, "attribute names must consist of lower-case letters or digits"))
+		} else if v == "" {
+			errs = errs.Also(apis.ErrInvalidValue(v, k))
+		}
+	}
+	return errs
+}
+
+// isValidAttributeName returns true if the name is a valid CloudEvents attribute name.
+func isValidAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSubscriberAudienceAnnotation verifies that the subscriber audience annotation, if present
+// and not empty, is an HTTP(S) URL. When the subscriber is set as a URI, the audience must be on its
+// host; the audiences of the subscribers set as a reference are checked once they are resolved.
+func validateSubscriberAudienceAnnotation(t *Trigger) *apis.FieldError {
+	v := strings.TrimSpace(t.GetAnnotations()[SubscriberAudienceAnnotationKey])
+	if v == "" {
+		return nil
+	}
+	audience, err := parseSubscriberAudience(v)
+	if err != nil {
+		return apis.ErrInvalidValue(v, SubscriberAudienceAnnotationKey)
+	}
+	if sub := t.Spec.Subscriber; sub.Ref == nil && sub.URI != nil && !strings.EqualFold(audience.Host, sub.URI.Host) {
+		return apis.ErrGeneric(fmt.Sprintf("audience must be on the host %s of the subscriber", sub.URI.Host), SubscriberAudienceAnnotationKey)
+	}
+	return nil
+}
+
+func validateBatchAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	count, hasCount := annotations[BatchMaxCountAnnotationKey]
+	if hasCount {
+		if i, err := strconv.ParseInt(count, 10, 32); err != nil || i <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(count, BatchMaxCountAnnotationKey))
+		}
+	}
+	maxBytes, hasMaxBytes := annotations[BatchMaxBytesAnnotationKey]
+	if hasMaxBytes {
+		if i, err := strconv.ParseInt(maxBytes, 10, 64); err != nil || i <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(maxBytes, BatchMaxBytesAnnotationKey))
+		}
+	}
+	maxLatency, hasMaxLatency := annotations[BatchMaxLatencyAnnotationKey]
+	if hasMaxLatency {
+		if d, err := time.ParseDuration(maxLatency); err != nil || d <= 0 || d > maxBatchLatency {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(maxLatency, "0s", maxBatchLatency.String(), BatchMaxLatencyAnnotationKey))
+		}
+	}
+	if !hasCount && (hasMaxBytes || hasMaxLatency) {
+		// The other settings don't enable batch delivery on their own.
+		errs = errs.Also(apis.ErrMissingField(BatchMaxCountAnnotationKey))
+	}
+	return errs
+}
+
+// validateReplayFromAnnotation verifies that the replay annotation, if present, is an RFC 3339
+// timestamp.
+func validateReplayFromAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[ReplayFromAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, v); err != nil {
+		return apis.ErrInvalidValue(v, ReplayFromAnnotationKey)
+	}
+	return nil
+}
+
+// validateSuspendedAnnotation verifies that the suspended annotation, if present, is a boolean.
+func validateSuspendedAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[SuspendedAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := strconv.ParseBool(v); err != nil {
+		return apis.ErrInvalidValue(v, SuspendedAnnotationKey)
+	}
 	return nil
 }
@@ -18,7 +18,12 @@ package v1beta1
 
 import (
 	"context"
+	"strings"
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
 func TestTrigger_Validate(t *testing.T) {
@@ -27,3 +32,369 @@ func TestTrigger_Validate(t *testing.T) {
 		t.Errorf("expected nil, got %v", err)
 	}
 }
+
+func TestTrigger_ValidateFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters string
+		// wantErr is a substring of the expected error, or empty if no error is expected.
+		wantErr string
+	}{{
+		name:    "valid filters",
+		filters: `[{"prefix": {"type": "com.example."}}, {"not": {"any": [{"exact": {"source": "a"}}, {"suffix": {"subject": ".tmp"}}]}}, {"all": [{"exact": {"myext": "1"}}]}]`,
+	}, {
+		name:    "valid SQL",
+		filters: `[{"sql": "type LIKE 'com.example.%' AND severity > 3"}]`,
+	}, {
+		name:    "invalid SQL",
+		filters: `[{"any": [{"exact": {"type": "a"}}, {"sql": "type LIKE"}]}]`,
+		wantErr: "invalid CESQL expression: metadata.annotations." + FiltersAnnotationKey + "[0].any[1].sql",
+	}, {
+		name:    "SQL with another dialect",
+		filters: `[{"sql": "TRUE", "exact": {"type": "a"}}]`,
+		wantErr: "expected exactly one dialect, got multiple",
+	}, {
+		name:    "invalid JSON",
+		filters: `{"prefix": {"type": "com.example."}}`,
+		wantErr: "invalid value",
+	}, {
+		name:    "unknown dialect",
+		filters: `[{"startsWith": {"type": "com.example."}}]`,
+		wantErr: "unknown field",
+	}, {
+		name:    "no dialect",
+		filters: `[{}]`,
+		wantErr: "expected exactly one, got neither",
+	}, {
+		name:    "multiple dialects",
+		filters: `[{"exact": {"type": "a"}, "prefix": {"type": "b"}}]`,
+		wantErr: "expected exactly one dialect, got multiple",
+	}, {
+		name:    "empty nested filters",
+		filters: `[{"any": []}]`,
+		wantErr: "expected at least one filter: metadata.annotations." + FiltersAnnotationKey + "[0].any",
+	}, {
+		name:    "invalid nested filter",
+		filters: `[{"not": {"prefix": {"type": ""}}}]`,
+		wantErr: "metadata.annotations." + FiltersAnnotationKey + "[0].not.prefix.type",
+	}, {
+		name:    "invalid attribute name",
+		filters: `[{"exact": {"Type": "a"}}]`,
+		wantErr: "invalid key name \"Type\"",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{FiltersAnnotationKey: tc.filters},
+			}}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateDeliveryLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     string
+	}{{
+		name: "valid limits",
+		annotations: map[string]string{
+			MaxConcurrencyAnnotationKey:       "10",
+			MaxRequestsPerSecondAnnotationKey: "0.5",
+		},
+	}, {
+		name:        "zero concurrency",
+		annotations: map[string]string{MaxConcurrencyAnnotationKey: "0"},
+		wantErr:     "invalid value: 0: metadata.annotations." + MaxConcurrencyAnnotationKey,
+	}, {
+		name:        "fractional concurrency",
+		annotations: map[string]string{MaxConcurrencyAnnotationKey: "1.5"},
+		wantErr:     "invalid value: 1.5: metadata.annotations." + MaxConcurrencyAnnotationKey,
+	}, {
+		name:        "negative rate",
+		annotations: map[string]string{MaxRequestsPerSecondAnnotationKey: "-1"},
+		wantErr:     "invalid value: -1: metadata.annotations." + MaxRequestsPerSecondAnnotationKey,
+	}, {
+		name:        "invalid rate",
+		annotations: map[string]string{MaxRequestsPerSecondAnnotationKey: "fast"},
+		wantErr:     "invalid value: fast: metadata.annotations." + MaxRequestsPerSecondAnnotationKey,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateSubscriberAudience(t *testing.T) {
+	tests := []struct {
+		name       string
+		audience   string
+		subscriber duckv1.Destination
+		wantErr    string
+	}{{
+		name:     "valid audience",
+		audience: "https://subscriber.example.com",
+	}, {
+		name:     "empty audience",
+		audience: " ",
+	}, {
+		name:     "audience not a URL",
+		audience: "client-id",
+		wantErr:  "invalid value: client-id: metadata.annotations." + SubscriberAudienceAnnotationKey,
+	}, {
+		name:       "audience on the host of the subscriber URI",
+		audience:   "https://subscriber.example.com",
+		subscriber: duckv1.Destination{URI: apis.HTTPS("subscriber.example.com")},
+	}, {
+		name:       "audience on another host than the subscriber URI",
+		audience:   "https://victim.example.com",
+		subscriber: duckv1.Destination{URI: apis.HTTPS("subscriber.example.com")},
+		wantErr:    "audience must be on the host subscriber.example.com of the subscriber: metadata.annotations." + SubscriberAudienceAnnotationKey,
+	}, {
+		name:     "audience of a subscriber reference",
+		audience: "https://subscriber.example.com",
+		subscriber: duckv1.Destination{
+			Ref: &duckv1.KReference{Kind: "Service", APIVersion: "v1", Name: "subscriber"},
+			URI: &apis.URL{Path: "/events"},
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SubscriberAudienceAnnotationKey: tc.audience},
+			}}
+			trig.Spec.Subscriber = tc.subscriber
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     string
+	}{{
+		name: "count only",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey: "100",
+		},
+	}, {
+		name: "all settings",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey:   "100",
+			BatchMaxBytesAnnotationKey:   "1000000",
+			BatchMaxLatencyAnnotationKey: "500ms",
+		},
+	}, {
+		name: "invalid count",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey: "0",
+		},
+		wantErr: "invalid value: 0: metadata.annotations." + BatchMaxCountAnnotationKey,
+	}, {
+		name: "invalid bytes",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey: "100",
+			BatchMaxBytesAnnotationKey: "1MB",
+		},
+		wantErr: "invalid value: 1MB: metadata.annotations." + BatchMaxBytesAnnotationKey,
+	}, {
+		name: "latency too long",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey:   "100",
+			BatchMaxLatencyAnnotationKey: "2m",
+		},
+		wantErr: "expected 0s <= 2m <= 1m0s: metadata.annotations." + BatchMaxLatencyAnnotationKey,
+	}, {
+		name: "invalid latency",
+		annotations: map[string]string{
+			BatchMaxCountAnnotationKey:   "100",
+			BatchMaxLatencyAnnotationKey: "soon",
+		},
+		wantErr: "metadata.annotations." + BatchMaxLatencyAnnotationKey,
+	}, {
+		name: "latency without count",
+		annotations: map[string]string{
+			BatchMaxLatencyAnnotationKey: "500ms",
+		},
+		wantErr: "missing field(s): metadata.annotations." + BatchMaxCountAnnotationKey,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateReplayFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		replayFrom string
+		wantErr    string
+	}{{
+		name:       "UTC timestamp",
+		replayFrom: "2021-03-01T12:00:00Z",
+	}, {
+		name:       "timestamp with offset",
+		replayFrom: "2021-03-01T12:00:00.5+01:00",
+	}, {
+		name:       "date only",
+		replayFrom: "2021-03-01",
+		wantErr:    "invalid value: 2021-03-01: metadata.annotations." + ReplayFromAnnotationKey,
+	}, {
+		name:       "relative time",
+		replayFrom: "-1h",
+		wantErr:    "invalid value: -1h: metadata.annotations." + ReplayFromAnnotationKey,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{ReplayFromAnnotationKey: tc.replayFrom},
+			}}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateSuspended(t *testing.T) {
+	tests := []struct {
+		name      string
+		suspended string
+		want      bool
+		wantErr   string
+	}{{
+		name:      "suspended",
+		suspended: "true",
+		want:      true,
+	}, {
+		name:      "resumed",
+		suspended: "false",
+	}, {
+		name:      "not a boolean",
+		suspended: "yes",
+		wantErr:   "invalid value: yes: metadata.annotations." + SuspendedAnnotationKey,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SuspendedAnnotationKey: tc.suspended},
+			}}
+			if got := trig.IsSuspended(); got != tc.want {
+				t.Errorf("IsSuspended() = %v, want %v", got, tc.want)
+			}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateMaxEventAge(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxEventAge string
+		wantErr     string
+	}{{
+		name:        "duration",
+		maxEventAge: "90m",
+	}, {
+		name:        "zero",
+		maxEventAge: "0s",
+		wantErr:     "invalid value: 0s: metadata.annotations." + MaxEventAgeAnnotationKey,
+	}, {
+		name:        "not a duration",
+		maxEventAge: "2d",
+		wantErr:     "invalid value: 2d: metadata.annotations." + MaxEventAgeAnnotationKey,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{MaxEventAgeAnnotationKey: tc.maxEventAge},
+			}}
+			err := trig.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateStatusUpdate(t *testing.T) {
+	trig := Trigger{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{FiltersAnnotationKey: `[{"sql": "type LIKE"}]`},
+	}}
+	ctx := apis.WithinSubResourceUpdate(context.Background(), &trig, "status")
+	if err := trig.Validate(ctx); err != nil {
+		t.Errorf("Validate() = %v, want no error in status updates", err)
+	}
+}
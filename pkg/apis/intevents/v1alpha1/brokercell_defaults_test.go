@@ -67,7 +67,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 				},
 			},
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest:        fanoutSpecPrefix + customCPURequest,
 						CPULimit:          fanoutSpecPrefix + customCPULimit,
@@ -108,7 +108,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 				},
 			},
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest:        fanoutSpecPrefix + customCPURequest,
 						CPULimit:          fanoutSpecPrefix + customCPULimit,
@@ -146,7 +146,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		name: "Defaulting for resource specification is not applied when some of the parameters are specified",
 		start: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest: "10000",
 					},
@@ -155,7 +155,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		},
 		want: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: (&ComponentParameters{
 						CPURequest:        "10000",
 						CPULimit:          "",
@@ -173,7 +173,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		name: "Defaulting for resource specification is not applied when a target CPU or memory parameter is specified",
 		start: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						AvgCPUUtilization: ptr.Int32(95),
 					},
@@ -182,7 +182,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		},
 		want: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: (&ComponentParameters{
 						AvgCPUUtilization: ptr.Int32(95),
 						AvgMemoryUsage:    nil,
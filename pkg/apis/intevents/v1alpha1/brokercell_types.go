@@ -124,6 +124,28 @@ type BrokerCellSpec struct {
 	// Components specifies parameters of each component (fanout, ingress,
 	// retry) of a BrokerCell.
 	Components ComponentsParametersSpec `json:"components,omitempty"`
+
+	// DeliveryTLS specifies the certificates used by the fanout and retry
+	// components to deliver events to subscribers over TLS.
+	// +optional
+	DeliveryTLS *DeliveryTLSSpec `json:"deliveryTLS,omitempty"`
+}
+
+// DeliveryTLSSpec references the Secrets, in the namespace of the BrokerCell,
+// holding the certificates used to deliver events to subscribers. The fanout
+// and retry pods mount the Secrets and reload the certificates when the Secrets
+// are updated.
+type DeliveryTLSSpec struct {
+	// CASecretName is the name of a Secret holding a PEM encoded bundle of CA
+	// certificates under the ca.crt key. They are trusted in addition to the
+	// system roots to verify the subscribers.
+	// +optional
+	CASecretName string `json:"caSecretName,omitempty"`
+
+	// ClientCertSecretName is the name of a kubernetes.io/tls Secret holding
+	// the client certificate and key presented to the subscribers.
+	// +optional
+	ClientCertSecretName string `json:"clientCertSecretName,omitempty"`
 }
 
 // BrokerCellStatus represents the current state of a BrokerCell.
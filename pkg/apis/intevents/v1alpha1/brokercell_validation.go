@@ -19,9 +19,11 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	resourceutil "github.com/google/knative-gcp/pkg/utils/resource"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 )
 
@@ -42,6 +44,35 @@ func (bcs *BrokerCellSpec) Validate(ctx context.Context) *apis.FieldError {
 	if bcs.Components.Retry != nil {
 		fieldErrors = bcs.Components.Retry.ValidateResourceRequirementSpecification(fieldErrors, "components.retry")
 	}
+	if bcs.DeliveryTLS != nil {
+		fieldErrors = fieldErrors.Also(bcs.DeliveryTLS.Validate(ctx).ViaField("deliveryTLS"))
+	}
+	return fieldErrors
+}
+
+// Validate verifies that the delivery TLS Secrets are valid Secret names, and that at least one of
+// them is set.
+func (tls *DeliveryTLSSpec) Validate(ctx context.Context) *apis.FieldError {
+	if tls.CASecretName == "" && tls.ClientCertSecretName == "" {
+		return apis.ErrMissingOneOf("caSecretName", "clientCertSecretName")
+	}
+	var fieldErrors *apis.FieldError
+	for _, secret := range []struct {
+		field string
+		name  string
+	}{
+		{field: "caSecretName", name: tls.CASecretName},
+		{field: "clientCertSecretName", name: tls.ClientCertSecretName},
+	} {
+		if secret.name == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(secret.name); len(errs) > 0 {
+			invalidValueError := apis.ErrInvalidValue(secret.name, secret.field)
+			invalidValueError.Details = strings.Join(errs, ", ")
+			fieldErrors = fieldErrors.Also(invalidValueError)
+		}
+	}
 	return fieldErrors
 }
 
@@ -18,9 +18,11 @@ package v1alpha1
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
 )
@@ -180,6 +182,48 @@ func TestBrokerCell_Validate(t *testing.T) {
 			},
 			want: nil,
 		},
+		{
+			name: "Valid delivery TLS",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.DeliveryTLS = &DeliveryTLSSpec{
+						CASecretName:         "subscribers-ca",
+						ClientCertSecretName: "broker-client-cert",
+					}
+					return spec
+				}()),
+			},
+			want: nil,
+		},
+		{
+			name: "Delivery TLS without Secrets",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.DeliveryTLS = &DeliveryTLSSpec{}
+					return spec
+				}()),
+			},
+			want: apis.ErrMissingOneOf("spec.deliveryTLS.caSecretName", "spec.deliveryTLS.clientCertSecretName"),
+		},
+		{
+			name: "Delivery TLS with an invalid Secret name",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.DeliveryTLS = &DeliveryTLSSpec{
+						CASecretName: "Invalid_Name",
+					}
+					return spec
+				}()),
+			},
+			want: func() *apis.FieldError {
+				fe := apis.ErrInvalidValue("Invalid_Name", "spec.deliveryTLS.caSecretName")
+				fe.Details = strings.Join(validation.IsDNS1123Subdomain("Invalid_Name"), ", ")
+				return fe
+			}(),
+		},
 	}
 
 	for _, test := range tests {
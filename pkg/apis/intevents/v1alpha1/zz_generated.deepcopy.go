@@ -89,6 +89,11 @@ func (in *BrokerCellList) DeepCopyObject() runtime.Object {
 func (in *BrokerCellSpec) DeepCopyInto(out *BrokerCellSpec) {
 	*out = *in
 	in.Components.DeepCopyInto(&out.Components)
+	if in.DeliveryTLS != nil {
+		in, out := &in.DeliveryTLS, &out.DeliveryTLS
+		*out = new(DeliveryTLSSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -186,6 +191,22 @@ func (in *ComponentsParametersSpec) DeepCopy() *ComponentsParametersSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryTLSSpec) DeepCopyInto(out *DeliveryTLSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryTLSSpec.
+func (in *DeliveryTLSSpec) DeepCopy() *DeliveryTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSpecification) DeepCopyInto(out *ResourceSpecification) {
 	*out = *in
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backpressure adapts how many events are pulled for each target to the health of the
+// target, so that a subscriber which is failing or slow isn't flooded with retries.
+//
+// The limit of a target starts at the maximum. At every interval, it's halved if the deliveries to
+// the target over the interval mostly failed or were slow. If the target is still unhealthy at a
+// limit of one event, pulling is paused for a while, after which it resumes with one event. The
+// limit then doubles at every interval with successful deliveries, until it's back to the maximum.
+package backpressure
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// Settings configures the backpressure of the targets.
+type Settings struct {
+	// Interval is the minimum duration between two adjustments of the limit of a target. Limits are
+	// only adjusted when they are read.
+	Interval time.Duration
+	// MinRequests is the minimum number of deliveries over an interval for a target to be deemed
+	// unhealthy. It's lowered to the limit of the target when the limit is lower.
+	MinRequests int
+	// FailureRatio is the ratio of failed deliveries over an interval at which a target is deemed
+	// unhealthy.
+	FailureRatio float64
+	// SlowLatency is the average latency of the deliveries over an interval at which a target is
+	// deemed unhealthy. Latency is ignored if zero.
+	SlowLatency time.Duration
+	// PauseDuration is how long pulling is paused for a target that is unhealthy at the lowest
+	// limit.
+	PauseDuration time.Duration
+}
+
+// DefaultSettings are the default backpressure settings.
+var DefaultSettings = Settings{
+	Interval:      10 * time.Second,
+	MinRequests:   10,
+	FailureRatio:  0.5,
+	SlowLatency:   10 * time.Second,
+	PauseDuration: 30 * time.Second,
+}
+
+// target holds the limit of a target and the results of the deliveries to it since the limit was
+// last adjusted.
+type target struct {
+	mu sync.Mutex
+	// initialized is false until the limit of the target is first read.
+	initialized bool
+	// limit is the maximum number of events pulled for the target, or zero if pulling is paused.
+	limit       int
+	pausedAt    time.Time
+	evaluatedAt time.Time
+	success     int
+	failures    int
+	latency     time.Duration
+}
+
+// unhealthy reports whether the deliveries since the last adjustment show that the target is
+// unhealthy.
+func (t *target) unhealthy(settings Settings) bool {
+	total := t.success + t.failures
+	minRequests := settings.MinRequests
+	if t.limit < minRequests {
+		minRequests = t.limit
+	}
+	if total == 0 || total < minRequests {
+		return false
+	}
+	if float64(t.failures) >= settings.FailureRatio*float64(total) {
+		return true
+	}
+	return settings.SlowLatency > 0 && t.latency/time.Duration(total) >= settings.SlowLatency
+}
+
+// Controller holds the backpressure of the targets. It is safe for concurrent use.
+type Controller struct {
+	settings Settings
+	now      func() time.Time
+	m        sync.Map
+}
+
+// NewController creates the backpressure controller of the targets with the given settings.
+func NewController(settings Settings) *Controller {
+	return &Controller{settings: settings, now: time.Now}
+}
+
+func (c *Controller) get(key *config.TargetKey) *target {
+	if t, ok := c.m.Load(*key); ok {
+		return t.(*target)
+	}
+	t, _ := c.m.LoadOrStore(*key, &target{})
+	return t.(*target)
+}
+
+// Record records the result and latency of a delivery to the target.
+func (c *Controller) Record(key *config.TargetKey, success bool, latency time.Duration) {
+	t := c.get(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if success {
+		t.success++
+	} else {
+		t.failures++
+	}
+	t.latency += latency
+}
+
+// Limit returns the maximum number of events to pull for the target, which is at most max, or zero
+// if pulling is paused. The limit is adjusted if the interval has elapsed since the last adjustment.
+func (c *Controller) Limit(key *config.TargetKey, max int) int {
+	t := c.get(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := c.now()
+	if !t.initialized {
+		t.initialized = true
+		t.limit = max
+		t.evaluatedAt = now
+	}
+	if t.limit > max {
+		t.limit = max
+	}
+	if now.Sub(t.evaluatedAt) < c.settings.Interval {
+		return t.limit
+	}
+
+	switch {
+	case t.limit == 0:
+		if now.Sub(t.pausedAt) >= c.settings.PauseDuration {
+			// Slow start.
+			t.limit = 1
+		}
+	case t.unhealthy(c.settings):
+		if t.limit == 1 {
+			t.limit = 0
+			t.pausedAt = now
+		} else {
+			t.limit /= 2
+		}
+	case t.success > 0 && t.limit < max:
+		t.limit *= 2
+		if t.limit > max {
+			t.limit = max
+		}
+	}
+	t.evaluatedAt = now
+	t.success, t.failures, t.latency = 0, 0, 0
+	return t.limit
+}
+
+// Prune forgets the targets that are no longer in the config.
+func (c *Controller) Prune(targets config.ReadonlyTargets) {
+	c.m.Range(func(key, _ interface{}) bool {
+		k := key.(config.TargetKey)
+		if _, ok := targets.GetTargetByKey(&k); !ok {
+			c.m.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backpressure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+const testMax = 8
+
+var (
+	testSettings = Settings{
+		Interval:      10 * time.Second,
+		MinRequests:   4,
+		FailureRatio:  0.5,
+		SlowLatency:   time.Second,
+		PauseDuration: 30 * time.Second,
+	}
+	testKey = (&config.Target{Namespace: "ns", Name: "target", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}).Key()
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestController() (*Controller, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := NewController(testSettings)
+	c.now = clock.now
+	return c, clock
+}
+
+// record records n deliveries with the given result and latency.
+func record(c *Controller, n int, success bool, latency time.Duration) {
+	for i := 0; i < n; i++ {
+		c.Record(testKey, success, latency)
+	}
+}
+
+// nextInterval moves the clock to the next interval and returns the adjusted limit.
+func nextInterval(c *Controller, clock *fakeClock) int {
+	clock.t = clock.t.Add(testSettings.Interval)
+	return c.Limit(testKey, testMax)
+}
+
+func TestLimitStartsAtMax(t *testing.T) {
+	c, _ := newTestController()
+	if got := c.Limit(testKey, testMax); got != testMax {
+		t.Errorf("limit got=%d, want=%d", got, testMax)
+	}
+	if got := c.Limit(testKey, testMax/2); got != testMax/2 {
+		t.Errorf("limit after lowering the max got=%d, want=%d", got, testMax/2)
+	}
+}
+
+func TestLimitHalvesWhenUnhealthy(t *testing.T) {
+	tests := []struct {
+		name     string
+		success  int
+		failures int
+		latency  time.Duration
+		want     int
+	}{{
+		name:     "failures",
+		success:  2,
+		failures: 2,
+		want:     testMax / 2,
+	}, {
+		name:    "slow deliveries",
+		success: 4,
+		latency: time.Second,
+		want:    testMax / 2,
+	}, {
+		name:     "failures below ratio",
+		success:  3,
+		failures: 1,
+		want:     testMax,
+	}, {
+		name:     "too few deliveries",
+		failures: 3,
+		want:     testMax,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, clock := newTestController()
+			c.Limit(testKey, testMax)
+			record(c, tc.success, true, tc.latency)
+			record(c, tc.failures, false, tc.latency)
+			if got := c.Limit(testKey, testMax); got != testMax {
+				t.Errorf("limit before the interval elapsed got=%d, want=%d", got, testMax)
+			}
+			if got := nextInterval(c, clock); got != tc.want {
+				t.Errorf("limit got=%d, want=%d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLimitPausesAndRampsUp(t *testing.T) {
+	c, clock := newTestController()
+	c.Limit(testKey, testMax)
+	for _, want := range []int{4, 2, 1} {
+		record(c, 4, false, 0)
+		if got := nextInterval(c, clock); got != want {
+			t.Fatalf("limit got=%d, want=%d", got, want)
+		}
+	}
+	// A single failure is enough at the lowest limit.
+	record(c, 1, false, 0)
+	if got := nextInterval(c, clock); got != 0 {
+		t.Fatalf("limit got=%d, want paused", got)
+	}
+	if got := nextInterval(c, clock); got != 0 {
+		t.Fatalf("limit before the pause elapsed got=%d, want paused", got)
+	}
+	clock.t = clock.t.Add(testSettings.PauseDuration)
+	if got := nextInterval(c, clock); got != 1 {
+		t.Fatalf("limit after the pause got=%d, want=1", got)
+	}
+	// No deliveries, no ramp up.
+	if got := nextInterval(c, clock); got != 1 {
+		t.Fatalf("limit without deliveries got=%d, want=1", got)
+	}
+	for _, want := range []int{2, 4, 8, 8} {
+		record(c, 1, true, 0)
+		if got := nextInterval(c, clock); got != want {
+			t.Fatalf("limit got=%d, want=%d", got, want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c, _ := newTestController()
+	targets := memory.NewEmptyTargets()
+	target := &config.Target{Namespace: "ns", Name: "kept", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	targets.MutateCellTenant(config.TestOnlyBrokerKey("ns", "broker"), func(m config.CellTenantMutation) {
+		m.UpsertTargets(target)
+	})
+	c.Record(target.Key(), true, 0)
+	c.Record(testKey, true, 0)
+
+	c.Prune(targets)
+	if _, ok := c.m.Load(*target.Key()); !ok {
+		t.Error("target in the config was pruned")
+	}
+	if _, ok := c.m.Load(*testKey); ok {
+		t.Error("target not in the config wasn't pruned")
+	}
+}
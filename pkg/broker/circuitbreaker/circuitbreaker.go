@@ -0,0 +1,320 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package circuitbreaker stops deliveries to the targets that keep failing, so that a subscriber
+// which is down doesn't hold handler concurrency until each delivery times out.
+//
+// A breaker starts closed, and opens when the ratio of failed deliveries over a rolling window
+// reaches a threshold. While open, deliveries are rejected without calling the target. After a
+// timeout, the breaker becomes half-open and lets a few probe deliveries through: it closes again
+// once they all succeed, and reopens as soon as one of them fails.
+//
+// Each state change is reported to the StateChangeFunc of the breakers, including the change from
+// open to half-open, which happens when the open timeout elapses even without any delivery.
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// ErrOpen is the error of a delivery rejected by an open breaker.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// numBuckets is the number of buckets the rolling window is divided into.
+const numBuckets = 10
+
+// State is the state of a breaker.
+type State int
+
+const (
+	// StateClosed lets all deliveries through.
+	StateClosed State = iota
+	// StateHalfOpen lets a limited number of probe deliveries through.
+	StateHalfOpen
+	// StateOpen rejects all deliveries.
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Settings configures the breakers.
+type Settings struct {
+	// Window is the duration over which the failure ratio is computed.
+	Window time.Duration
+	// MinRequests is the minimum number of deliveries in the window for the breaker to open.
+	MinRequests int
+	// FailureRatio is the ratio of failed deliveries in the window at which the breaker opens.
+	FailureRatio float64
+	// OpenTimeout is how long the breaker stays open before letting probe deliveries through.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of successful probe deliveries required to close the breaker.
+	HalfOpenProbes int
+}
+
+// DefaultSettings are the default breaker settings.
+var DefaultSettings = Settings{
+	Window:         10 * time.Second,
+	MinRequests:    20,
+	FailureRatio:   0.5,
+	OpenTimeout:    30 * time.Second,
+	HalfOpenProbes: 3,
+}
+
+// StateChangeFunc is called when the breaker of a target changes state. It is called while the
+// breaker is locked, so that the changes of a breaker are reported in order, and must not call
+// the breaker.
+type StateChangeFunc func(key *config.TargetKey, from, to State)
+
+// Attempt is a delivery let through by a breaker. Its result is recorded with Breaker.Record.
+type Attempt struct {
+	// generation is the breaker generation the attempt was let through in.
+	generation uint64
+}
+
+type bucket struct {
+	// epoch identifies the time slice of the bucket. Counts of a past epoch are stale.
+	epoch    int64
+	success  int
+	failures int
+}
+
+// Breaker is the circuit breaker of a single target. It is safe for concurrent use.
+type Breaker struct {
+	key           config.TargetKey
+	settings      Settings
+	now           func() time.Time
+	onStateChange StateChangeFunc
+
+	mu    sync.Mutex
+	state State
+	// generation is incremented on every state change, so that the results of the attempts let
+	// through in a previous state are not counted towards the current one.
+	generation uint64
+	openedAt   time.Time
+	// openTimer moves the breaker to half-open once the open timeout has elapsed. Nil unless open.
+	openTimer *time.Timer
+	// probes is the number of probe deliveries let through while half-open.
+	probes int
+	// probeSuccesses is the number of these probes that succeeded.
+	probeSuccesses int
+	buckets        [numBuckets]bucket
+}
+
+func newBreaker(key config.TargetKey, settings Settings, now func() time.Time, onStateChange StateChangeFunc) *Breaker {
+	return &Breaker{key: key, settings: settings, now: now, onStateChange: onStateChange}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireOpen()
+	return b.state
+}
+
+// Allow reports whether a delivery to the target may be attempted. If it may, the result of the
+// delivery must be recorded with Record.
+func (b *Breaker) Allow() (Attempt, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireOpen()
+	switch b.state {
+	case StateOpen:
+		return Attempt{}, false
+	case StateHalfOpen:
+		if b.probes >= b.settings.HalfOpenProbes {
+			return Attempt{}, false
+		}
+		b.probes++
+	}
+	return Attempt{generation: b.generation}, true
+}
+
+// Record records the result of an attempted delivery.
+func (b *Breaker) Record(a Attempt, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if a.generation != b.generation {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		bk := b.currentBucket()
+		if success {
+			bk.success++
+			return
+		}
+		bk.failures++
+		if b.shouldOpen() {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setState(StateOpen)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.settings.HalfOpenProbes {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// expireOpen moves an open breaker to half-open once its open timeout has elapsed.
+func (b *Breaker) expireOpen() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+// expire is called by the open timer.
+func (b *Breaker) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireOpen()
+}
+
+// stop stops the open timer of a breaker that is no longer used.
+func (b *Breaker) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopTimer()
+}
+
+func (b *Breaker) stopTimer() {
+	if b.openTimer != nil {
+		b.openTimer.Stop()
+		b.openTimer = nil
+	}
+}
+
+func (b *Breaker) setState(s State) {
+	from := b.state
+	b.state = s
+	b.generation++
+	b.probes = 0
+	b.probeSuccesses = 0
+	b.stopTimer()
+	switch s {
+	case StateOpen:
+		b.openedAt = b.now()
+		b.openTimer = time.AfterFunc(b.settings.OpenTimeout, b.expire)
+	case StateClosed:
+		b.buckets = [numBuckets]bucket{}
+	}
+	if b.onStateChange != nil {
+		b.onStateChange(&b.key, from, s)
+	}
+}
+
+// currentBucket returns the bucket of the current time slice, resetting it if it's stale.
+func (b *Breaker) currentBucket() *bucket {
+	epoch := b.now().UnixNano() / int64(b.bucketDuration())
+	bk := &b.buckets[epoch%numBuckets]
+	if bk.epoch != epoch {
+		*bk = bucket{epoch: epoch}
+	}
+	return bk
+}
+
+func (b *Breaker) bucketDuration() time.Duration {
+	if d := b.settings.Window / numBuckets; d > 0 {
+		return d
+	}
+	return 1
+}
+
+// shouldOpen reports whether the failure ratio over the window has reached the threshold.
+func (b *Breaker) shouldOpen() bool {
+	oldest := b.now().UnixNano()/int64(b.bucketDuration()) - numBuckets + 1
+	var success, failures int
+	for _, bk := range b.buckets {
+		if bk.epoch >= oldest {
+			success += bk.success
+			failures += bk.failures
+		}
+	}
+	total := success + failures
+	return total >= b.settings.MinRequests && float64(failures) >= b.settings.FailureRatio*float64(total)
+}
+
+// Breakers holds the breakers of the targets.
+type Breakers struct {
+	settings      Settings
+	now           func() time.Time
+	onStateChange StateChangeFunc
+	m             sync.Map
+}
+
+// NewBreakers creates the breakers of the targets with the given settings. The state changes of
+// the breakers are reported to onStateChange, if not nil.
+func NewBreakers(settings Settings, onStateChange StateChangeFunc) *Breakers {
+	return &Breakers{settings: settings, now: time.Now, onStateChange: onStateChange}
+}
+
+// Get returns the breaker of the target, creating it if needed.
+func (bs *Breakers) Get(key *config.TargetKey) *Breaker {
+	if b, ok := bs.m.Load(*key); ok {
+		return b.(*Breaker)
+	}
+	b, _ := bs.m.LoadOrStore(*key, newBreaker(*key, bs.settings, bs.now, bs.onStateChange))
+	return b.(*Breaker)
+}
+
+// Lookup returns the breaker of the target, if it has one.
+func (bs *Breakers) Lookup(key *config.TargetKey) (*Breaker, bool) {
+	b, ok := bs.m.Load(*key)
+	if !ok {
+		return nil, false
+	}
+	return b.(*Breaker), true
+}
+
+// Range calls f sequentially for each target breaker. If f returns false, Range stops the
+// iteration.
+func (bs *Breakers) Range(f func(key *config.TargetKey, b *Breaker) bool) {
+	bs.m.Range(func(key, value interface{}) bool {
+		k := key.(config.TargetKey)
+		return f(&k, value.(*Breaker))
+	})
+}
+
+// Prune forgets the breakers of the targets that are no longer in the config.
+func (bs *Breakers) Prune(targets config.ReadonlyTargets) {
+	bs.m.Range(func(key, value interface{}) bool {
+		k := key.(config.TargetKey)
+		if _, ok := targets.GetTargetByKey(&k); !ok {
+			bs.m.Delete(key)
+			value.(*Breaker).stop()
+		}
+		return true
+	})
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+var testSettings = Settings{
+	Window:         10 * time.Second,
+	MinRequests:    4,
+	FailureRatio:   0.5,
+	OpenTimeout:    30 * time.Second,
+	HalfOpenProbes: 2,
+}
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestBreaker() (*Breaker, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	return newBreaker(config.TargetKey{}, testSettings, clock.now, nil), clock
+}
+
+// deliver attempts a delivery with the given result and reports whether it was let through.
+func deliver(b *Breaker, success bool) bool {
+	a, ok := b.Allow()
+	if ok {
+		b.Record(a, success)
+	}
+	return ok
+}
+
+func TestBreakerOpensOnFailureRatio(t *testing.T) {
+	b, _ := newTestBreaker()
+	deliver(b, true)
+	deliver(b, false)
+	deliver(b, true)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state before min requests got=%v, want=%v", got, StateClosed)
+	}
+	deliver(b, false)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state got=%v, want=%v", got, StateOpen)
+	}
+	if deliver(b, true) {
+		t.Error("open breaker allowed a delivery")
+	}
+}
+
+func TestBreakerStaysClosedBelowFailureRatio(t *testing.T) {
+	b, _ := newTestBreaker()
+	for i := 0; i < 10; i++ {
+		deliver(b, true)
+		deliver(b, true)
+		deliver(b, false)
+	}
+	if got := b.State(); got != StateClosed {
+		t.Errorf("state got=%v, want=%v", got, StateClosed)
+	}
+}
+
+func TestBreakerForgetsOldResults(t *testing.T) {
+	b, clock := newTestBreaker()
+	deliver(b, false)
+	deliver(b, false)
+	deliver(b, false)
+	clock.t = clock.t.Add(testSettings.Window)
+	deliver(b, false)
+	if got := b.State(); got != StateClosed {
+		t.Errorf("state got=%v, want=%v", got, StateClosed)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes []bool
+		want   State
+	}{{
+		name:   "probes succeed",
+		probes: []bool{true, true},
+		want:   StateClosed,
+	}, {
+		name:   "probe fails",
+		probes: []bool{true, false},
+		want:   StateOpen,
+	}, {
+		name:   "probes in flight",
+		probes: []bool{true},
+		want:   StateHalfOpen,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, clock := newTestBreaker()
+			for i := 0; i < testSettings.MinRequests; i++ {
+				deliver(b, false)
+			}
+			clock.t = clock.t.Add(testSettings.OpenTimeout - time.Second)
+			if got := b.State(); got != StateOpen {
+				t.Fatalf("state before open timeout got=%v, want=%v", got, StateOpen)
+			}
+			clock.t = clock.t.Add(time.Second)
+			if got := b.State(); got != StateHalfOpen {
+				t.Fatalf("state after open timeout got=%v, want=%v", got, StateHalfOpen)
+			}
+
+			var attempts []Attempt
+			for range tc.probes {
+				a, ok := b.Allow()
+				if !ok {
+					t.Fatal("half-open breaker didn't allow a probe")
+				}
+				attempts = append(attempts, a)
+			}
+			if len(tc.probes) == testSettings.HalfOpenProbes {
+				if _, ok := b.Allow(); ok {
+					t.Error("half-open breaker allowed more probes than configured")
+				}
+			}
+			for i, success := range tc.probes {
+				b.Record(attempts[i], success)
+			}
+			if got := b.State(); got != tc.want {
+				t.Errorf("state got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	b, clock := newTestBreaker()
+	stale, _ := b.Allow()
+	for i := 0; i < testSettings.MinRequests; i++ {
+		deliver(b, false)
+	}
+	clock.t = clock.t.Add(testSettings.OpenTimeout)
+	b.Allow()
+	// A failure of a delivery let through while closed doesn't reopen the half-open breaker.
+	b.Record(stale, false)
+	if got := b.State(); got != StateHalfOpen {
+		t.Errorf("state got=%v, want=%v", got, StateHalfOpen)
+	}
+}
+
+func TestBreakers(t *testing.T) {
+	targets := memory.NewEmptyTargets()
+	broker := config.TestOnlyBrokerKey("ns", "broker")
+	kept := &config.Target{Namespace: "ns", Name: "kept", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	deleted := &config.Target{Namespace: "ns", Name: "deleted", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	targets.MutateCellTenant(broker, func(m config.CellTenantMutation) {
+		m.UpsertTargets(kept)
+	})
+
+	bs := NewBreakers(testSettings, nil)
+	keptBreaker := bs.Get(kept.Key())
+	if bs.Get(kept.Key()) != keptBreaker {
+		t.Error("Get returned a different breaker for the same target")
+	}
+	bs.Get(deleted.Key())
+
+	bs.Prune(targets)
+	var got []string
+	bs.Range(func(key *config.TargetKey, b *Breaker) bool {
+		got = append(got, key.String())
+		return true
+	})
+	if len(got) != 1 || got[0] != kept.Key().String() {
+		t.Errorf("breakers after Prune got=%v, want [%v]", got, kept.Key())
+	}
+}
+
+type stateChange struct {
+	From, To State
+}
+
+type stateChanges struct {
+	mu      sync.Mutex
+	changes []stateChange
+}
+
+func (s *stateChanges) record(_ *config.TargetKey, from, to State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, stateChange{From: from, To: to})
+}
+
+func (s *stateChanges) get() []stateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stateChange(nil), s.changes...)
+}
+
+func TestBreakerReportsStateChanges(t *testing.T) {
+	var changes stateChanges
+	settings := testSettings
+	settings.OpenTimeout = 10 * time.Millisecond
+	b := newBreaker(config.TargetKey{}, settings, time.Now, changes.record)
+	defer b.stop()
+
+	for i := 0; i < settings.MinRequests; i++ {
+		deliver(b, false)
+	}
+	want := []stateChange{{From: StateClosed, To: StateOpen}}
+	if diff := cmp.Diff(want, changes.get()); diff != "" {
+		t.Fatalf("state changes after failures (-want,+got): %v", diff)
+	}
+
+	// The breaker becomes half-open once the open timeout elapses, without any delivery.
+	want = append(want, stateChange{From: StateOpen, To: StateHalfOpen})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(changes.get()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if diff := cmp.Diff(want, changes.get()); diff != "" {
+		t.Fatalf("state changes after open timeout (-want,+got): %v", diff)
+	}
+
+	deliver(b, true)
+	deliver(b, true)
+	want = append(want, stateChange{From: StateHalfOpen, To: StateClosed})
+	if diff := cmp.Diff(want, changes.get()); diff != "" {
+		t.Errorf("state changes after probes (-want,+got): %v", diff)
+	}
+}
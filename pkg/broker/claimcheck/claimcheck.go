@@ -0,0 +1,185 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package claimcheck offloads large event payloads to a GCS bucket so that events larger than the
+// Pub/Sub message size limit can go through a broker. The ingress replaces the payload of such an
+// event by a reference to the object holding it, and the payload is read back before the event is
+// delivered to a subscriber.
+//
+// Objects are not deleted after delivery as each event is delivered to any number of triggers,
+// possibly after retries. An object lifecycle rule should be set on the bucket to delete them.
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/gclient/storage"
+)
+
+const (
+	// ReferenceExtension is the CloudEvent extension holding the reference to the GCS object
+	// holding the event payload, in the gs://bucket/object format.
+	ReferenceExtension = "knativeclaimcheck"
+
+	referenceScheme = "gs://"
+)
+
+var (
+	// ErrInvalidReference is the error when the reference extension of an event can't be parsed.
+	ErrInvalidReference = errors.New("invalid claim check reference")
+	// ErrForeignReference is the error when the reference extension of an event points to an object
+	// that was not written by the Store for the broker of the event.
+	ErrForeignReference = errors.New("claim check reference outside of the broker claim checks")
+)
+
+// Store offloads the payloads of large events to a GCS bucket.
+type Store struct {
+	client storage.Client
+	bucket string
+	// threshold is the payload size in bytes above which a payload is offloaded.
+	threshold int
+}
+
+// NewStore creates a Store that offloads the payloads larger than threshold bytes to the bucket.
+func NewStore(client storage.Client, bucket string, threshold int) *Store {
+	return &Store{client: client, bucket: bucket, threshold: threshold}
+}
+
+// Offload writes the event payload to the bucket if it is larger than the threshold, and replaces it
+// by a reference to the written object. The event is left as is otherwise.
+func (s *Store) Offload(ctx context.Context, broker *config.CellTenantKey, e *event.Event) error {
+	if len(e.DataEncoded) <= s.threshold {
+		return nil
+	}
+	name := objectPrefix(broker) + uuid.New().String()
+	w := s.client.Bucket(s.bucket).Object(name).NewWriter(ctx)
+	if _, err := w.Write(e.DataEncoded); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write event payload to gs://%s/%s: %w", s.bucket, name, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write event payload to gs://%s/%s: %w", s.bucket, name, err)
+	}
+	e.SetExtension(ReferenceExtension, referenceScheme+s.bucket+"/"+name)
+	e.DataEncoded = nil
+	e.DataBase64 = false
+	return nil
+}
+
+// HasReference returns true if the event payload has been offloaded.
+func HasReference(e *event.Event) bool {
+	_, ok := e.Extensions()[ReferenceExtension]
+	return ok
+}
+
+// StripReference removes the reference extension from the event. The ingress strips it from the
+// events it receives, so that only the references set by a Store are ever read back.
+func StripReference(e *event.Event) {
+	e.SetExtension(ReferenceExtension, nil)
+}
+
+// objectPrefix returns the prefix of the names of the objects holding the payloads of the broker.
+func objectPrefix(broker *config.CellTenantKey) string {
+	return path.Join(broker.Namespace(), broker.Name()) + "/"
+}
+
+// Reader reads back the payloads offloaded by a Store. Its storage client is created on first use,
+// so that it doesn't need credentials unless claim checks are used.
+type Reader struct {
+	createClient storage.CreateFn
+	// bucket is the bucket the Store offloads payloads to. References to other buckets are refused.
+	bucket string
+
+	mu     sync.Mutex
+	client storage.Client
+}
+
+// NewReader creates a Reader of the payloads offloaded to the bucket. It refuses all the references
+// if the bucket is empty.
+func NewReader(createClient storage.CreateFn, bucket string) *Reader {
+	return &Reader{createClient: createClient, bucket: bucket}
+}
+
+// Rehydrate returns a copy of the event of the broker with the offloaded payload read back from GCS,
+// and without the reference extension. The event itself is not modified, so that it stays small if
+// it needs to be sent to Pub/Sub again. Only the objects written by the Store for the broker are
+// read.
+func (r *Reader) Rehydrate(ctx context.Context, broker *config.CellTenantKey, e *event.Event) (*event.Event, error) {
+	ref, ok := e.Extensions()[ReferenceExtension].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReference, e.Extensions()[ReferenceExtension])
+	}
+	bucket, name, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	if r.bucket == "" || bucket != r.bucket || !strings.HasPrefix(name, objectPrefix(broker)) || path.Clean(name) != name {
+		return nil, fmt.Errorf("%w: %q", ErrForeignReference, ref)
+	}
+	client, err := r.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	rc, err := client.Bucket(bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event payload from %s: %w", ref, err)
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event payload from %s: %w", ref, err)
+	}
+	rehydrated := e.Clone()
+	rehydrated.SetExtension(ReferenceExtension, nil)
+	rehydrated.DataEncoded = data
+	return &rehydrated, nil
+}
+
+func (r *Reader) getClient() (storage.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		return r.client, nil
+	}
+	// The client outlives the request it is created for.
+	client, err := r.createClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	r.client = client
+	return client, nil
+}
+
+func parseReference(ref string) (bucket, name string, err error) {
+	if !strings.HasPrefix(ref, referenceScheme) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(ref, referenceScheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"google.golang.org/api/option"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/gclient/storage"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage/testing"
+)
+
+func newTestClient(t *testing.T, data gstorage.TestClientData) storage.Client {
+	t.Helper()
+	client, err := gstorage.TestClientCreator(data)(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func newTestEvent(t *testing.T, data []byte) *event.Event {
+	t.Helper()
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	if err := e.SetData("application/octet-stream", data); err != nil {
+		t.Fatal(err)
+	}
+	return &e
+}
+
+func TestOffloadAndRehydrate(t *testing.T) {
+	client := newTestClient(t, gstorage.TestClientData{})
+	store := NewStore(client, "bucket", 10)
+	reader := NewReader(func(context.Context, ...option.ClientOption) (storage.Client, error) {
+		return client, nil
+	}, "bucket")
+	broker := (&config.CellTenant{Namespace: "ns", Name: "broker", Type: config.CellTenantType_BROKER}).Key()
+
+	small := newTestEvent(t, []byte("small"))
+	if err := store.Offload(context.Background(), broker, small); err != nil {
+		t.Fatalf("Offload() failed: %v", err)
+	}
+	if HasReference(small) {
+		t.Errorf("Offload() added a reference to an event under the threshold: %v", small)
+	}
+
+	payload := bytes.Repeat([]byte("x"), 100)
+	large := newTestEvent(t, payload)
+	if err := store.Offload(context.Background(), broker, large); err != nil {
+		t.Fatalf("Offload() failed: %v", err)
+	}
+	if !HasReference(large) {
+		t.Fatalf("Offload() didn't add a reference to an event over the threshold: %v", large)
+	}
+	if ref := large.Extensions()[ReferenceExtension].(string); !strings.HasPrefix(ref, "gs://bucket/ns/broker/") {
+		t.Errorf("unexpected reference %q", ref)
+	}
+	if len(large.Data()) != 0 {
+		t.Errorf("Offload() kept the payload of %d bytes", len(large.Data()))
+	}
+
+	rehydrated, err := reader.Rehydrate(context.Background(), broker, large)
+	if err != nil {
+		t.Fatalf("Rehydrate() failed: %v", err)
+	}
+	if !bytes.Equal(rehydrated.Data(), payload) {
+		t.Errorf("Rehydrate() data = %q, want %q", rehydrated.Data(), payload)
+	}
+	if HasReference(rehydrated) {
+		t.Error("Rehydrate() kept the reference extension")
+	}
+	if rehydrated.DataContentType() != "application/octet-stream" {
+		t.Errorf("Rehydrate() data content type = %q", rehydrated.DataContentType())
+	}
+	if !HasReference(large) {
+		t.Error("Rehydrate() modified the original event")
+	}
+}
+
+func TestOffloadWriteError(t *testing.T) {
+	wantErr := errors.New("write error")
+	client := newTestClient(t, gstorage.TestClientData{BucketData: gstorage.TestBucketData{ObjectWriteErr: wantErr}})
+	store := NewStore(client, "bucket", 0)
+	broker := (&config.CellTenant{Namespace: "ns", Name: "broker", Type: config.CellTenantType_BROKER}).Key()
+	e := newTestEvent(t, []byte("payload"))
+	if err := store.Offload(context.Background(), broker, e); !errors.Is(err, wantErr) {
+		t.Errorf("Offload() error = %v, want %v", err, wantErr)
+	}
+	if HasReference(e) {
+		t.Error("Offload() added a reference after a failed write")
+	}
+}
+
+func TestRehydrateErrors(t *testing.T) {
+	client := newTestClient(t, gstorage.TestClientData{BucketData: gstorage.TestBucketData{
+		Objects: map[string][]byte{
+			"ns/broker/exists":       []byte("payload"),
+			"other-ns/broker/exists": []byte("secret"),
+		},
+	}})
+	newReader := func(bucket string) *Reader {
+		return NewReader(func(context.Context, ...option.ClientOption) (storage.Client, error) {
+			return client, nil
+		}, bucket)
+	}
+	broker := (&config.CellTenant{Namespace: "ns", Name: "broker", Type: config.CellTenantType_BROKER}).Key()
+	tests := []struct {
+		name     string
+		disabled bool
+		ref      interface{}
+		wantErr  error
+	}{
+		{name: "existing object", ref: "gs://bucket/ns/broker/exists"},
+		{name: "missing object", ref: "gs://bucket/ns/broker/missing", wantErr: gcs.ErrObjectNotExist},
+		{name: "not a gs reference", ref: "https://example.com/exists", wantErr: ErrInvalidReference},
+		{name: "missing object name", ref: "gs://bucket", wantErr: ErrInvalidReference},
+		{name: "not a string", ref: 42, wantErr: ErrInvalidReference},
+		{name: "forged reference to another bucket", ref: "gs://other-bucket/ns/broker/exists", wantErr: ErrForeignReference},
+		{name: "forged reference to another broker", ref: "gs://bucket/other-ns/broker/exists", wantErr: ErrForeignReference},
+		{name: "forged reference out of the broker prefix", ref: "gs://bucket/ns/broker/../../other-ns/broker/exists", wantErr: ErrForeignReference},
+		{name: "claim check disabled", disabled: true, ref: "gs://bucket/ns/broker/exists", wantErr: ErrForeignReference},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket := "bucket"
+			if tc.disabled {
+				bucket = ""
+			}
+			e := newTestEvent(t, nil)
+			e.SetExtension(ReferenceExtension, tc.ref)
+			_, err := newReader(bucket).Rehydrate(context.Background(), broker, e)
+			if tc.wantErr == nil && err != nil || tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Rehydrate() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
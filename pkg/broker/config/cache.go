@@ -17,8 +17,10 @@ limitations under the License.
 package config
 
 import (
+	"sync"
 	"sync/atomic"
 
+	"github.com/cloudevents/sdk-go/v2/event"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 )
@@ -28,17 +30,46 @@ type CachedTargets struct {
 	Value atomic.Value
 }
 
+// targetsSnapshot is a stored TargetsConfig along with the indexes of its CellTenants.
+type targetsSnapshot struct {
+	config *TargetsConfig
+	// indexes holds the *targetIndex of each CellTenant, keyed by the CellTenant's
+	// PersistenceString(). They are built on first use, so that a config is only indexed where it
+	// is used to match events.
+	indexes sync.Map
+}
+
+func (s *targetsSnapshot) index(key string, t *CellTenant) *targetIndex {
+	if idx, ok := s.indexes.Load(key); ok {
+		return idx.(*targetIndex)
+	}
+	idx, _ := s.indexes.LoadOrStore(key, newTargetIndex(t.Targets))
+	return idx.(*targetIndex)
+}
+
 var _ ReadonlyTargets = (*CachedTargets)(nil)
 
 // Store atomically stores a TargetsConfig.
 func (ct *CachedTargets) Store(t *TargetsConfig) {
-	ct.Value.Store(t)
+	ct.Value.Store(&targetsSnapshot{config: t})
 }
 
 // Load atomically loads a stored TargetsConfig.
 // If there was no TargetsConfig stored, nil will be returned.
 func (ct *CachedTargets) Load() *TargetsConfig {
-	tc := ct.Value.Load().(*TargetsConfig)
+	s := ct.loadSnapshot()
+	if s == nil {
+		return nil
+	}
+	return s.config
+}
+
+func (ct *CachedTargets) loadSnapshot() *targetsSnapshot {
+	s, _ := ct.Value.Load().(*targetsSnapshot)
+	if s == nil || s.config == nil {
+		return s
+	}
+	tc := s.config
 
 	// To support downgrades, we need to pretend entries without a CellTenantType are actually
 	// Broker typed. That way, if the newer BrokerCell code is running, but has an older ConfigMap,
@@ -53,7 +84,7 @@ func (ct *CachedTargets) Load() *TargetsConfig {
 		}
 	}
 
-	return tc
+	return s
 }
 
 // RangeAllTargets ranges over all targets.
@@ -94,6 +125,23 @@ func (ct *CachedTargets) GetCellTenantByKey(key *CellTenantKey) (*CellTenant, bo
 	return b, ok
 }
 
+// RangeCandidateTargets ranges over the targets of a CellTenant that may accept the event. Targets
+// that require other values for the indexed attributes are skipped, but the filters of the given
+// targets still need to be evaluated.
+// Do not modify the given Target copy.
+func (ct *CachedTargets) RangeCandidateTargets(key *CellTenantKey, event *event.Event, f func(*Target) bool) {
+	s := ct.loadSnapshot()
+	if s == nil || s.config == nil {
+		return
+	}
+	k := key.PersistenceString()
+	b, ok := s.config.CellTenants[k]
+	if !ok {
+		return
+	}
+	s.index(k, b).rangeCandidates(event, f)
+}
+
 // RangeBrokers ranges over all brokers.
 // Do not modify the given Broker copy.
 func (ct *CachedTargets) RangeCellTenants(f func(*CellTenant) bool) {
@@ -16,6 +16,10 @@ limitations under the License.
 
 package config
 
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
 // ReadonlyTargets provides "read" functions for CellTenants and targets.
 type ReadonlyTargets interface {
 	// RangeAllTargets ranges over all targets.
@@ -24,6 +28,11 @@ type ReadonlyTargets interface {
 	// GetTargetByKey returns a target by its trigger key, if it exists.
 	// Do not modify the returned Target copy.
 	GetTargetByKey(key *TargetKey) (*Target, bool)
+	// RangeCandidateTargets ranges over the targets of a CellTenant that may accept the event,
+	// using an index over the exact values that targets require for some attributes. The filters
+	// of the given targets still need to be evaluated.
+	// Do not modify the given Target copy.
+	RangeCandidateTargets(key *CellTenantKey, event *event.Event, f func(*Target) bool)
 	// GetCellTenantByKey returns a CellTenant and its Targets, if it exists.
 	// Do not modify the returned CellTenant copy.
 	GetCellTenantByKey(key *CellTenantKey) (*CellTenant, bool)
@@ -48,6 +57,16 @@ type CellTenantMutation interface {
 	SetDecoupleQueue(q *Queue) CellTenantMutation
 	// SetState sets the CellTenant's state.
 	SetState(s State) CellTenantMutation
+	// SetRateLimit sets the CellTenant's ingress rate limit.
+	SetRateLimit(l *RateLimit) CellTenantMutation
+	// SetOrderingKeyExtension sets the CellTenant's ordering key extension.
+	SetOrderingKeyExtension(ext string) CellTenantMutation
+	// SetIngressAuth sets the CellTenant's ingress authentication requirements.
+	SetIngressAuth(a *IngressAuth) CellTenantMutation
+	// SetDeadLetter sets the CellTenant's dead letter sink.
+	SetDeadLetter(d *DeadLetter) CellTenantMutation
+	// SetGeneration sets the generation of the resource the CellTenant was built from.
+	SetGeneration(g int64) CellTenantMutation
 	// UpsertTargets upserts Targets to the CellTenant.
 	// The targets' namespace, CellTenantType, and CellTenantName will be set to the CellTenant's
 	// value.
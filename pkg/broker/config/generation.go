@@ -0,0 +1,40 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+)
+
+// HasBrokerGeneration returns true if the config has the CellTenant of the Broker, built from its
+// current generation or a later one.
+func (x *TargetsConfig) HasBrokerGeneration(b *brokerv1beta1.Broker) bool {
+	ct := x.GetCellTenants()[KeyFromBroker(b).PersistenceString()]
+	return ct != nil && ct.Id == string(b.UID) && ct.Generation >= b.Generation
+}
+
+// HasTriggerGeneration returns true if the config has the Target of the Trigger, built from its
+// current generation or a later one.
+func (x *TargetsConfig) HasTriggerGeneration(t *brokerv1beta1.Trigger) bool {
+	key := &CellTenantKey{
+		cellTenantType: CellTenantType_BROKER,
+		namespace:      t.Namespace,
+		name:           t.Spec.Broker,
+	}
+	target := x.GetCellTenants()[key.PersistenceString()].GetTargets()[t.Name]
+	return target != nil && target.Id == string(t.UID) && target.Generation >= t.Generation
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
+
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+)
+
+func TestHasGeneration(t *testing.T) {
+	tc := &TargetsConfig{CellTenants: map[string]*CellTenant{
+		"ns/broker": {
+			Id:         "broker-uid",
+			Type:       CellTenantType_BROKER,
+			Namespace:  "ns",
+			Name:       "broker",
+			Generation: 2,
+			Targets: map[string]*Target{
+				"trigger": {
+					Id:         "trigger-uid",
+					Namespace:  "ns",
+					Name:       "trigger",
+					Generation: 3,
+				},
+			},
+		},
+	}}
+	broker := func(name string, uid types.UID, generation int64) *brokerv1beta1.Broker {
+		return &brokerv1beta1.Broker{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns", Name: name, UID: uid, Generation: generation,
+		}}
+	}
+	trigger := func(brokerName, name string, uid types.UID, generation int64) *brokerv1beta1.Trigger {
+		return &brokerv1beta1.Trigger{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, UID: uid, Generation: generation},
+			Spec:       eventingv1beta1.TriggerSpec{Broker: brokerName},
+		}
+	}
+
+	brokerCases := []struct {
+		name   string
+		broker *brokerv1beta1.Broker
+		want   bool
+	}{
+		{"same generation", broker("broker", "broker-uid", 2), true},
+		{"older generation", broker("broker", "broker-uid", 1), true},
+		{"newer generation", broker("broker", "broker-uid", 3), false},
+		{"recreated", broker("broker", "other-uid", 1), false},
+		{"missing", broker("other", "broker-uid", 1), false},
+	}
+	for _, c := range brokerCases {
+		t.Run("broker "+c.name, func(t *testing.T) {
+			if got := tc.HasBrokerGeneration(c.broker); got != c.want {
+				t.Errorf("HasBrokerGeneration() = %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	triggerCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    bool
+	}{
+		{"same generation", trigger("broker", "trigger", "trigger-uid", 3), true},
+		{"newer generation", trigger("broker", "trigger", "trigger-uid", 4), false},
+		{"recreated", trigger("broker", "trigger", "other-uid", 1), false},
+		{"other broker", trigger("other", "trigger", "trigger-uid", 1), false},
+		{"missing", trigger("broker", "other", "trigger-uid", 1), false},
+	}
+	for _, c := range triggerCases {
+		t.Run("trigger "+c.name, func(t *testing.T) {
+			if got := tc.HasTriggerGeneration(c.trigger); got != c.want {
+				t.Errorf("HasTriggerGeneration() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
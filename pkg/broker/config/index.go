@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+// indexedAttributes are the attributes that targets can be indexed by, in order of preference.
+// They are string attributes that compare the same way in the attributes filter and in the exact
+// dialect of the Subscriptions API filters.
+var indexedAttributes = []struct {
+	name  string
+	value func(*event.Event) string
+}{
+	{"type", (*event.Event).Type},
+	{"source", (*event.Event).Source},
+	{"subject", (*event.Event).Subject},
+}
+
+// targetIndex is an inverted index of the targets of a CellTenant. Each target that requires an
+// exact value for one of the indexedAttributes is indexed by the first such attribute, so that
+// looking up the attribute values of an event skips the targets that can't accept the event.
+type targetIndex struct {
+	// byValue holds the indexed targets, by attribute and then by the required value. It has one
+	// entry per attribute in indexedAttributes.
+	byValue []map[string][]*Target
+	// unindexed are the targets that don't require an exact value for any indexed attribute.
+	unindexed []*Target
+}
+
+func newTargetIndex(targets map[string]*Target) *targetIndex {
+	idx := &targetIndex{byValue: make([]map[string][]*Target, len(indexedAttributes))}
+	for i := range idx.byValue {
+		idx.byValue[i] = make(map[string][]*Target)
+	}
+	for _, t := range targets {
+		if i, value, ok := indexKey(t); ok {
+			idx.byValue[i][value] = append(idx.byValue[i][value], t)
+		} else {
+			idx.unindexed = append(idx.unindexed, t)
+		}
+	}
+	return idx
+}
+
+// indexKey returns the position in indexedAttributes and the value of the attribute to index the
+// target by, if there is one.
+func indexKey(t *Target) (int, string, bool) {
+	for i, attr := range indexedAttributes {
+		// An empty value in the attributes filter matches any value.
+		if v := t.FilterAttributes[attr.name]; v != "" {
+			return i, v, true
+		}
+		// Top level filters must all pass, so an exact filter on the attribute is required.
+		for _, f := range t.Filters {
+			if v := f.GetExact()[attr.name]; v != "" {
+				return i, v, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// rangeCandidates ranges over the targets that may accept the event.
+func (idx *targetIndex) rangeCandidates(event *event.Event, f func(*Target) bool) {
+	for _, t := range idx.unindexed {
+		if !f(t) {
+			return
+		}
+	}
+	for i, attr := range indexedAttributes {
+		for _, t := range idx.byValue[i][attr.value(event)] {
+			if !f(t) {
+				return
+			}
+		}
+	}
+}
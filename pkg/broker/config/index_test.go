@@ -0,0 +1,176 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRangeCandidateTargets(t *testing.T) {
+	targets := map[string]*Target{
+		"no-filter": {Name: "no-filter"},
+		"type": {
+			Name:             "type",
+			FilterAttributes: map[string]string{"type": "com.example.created"},
+		},
+		"other-type": {
+			Name:             "other-type",
+			FilterAttributes: map[string]string{"type": "com.example.deleted", "source": "src"},
+		},
+		"any-type": {
+			Name:             "any-type",
+			FilterAttributes: map[string]string{"type": "", "source": "src"},
+		},
+		"other-source": {
+			Name:             "other-source",
+			FilterAttributes: map[string]string{"source": "other"},
+		},
+		"extension": {
+			Name:             "extension",
+			FilterAttributes: map[string]string{"ext": "a"},
+		},
+		"exact-subject": {
+			Name:    "exact-subject",
+			Filters: []*SubscriptionsAPIFilter{{Exact: map[string]string{"subject": "sub"}}},
+		},
+		"exact-other-subject": {
+			Name: "exact-other-subject",
+			Filters: []*SubscriptionsAPIFilter{
+				{Prefix: map[string]string{"type": "com.example."}},
+				{Exact: map[string]string{"subject": "other"}},
+			},
+		},
+		"nested-exact": {
+			Name: "nested-exact",
+			Filters: []*SubscriptionsAPIFilter{{Any: []*SubscriptionsAPIFilter{
+				{Exact: map[string]string{"type": "com.example.deleted"}},
+				{Exact: map[string]string{"type": "com.example.updated"}},
+			}}},
+		},
+	}
+	ct := &CachedTargets{}
+	ct.Store(&TargetsConfig{CellTenants: map[string]*CellTenant{
+		"ns/broker": {Type: CellTenantType_BROKER, Namespace: "ns", Name: "broker", Targets: targets},
+		"ns/other": {Type: CellTenantType_BROKER, Namespace: "ns", Name: "other", Targets: map[string]*Target{
+			"other-broker": {Name: "other-broker"},
+		}},
+	}})
+
+	e := event.New()
+	e.SetType("com.example.created")
+	e.SetSource("src")
+	e.SetSubject("sub")
+
+	var got []string
+	ct.RangeCandidateTargets(TestOnlyBrokerKey("ns", "broker"), &e, func(t *Target) bool {
+		got = append(got, t.Name)
+		return true
+	})
+	sort.Strings(got)
+	want := []string{"any-type", "exact-subject", "extension", "nested-exact", "no-filter", "type"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RangeCandidateTargets (-want,+got): %v", diff)
+	}
+
+	var count int
+	ct.RangeCandidateTargets(TestOnlyBrokerKey("ns", "broker"), &e, func(*Target) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("RangeCandidateTargets didn't stop after %d targets", count)
+	}
+
+	ct.RangeCandidateTargets(TestOnlyBrokerKey("ns", "missing"), &e, func(target *Target) bool {
+		t.Errorf("RangeCandidateTargets of a missing broker got target %v", target)
+		return true
+	})
+}
+
+func TestRangeCandidateTargetsReindexesOnStore(t *testing.T) {
+	ct := &CachedTargets{}
+	store := func(targetType string) {
+		ct.Store(&TargetsConfig{CellTenants: map[string]*CellTenant{
+			"ns/broker": {Type: CellTenantType_BROKER, Namespace: "ns", Name: "broker", Targets: map[string]*Target{
+				"t": {Name: "t", FilterAttributes: map[string]string{"type": targetType}},
+			}},
+		}})
+	}
+	candidates := func(eventType string) int {
+		e := event.New()
+		e.SetType(eventType)
+		var n int
+		ct.RangeCandidateTargets(TestOnlyBrokerKey("ns", "broker"), &e, func(*Target) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	store("a")
+	if n := candidates("a"); n != 1 {
+		t.Errorf("got %d candidates before the update, want 1", n)
+	}
+	store("b")
+	if n := candidates("a"); n != 0 {
+		t.Errorf("got %d candidates for the old type after the update, want 0", n)
+	}
+	if n := candidates("b"); n != 1 {
+		t.Errorf("got %d candidates for the new type after the update, want 1", n)
+	}
+}
+
+// BenchmarkRangeCandidateTargets compares ranging over the candidate targets of an event with
+// ranging over all targets of the broker, for a broker whose targets filter on distinct types.
+func BenchmarkRangeCandidateTargets(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		targets := make(map[string]*Target, n)
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("target-%d", i)
+			targets[name] = &Target{Name: name, FilterAttributes: map[string]string{"type": fmt.Sprintf("type-%d", i)}}
+		}
+		ct := &CachedTargets{}
+		ct.Store(&TargetsConfig{CellTenants: map[string]*CellTenant{
+			"ns/broker": {Type: CellTenantType_BROKER, Namespace: "ns", Name: "broker", Targets: targets},
+		}})
+		key := TestOnlyBrokerKey("ns", "broker")
+		e := event.New()
+		e.SetType("type-0")
+
+		b.Run(fmt.Sprintf("all/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				broker, _ := ct.GetCellTenantByKey(key)
+				for _, t := range broker.Targets {
+					_ = t.FilterAttributes["type"] == e.Type()
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("candidates/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ct.RangeCandidateTargets(key, &e, func(t *Target) bool {
+					_ = t.FilterAttributes["type"] == e.Type()
+					return true
+				})
+			}
+		})
+	}
+}
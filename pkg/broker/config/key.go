@@ -65,6 +65,16 @@ func (k *CellTenantKey) String() string {
 	return fmt.Sprintf("%s:%s//%s", k.cellTenantType, k.namespace, k.name)
 }
 
+// Namespace is the namespace of the CellTenant.
+func (k *CellTenantKey) Namespace() string {
+	return k.namespace
+}
+
+// Name is the name of the CellTenant.
+func (k *CellTenantKey) Name() string {
+	return k.name
+}
+
 // PersistenceString is the string that is persisted as the key for this Broker in the protobuf. It
 // is stable and can only change if all existing usage locations are made backwards compatible,
 // supporting _both_ the old and the new format, for at least one release.
@@ -54,6 +54,36 @@ func (m *cellTenantMutation) SetState(s config.State) config.CellTenantMutation
 	return m
 }
 
+func (m *cellTenantMutation) SetRateLimit(l *config.RateLimit) config.CellTenantMutation {
+	m.delete = false
+	m.b.RateLimit = l
+	return m
+}
+
+func (m *cellTenantMutation) SetOrderingKeyExtension(ext string) config.CellTenantMutation {
+	m.delete = false
+	m.b.OrderingKeyExtension = ext
+	return m
+}
+
+func (m *cellTenantMutation) SetIngressAuth(a *config.IngressAuth) config.CellTenantMutation {
+	m.delete = false
+	m.b.IngressAuth = a
+	return m
+}
+
+func (m *cellTenantMutation) SetDeadLetter(d *config.DeadLetter) config.CellTenantMutation {
+	m.delete = false
+	m.b.DeadLetter = d
+	return m
+}
+
+func (m *cellTenantMutation) SetGeneration(g int64) config.CellTenantMutation {
+	m.delete = false
+	m.b.Generation = g
+	return m
+}
+
 func (m *cellTenantMutation) UpsertTargets(targets ...*config.Target) config.CellTenantMutation {
 	m.delete = false
 	if m.b.Targets == nil {
@@ -96,6 +96,46 @@ func TestMutateBroker(t *testing.T) {
 		assertBroker(t, wantBroker, targets)
 	})
 
+	t.Run("update broker rate limit", func(t *testing.T) {
+		wantBroker.RateLimit = &config.RateLimit{EventsPerSecond: 10, EventsBurst: 20}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetRateLimit(&config.RateLimit{EventsPerSecond: 10, EventsBurst: 20})
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("update broker ordering key extension", func(t *testing.T) {
+		wantBroker.OrderingKeyExtension = "partitionkey"
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetOrderingKeyExtension("partitionkey")
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("update broker ingress auth", func(t *testing.T) {
+		wantBroker.IngressAuth = &config.IngressAuth{Audiences: []string{"broker"}}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetIngressAuth(&config.IngressAuth{Audiences: []string{"broker"}})
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("update broker dead letter", func(t *testing.T) {
+		wantBroker.DeadLetter = &config.DeadLetter{Address: "http://dls.example.com", MaxDeliveryAttempts: 5}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetDeadLetter(&config.DeadLetter{Address: "http://dls.example.com", MaxDeliveryAttempts: 5})
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("update broker generation", func(t *testing.T) {
+		wantBroker.Generation = 3
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetGeneration(3)
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
 	t1 := &config.Target{
 		Id:             "uid-1",
 		Address:        "consumer1.example.com",
@@ -171,6 +211,11 @@ func TestMutateBroker(t *testing.T) {
 			"t1": t1,
 			"t2": t2,
 		}
+		wantBroker.RateLimit = nil
+		wantBroker.OrderingKeyExtension = ""
+		wantBroker.IngressAuth = nil
+		wantBroker.DeadLetter = nil
+		wantBroker.Generation = 0
 		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
 			// Delete should "delete" the broker.
 			m.Delete()
@@ -0,0 +1,195 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MaxShardBytes is the maximum size of a compressed shard. It leaves room for the metadata of the
+	// ConfigMap holding the shard, which is limited to 1MiB.
+	MaxShardBytes = 768 * 1024
+
+	// MaxShards is the maximum number of shards of a TargetsConfig.
+	MaxShards = 16
+)
+
+// ShardName returns the name of the file, or of the ConfigMap key, holding the shard with the given
+// index of the targets stored under name.
+func ShardName(name string, index int) string {
+	return fmt.Sprintf("%s-%d.gz", name, index)
+}
+
+// Version returns the version of the TargetsConfig, a hash of its content. Equal configs have the
+// same version.
+func Version(tc *TargetsConfig) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(tc)
+	if err != nil {
+		return "", err
+	}
+	return versionOf(data), nil
+}
+
+func versionOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncodeShards compresses the TargetsConfig and splits it into as few shards as possible, sharded by
+// CellTenant key, so that each compressed shard is at most maxBytes. It fails if the TargetsConfig
+// doesn't fit in maxShards shards.
+func EncodeShards(tc *TargetsConfig, maxBytes, maxShards int) ([][]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(tc)
+	if err != nil {
+		return nil, err
+	}
+	version := versionOf(data)
+
+	// The compressed size of the whole config gives the minimum number of shards.
+	whole, err := compress(data)
+	if err != nil {
+		return nil, err
+	}
+	count := (len(whole) + maxBytes - 1) / maxBytes
+	if count == 0 {
+		count = 1
+	}
+	for ; count <= maxShards; count++ {
+		shards, err := encodeShards(tc, version, count)
+		if err != nil {
+			return nil, err
+		}
+		if fitShards(shards, maxBytes) {
+			return shards, nil
+		}
+	}
+	return nil, fmt.Errorf("targets config of %d compressed bytes doesn't fit in %d shards of %d bytes", len(whole), maxShards, maxBytes)
+}
+
+func encodeShards(tc *TargetsConfig, version string, count int) ([][]byte, error) {
+	configs := make([]*TargetsConfig, count)
+	for i := range configs {
+		configs[i] = &TargetsConfig{CellTenants: make(map[string]*CellTenant)}
+	}
+	for key, ct := range tc.CellTenants {
+		configs[shardIndex(key, count)].CellTenants[key] = ct
+	}
+	shards := make([][]byte, count)
+	for i, c := range configs {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(&TargetsConfigShard{
+			Version: version,
+			Index:   int32(i),
+			Count:   int32(count),
+			Config:  c,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if shards[i], err = compress(data); err != nil {
+			return nil, err
+		}
+	}
+	return shards, nil
+}
+
+func fitShards(shards [][]byte, maxBytes int) bool {
+	for _, s := range shards {
+		if len(s) > maxBytes {
+			return false
+		}
+	}
+	return true
+}
+
+// shardIndex returns the index of the shard of the CellTenant with the given key.
+func shardIndex(key string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+// DecodeShard decompresses and unmarshals a shard.
+func DecodeShard(b []byte) (*TargetsConfigShard, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress shard: %w", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress shard: %w", err)
+	}
+	shard := &TargetsConfigShard{}
+	if err := proto.Unmarshal(data, shard); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal shard: %w", err)
+	}
+	return shard, nil
+}
+
+// DecodeShards reassembles a TargetsConfig from its shards, ordered by index. Shards beyond the
+// count of the first shard are ignored. It fails unless all the shards of the first shard's
+// version are present, e.g. while only some of them were updated.
+func DecodeShards(shards [][]byte) (*TargetsConfig, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("no shards")
+	}
+	first, err := DecodeShard(shards[0])
+	if err != nil {
+		return nil, err
+	}
+	count := int(first.Count)
+	if count > len(shards) {
+		return nil, fmt.Errorf("got %d shards of version %s, want %d", len(shards), first.Version, count)
+	}
+	tc := &TargetsConfig{CellTenants: make(map[string]*CellTenant)}
+	for i := 0; i < count; i++ {
+		shard := first
+		if i > 0 {
+			if shard, err = DecodeShard(shards[i]); err != nil {
+				return nil, err
+			}
+		}
+		if shard.Version != first.Version || int(shard.Count) != count || int(shard.Index) != i {
+			return nil, fmt.Errorf("shard %d is %d/%d of version %s, want %d/%d of version %s",
+				i, shard.Index, shard.Count, shard.Version, i, count, first.Version)
+		}
+		for key, ct := range shard.GetConfig().GetCellTenants() {
+			tc.CellTenants[key] = ct
+		}
+	}
+	return tc, nil
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// testShardsConfig returns a TargetsConfig with n brokers, whose addresses don't compress well.
+func testShardsConfig(n int) *TargetsConfig {
+	tc := &TargetsConfig{CellTenants: make(map[string]*CellTenant)}
+	for i := 0; i < n; i++ {
+		ct := &CellTenant{
+			Type:      CellTenantType_BROKER,
+			Namespace: "ns",
+			Name:      fmt.Sprintf("broker-%d", i),
+			Address:   fmt.Sprintf("http://%x.example.com", sha256.Sum256([]byte{byte(i), byte(i >> 8)})),
+		}
+		tc.CellTenants[ct.Key().PersistenceString()] = ct
+	}
+	return tc
+}
+
+func TestEncodeDecodeShards(t *testing.T) {
+	cases := []struct {
+		name      string
+		brokers   int
+		maxBytes  int
+		wantCount int
+	}{{
+		name:      "empty",
+		maxBytes:  MaxShardBytes,
+		wantCount: 1,
+	}, {
+		name:      "small",
+		brokers:   10,
+		maxBytes:  MaxShardBytes,
+		wantCount: 1,
+	}, {
+		name:     "sharded",
+		brokers:  200,
+		maxBytes: 4096,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := testShardsConfig(tc.brokers)
+			shards, err := EncodeShards(want, tc.maxBytes, MaxShards)
+			if err != nil {
+				t.Fatalf("EncodeShards() got unexpected error: %v", err)
+			}
+			if tc.wantCount > 0 && len(shards) != tc.wantCount {
+				t.Errorf("EncodeShards() got %d shards, want %d", len(shards), tc.wantCount)
+			}
+			if tc.wantCount == 0 && len(shards) < 2 {
+				t.Errorf("EncodeShards() got %d shards, want several", len(shards))
+			}
+			for i, s := range shards {
+				if len(s) > tc.maxBytes {
+					t.Errorf("shard %d got %d bytes, want at most %d", i, len(s), tc.maxBytes)
+				}
+			}
+			got, err := DecodeShards(shards)
+			if err != nil {
+				t.Fatalf("DecodeShards() got unexpected error: %v", err)
+			}
+			if !proto.Equal(want, got) {
+				t.Errorf("DecodeShards() got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeShardsTooLarge(t *testing.T) {
+	if _, err := EncodeShards(testShardsConfig(200), 1024, 2); err == nil {
+		t.Error("EncodeShards() got no error for a config that doesn't fit")
+	}
+}
+
+func TestDecodeShardsInconsistent(t *testing.T) {
+	oldShards, err := EncodeShards(testShardsConfig(200), 4096, MaxShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newShards, err := EncodeShards(testShardsConfig(201), 4096, MaxShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldShards) != len(newShards) {
+		t.Fatalf("got %d and %d shards, want the same count", len(oldShards), len(newShards))
+	}
+
+	t.Run("shards of different versions", func(t *testing.T) {
+		mixed := append([][]byte{newShards[0]}, oldShards[1:]...)
+		if _, err := DecodeShards(mixed); err == nil {
+			t.Error("DecodeShards() got no error for shards of different versions")
+		}
+	})
+
+	t.Run("missing shard", func(t *testing.T) {
+		if _, err := DecodeShards(newShards[:len(newShards)-1]); err == nil {
+			t.Error("DecodeShards() got no error for a missing shard")
+		}
+	})
+
+	t.Run("stale shards are ignored", func(t *testing.T) {
+		small, err := EncodeShards(testShardsConfig(1), 4096, MaxShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecodeShards(append(small, oldShards[1:]...))
+		if err != nil {
+			t.Fatalf("DecodeShards() got unexpected error: %v", err)
+		}
+		if !proto.Equal(testShardsConfig(1), got) {
+			t.Errorf("DecodeShards() got=%v, want=%v", got, testShardsConfig(1))
+		}
+	})
+
+	t.Run("not a shard", func(t *testing.T) {
+		if _, err := DecodeShards([][]byte{[]byte("targets")}); err == nil {
+			t.Error("DecodeShards() got no error for an invalid shard")
+		}
+	})
+}
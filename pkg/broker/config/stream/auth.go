@@ -0,0 +1,152 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+)
+
+const (
+	// TokenAudience is the audience of the projected service account tokens the data plane pods
+	// authenticate to the Server with.
+	TokenAudience = "targets-config.events.cloud.google.com"
+
+	// serviceAccountPrefix is the prefix of the usernames of service accounts.
+	serviceAccountPrefix = "system:serviceaccount:"
+	// podNameKey is the extra info of the user of a token bound to a pod with the pod name.
+	podNameKey = "authentication.kubernetes.io/pod-name"
+	// reviewTTL is how long the caller of a reviewed token is cached.
+	reviewTTL = time.Minute
+)
+
+// ErrUnauthenticated is the error of a token that doesn't authenticate a service account.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Caller is the service account that made a request to the Server.
+type Caller struct {
+	Namespace      string
+	ServiceAccount string
+	// Pod is the name of the pod the token of the caller is bound to, or empty if the token is not
+	// bound to a pod.
+	Pod string
+}
+
+// Authenticator authenticates the bearer tokens of the requests to the Server.
+type Authenticator interface {
+	// Authenticate returns the caller the token was issued to, or an error wrapping
+	// ErrUnauthenticated if the token is not valid.
+	Authenticate(ctx context.Context, token string) (*Caller, error)
+}
+
+// TokenReviewAuthenticator authenticates Kubernetes service account tokens with the TokenReview
+// API. The tokens must have the given audience. Reviewed tokens are cached for a minute, as the
+// data plane pods poll with the same token until the kubelet rotates it.
+type TokenReviewAuthenticator struct {
+	client   authenticationv1client.TokenReviewInterface
+	audience string
+	now      func() time.Time
+
+	mu      sync.Mutex
+	reviews map[string]review
+}
+
+type review struct {
+	caller  *Caller
+	expires time.Time
+}
+
+var _ Authenticator = (*TokenReviewAuthenticator)(nil)
+
+// NewTokenReviewAuthenticator creates a TokenReviewAuthenticator of the tokens with the audience.
+func NewTokenReviewAuthenticator(client authenticationv1client.TokenReviewInterface, audience string) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{
+		client:   client,
+		audience: audience,
+		now:      time.Now,
+		reviews:  make(map[string]review),
+	}
+}
+
+// Authenticate implements Authenticator.
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, token string) (*Caller, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: no token", ErrUnauthenticated)
+	}
+	if caller, ok := a.cached(token); ok {
+		return caller, nil
+	}
+	tr, err := a.client.Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: []string{a.audience}},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to review token: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthenticated, tr.Status.Error)
+	}
+	caller, err := callerOf(tr.Status.User)
+	if err != nil {
+		return nil, err
+	}
+	a.cache(token, caller)
+	return caller, nil
+}
+
+func (a *TokenReviewAuthenticator) cached(token string) (*Caller, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r, ok := a.reviews[token]
+	if !ok || a.now().After(r.expires) {
+		return nil, false
+	}
+	return r.caller, true
+}
+
+// cache caches the caller of the token, and forgets the expired reviews.
+func (a *TokenReviewAuthenticator) cache(token string, caller *Caller) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for t, r := range a.reviews {
+		if now.After(r.expires) {
+			delete(a.reviews, t)
+		}
+	}
+	a.reviews[token] = review{caller: caller, expires: now.Add(reviewTTL)}
+}
+
+// callerOf returns the service account of the user of a reviewed token.
+func callerOf(user authenticationv1.UserInfo) (*Caller, error) {
+	parts := strings.Split(strings.TrimPrefix(user.Username, serviceAccountPrefix), ":")
+	if !strings.HasPrefix(user.Username, serviceAccountPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("%w: %q is not a service account", ErrUnauthenticated, user.Username)
+	}
+	caller := &Caller{Namespace: parts[0], ServiceAccount: parts[1]}
+	if pods := user.Extra[podNameKey]; len(pods) == 1 {
+		caller.Pod = pods[0]
+	}
+	return caller, nil
+}
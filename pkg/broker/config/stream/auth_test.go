@@ -0,0 +1,125 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+)
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	users := map[string]authenticationv1.UserInfo{
+		"pod-token": {
+			Username: "system:serviceaccount:ns:broker",
+			Extra:    map[string]authenticationv1.ExtraValue{podNameKey: {"pod"}},
+		},
+		"sa-token": {
+			Username: "system:serviceaccount:ns:broker",
+		},
+		"user-token": {
+			Username: "someone@example.com",
+		},
+	}
+	var reviews int
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		tr := action.(clientgotesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if diff := cmp.Diff([]string{TokenAudience}, tr.Spec.Audiences); diff != "" {
+			t.Errorf("Unexpected token review audiences (-want,+got): %v", diff)
+		}
+		user, ok := users[tr.Spec.Token]
+		tr.Status = authenticationv1.TokenReviewStatus{Authenticated: ok, User: user}
+		if !ok {
+			tr.Status.Error = "invalid token"
+		}
+		return true, tr, nil
+	})
+	a := NewTokenReviewAuthenticator(client.AuthenticationV1().TokenReviews(), TokenAudience)
+
+	cases := []struct {
+		name    string
+		token   string
+		want    *Caller
+		wantErr bool
+	}{{
+		name:  "token bound to a pod",
+		token: "pod-token",
+		want:  &Caller{Namespace: "ns", ServiceAccount: "broker", Pod: "pod"},
+	}, {
+		name:  "token not bound to a pod",
+		token: "sa-token",
+		want:  &Caller{Namespace: "ns", ServiceAccount: "broker"},
+	}, {
+		name:    "not a service account",
+		token:   "user-token",
+		wantErr: true,
+	}, {
+		name:    "invalid token",
+		token:   "invalid-token",
+		wantErr: true,
+	}, {
+		name:    "no token",
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := a.Authenticate(context.Background(), tc.token)
+			if tc.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Unexpected error, got %v, want %v", err, ErrUnauthenticated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected caller (-want,+got): %v", diff)
+			}
+		})
+	}
+
+	// Reviewed tokens are cached until the review expires.
+	a = NewTokenReviewAuthenticator(client.AuthenticationV1().TokenReviews(), TokenAudience)
+	reviews = 0
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authenticate(context.Background(), "pod-token"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if reviews != 1 {
+		t.Errorf("Unexpected number of token reviews, got %d, want 1", reviews)
+	}
+	now = now.Add(reviewTTL + time.Second)
+	if _, err := a.Authenticate(context.Background(), "pod-token"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reviews != 2 {
+		t.Errorf("Unexpected number of token reviews after expiry, got %d, want 2", reviews)
+	}
+}
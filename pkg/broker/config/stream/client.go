@@ -0,0 +1,251 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"knative.dev/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+)
+
+const (
+	defaultRetryDelay = 5 * time.Second
+	// defaultClientTimeout leaves room for the poll timeout of the server.
+	defaultClientTimeout = 2 * defaultPollTimeout
+)
+
+var errNotFound = errors.New("the server has no targets config for the BrokerCell")
+
+// Targets implements config.ReadonlyTargets with data streamed
+// from a Server. It starts with the targets loaded from the volume,
+// and falls back to the updates of the volume while the server
+// can't be reached.
+type Targets struct {
+	config.CachedTargets
+	url        string
+	tokenFile  string
+	client     *http.Client
+	retryDelay time.Duration
+	notifyChan chan<- struct{}
+	volumeOpts []volume.Option
+	fallback   *volume.Targets
+
+	// mu guards version and streaming.
+	mu sync.Mutex
+	// version is the version of the streamed config, or empty if the config was loaded from the
+	// volume.
+	version string
+	// streaming is true while the server can be reached.
+	streaming bool
+}
+
+var _ config.ReadonlyTargets = (*Targets)(nil)
+
+// NewTargets initializes the targets config from the volume and starts streaming updates from the
+// server until the context is done. It only loads the targets from the volume if no URL is given.
+func NewTargets(ctx context.Context, opts ...Option) (config.ReadonlyTargets, error) {
+	t := &Targets{
+		client:     &http.Client{Timeout: defaultClientTimeout},
+		retryDelay: defaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.url == "" {
+		return volume.NewTargetsFromFile(append(t.volumeOpts, volume.WithNotifyChan(t.notifyChan))...)
+	}
+
+	volumeCh := make(chan struct{})
+	fallback, err := volume.NewTargetsFromFile(append(t.volumeOpts, volume.WithNotifyChan(volumeCh))...)
+	if err != nil {
+		return nil, err
+	}
+	t.fallback = fallback.(*volume.Targets)
+	t.Store(t.fallback.Load())
+
+	go t.watchVolume(ctx, volumeCh)
+	go t.stream(ctx)
+	return t, nil
+}
+
+// watchVolume loads the targets from the volume when it's updated while the server can't be
+// reached.
+func (t *Targets) watchVolume(ctx context.Context, ch <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+		t.mu.Lock()
+		streaming := t.streaming
+		if !streaming {
+			t.Store(t.fallback.Load())
+		}
+		t.mu.Unlock()
+		if !streaming {
+			t.notify(ctx)
+		}
+	}
+}
+
+// stream polls the server for updates until the context is done.
+func (t *Targets) stream(ctx context.Context) {
+	logger := logging.FromContext(ctx).With(zap.String("url", t.url))
+	for ctx.Err() == nil {
+		upd, err := t.poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			t.mu.Lock()
+			if t.streaming {
+				logger.Warn("Failed to stream targets config, falling back to the volume", zap.Error(err))
+			}
+			// The volume may update the targets from now on, so the next update must be a snapshot.
+			t.version, t.streaming = "", false
+			t.mu.Unlock()
+			select {
+			case <-ctx.Done():
+			case <-time.After(t.retryDelay):
+			}
+			continue
+		}
+		if upd == nil {
+			continue
+		}
+		if err := t.apply(upd); err != nil {
+			logger.Warn("Failed to apply targets config update, requesting a snapshot", zap.Error(err))
+			continue
+		}
+		logger.Debug("Streamed targets config", zap.String("version", upd.Version))
+		t.notify(ctx)
+	}
+}
+
+// poll requests the update from the version the client has. It returns nil if the config didn't
+// change before the server's poll timeout.
+func (t *Targets) poll(ctx context.Context) (*config.TargetsConfigUpdate, error) {
+	t.mu.Lock()
+	version := t.version
+	t.mu.Unlock()
+
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set(VersionParam, version)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.tokenFile != "" {
+		token, err := ioutil.ReadFile(t.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusNotFound:
+		return nil, errNotFound
+	default:
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	upd := &config.TargetsConfigUpdate{}
+	if err := proto.Unmarshal(data, upd); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal targets config update: %w", err)
+	}
+	return upd, nil
+}
+
+// apply stores the config of a snapshot, or applies a delta to the streamed config. If the delta
+// doesn't apply, the version is reset so that the next poll gets a snapshot.
+func (t *Targets) apply(upd *config.TargetsConfigUpdate) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if upd.BaseVersion == "" {
+		tc := upd.Config
+		if tc == nil {
+			tc = &config.TargetsConfig{}
+		}
+		t.Store(tc)
+		t.version, t.streaming = upd.Version, true
+		return nil
+	}
+
+	if !t.streaming || upd.BaseVersion != t.version {
+		have := t.version
+		t.version = ""
+		return fmt.Errorf("delta from version %q doesn't apply to version %q", upd.BaseVersion, have)
+	}
+	tc := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	for key, ct := range t.Load().GetCellTenants() {
+		tc.CellTenants[key] = ct
+	}
+	for key, ct := range upd.Config.GetCellTenants() {
+		tc.CellTenants[key] = ct
+	}
+	for _, key := range upd.DeletedCellTenants {
+		delete(tc.CellTenants, key)
+	}
+	if version, err := config.Version(tc); err != nil || version != upd.Version {
+		t.version = ""
+		return fmt.Errorf("delta to version %q resulted in version %q", upd.Version, version)
+	}
+	t.Store(tc)
+	t.version = upd.Version
+	return nil
+}
+
+func (t *Targets) notify(ctx context.Context) {
+	if t.notifyChan == nil {
+		return
+	}
+	select {
+	case t.notifyChan <- struct{}{}:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+)
+
+// writeVolume atomically writes the config to the file, like a ConfigMap volume update.
+func writeVolume(t *testing.T, path string, tc *config.TargetsConfig) {
+	t.Helper()
+	b, err := proto.Marshal(tc)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to rename config file: %v", err)
+	}
+}
+
+// waitForConfig waits for a notification after which the targets have the given config.
+func waitForConfig(t *testing.T, ch <-chan struct{}, targets config.ReadonlyTargets, want *config.TargetsConfig) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-ch:
+		case <-timeout:
+			t.Fatalf("Timed out waiting for config, got=%+v, want=%+v", targets.(*Targets).Load(), want)
+		}
+		if proto.Equal(targets.(*Targets).Load(), want) {
+			return
+		}
+	}
+}
+
+func TestStreamTargets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "streamtest-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "targets")
+	fromVolume := targetsConfig(broker("volume", "a"))
+	writeVolume(t, path, fromVolume)
+
+	tokenPath := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenPath, []byte("token\n"), 0644); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+
+	s := NewLocalServer(100 * time.Millisecond)
+	defer s.Close()
+	s.RequireAuthentication(fakeAuthenticator{"token": {Namespace: "ns", ServiceAccount: "broker", Pod: "pod"}}, "broker")
+
+	ch := make(chan struct{}, 10)
+	targets, err := NewTargets(ctx,
+		WithURL(s.URL("ns", "cell")),
+		WithVolumeOptions(volume.WithPath(path)),
+		WithNotifyChan(ch),
+		WithRetryDelay(10*time.Millisecond),
+		WithTokenFile(tokenPath),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create targets: %v", err)
+	}
+	// The server has no config yet.
+	if got := targets.(*Targets).Load(); !proto.Equal(got, fromVolume) {
+		t.Errorf("Unexpected initial targets, got=%+v, want=%+v", got, fromVolume)
+	}
+
+	// A snapshot.
+	b1, b2 := broker("b1", "a1"), broker("b2", "a2")
+	tc := targetsConfig(b1, b2)
+	if _, err := s.Publish("ns", "cell", tc); err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	waitForConfig(t, ch, targets, tc)
+
+	// The client reports the config it applied.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if applied, ok := s.Applied("ns", "cell"); ok && proto.Equal(applied, tc) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the client to report the applied config")
+		}
+	}
+
+	// Updates of the volume are ignored while streaming.
+	writeVolume(t, path, targetsConfig(broker("volume", "b")))
+	time.Sleep(200 * time.Millisecond)
+	if got := targets.(*Targets).Load(); !proto.Equal(got, tc) {
+		t.Errorf("Unexpected targets after volume update, got=%+v, want=%+v", got, tc)
+	}
+
+	// A delta.
+	tc = targetsConfig(broker("b1", "changed"), broker("b3", "a3"))
+	if _, err := s.Publish("ns", "cell", tc); err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	waitForConfig(t, ch, targets, tc)
+
+	// Falls back to the volume updates without the server.
+	s.Delete("ns", "cell")
+	time.Sleep(200 * time.Millisecond)
+	fromVolume = targetsConfig(broker("volume", "c"))
+	writeVolume(t, path, fromVolume)
+	waitForConfig(t, ch, targets, fromVolume)
+
+	// Streams again once the server has a config.
+	if _, err := s.Publish("ns", "cell", tc); err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	waitForConfig(t, ch, targets, tc)
+}
+
+func TestStreamTargetsWithoutURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "streamtest-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "targets")
+	tc := targetsConfig(broker("volume", "a"))
+	writeVolume(t, path, tc)
+
+	targets, err := NewTargets(context.Background(), WithVolumeOptions(volume.WithPath(path)))
+	if err != nil {
+		t.Fatalf("Failed to create targets: %v", err)
+	}
+	vt, ok := targets.(*volume.Targets)
+	if !ok {
+		t.Fatalf("Expected targets loaded from the volume, got %T", targets)
+	}
+	if got := vt.Load(); !proto.Equal(got, tc) {
+		t.Errorf("Unexpected targets, got=%+v, want=%+v", got, tc)
+	}
+}
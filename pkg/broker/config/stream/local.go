@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"net/http/httptest"
+	"time"
+)
+
+// LocalServer is a Server listening on a local port, to stream targets config in process, e.g. in
+// tests, without a controller.
+type LocalServer struct {
+	*Server
+	srv *httptest.Server
+}
+
+// NewLocalServer starts a LocalServer with the given poll timeout.
+func NewLocalServer(pollTimeout time.Duration) *LocalServer {
+	s := NewServer()
+	s.pollTimeout = pollTimeout
+	s.setServing()
+	return &LocalServer{Server: s, srv: httptest.NewServer(s)}
+}
+
+// URL returns the URL of the targets config of the BrokerCell, to stream it with WithURL.
+func (s *LocalServer) URL(namespace, name string) string {
+	return s.srv.URL + TargetsPath(namespace, name)
+}
+
+// Close stops the server, after the requests in progress are answered.
+func (s *LocalServer) Close() {
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+)
+
+// Option is the option to load targets.
+type Option func(*Targets)
+
+// WithURL is the option to stream targets from the given URL of a Server, including the path of the
+// BrokerCell (see TargetsPath). Without a URL, targets are only loaded from the volume.
+func WithURL(url string) Option {
+	return func(t *Targets) {
+		t.url = url
+	}
+}
+
+// WithVolumeOptions is the option to load the targets from the volume with the given options
+// when they can't be streamed.
+func WithVolumeOptions(opts ...volume.Option) Option {
+	return func(t *Targets) {
+		t.volumeOpts = append(t.volumeOpts, opts...)
+	}
+}
+
+// WithNotifyChan is the option to notify the given channel
+// when the config cache was updated.
+func WithNotifyChan(ch chan<- struct{}) Option {
+	return func(t *Targets) {
+		t.notifyChan = ch
+	}
+}
+
+// WithTokenFile is the option to authenticate to the server with the token in the given file,
+// e.g. a projected service account token with the TokenAudience. The file is read for every poll,
+// as the token is rotated.
+func WithTokenFile(path string) Option {
+	return func(t *Targets) {
+		t.tokenFile = path
+	}
+}
+
+// WithHTTPClient is the option to stream targets with the given HTTP client.
+func WithHTTPClient(c *http.Client) Option {
+	return func(t *Targets) {
+		t.client = c
+	}
+}
+
+// WithRetryDelay is the option to wait for the given delay before polling the server again after
+// an error.
+func WithRetryDelay(d time.Duration) Option {
+	return func(t *Targets) {
+		t.retryDelay = d
+	}
+}
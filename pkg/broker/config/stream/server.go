@@ -0,0 +1,472 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stream distributes the targets config of BrokerCells from the controller to the data
+// plane over HTTP long polling. The controller publishes each new config to a Server, and the data
+// plane pods poll it with the version they have, getting the delta from that version as soon as
+// the config changes. The versions the pods poll with also tell the controller which config the
+// data plane applied.
+//
+// When the Server requires authentication, the pods poll with a projected service account token
+// with the TokenAudience, and only get the config of the BrokerCells of their namespace. The pod
+// a token is bound to is the pod whose version is reported.
+package stream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"knative.dev/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const (
+	// PathPrefix is the path under which the Server serves the targets config of BrokerCells, at
+	// PathPrefix + "<namespace>/<name>".
+	PathPrefix = "/targets/"
+	// VersionParam is the query parameter with the version of the targets config the client has,
+	// which is the version it applied.
+	VersionParam = "version"
+	// ContentType is the content type of the serialized config.TargetsConfigUpdate responses.
+	ContentType = "application/x-protobuf"
+
+	defaultPollTimeout = 30 * time.Second
+	// historySize is the number of the latest versions of a targets config that deltas are computed
+	// from. Clients with an older version get a full snapshot.
+	historySize = 8
+	// reportTTL is the number of poll timeouts after which a pod that stopped polling is no longer
+	// taken into account to tell the config applied by the data plane.
+	reportTTL = 2
+)
+
+// TargetsPath returns the path of the targets config of the BrokerCell.
+func TargetsPath(namespace, name string) string {
+	return PathPrefix + namespace + "/" + name
+}
+
+// Server serves the latest targets config published for each BrokerCell. A request blocks until
+// the config differs from the version given by the client, or until the poll timeout, in which
+// case the response is 304 Not Modified. Requests for a BrokerCell without a config get 404 Not
+// Found.
+type Server struct {
+	mu          sync.Mutex
+	cells       map[string]*cell
+	pollTimeout time.Duration
+	// observers are called when the config applied by the data plane of a BrokerCell changes.
+	observers []func(namespace, name string)
+	// serving is true once the Server serves the data plane.
+	serving bool
+	// auth authenticates the requests, if authentication is required.
+	auth Authenticator
+	// serviceAccount is the service account of the data plane pods, if authentication is required.
+	serviceAccount string
+	// pods lists the data plane pods of the BrokerCells, if they are tracked.
+	pods Pods
+}
+
+// Pods lists the data plane pods of the BrokerCells.
+type Pods interface {
+	// Pods returns the names of the data plane pods of the BrokerCell, mapped to whether they are
+	// ready.
+	Pods(namespace, name string) (map[string]bool, error)
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// cell holds the targets config of a BrokerCell.
+type cell struct {
+	version string
+	config  *config.TargetsConfig
+	// history holds the latest versions of the config, oldest first, including the current one.
+	history []snapshot
+	// changed is closed when the config changes.
+	changed chan struct{}
+	// reports holds the last version each data plane pod polled with, by pod name.
+	reports map[string]report
+	// applied is the version of the config applied by the data plane, or empty if unknown.
+	applied string
+}
+
+type report struct {
+	version string
+	at      time.Time
+}
+
+type snapshot struct {
+	version string
+	config  *config.TargetsConfig
+}
+
+// NewServer creates a Server without any targets config.
+func NewServer() *Server {
+	return &Server{
+		cells:       make(map[string]*cell),
+		pollTimeout: defaultPollTimeout,
+	}
+}
+
+// Publish makes tc the targets config of the BrokerCell, and wakes up the requests waiting for a
+// change. It returns the version of tc. tc must not be modified afterwards.
+func (s *Server) Publish(namespace, name string, tc *config.TargetsConfig) (string, error) {
+	version, err := config.Version(tc)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := namespace + "/" + name
+	c, ok := s.cells[key]
+	if !ok {
+		c = &cell{changed: make(chan struct{}), reports: make(map[string]report)}
+		s.cells[key] = c
+	}
+	if c.version == version {
+		return version, nil
+	}
+	c.version, c.config = version, tc
+	c.history = append(c.history, snapshot{version: version, config: tc})
+	if len(c.history) > historySize {
+		c.history = c.history[len(c.history)-historySize:]
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return version, nil
+}
+
+// Delete removes the targets config of the BrokerCell. The requests waiting for a change are
+// answered with 404 Not Found.
+func (s *Server) Delete(namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := namespace + "/" + name
+	if c, ok := s.cells[key]; ok {
+		close(c.changed)
+		delete(s.cells, key)
+	}
+}
+
+// RequireAuthentication makes the Server only serve the requests authenticated by auth as the
+// given service account, in the namespace of the BrokerCell. It must be called before the Server
+// serves requests.
+func (s *Server) RequireAuthentication(auth Authenticator, serviceAccount string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth, s.serviceAccount = auth, serviceAccount
+}
+
+// TrackPods makes the Server only take the reports of the data plane pods of a BrokerCell into
+// account, and only know the config applied by the data plane once all its ready pods reported
+// it. It must be called before the Server serves requests.
+func (s *Server) TrackPods(pods Pods) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pods = pods
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, PathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	caller, status := s.authorize(r, key)
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	have := r.URL.Query().Get(VersionParam)
+	if caller != nil && caller.Pod != "" {
+		s.report(key, caller.Pod, have)
+	}
+
+	timer := time.NewTimer(s.pollTimeout)
+	defer timer.Stop()
+	for {
+		upd, changed, ok := s.update(key, have)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if upd != nil {
+			s.write(r.Context(), w, upd)
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// authorize checks that the request is allowed to get the config of the BrokerCell, and returns
+// its caller, which is nil if authentication is not required. It returns the status of the
+// response otherwise.
+func (s *Server) authorize(r *http.Request, key string) (*Caller, int) {
+	s.mu.Lock()
+	auth, serviceAccount := s.auth, s.serviceAccount
+	s.mu.Unlock()
+	if auth == nil {
+		return nil, http.StatusOK
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	caller, err := auth.Authenticate(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrUnauthenticated) {
+			return nil, http.StatusUnauthorized
+		}
+		logging.FromContext(r.Context()).Error("Failed to authenticate targets config request", zap.Error(err))
+		return nil, http.StatusInternalServerError
+	}
+	namespace, _ := splitKey(key)
+	if caller.Namespace != namespace || caller.ServiceAccount != serviceAccount {
+		return nil, http.StatusForbidden
+	}
+	return caller, http.StatusOK
+}
+
+// Report records that the data plane pod applied the given version of the targets config of the
+// BrokerCell. It is called for each poll of a pod.
+func (s *Server) Report(namespace, name, pod, version string) {
+	s.report(namespace+"/"+name, pod, version)
+}
+
+func (s *Server) report(key, pod, version string) {
+	s.mu.Lock()
+	c, ok := s.cells[key]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	namespace, name := splitKey(key)
+	pods, err := s.listPods(namespace, name)
+	if err != nil {
+		s.mu.Unlock()
+		logging.FromContext(context.Background()).Error("Failed to list the data plane pods", zap.String("brokercell", key), zap.Error(err))
+		return
+	}
+	// Only the pods of the BrokerCell tell the config its data plane applied.
+	if _, ok := pods[pod]; pods != nil && !ok {
+		s.mu.Unlock()
+		return
+	}
+	c.reports[pod] = report{version: version, at: time.Now()}
+	applied, _ := s.applied(c, pods)
+	changed := applied.version != c.applied
+	c.applied = applied.version
+	observers := s.observers
+	s.mu.Unlock()
+
+	if changed {
+		for _, f := range observers {
+			go f(namespace, name)
+		}
+	}
+}
+
+// Applied returns the targets config of the BrokerCell that all its data plane pods applied, that is
+// the oldest version that a pod polled with lately. It returns false if it is unknown, either
+// because no pod polled lately, because a ready pod of the BrokerCell didn't poll yet, or because
+// a pod has a version that is no longer in the history.
+func (s *Server) Applied(namespace, name string) (*config.TargetsConfig, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cells[namespace+"/"+name]
+	if !ok {
+		return nil, false
+	}
+	pods, err := s.listPods(namespace, name)
+	if err != nil {
+		return nil, false
+	}
+	applied, ok := s.applied(c, pods)
+	return applied.config, ok
+}
+
+// listPods returns the data plane pods of the BrokerCell, mapped to whether they are ready, or nil
+// if the pods are not tracked. It must be called with the lock held.
+func (s *Server) listPods(namespace, name string) (map[string]bool, error) {
+	if s.pods == nil {
+		return nil, nil
+	}
+	pods, err := s.pods.Pods(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if pods == nil {
+		pods = make(map[string]bool)
+	}
+	return pods, nil
+}
+
+// applied returns the snapshot of the config applied by the data plane of the cell, and forgets
+// about the pods that stopped polling or are no longer pods of the cell. If the pods are tracked,
+// all the ready pods must have reported. It must be called with the lock held.
+func (s *Server) applied(c *cell, pods map[string]bool) (snapshot, bool) {
+	for pod, r := range c.reports {
+		_, isPod := pods[pod]
+		if time.Since(r.at) > reportTTL*s.pollTimeout || (pods != nil && !isPod) {
+			delete(c.reports, pod)
+		}
+	}
+	for pod, ready := range pods {
+		if _, ok := c.reports[pod]; ready && !ok {
+			return snapshot{}, false
+		}
+	}
+	oldest := -1
+	for pod, r := range c.reports {
+		if pods != nil && !pods[pod] {
+			// The pods that are not ready don't tell the applied config.
+			continue
+		}
+		i := historyIndex(c.history, r.version)
+		if i < 0 {
+			return snapshot{}, false
+		}
+		if oldest < 0 || i < oldest {
+			oldest = i
+		}
+	}
+	if oldest < 0 {
+		return snapshot{}, false
+	}
+	return c.history[oldest], true
+}
+
+func historyIndex(history []snapshot, version string) int {
+	if version == "" {
+		return -1
+	}
+	for i, old := range history {
+		if old.version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func splitKey(key string) (namespace, name string) {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// OnApplied registers f to be called when the targets config applied by the data plane of a
+// BrokerCell changes.
+func (s *Server) OnApplied(f func(namespace, name string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, f)
+}
+
+// Serving returns true once the Server serves the data plane, i.e. once the data plane reports the
+// targets config it applied.
+func (s *Server) Serving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serving
+}
+
+func (s *Server) setServing() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serving = true
+}
+
+// update returns the update of the targets config of the BrokerCell from the given version, or nil
+// if the config is still at that version, along with a channel closed on the next change. It
+// returns false if the BrokerCell has no config.
+func (s *Server) update(key, have string) (*config.TargetsConfigUpdate, <-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.cells[key]
+	if !ok {
+		return nil, nil, false
+	}
+	if c.version == have {
+		return nil, c.changed, true
+	}
+	if i := historyIndex(c.history, have); i >= 0 {
+		return delta(c.history[i], snapshot{version: c.version, config: c.config}), c.changed, true
+	}
+	return &config.TargetsConfigUpdate{Version: c.version, Config: c.config}, c.changed, true
+}
+
+func (s *Server) write(ctx context.Context, w http.ResponseWriter, upd *config.TargetsConfigUpdate) {
+	data, err := proto.Marshal(upd)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to serialize targets config update", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// delta returns the update from the old to the new config, with the CellTenants that were added or
+// changed, and the keys of the ones that were deleted.
+func delta(from, to snapshot) *config.TargetsConfigUpdate {
+	upd := &config.TargetsConfigUpdate{
+		Version:     to.version,
+		BaseVersion: from.version,
+		Config:      &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)},
+	}
+	for key, ct := range to.config.GetCellTenants() {
+		if old, ok := from.config.GetCellTenants()[key]; !ok || !proto.Equal(old, ct) {
+			upd.Config.CellTenants[key] = ct
+		}
+	}
+	for key := range from.config.GetCellTenants() {
+		if _, ok := to.config.GetCellTenants()[key]; !ok {
+			upd.DeletedCellTenants = append(upd.DeletedCellTenants, key)
+		}
+	}
+	sort.Strings(upd.DeletedCellTenants)
+	return upd
+}
+
+// ListenAndServe serves the targets config on the given address until the context is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s}
+	s.setServing()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func broker(name, address string) *config.CellTenant {
+	return &config.CellTenant{
+		Id:        "id-" + name,
+		Type:      config.CellTenantType_BROKER,
+		Name:      name,
+		Namespace: "ns",
+		Address:   address,
+		State:     config.State_READY,
+	}
+}
+
+func targetsConfig(brokers ...*config.CellTenant) *config.TargetsConfig {
+	tc := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	for _, b := range brokers {
+		tc.CellTenants[b.Key().PersistenceString()] = b
+	}
+	return tc
+}
+
+func mustVersion(t *testing.T, tc *config.TargetsConfig) string {
+	t.Helper()
+	v, err := config.Version(tc)
+	if err != nil {
+		t.Fatalf("Failed to get config version: %v", err)
+	}
+	return v
+}
+
+// get polls the server with the given version, and returns the response status and update.
+func get(t *testing.T, s *LocalServer, version string) (int, *config.TargetsConfigUpdate) {
+	t.Helper()
+	resp, err := http.Get(s.URL("ns", "cell") + "?" + VersionParam + "=" + url.QueryEscape(version))
+	if err != nil {
+		t.Fatalf("Failed to poll the server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the response: %v", err)
+	}
+	upd := &config.TargetsConfigUpdate{}
+	if err := proto.Unmarshal(data, upd); err != nil {
+		t.Fatalf("Failed to unmarshal the update: %v", err)
+	}
+	return resp.StatusCode, upd
+}
+
+func TestServerNotFound(t *testing.T) {
+	s := NewLocalServer(time.Second)
+	defer s.Close()
+
+	if status, _ := get(t, s, ""); status != http.StatusNotFound {
+		t.Errorf("Unexpected status without config, got %d, want %d", status, http.StatusNotFound)
+	}
+
+	if _, err := s.Publish("ns", "cell", targetsConfig()); err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	s.Delete("ns", "cell")
+	if status, _ := get(t, s, ""); status != http.StatusNotFound {
+		t.Errorf("Unexpected status after delete, got %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestServerSnapshot(t *testing.T) {
+	s := NewLocalServer(100 * time.Millisecond)
+	defer s.Close()
+
+	tc := targetsConfig(broker("b1", "a1"), broker("b2", "a2"))
+	version, err := s.Publish("ns", "cell", tc)
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	if want := mustVersion(t, tc); version != want {
+		t.Errorf("Unexpected published version, got %q, want %q", version, want)
+	}
+
+	for _, have := range []string{"", "unknown-version"} {
+		status, upd := get(t, s, have)
+		if status != http.StatusOK {
+			t.Fatalf("Unexpected status from version %q, got %d, want %d", have, status, http.StatusOK)
+		}
+		want := &config.TargetsConfigUpdate{Version: version, Config: tc}
+		if diff := cmp.Diff(want, upd, protocmp.Transform()); diff != "" {
+			t.Errorf("Unexpected snapshot from version %q (-want,+got): %v", have, diff)
+		}
+	}
+
+	if status, _ := get(t, s, version); status != http.StatusNotModified {
+		t.Errorf("Unexpected status from the current version, got %d, want %d", status, http.StatusNotModified)
+	}
+}
+
+func TestServerDelta(t *testing.T) {
+	s := NewLocalServer(10 * time.Second)
+	defer s.Close()
+
+	b1, b2, b3 := broker("b1", "a1"), broker("b2", "a2"), broker("b3", "a3")
+	from, err := s.Publish("ns", "cell", targetsConfig(b1, b2))
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+
+	// The poll from the current version waits for the next change.
+	b1Changed := broker("b1", "changed")
+	tc := targetsConfig(b1Changed, b3)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.Publish("ns", "cell", tc)
+	}()
+	status, upd := get(t, s, from)
+	if status != http.StatusOK {
+		t.Fatalf("Unexpected status, got %d, want %d", status, http.StatusOK)
+	}
+	want := &config.TargetsConfigUpdate{
+		Version:            mustVersion(t, tc),
+		BaseVersion:        from,
+		Config:             targetsConfig(b1Changed, b3),
+		DeletedCellTenants: []string{b2.Key().PersistenceString()},
+	}
+	if diff := cmp.Diff(want, upd, protocmp.Transform()); diff != "" {
+		t.Errorf("Unexpected delta (-want,+got): %v", diff)
+	}
+}
+
+func TestServerHistory(t *testing.T) {
+	s := NewLocalServer(time.Second)
+	defer s.Close()
+
+	first, err := s.Publish("ns", "cell", targetsConfig(broker("b", "a0")))
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	for i := 1; i <= historySize; i++ {
+		if _, err := s.Publish("ns", "cell", targetsConfig(broker("b", string(rune('a'+i))))); err != nil {
+			t.Fatalf("Failed to publish config: %v", err)
+		}
+	}
+	// The first version is out of the history, so the client gets a snapshot.
+	_, upd := get(t, s, first)
+	if upd.GetBaseVersion() != "" {
+		t.Errorf("Expected a snapshot from a version out of the history, got a delta from %q", upd.GetBaseVersion())
+	}
+}
+
+func TestServerApplied(t *testing.T) {
+	s := NewLocalServer(50 * time.Millisecond)
+	defer s.Close()
+
+	appliedCh := make(chan struct{}, 10)
+	s.OnApplied(func(namespace, name string) {
+		if namespace == "ns" && name == "cell" {
+			appliedCh <- struct{}{}
+		}
+	})
+	waitApplied := func() {
+		t.Helper()
+		select {
+		case <-appliedCh:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the applied config to change")
+		}
+	}
+	wantApplied := func(want *config.TargetsConfig) {
+		t.Helper()
+		got, ok := s.Applied("ns", "cell")
+		if want == nil {
+			if ok {
+				t.Errorf("Expected the applied config to be unknown, got %+v", got)
+			}
+			return
+		}
+		if !ok || !proto.Equal(got, want) {
+			t.Errorf("Unexpected applied config, got=%+v, %v, want=%+v", got, ok, want)
+		}
+	}
+
+	tc1 := targetsConfig(broker("b", "a1"))
+	v1, err := s.Publish("ns", "cell", tc1)
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	// No pod reported yet.
+	wantApplied(nil)
+
+	s.Report("ns", "cell", "pod-a", v1)
+	waitApplied()
+	s.Report("ns", "cell", "pod-b", v1)
+	wantApplied(tc1)
+
+	// The data plane applied the oldest version the pods have.
+	tc2 := targetsConfig(broker("b", "a2"))
+	v2, err := s.Publish("ns", "cell", tc2)
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	s.Report("ns", "cell", "pod-a", v2)
+	wantApplied(tc1)
+	s.Report("ns", "cell", "pod-b", v2)
+	waitApplied()
+	wantApplied(tc2)
+
+	// A pod without a streamed config makes the applied config unknown.
+	s.Report("ns", "cell", "pod-b", "")
+	waitApplied()
+	wantApplied(nil)
+
+	// Until it stops polling.
+	time.Sleep(reportTTL*50*time.Millisecond + 50*time.Millisecond)
+	s.Report("ns", "cell", "pod-a", v2)
+	waitApplied()
+	wantApplied(tc2)
+
+	// Polls report the version of the pod the token of the caller is bound to.
+	s.RequireAuthentication(fakeAuthenticator{"token": {Namespace: "ns", ServiceAccount: "broker", Pod: "pod-c"}}, "broker")
+	req, err := http.NewRequest(http.MethodGet, s.URL("ns", "cell")+"?"+url.Values{VersionParam: {v1}}.Encode(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to poll the server: %v", err)
+	}
+	resp.Body.Close()
+	waitApplied()
+	wantApplied(tc1)
+}
+
+// fakePods are the pods of the BrokerCell ns/cell, mapped to whether they are ready.
+type fakePods map[string]bool
+
+func (f fakePods) Pods(namespace, name string) (map[string]bool, error) {
+	if namespace != "ns" || name != "cell" {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func TestServerAppliedTrackedPods(t *testing.T) {
+	s := NewLocalServer(time.Minute)
+	defer s.Close()
+	s.TrackPods(fakePods{"ingress": true, "fanout": true, "retry": false})
+
+	tc1 := targetsConfig(broker("b", "a1"))
+	v1, err := s.Publish("ns", "cell", tc1)
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+	tc2 := targetsConfig(broker("b", "a2"))
+	v2, err := s.Publish("ns", "cell", tc2)
+	if err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+
+	wantApplied := func(want *config.TargetsConfig) {
+		t.Helper()
+		got, ok := s.Applied("ns", "cell")
+		if want == nil {
+			if ok {
+				t.Errorf("Expected the applied config to be unknown, got %+v", got)
+			}
+			return
+		}
+		if !ok || !proto.Equal(got, want) {
+			t.Errorf("Unexpected applied config, got=%+v, %v, want=%+v", got, ok, want)
+		}
+	}
+
+	// The reports of pods that are not pods of the BrokerCell are ignored.
+	s.Report("ns", "cell", "unknown", v2)
+	wantApplied(nil)
+
+	// All the ready pods must report.
+	s.Report("ns", "cell", "ingress", v2)
+	wantApplied(nil)
+	s.Report("ns", "cell", "fanout", v2)
+	wantApplied(tc2)
+
+	// The pods that are not ready don't tell the applied config.
+	s.Report("ns", "cell", "retry", v1)
+	wantApplied(tc2)
+}
+
+// fakeAuthenticator authenticates the tokens of its callers.
+type fakeAuthenticator map[string]*Caller
+
+func (f fakeAuthenticator) Authenticate(_ context.Context, token string) (*Caller, error) {
+	if caller, ok := f[token]; ok {
+		return caller, nil
+	}
+	return nil, fmt.Errorf("%w: unknown token", ErrUnauthenticated)
+}
+
+func TestServerAuthentication(t *testing.T) {
+	s := NewLocalServer(50 * time.Millisecond)
+	defer s.Close()
+	s.RequireAuthentication(fakeAuthenticator{
+		"data-plane":      {Namespace: "ns", ServiceAccount: "broker", Pod: "pod"},
+		"other-namespace": {Namespace: "other", ServiceAccount: "broker", Pod: "pod"},
+		"other-sa":        {Namespace: "ns", ServiceAccount: "default", Pod: "pod"},
+	}, "broker")
+	if _, err := s.Publish("ns", "cell", targetsConfig()); err != nil {
+		t.Fatalf("Failed to publish config: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		want  int
+	}{{
+		name: "no token",
+		want: http.StatusUnauthorized,
+	}, {
+		name:  "unknown token",
+		token: "unknown",
+		want:  http.StatusUnauthorized,
+	}, {
+		name:  "service account of another namespace",
+		token: "other-namespace",
+		want:  http.StatusForbidden,
+	}, {
+		name:  "other service account",
+		token: "other-sa",
+		want:  http.StatusForbidden,
+	}, {
+		name:  "data plane",
+		token: "data-plane",
+		want:  http.StatusOK,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, s.URL("ns", "cell"), nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to poll the server: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Errorf("Unexpected status, got %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
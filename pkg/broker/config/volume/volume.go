@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/google/knative-gcp/pkg/broker/config"
@@ -32,8 +34,9 @@ const (
 )
 
 // Targets implements config.ReadonlyTargets with data
-// loaded from a file.
-// It also watches the file for any changes and will automatically
+// loaded from a file, or reassembled from the compressed shards
+// next to it (see config.ShardName).
+// It also watches the files for any changes and will automatically
 // refresh the in memory cache.
 type Targets struct {
 	config.CachedTargets
@@ -70,9 +73,8 @@ func NewTargetsFromFile(opts ...Option) (config.ReadonlyTargets, error) {
 }
 
 func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
-	configFile := filepath.Clean(t.path)
 	configDir, _ := filepath.Split(t.path)
-	realConfigFile, _ := filepath.EvalSymlinks(t.path)
+	realConfigFile := t.realConfigFile()
 	if err := watcher.Add(configDir); err != nil {
 		return err
 	}
@@ -86,12 +88,12 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 					// 'Events' channel is closed.
 					return
 				}
-				currentConfigFile, _ := filepath.EvalSymlinks(t.path)
+				currentConfigFile := t.realConfigFile()
 
-				// Re-sync if the file was updated/created or
-				// if the real file was replaced.
+				// Re-sync if a config file was updated/created or
+				// if the real files were replaced.
 				const writeOrCreateMask = fsnotify.Write | fsnotify.Create
-				if (filepath.Clean(event.Name) == configFile &&
+				if (t.isConfigFile(event.Name) &&
 					event.Op&writeOrCreateMask != 0) ||
 					(currentConfigFile != "" && currentConfigFile != realConfigFile) {
 					realConfigFile = currentConfigFile
@@ -114,7 +116,40 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 	return nil
 }
 
+// realConfigFile returns the real path of the first shard, or of the config file if it isn't
+// sharded. K8s replaces all the files of a ConfigMap volume at once, so they all change together.
+func (t *Targets) realConfigFile() string {
+	if f, err := filepath.EvalSymlinks(config.ShardName(t.path, 0)); err == nil {
+		return f
+	}
+	f, _ := filepath.EvalSymlinks(t.path)
+	return f
+}
+
+// isConfigFile returns true if name is the config file or one of its shards.
+func (t *Targets) isConfigFile(name string) bool {
+	configFile := filepath.Clean(t.path)
+	name = filepath.Clean(name)
+	return name == configFile || (strings.HasPrefix(name, configFile+"-") && strings.HasSuffix(name, ".gz"))
+}
+
+// sync loads the targets from the shards if there are any, or from the config file otherwise. The
+// targets are kept unchanged until a complete set of shards is found, as the shards may be written
+// separately.
 func (t *Targets) sync() error {
+	shards, err := t.readShards()
+	if err != nil {
+		return fmt.Errorf("failed to read config shards: %w", err)
+	}
+	if len(shards) > 0 {
+		val, err := config.DecodeShards(shards)
+		if err != nil {
+			return fmt.Errorf("failed to reassemble config shards: %w", err)
+		}
+		t.Store(val)
+		return nil
+	}
+
 	b, err := t.readFile()
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
@@ -132,3 +167,19 @@ func (t *Targets) sync() error {
 func (t *Targets) readFile() ([]byte, error) {
 	return ioutil.ReadFile(t.path)
 }
+
+// readShards reads the shards of the config, ordered by index, up to the first missing one.
+func (t *Targets) readShards() ([][]byte, error) {
+	var shards [][]byte
+	for i := 0; i < config.MaxShards; i++ {
+		b, err := ioutil.ReadFile(config.ShardName(t.path, i))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, b)
+	}
+	return shards, nil
+}
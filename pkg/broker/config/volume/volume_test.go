@@ -17,8 +17,11 @@ limitations under the License.
 package volume
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -181,6 +184,85 @@ func TestSyncConfigFromFile(t *testing.T) {
 	}
 }
 
+// shardedConfig returns a TargetsConfig with n brokers along with its shards, each of which is at
+// most 2KiB.
+func shardedConfig(t *testing.T, n int) (*config.TargetsConfig, [][]byte) {
+	t.Helper()
+	data := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	for i := 0; i < n; i++ {
+		b := &config.CellTenant{
+			// Random-like IDs so that the config doesn't compress too well.
+			Id:        fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprint(i)))),
+			Type:      config.CellTenantType_BROKER,
+			Name:      fmt.Sprintf("broker%d", i),
+			Namespace: "ns",
+			Address:   fmt.Sprintf("broker%d.ns.example.com", i),
+			State:     config.State_READY,
+		}
+		data.CellTenants[b.Key().PersistenceString()] = b
+	}
+	shards, err := config.EncodeShards(data, 2048, config.MaxShards)
+	if err != nil {
+		t.Fatalf("unexpected error from encoding shards: %v", err)
+	}
+	if len(shards) < 2 {
+		t.Fatalf("got %d shards, want several", len(shards))
+	}
+	return data, shards
+}
+
+func TestSyncConfigFromShards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "targets")
+
+	data, shards := shardedConfig(t, 200)
+	for i, s := range shards {
+		if err := ioutil.WriteFile(config.ShardName(path, i), s, 0644); err != nil {
+			t.Fatalf("unexpected error from writing shard: %v", err)
+		}
+	}
+
+	ch := make(chan struct{}, 1)
+	targets, err := NewTargetsFromFile(WithPath(path), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("unexpected error from NewTargetsFromFile: %v", err)
+	}
+	if got := targets.(*Targets).Load(); !proto.Equal(data, got) {
+		t.Errorf("initial targets got=%+v, want=%+v", got, data)
+	}
+
+	newData, newShards := shardedConfig(t, 201)
+	if len(newShards) != len(shards) {
+		t.Fatalf("got %d new shards, want %d", len(newShards), len(shards))
+	}
+	// Until all the shards are updated, the targets are left unchanged.
+	for i := len(newShards) - 1; i > 0; i-- {
+		atomicWriteFile(t, config.ShardName(path, i), newShards[i])
+	}
+	select {
+	case <-ch:
+		t.Fatal("targets were updated from an incomplete set of shards")
+	case <-time.After(500 * time.Millisecond):
+	}
+	if got := targets.(*Targets).Load(); !proto.Equal(data, got) {
+		t.Errorf("targets got=%+v, want=%+v", got, data)
+	}
+
+	atomicWriteFile(t, config.ShardName(path, 0), newShards[0])
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for the notification")
+	}
+	if got := targets.(*Targets).Load(); !proto.Equal(newData, got) {
+		t.Errorf("updated targets got=%+v, want=%+v", got, newData)
+	}
+}
+
 func atomicWriteFile(t *testing.T, file string, bytes []byte) {
 	t.Helper()
 	// In order to more closely replicate how K8s writes ConfigMaps to the file system, we will
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+// ArrivalTimeAttribute is the extension set by the broker ingress to the time at which the event
+// arrived at the broker.
+const ArrivalTimeAttribute = "knativearrivaltime"
+
+// GetArrivalTime returns the time at which the event arrived at the broker. If the event has no
+// arrival time or an invalid one, false is returned.
+func GetArrivalTime(e *event.Event) (time.Time, bool) {
+	raw, ok := e.Extensions()[ArrivalTimeAttribute]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := cetypes.ToTime(raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
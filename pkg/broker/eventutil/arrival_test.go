@@ -0,0 +1,64 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+func TestGetArrivalTime(t *testing.T) {
+	arrival := time.Date(2021, 3, 1, 12, 0, 0, 500, time.UTC)
+	cases := []struct {
+		name   string
+		value  interface{}
+		want   time.Time
+		wantOk bool
+	}{{
+		name: "no arrival time",
+	}, {
+		name:   "timestamp",
+		value:  cetypes.Timestamp{Time: arrival},
+		want:   arrival,
+		wantOk: true,
+	}, {
+		// Extensions are strings once the event went through Pub/Sub.
+		name:   "string",
+		value:  arrival.Format(time.RFC3339Nano),
+		want:   arrival,
+		wantOk: true,
+	}, {
+		name:  "invalid",
+		value: "yesterday",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := event.New()
+			if tc.value != nil {
+				e.SetExtension(ArrivalTimeAttribute, tc.value)
+			}
+			got, ok := GetArrivalTime(&e)
+			if ok != tc.wantOk || !got.Equal(tc.want) {
+				t.Errorf("GetArrivalTime() = %v, %v, want %v, %v", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// OrderingKey returns the ordering key of the event sent to the broker. It is the value of the
+// broker's ordering key extension. An empty string is returned if the broker is not ordered or the
+// event doesn't have the extension.
+func OrderingKey(broker *config.CellTenant, e *event.Event) string {
+	if broker.GetOrderingKeyExtension() == "" {
+		return ""
+	}
+	v, ok := e.Extensions()[broker.GetOrderingKeyExtension()]
+	if !ok {
+		return ""
+	}
+	key, err := cetypes.Format(v)
+	if err != nil {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func TestOrderingKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		extension  string
+		extensions map[string]interface{}
+		want       string
+	}{{
+		name:       "broker not ordered",
+		extensions: map[string]interface{}{"partitionkey": "foo"},
+	}, {
+		name:      "event without extension",
+		extension: "partitionkey",
+	}, {
+		name:       "string extension",
+		extension:  "partitionkey",
+		extensions: map[string]interface{}{"partitionkey": "foo"},
+		want:       "foo",
+	}, {
+		name:       "non-string extension",
+		extension:  "partitionkey",
+		extensions: map[string]interface{}{"partitionkey": 42},
+		want:       "42",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := event.New()
+			for k, v := range tc.extensions {
+				e.SetExtension(k, v)
+			}
+			broker := &config.CellTenant{OrderingKeyExtension: tc.extension}
+			if got := OrderingKey(broker, &e); got != tc.want {
+				t.Errorf("OrderingKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+const (
+	// maxRecentErrors is the number of recent delivery errors kept for each target.
+	maxRecentErrors = 10
+)
+
+var (
+	_ Introspectable = (*FanoutPool)(nil)
+	_ Introspectable = (*RetryPool)(nil)
+)
+
+// HandlerInfo describes a handler of a pool for introspection.
+type HandlerInfo struct {
+	// Key is the key of the broker or trigger the handler pulls events for.
+	Key          string    `json:"key"`
+	Subscription string    `json:"subscription"`
+	Replay       bool      `json:"replay,omitempty"`
+	Alive        bool      `json:"alive"`
+	StartTime    time.Time `json:"startTime"`
+	InFlight     int64     `json:"inFlight"`
+	// Breakers maps the names of the targets of the handler to the state of their circuit breaker,
+	// for the targets that have one.
+	Breakers map[string]string `json:"breakers,omitempty"`
+}
+
+// Introspectable is a handler pool that can be inspected through the admin server.
+type Introspectable interface {
+	// Targets returns the targets config loaded by the pool.
+	Targets() config.ReadonlyTargets
+	// Handlers returns the handlers of the pool.
+	Handlers() []HandlerInfo
+	// DeliveryErrors returns the recent delivery errors of the targets.
+	DeliveryErrors() *deliver.ErrorLog
+}
+
+func newHandlerInfo(key string, h *Handler) HandlerInfo {
+	return HandlerInfo{
+		Key:          key,
+		Subscription: h.Subscription.ID(),
+		Alive:        h.IsAlive(),
+		StartTime:    h.StartTime(),
+		InFlight:     h.InFlight(),
+	}
+}
+
+// AdminServer serves the state of a handler pool as JSON, to debug delivery issues. It must only be
+// exposed on a port that can't be reached from outside the cluster, as it discloses the targets
+// config.
+type AdminServer struct {
+	pool Introspectable
+}
+
+// NewAdminServer creates an AdminServer for the pool.
+func NewAdminServer(pool Introspectable) *AdminServer {
+	return &AdminServer{pool: pool}
+}
+
+type targetsResponse struct {
+	// Version is the version of the targets config, if it can be computed.
+	Version string `json:"version,omitempty"`
+	// Targets is the text format of the targets config.
+	Targets string `json:"targets"`
+}
+
+type handlersResponse struct {
+	Handlers []HandlerInfo `json:"handlers"`
+	// InFlight is the number of events being processed by all the handlers.
+	InFlight int64 `json:"inFlight"`
+}
+
+type errorsResponse struct {
+	// Targets maps the key of the targets to their recent delivery errors, oldest first.
+	Targets map[string][]deliver.DeliveryError `json:"targets"`
+}
+
+func (s *AdminServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var resp interface{}
+	switch req.URL.Path {
+	case "/targets":
+		targets := s.pool.Targets()
+		resp = targetsResponse{Version: targetsVersion(targets), Targets: targets.DebugString()}
+	case "/handlers":
+		handlers := s.pool.Handlers()
+		var inFlight int64
+		for _, h := range handlers {
+			inFlight += h.InFlight
+		}
+		resp = handlersResponse{Handlers: handlers, InFlight: inFlight}
+	case "/errors":
+		errs := make(map[string][]deliver.DeliveryError)
+		s.pool.DeliveryErrors().Range(func(key *config.TargetKey, recent []deliver.DeliveryError) bool {
+			errs[key.String()] = recent
+			return true
+		})
+		resp = errorsResponse{Targets: errs}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.FromContext(req.Context()).Error("failed to write admin response", zap.Error(err))
+	}
+}
+
+// targetsVersion returns the version of the targets config, or an empty string if the targets don't
+// expose their config.
+func targetsVersion(targets config.ReadonlyTargets) string {
+	loader, ok := targets.(interface{ Load() *config.TargetsConfig })
+	if !ok {
+		return ""
+	}
+	tc := loader.Load()
+	if tc == nil {
+		return ""
+	}
+	version, err := config.Version(tc)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+// StartAdminServer serves the state of the pool on the given port until the context is done.
+func StartAdminServer(ctx context.Context, pool Introspectable, port int) {
+	srv := &http.Server{
+		Addr:    ":" + strconv.Itoa(port),
+		Handler: NewAdminServer(pool),
+	}
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			logging.FromContext(ctx).Error("failed to shutdown the admin server", zap.Error(err))
+		}
+	}()
+	go func() {
+		logging.FromContext(ctx).Info("Starting the admin server", zap.Int("port", port))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.FromContext(ctx).Error("the admin server has stopped unexpectedly", zap.Error(err))
+		}
+	}()
+}
+
+// sortHandlers sorts the handlers by key, the replay handler of a target after its retry handler.
+func sortHandlers(handlers []HandlerInfo) {
+	sort.Slice(handlers, func(i, j int) bool {
+		if handlers[i].Key != handlers[j].Key {
+			return handlers[i].Key < handlers[j].Key
+		}
+		return !handlers[i].Replay && handlers[j].Replay
+	})
+}
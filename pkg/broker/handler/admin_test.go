@@ -0,0 +1,154 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+)
+
+type fakeIntrospectable struct {
+	targets  config.ReadonlyTargets
+	handlers []HandlerInfo
+	errors   *deliver.ErrorLog
+}
+
+func (f *fakeIntrospectable) Targets() config.ReadonlyTargets   { return f.targets }
+func (f *fakeIntrospectable) Handlers() []HandlerInfo           { return f.handlers }
+func (f *fakeIntrospectable) DeliveryErrors() *deliver.ErrorLog { return f.errors }
+
+func TestAdminServer(t *testing.T) {
+	target := &config.Target{
+		Namespace:      "ns",
+		Name:           "trigger",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "broker",
+	}
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(target.Key().ParentKey(), func(m config.CellTenantMutation) {
+		m.UpsertTargets(target)
+	})
+	version, err := config.Version(&config.TargetsConfig{CellTenants: map[string]*config.CellTenant{
+		target.Key().ParentKey().PersistenceString(): {
+			Type:      config.CellTenantType_BROKER,
+			Namespace: "ns",
+			Name:      "broker",
+			Targets:   map[string]*config.Target{"trigger": target},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startTime := time.Unix(1000, 0).UTC()
+	handlers := []HandlerInfo{{
+		Key:          target.Key().String(),
+		Subscription: "retry-sub",
+		Alive:        true,
+		StartTime:    startTime,
+		InFlight:     2,
+		Breakers:     map[string]string{"trigger": "open"},
+	}, {
+		Key:          target.Key().String(),
+		Subscription: "replay-sub",
+		Replay:       true,
+		Alive:        false,
+		StartTime:    startTime,
+		InFlight:     1,
+	}}
+	errorLog := deliver.NewErrorLog(maxRecentErrors)
+	errorLog.Record(target.Key(), errors.New("delivery failed"))
+
+	srv := httptest.NewServer(NewAdminServer(&fakeIntrospectable{
+		targets:  targets,
+		handlers: handlers,
+		errors:   errorLog,
+	}))
+	defer srv.Close()
+
+	t.Run("targets", func(t *testing.T) {
+		var got targetsResponse
+		getJSON(t, srv.URL+"/targets", http.StatusOK, &got)
+		want := targetsResponse{Version: version, Targets: targets.DebugString()}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("targets (-want,+got): %v", diff)
+		}
+	})
+
+	t.Run("handlers", func(t *testing.T) {
+		var got handlersResponse
+		getJSON(t, srv.URL+"/handlers", http.StatusOK, &got)
+		want := handlersResponse{Handlers: handlers, InFlight: 3}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("handlers (-want,+got): %v", diff)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var got errorsResponse
+		getJSON(t, srv.URL+"/errors", http.StatusOK, &got)
+		if errs := got.Targets[target.Key().String()]; len(errs) != 1 || errs[0].Message != "delivery failed" {
+			t.Errorf("errors of %v got=%v, want a single delivery failed error", target.Key(), errs)
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		getJSON(t, srv.URL+"/unknown", http.StatusNotFound, nil)
+	})
+
+	t.Run("not a GET", func(t *testing.T) {
+		resp, err := http.Post(srv.URL+"/targets", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("status got=%d, want=%d", resp.StatusCode, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func getJSON(t *testing.T, url string, wantStatus int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("status got=%d, want=%d", resp.StatusCode, wantStatus)
+	}
+	if v == nil {
+		return
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type got=%q, want application/json", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+)
+
+// The key used to store/retrieve the delivery attempt in the context.
+type deliveryAttemptKey struct{}
+
+// WithDeliveryAttempt sets the number of times the event being processed has been delivered by
+// Pub/Sub, including this delivery, in the context.
+func WithDeliveryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, deliveryAttemptKey{}, attempt)
+}
+
+// GetDeliveryAttempt gets the delivery attempt from the context. Pub/Sub only counts the delivery
+// attempts of the subscriptions with a dead letter policy, so it may not be present.
+func GetDeliveryAttempt(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(deliveryAttemptKey{}).(int)
+	return attempt, ok
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeliveryAttempt(t *testing.T) {
+	if _, ok := GetDeliveryAttempt(context.Background()); ok {
+		t.Error("GetDeliveryAttempt got an attempt from an empty context")
+	}
+
+	ctx := WithDeliveryAttempt(context.Background(), 3)
+	got, ok := GetDeliveryAttempt(ctx)
+	if !ok {
+		t.Fatal("GetDeliveryAttempt got no attempt")
+	}
+	if got != 3 {
+		t.Errorf("delivery attempt from context got=%d, want=3", got)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+	"time"
+)
+
+// The key used to store/retrieve the publish time in the context.
+type publishTimeKey struct{}
+
+// WithPublishTime sets the time at which the event being processed was published to Pub/Sub in the
+// context.
+func WithPublishTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, publishTimeKey{}, t)
+}
+
+// GetPublishTime gets the publish time from the context.
+func GetPublishTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(publishTimeKey{}).(time.Time)
+	return t, ok
+}
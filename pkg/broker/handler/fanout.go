@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -28,6 +29,8 @@ import (
 	"github.com/google/knative-gcp/pkg/logging"
 	"go.uber.org/zap"
 
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
@@ -57,10 +60,28 @@ type FanoutPool struct {
 	// For sending retry events. We only need a shared client.
 	// And we can set retry topic dynamically.
 	deliverRetryClient ceclient.Client
+	// For sending the events of ordered brokers to the retry topics with their ordering key.
+	orderedRetry *deliver.OrderedRetryPublisher
 	// For initial events delivery. We only need a shared client.
 	// And we can set target address dynamically.
 	deliverClient *http.Client
 	statsReporter *metrics.DeliveryReporter
+	// For reading back the event payloads offloaded to GCS by the ingress.
+	claimCheck *claimcheck.Reader
+	// The circuit breakers of the targets, shared by all handlers. Nil if disabled.
+	breakers *circuitbreaker.Breakers
+	// The context of the last sync, holding the logger and tags the state changes of the circuit
+	// breakers are reported with. It holds a syncContext, as an atomic.Value only holds values of
+	// the same concrete type.
+	breakerCtx atomic.Value
+	// For enforcing the delivery limits of the targets.
+	limiter *deliver.TargetLimiter
+	// For batching the deliveries to the targets with a batch policy.
+	batchers *deliver.Batchers
+	// The recent delivery errors of the targets, for introspection.
+	deliveryErrors *deliver.ErrorLog
+	// The compiled CESQL expressions of the targets' filters.
+	expressions *filter.Expressions
 }
 
 type fanoutHandlerCache struct {
@@ -93,6 +114,7 @@ func NewFanoutPool(
 	deliverClient *http.Client,
 	retryClient RetryClient,
 	statsReporter *metrics.DeliveryReporter,
+	claimCheck *claimcheck.Reader,
 	opts ...Option,
 ) (*FanoutPool, error) {
 	options, err := NewOptions(opts...)
@@ -112,6 +134,11 @@ func NewFanoutPool(
 	if options.DeliveryTimeout == 0 || options.DeliveryTimeout > options.TimeoutPerEvent-timeoutCushion {
 		options.DeliveryTimeout = options.TimeoutPerEvent - timeoutCushion
 	}
+	if options.DeliveryTLS != nil {
+		if deliverClient, err = deliverClientWithTLS(deliverClient, options.DeliveryTLS); err != nil {
+			return nil, err
+		}
+	}
 	p := &FanoutPool{
 		targets:            targets,
 		options:            options,
@@ -119,7 +146,16 @@ func NewFanoutPool(
 		pubsubClient:       pubsubClient,
 		deliverClient:      deliverClient,
 		deliverRetryClient: retryClient,
+		orderedRetry:       deliver.NewOrderedRetryPublisher(pubsubClient),
 		statsReporter:      statsReporter,
+		claimCheck:         claimCheck,
+		limiter:            deliver.NewTargetLimiter(),
+		batchers:           deliver.NewBatchers(options.DeliveryTimeout),
+		deliveryErrors:     deliver.NewErrorLog(maxRecentErrors),
+		expressions:        &filter.Expressions{},
+	}
+	if options.CircuitBreaker != nil {
+		p.breakers = circuitbreaker.NewBreakers(*options.CircuitBreaker, p.reportBreakerState)
 	}
 	return p, nil
 }
@@ -130,10 +166,20 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 	if err != nil {
 		logging.FromContext(ctx).Error("failed to add tags to context", zap.Error(err))
 	}
+	p.breakerCtx.Store(syncContext{ctx})
+
+	p.expressions.CompileTargets(ctx, p.targets)
+	p.syncBreakers(ctx)
+	p.deliveryErrors.Prune(p.targets)
+	p.limiter.Prune(p.targets)
+	p.orderedRetry.Prune()
+	if p.options.IDTokens != nil {
+		p.options.IDTokens.Prune(deliver.Audiences(p.targets))
+	}
 
 	p.pool.Range(func(key config.CellTenantKey, value *fanoutHandlerCache) bool {
 		if _, ok := p.targets.GetCellTenantByKey(&key); !ok {
-			value.Stop()
+			go value.Drain(p.options.DrainTimeout)
 			p.pool.Delete(key)
 		}
 		return true
@@ -145,8 +191,8 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 			if !value.shouldRenew(b) {
 				return true
 			}
-			// Stop and clean up the old handler before we start a new one.
-			value.Stop()
+			// Drain and clean up the old handler while a new one starts.
+			go value.Drain(p.options.DrainTimeout)
 			p.pool.Delete(*b.Key())
 		}
 
@@ -163,14 +209,21 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 			sub,
 			processors.ChainProcessors(
 				&fanout.Processor{MaxConcurrency: p.options.MaxConcurrencyPerEvent, Targets: p.targets},
-				&filter.Processor{Targets: p.targets},
+				&filter.Processor{Targets: p.targets, Expressions: p.expressions},
 				&deliver.Processor{
-					DeliverClient:      p.deliverClient,
-					Targets:            p.targets,
-					RetryOnFailure:     true,
-					DeliverRetryClient: p.deliverRetryClient,
-					DeliverTimeout:     p.options.DeliveryTimeout,
-					StatsReporter:      p.statsReporter,
+					DeliverClient:         p.deliverClient,
+					Targets:               p.targets,
+					RetryOnFailure:        true,
+					DeliverRetryClient:    p.deliverRetryClient,
+					OrderedRetryPublisher: p.orderedRetry,
+					DeliverTimeout:        p.options.DeliveryTimeout,
+					StatsReporter:         p.statsReporter,
+					ClaimCheck:            p.claimCheck,
+					Breakers:              p.breakers,
+					Limiter:               p.limiter,
+					IDTokens:              p.options.IDTokens,
+					Batchers:              p.batchers,
+					Errors:                p.deliveryErrors,
 				},
 			),
 			p.options.TimeoutPerEvent,
@@ -196,6 +249,102 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 	return nil
 }
 
+// syncBreakers forgets the circuit breakers of the deleted targets, and logs the breakers that
+// are not closed.
+func (p *FanoutPool) syncBreakers(ctx context.Context) {
+	if p.breakers == nil {
+		return
+	}
+	p.breakers.Prune(p.targets)
+	p.breakers.Range(func(key *config.TargetKey, b *circuitbreaker.Breaker) bool {
+		if state := b.State(); state != circuitbreaker.StateClosed {
+			logging.FromContext(ctx).Debug("target circuit breaker is not closed", zap.Stringer("target", key), zap.Stringer("state", state))
+		}
+		return true
+	})
+}
+
+type syncContext struct {
+	ctx context.Context
+}
+
+// reportBreakerState reports the state change of the circuit breaker of a target.
+func (p *FanoutPool) reportBreakerState(key *config.TargetKey, from, to circuitbreaker.State) {
+	ctx := context.Background()
+	if sc, ok := p.breakerCtx.Load().(syncContext); ok {
+		ctx = sc.ctx
+	}
+	if target, ok := p.targets.GetTargetByKey(key); ok {
+		var err error
+		if ctx, err = metrics.AddTargetTags(ctx, target); err != nil {
+			logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+		}
+	}
+	logging.FromContext(ctx).Info("target circuit breaker state changed",
+		zap.Stringer("target", key),
+		zap.Stringer("from", from),
+		zap.Stringer("to", to),
+	)
+	p.statsReporter.ReportCircuitBreakerState(ctx, int64(to))
+}
+
+// Drain drains all the handlers of the pool concurrently, and returns once they have stopped. It is
+// meant to be called on shutdown, after the pool stopped syncing.
+func (p *FanoutPool) Drain() {
+	var handlers []*Handler
+	p.pool.Range(func(_ config.CellTenantKey, value *fanoutHandlerCache) bool {
+		handlers = append(handlers, &value.Handler)
+		return true
+	})
+	drainHandlers(p.options.DrainTimeout, handlers)
+	p.batchers.Stop()
+}
+
+// Targets returns the targets config of the pool.
+func (p *FanoutPool) Targets() config.ReadonlyTargets {
+	return p.targets
+}
+
+// Handlers returns the handlers of the brokers.
+func (p *FanoutPool) Handlers() []HandlerInfo {
+	var handlers []HandlerInfo
+	p.pool.Range(func(key config.CellTenantKey, value *fanoutHandlerCache) bool {
+		h := newHandlerInfo(key.String(), &value.Handler)
+		h.Breakers = p.breakerStates(&key)
+		handlers = append(handlers, h)
+		return true
+	})
+	sortHandlers(handlers)
+	return handlers
+}
+
+// breakerStates returns the states of the circuit breakers of the targets of a broker, keyed by
+// target name.
+func (p *FanoutPool) breakerStates(key *config.CellTenantKey) map[string]string {
+	if p.breakers == nil {
+		return nil
+	}
+	b, ok := p.targets.GetCellTenantByKey(key)
+	if !ok {
+		return nil
+	}
+	var states map[string]string
+	for _, t := range b.Targets {
+		if breaker, ok := p.breakers.Lookup(t.Key()); ok {
+			if states == nil {
+				states = make(map[string]string)
+			}
+			states[t.Name] = breaker.State().String()
+		}
+	}
+	return states
+}
+
+// DeliveryErrors returns the recent delivery errors of the targets.
+func (p *FanoutPool) DeliveryErrors() *deliver.ErrorLog {
+	return p.deliveryErrors
+}
+
 // syncMapBrokerKey is a typed version of sync.Map.
 type syncMapBrokerKey struct {
 	m sync.Map
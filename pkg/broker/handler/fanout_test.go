@@ -26,7 +26,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlertesting "github.com/google/knative-gcp/pkg/broker/handler/testing"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
@@ -395,6 +397,40 @@ func TestFanoutSyncPoolE2E(t *testing.T) {
 	})
 }
 
+func TestFanoutBreakerStates(t *testing.T) {
+	broker := config.TestOnlyBrokerKey("ns", "broker")
+	failing := &config.Target{Namespace: "ns", Name: "failing", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	healthy := &config.Target{Namespace: "ns", Name: "healthy", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	unused := &config.Target{Namespace: "ns", Name: "unused", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(broker, func(m config.CellTenantMutation) {
+		m.UpsertTargets(failing, healthy, unused)
+	})
+
+	p := &FanoutPool{
+		targets: targets,
+		breakers: circuitbreaker.NewBreakers(circuitbreaker.Settings{
+			Window:         time.Minute,
+			MinRequests:    1,
+			FailureRatio:   0.5,
+			OpenTimeout:    time.Hour,
+			HalfOpenProbes: 1,
+		}, nil),
+	}
+	b := p.breakers.Get(failing.Key())
+	a, _ := b.Allow()
+	b.Record(a, false)
+	p.breakers.Get(healthy.Key())
+
+	want := map[string]string{"failing": "open", "healthy": "closed"}
+	if diff := cmp.Diff(want, p.breakerStates(broker)); diff != "" {
+		t.Errorf("breaker states (-want,+got): %v", diff)
+	}
+	if got := p.breakerStates(config.TestOnlyBrokerKey("ns", "deleted")); got != nil {
+		t.Errorf("breaker states of a deleted broker got=%v, want nil", got)
+	}
+}
+
 func assertFanoutHandlers(t *testing.T, p *FanoutPool, targets config.Targets) {
 	t.Helper()
 	gotHandlers := make(map[config.CellTenantKey]bool)
@@ -415,6 +451,19 @@ func assertFanoutHandlers(t *testing.T, p *FanoutPool, targets config.Targets) {
 	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
 		t.Errorf("handlers map (-want,+got): %v", diff)
 	}
+
+	// The introspected handlers match the pool.
+	wantInfo := make(map[string]bool, len(wantHandlers))
+	for key := range wantHandlers {
+		wantInfo[key.String()] = true
+	}
+	gotInfo := make(map[string]bool)
+	for _, h := range p.Handlers() {
+		gotInfo[h.Key] = h.Alive
+	}
+	if diff := cmp.Diff(wantInfo, gotInfo); diff != "" {
+		t.Errorf("introspected handlers (-want,+got): %v", diff)
+	}
 }
 
 func wantTags() map[string]string {
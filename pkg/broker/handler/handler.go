@@ -19,12 +19,14 @@ package handler
 import (
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/metrics"
@@ -44,11 +46,38 @@ type Handler struct {
 	// Timeout is the timeout for processing each individual event.
 	Timeout time.Duration
 
+	// Limit returns the maximum number of events processed at once, or zero to pause processing.
+	// It is checked for every message, so that the limit can change without renewing the handler.
+	// The pulled messages wait until they are below the limit. Nil means no limit other than the
+	// receive settings of the subscription.
+	Limit func() int
+
+	// admitted tracks the events processed under the limit.
+	admitted *admitted
+
 	// cancel is function to stop pulling messages.
 	cancel context.CancelFunc
 
+	// processing is the context of the events being processed. Unlike the context of the pulled
+	// messages, it is not cancelled when the handler stops pulling messages, so that the events
+	// being processed can finish while the handler drains.
+	processing context.Context
+
+	// cancelProcessing cancels the events being processed.
+	cancelProcessing context.CancelFunc
+
+	// stopped is closed once the handler has stopped pulling messages and the processing of all
+	// pulled messages has returned.
+	stopped chan struct{}
+
 	// alive is a bool indicator that the handler is still alive.
 	alive atomic.Value
+
+	// startTime is when the handler was started.
+	startTime time.Time
+
+	// inFlight is the number of events being processed.
+	inFlight int64
 }
 
 // NewHandler creates a new Handler.
@@ -66,20 +95,47 @@ func NewHandler(
 
 // Start starts the handler.
 // done func will be called if the pubsub inbound is closed.
+// If the subscription has message ordering enabled, the Pub/Sub client processes messages with
+// the same ordering key one at a time and in order.
 func (h *Handler) Start(ctx context.Context, done func(error)) {
 	ctx, h.cancel = context.WithCancel(ctx)
+	h.processing, h.cancelProcessing = context.WithCancel(context.Background())
+	h.stopped = make(chan struct{})
+	h.startTime = time.Now()
+	h.admitted = &admitted{}
 	h.alive.Store(true)
 
 	go func() {
 		// For any reason if inbound is closed, mark alive as false.
 		defer h.alive.Store(false)
-		done(h.Subscription.Receive(ctx, h.receive))
+		// The Pub/Sub client only returns once all the received messages are processed.
+		err := h.Subscription.Receive(ctx, h.receive)
+		close(h.stopped)
+		done(err)
 	}()
 }
 
-// Stop stops the handlers.
+// Stop stops the handlers. The events being processed are cancelled and their messages nacked.
 func (h *Handler) Stop() {
 	h.cancel()
+	h.cancelProcessing()
+}
+
+// Drain stops pulling new messages and waits for the events being processed to finish, for at most
+// the grace period. The events still being processed after the grace period are cancelled and
+// their messages nacked. It returns once the handler has stopped.
+func (h *Handler) Drain(grace time.Duration) {
+	h.cancel()
+	defer h.cancelProcessing()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-h.stopped:
+		return
+	case <-timer.C:
+	}
+	h.cancelProcessing()
+	<-h.stopped
 }
 
 // IsAlive indicates whether the handler is alive.
@@ -87,8 +143,92 @@ func (h *Handler) IsAlive() bool {
 	return h.alive.Load().(bool)
 }
 
+// StartTime returns when the handler was started.
+func (h *Handler) StartTime() time.Time {
+	return h.startTime
+}
+
+// InFlight returns the number of events being processed by the handler.
+func (h *Handler) InFlight() int64 {
+	return atomic.LoadInt64(&h.inFlight)
+}
+
+// limitRecheckInterval is how often the messages waiting for the limit of a handler check it
+// again, as the limit may rise without any event finishing, e.g. after a pause.
+const limitRecheckInterval = time.Second
+
+// admitted tracks the events a handler processes under its limit.
+type admitted struct {
+	mu sync.Mutex
+	// count is the number of admitted events being processed.
+	count int
+	// released is closed when an admitted event finishes, to wake up the waiting messages.
+	released chan struct{}
+}
+
+// admit waits until the handler processes fewer events than its limit, and reserves a slot for the
+// event. It returns false without reserving a slot if ctx is done first.
+func (h *Handler) admit(ctx context.Context) bool {
+	if h.Limit == nil {
+		return true
+	}
+	a := h.admitted
+	var ticker *time.Ticker
+	for {
+		a.mu.Lock()
+		if a.count < h.Limit() {
+			a.count++
+			a.mu.Unlock()
+			if ticker != nil {
+				ticker.Stop()
+			}
+			return true
+		}
+		if a.released == nil {
+			a.released = make(chan struct{})
+		}
+		released := a.released
+		a.mu.Unlock()
+
+		if ticker == nil {
+			ticker = time.NewTicker(limitRecheckInterval)
+		}
+		select {
+		case <-released:
+		case <-ticker.C:
+		case <-ctx.Done():
+			ticker.Stop()
+			return false
+		}
+	}
+}
+
+// release frees the slot of an admitted event.
+func (h *Handler) release() {
+	if h.Limit == nil {
+		return
+	}
+	a := h.admitted
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.count--
+	if a.released != nil {
+		close(a.released)
+		a.released = nil
+	}
+}
+
 // receive converts message to events and invoke processor chain.
 func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
+	// The message waits for the limit until the handler stops pulling, and is then redelivered.
+	if !h.admit(ctx) {
+		msg.Nack()
+		return
+	}
+	defer h.release()
+	atomic.AddInt64(&h.inFlight, 1)
+	defer atomic.AddInt64(&h.inFlight, -1)
+	ctx = processingContext{Context: h.processing, values: ctx}
 	ctx = metrics.StartEventProcessing(ctx)
 	event, err := binding.ToEvent(ctx, cepubsub.NewMessage(msg))
 	if isNonRetryable(err) {
@@ -104,6 +244,10 @@ func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 		return
 	}
 
+	ctx = handlerctx.WithPublishTime(ctx, msg.PublishTime)
+	if msg.DeliveryAttempt != nil {
+		ctx = handlerctx.WithDeliveryAttempt(ctx, *msg.DeliveryAttempt)
+	}
 	if h.Timeout != 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
@@ -118,6 +262,17 @@ func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 	msg.Ack()
 }
 
+// processingContext has the values of the context of a received message, and the cancellation of
+// the processing context of the handler.
+type processingContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c processingContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
 func isNonRetryable(err error) bool {
 	// The following errors can be returned by ToEvent and are not retryable.
 	// TODO Should binding.ToEvent consolidate them and return the generic ErrCannotConvertToEvent?
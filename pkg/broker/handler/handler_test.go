@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,6 +45,14 @@ const (
 )
 
 func testPubsubClient(ctx context.Context, t testing.TB, projectID string) (*pubsub.Client, func()) {
+	t.Helper()
+	_, c, close := testPubsubServer(ctx, t, projectID)
+	return c, close
+}
+
+// testPubsubServer is like testPubsubClient, and also returns the test server to inspect the acks
+// and modacks of its messages.
+func testPubsubServer(ctx context.Context, t testing.TB, projectID string) (*pstest.Server, *pubsub.Client, func()) {
 	t.Helper()
 	srv := pstest.NewServer()
 	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
@@ -58,7 +67,7 @@ func testPubsubClient(ctx context.Context, t testing.TB, projectID string) (*pub
 	if err != nil {
 		t.Fatalf("failed to create test pubsub client: %v", err)
 	}
-	return c, close
+	return srv, c, close
 }
 
 func TestHandler(t *testing.T) {
@@ -160,6 +169,217 @@ func TestHandler(t *testing.T) {
 	})
 }
 
+// blockingProcessor blocks the processing of events until it's released or cancelled.
+type blockingProcessor struct {
+	processors.BaseProcessor
+
+	started chan *event.Event
+	release chan struct{}
+}
+
+func (p *blockingProcessor) Process(ctx context.Context, e *event.Event) error {
+	p.started <- e
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestHandlerDrain(t *testing.T) {
+	cases := []struct {
+		name     string
+		grace    time.Duration
+		release  bool
+		wantNack bool
+	}{{
+		name:    "in-flight event finishes within the grace period",
+		grace:   time.Minute,
+		release: true,
+	}, {
+		name:     "in-flight event is nacked after the grace period",
+		grace:    100 * time.Millisecond,
+		wantNack: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv, c, closeClient := testPubsubServer(ctx, t, testProjectID)
+			defer closeClient()
+
+			topic, err := c.CreateTopic(ctx, testTopic)
+			if err != nil {
+				t.Fatalf("failed to create topic: %v", err)
+			}
+			sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{
+				Topic: topic,
+			})
+			if err != nil {
+				t.Fatalf("failed to create subscription: %v", err)
+			}
+			p, err := cepubsub.New(ctx,
+				cepubsub.WithClient(c),
+				cepubsub.WithProjectID(testProjectID),
+				cepubsub.WithTopicID(testTopic),
+			)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents pubsub protocol: %v", err)
+			}
+
+			processor := &blockingProcessor{
+				started: make(chan *event.Event, 1),
+				release: make(chan struct{}),
+			}
+			h := NewHandler(sub, processor, time.Minute)
+			h.Start(ctx, func(err error) {})
+
+			testEvent := event.New()
+			testEvent.SetID("id")
+			testEvent.SetSource("source")
+			testEvent.SetType("type")
+			if err := p.Send(ctx, binding.ToMessage(&testEvent)); err != nil {
+				t.Fatalf("failed to seed event to pubsub: %v", err)
+			}
+			if got := nextEventWithTimeout(processor.started); got == nil {
+				t.Fatal("the event wasn't processed")
+			}
+
+			drained := make(chan struct{})
+			go func() {
+				h.Drain(tc.grace)
+				close(drained)
+			}()
+			if tc.release {
+				select {
+				case <-drained:
+					t.Fatal("the handler was drained before the in-flight event finished")
+				case <-time.After(100 * time.Millisecond):
+				}
+				close(processor.release)
+			}
+			select {
+			case <-drained:
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out draining the handler")
+			}
+
+			// The ack or nack is sent by the client in the background once the handler is drained.
+			var msg *pstest.Message
+			if tc.wantNack {
+				msg = waitForMessage(t, srv, isNacked)
+			} else {
+				msg = waitForMessage(t, srv, func(m *pstest.Message) bool { return m.Acks > 0 })
+			}
+			if got, want := msg.Acks > 0, !tc.wantNack; got != want {
+				t.Errorf("message acked got=%v, want=%v", got, want)
+			}
+			if got, want := isNacked(msg), tc.wantNack; got != want {
+				t.Errorf("message nacked got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandlerLimit(t *testing.T) {
+	ctx := context.Background()
+	c, closeClient := testPubsubClient(ctx, t, testProjectID)
+	defer closeClient()
+
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{
+		Topic: topic,
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	p, err := cepubsub.New(ctx,
+		cepubsub.WithClient(c),
+		cepubsub.WithProjectID(testProjectID),
+		cepubsub.WithTopicID(testTopic),
+	)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents pubsub protocol: %v", err)
+	}
+
+	processor := &blockingProcessor{
+		started: make(chan *event.Event, 2),
+		release: make(chan struct{}),
+	}
+	// Processing starts paused.
+	var limit int64
+	h := NewHandler(sub, processor, time.Minute)
+	h.Limit = func() int { return int(atomic.LoadInt64(&limit)) }
+	h.Start(ctx, func(err error) {})
+	defer h.Stop()
+
+	for _, id := range []string{"id1", "id2"} {
+		testEvent := event.New()
+		testEvent.SetID(id)
+		testEvent.SetSource("source")
+		testEvent.SetType("type")
+		if err := p.Send(ctx, binding.ToMessage(&testEvent)); err != nil {
+			t.Fatalf("failed to seed event to pubsub: %v", err)
+		}
+	}
+
+	assertNotStarted := func(msg string) {
+		t.Helper()
+		select {
+		case e := <-processor.started:
+			t.Fatalf("event %q was processed %s", e.ID(), msg)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	assertNotStarted("while paused")
+
+	// The waiting messages check the raised limit without any event finishing.
+	atomic.StoreInt64(&limit, 1)
+	select {
+	case <-processor.started:
+	case <-time.After(5 * limitRecheckInterval):
+		t.Fatal("no event was processed after the limit was raised")
+	}
+	assertNotStarted("above the limit")
+
+	processor.release <- struct{}{}
+	if got := nextEventWithTimeout(processor.started); got == nil {
+		t.Fatal("the second event wasn't processed after the first one finished")
+	}
+	processor.release <- struct{}{}
+}
+
+// waitForMessage waits until the only message of the test server satisfies the condition, and
+// returns it.
+func waitForMessage(t *testing.T, srv *pstest.Server, cond func(*pstest.Message) bool) *pstest.Message {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		if msgs := srv.Messages(); len(msgs) == 1 && cond(msgs[0]) {
+			return msgs[0]
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for the message, got %+v", srv.Messages())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// isNacked returns true if the message was nacked, which modacks it with a zero deadline.
+func isNacked(m *pstest.Message) bool {
+	for _, modack := range m.Modacks {
+		if modack.AckDeadline == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type BenchProcessor struct {
 	processors.BaseProcessor
 
@@ -21,12 +21,20 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/broker/idtoken"
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
 )
 
 var (
 	defaultHandlerConcurrency     = runtime.NumCPU()
 	defaultMaxConcurrencyPerEvent = 1
 	defaultTimeout                = 10 * time.Minute
+	// defaultDrainTimeout leaves some room within the default termination grace period of pods.
+	defaultDrainTimeout = 25 * time.Second
 
 	// This is the pubsub default MaxExtension.
 	// It would not make sense for handler timeout per event be greater
@@ -50,15 +58,43 @@ type Options struct {
 	DeliveryTimeout time.Duration
 	// PubsubReceiveSettings is the pubsub receive settings.
 	PubsubReceiveSettings pubsub.ReceiveSettings
+	// CircuitBreaker is the settings of the circuit breakers of the targets.
+	// Circuit breaking is disabled if nil.
+	CircuitBreaker *circuitbreaker.Settings
+	// RetryBackoff decides how long the retry handlers hold the events whose delivery failed
+	// before nacking them. Events are nacked right away if nil.
+	RetryBackoff *deliver.RetryBackoff
+	// NonRetryableStatusCodes are the target response status codes after which the retry handlers
+	// send events to the dead letter sink without further retries.
+	NonRetryableStatusCodes []int
+	// IDTokens mints the ID tokens sent to the subscribers that require authentication.
+	// Deliveries to such subscribers fail if nil.
+	IDTokens *idtoken.Minter
+	// DeliveryTLS provides the TLS configuration of the connections to the subscribers, which
+	// replaces the one of the deliver client when set.
+	DeliveryTLS *tlsconfig.Reloader
+	// DrainTimeout is how long the handlers that are stopped on shutdown or renewed on config
+	// change wait for the events being processed before nacking them.
+	DrainTimeout time.Duration
+	// Backpressure is the settings with which the retry handlers adapt the number of events they
+	// pull for each target to the health of the target. Backpressure is disabled if nil.
+	Backpressure *backpressure.Settings
 }
 
 // NewOptions creates a Options.
 func NewOptions(opts ...Option) (*Options, error) {
+	cb := circuitbreaker.DefaultSettings
+	rb := deliver.DefaultRetryBackoff
+	bp := backpressure.DefaultSettings
 	opt := &Options{
 		HandlerConcurrency:     defaultHandlerConcurrency,
 		MaxConcurrencyPerEvent: defaultMaxConcurrencyPerEvent,
 		TimeoutPerEvent:        defaultTimeout,
 		PubsubReceiveSettings:  pubsub.DefaultReceiveSettings,
+		CircuitBreaker:         &cb,
+		RetryBackoff:           &rb,
+		DrainTimeout:           defaultDrainTimeout,
+		Backpressure:           &bp,
 	}
 	for _, o := range opts {
 		o(opt)
@@ -107,3 +143,53 @@ func WithDeliveryTimeout(t time.Duration) Option {
 		o.DeliveryTimeout = t
 	}
 }
+
+// WithCircuitBreaker sets the CircuitBreaker settings. Nil settings disable circuit breaking.
+func WithCircuitBreaker(s *circuitbreaker.Settings) Option {
+	return func(o *Options) {
+		o.CircuitBreaker = s
+	}
+}
+
+// WithRetryBackoff sets the RetryBackoff. A nil backoff makes retry handlers nack failed events
+// right away.
+func WithRetryBackoff(b *deliver.RetryBackoff) Option {
+	return func(o *Options) {
+		o.RetryBackoff = b
+	}
+}
+
+// WithNonRetryableStatusCodes sets the NonRetryableStatusCodes.
+func WithNonRetryableStatusCodes(codes ...int) Option {
+	return func(o *Options) {
+		o.NonRetryableStatusCodes = codes
+	}
+}
+
+// WithIDTokens sets the IDTokens minter.
+func WithIDTokens(m *idtoken.Minter) Option {
+	return func(o *Options) {
+		o.IDTokens = m
+	}
+}
+
+// WithDeliveryTLS sets the DeliveryTLS configuration.
+func WithDeliveryTLS(r *tlsconfig.Reloader) Option {
+	return func(o *Options) {
+		o.DeliveryTLS = r
+	}
+}
+
+// WithDrainTimeout sets the DrainTimeout.
+func WithDrainTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.DrainTimeout = t
+	}
+}
+
+// WithBackpressure sets the Backpressure settings. Nil settings disable backpressure.
+func WithBackpressure(s *backpressure.Settings) Option {
+	return func(o *Options) {
+		o.Backpressure = s
+	}
+}
@@ -22,6 +22,11 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
 )
 
 func TestWithHandlerConcurrency(t *testing.T) {
@@ -96,3 +101,127 @@ func TestWithDeliveryTimeout(t *testing.T) {
 		t.Errorf("options timeout per event got=%v, want=%v", opt.DeliveryTimeout, want)
 	}
 }
+
+func TestWithCircuitBreaker(t *testing.T) {
+	opt, err := NewOptions()
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&circuitbreaker.DefaultSettings, opt.CircuitBreaker); diff != "" {
+		t.Errorf("options default CircuitBreaker (-want,+got): %v", diff)
+	}
+
+	want := &circuitbreaker.Settings{
+		Window:         time.Minute,
+		MinRequests:    5,
+		FailureRatio:   0.9,
+		OpenTimeout:    time.Second,
+		HalfOpenProbes: 1,
+	}
+	opt, err = NewOptions(WithCircuitBreaker(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, opt.CircuitBreaker); diff != "" {
+		t.Errorf("options CircuitBreaker (-want,+got): %v", diff)
+	}
+
+	opt, err = NewOptions(WithCircuitBreaker(nil))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.CircuitBreaker != nil {
+		t.Errorf("options CircuitBreaker got=%v, want nil", opt.CircuitBreaker)
+	}
+}
+
+func TestWithRetryBackoff(t *testing.T) {
+	opt, err := NewOptions()
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&deliver.DefaultRetryBackoff, opt.RetryBackoff); diff != "" {
+		t.Errorf("options default RetryBackoff (-want,+got): %v", diff)
+	}
+
+	want := &deliver.RetryBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Second}
+	opt, err = NewOptions(WithRetryBackoff(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, opt.RetryBackoff); diff != "" {
+		t.Errorf("options RetryBackoff (-want,+got): %v", diff)
+	}
+
+	opt, err = NewOptions(WithRetryBackoff(nil))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.RetryBackoff != nil {
+		t.Errorf("options RetryBackoff got=%v, want nil", opt.RetryBackoff)
+	}
+}
+
+func TestWithNonRetryableStatusCodes(t *testing.T) {
+	opt, err := NewOptions(WithNonRetryableStatusCodes(400, 413))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]int{400, 413}, opt.NonRetryableStatusCodes); diff != "" {
+		t.Errorf("options NonRetryableStatusCodes (-want,+got): %v", diff)
+	}
+}
+
+func TestWithDeliveryTLS(t *testing.T) {
+	r := &tlsconfig.Reloader{}
+	opt, err := NewOptions(WithDeliveryTLS(r))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.DeliveryTLS != r {
+		t.Errorf("options delivery TLS got=%p, want=%p", opt.DeliveryTLS, r)
+	}
+}
+
+func TestWithDrainTimeout(t *testing.T) {
+	want := 5 * time.Second
+	opt, err := NewOptions(WithDrainTimeout(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.DrainTimeout != want {
+		t.Errorf("options drain timeout got=%v, want=%v", opt.DrainTimeout, want)
+	}
+}
+
+func TestWithBackpressure(t *testing.T) {
+	opt, err := NewOptions()
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&backpressure.DefaultSettings, opt.Backpressure); diff != "" {
+		t.Errorf("options default Backpressure (-want,+got): %v", diff)
+	}
+
+	want := &backpressure.Settings{
+		Interval:      time.Minute,
+		MinRequests:   5,
+		FailureRatio:  0.9,
+		PauseDuration: time.Second,
+	}
+	opt, err = NewOptions(WithBackpressure(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, opt.Backpressure); diff != "" {
+		t.Errorf("options Backpressure (-want,+got): %v", diff)
+	}
+
+	opt, err = NewOptions(WithBackpressure(nil))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.Backpressure != nil {
+		t.Errorf("options Backpressure got=%v, want nil", opt.Backpressure)
+	}
+}
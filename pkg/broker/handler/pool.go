@@ -144,3 +144,16 @@ func watch(ctx context.Context, syncPool SyncPool, syncSignal <-chan struct{}, c
 		}
 	}
 }
+
+// drainHandlers drains the handlers concurrently, and returns once they have all stopped.
+func drainHandlers(grace time.Duration, handlers []*Handler) {
+	var wg sync.WaitGroup
+	for _, h := range handlers {
+		wg.Add(1)
+		go func(h *Handler) {
+			defer wg.Done()
+			h.Drain(grace)
+		}(h)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+)
+
+// RetryBackoff decides how long a failed event is held before it is nacked, so that Pub/Sub
+// doesn't redeliver it before the target is expected to accept it. The lease of a held event keeps
+// being extended by the Pub/Sub client.
+type RetryBackoff struct {
+	// BaseDelay is the delay after the first throttled delivery attempt of an event without a
+	// Retry-After header. It doubles with each subsequent attempt.
+	BaseDelay time.Duration
+	// MaxDelay is the longest an event is held.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryBackoff is the default retry backoff.
+var DefaultRetryBackoff = RetryBackoff{
+	BaseDelay: time.Second,
+	MaxDelay:  time.Minute,
+}
+
+// Delay returns how long to hold the event after err, the failure of its delivery attempt number
+// attempt, or zero if it should be nacked right away. The Retry-After header of the target's
+// response is honored if present. Otherwise, throttled deliveries are delayed exponentially.
+func (b *RetryBackoff) Delay(err error, attempt int) time.Duration {
+	var d time.Duration
+	var te *targetError
+	switch {
+	case errors.As(err, &te) && te.retryAfter > 0:
+		d = te.retryAfter
+	case isThrottled(err):
+		d = b.BaseDelay
+		for i := 1; i < attempt && d < b.MaxDelay; i++ {
+			d *= 2
+		}
+	}
+	if d > b.MaxDelay {
+		return b.MaxDelay
+	}
+	return d
+}
+
+// isThrottled reports whether err shows that the target can't accept the event for now, as opposed
+// to e.g. a rejection of the event.
+func isThrottled(err error) bool {
+	if errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrSuspended) {
+		return true
+	}
+	var te *targetError
+	return errors.As(err, &te) &&
+		(te.statusCode == http.StatusTooManyRequests || te.statusCode == http.StatusServiceUnavailable)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number of seconds or an HTTP
+// date, into the delay from now. It returns zero if the value is missing or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		if int64(seconds) > int64(math.MaxInt64/time.Second) {
+			return math.MaxInt64
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+)
+
+func TestRetryBackoffDelay(t *testing.T) {
+	b := &RetryBackoff{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	cases := []struct {
+		name    string
+		err     error
+		attempt int
+		want    time.Duration
+	}{{
+		name: "retry after",
+		err:  &targetError{statusCode: http.StatusServiceUnavailable, retryAfter: 5 * time.Second},
+		want: 5 * time.Second,
+	}, {
+		name: "retry after above max delay",
+		err:  &targetError{statusCode: http.StatusTooManyRequests, retryAfter: time.Hour},
+		want: 10 * time.Second,
+	}, {
+		name:    "throttled first attempt",
+		err:     &targetError{statusCode: http.StatusTooManyRequests},
+		attempt: 1,
+		want:    time.Second,
+	}, {
+		name:    "throttled third attempt",
+		err:     &targetError{statusCode: http.StatusServiceUnavailable},
+		attempt: 3,
+		want:    4 * time.Second,
+	}, {
+		name:    "throttled many attempts",
+		err:     &targetError{statusCode: http.StatusTooManyRequests},
+		attempt: 100,
+		want:    10 * time.Second,
+	}, {
+		name:    "limit exceeded",
+		err:     fmt.Errorf("rejected: %w", ErrLimitExceeded),
+		attempt: 2,
+		want:    2 * time.Second,
+	}, {
+		name: "circuit breaker open",
+		err:  fmt.Errorf("rejected: %w", circuitbreaker.ErrOpen),
+		want: time.Second,
+	}, {
+		name: "target suspended",
+		err:  fmt.Errorf("skipped: %w", ErrSuspended),
+		want: time.Second,
+	}, {
+		name:    "server error",
+		err:     &targetError{statusCode: http.StatusInternalServerError},
+		attempt: 3,
+	}, {
+		name: "other error",
+		err:  errors.New("other error"),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := b.Delay(tc.err, tc.attempt); got != tc.want {
+				t.Errorf("Delay() got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "120", want: 2 * time.Minute},
+		{value: " 3 ", want: 3 * time.Second},
+		{value: "-1", want: 0},
+		{value: "soon", want: 0},
+		{value: "Mon, 01 Mar 2021 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Mon, 01 Mar 2021 11:00:00 GMT", want: 0},
+	}
+	for _, tc := range cases {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) got=%v, want=%v", tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/metrics"
+)
+
+const (
+	// batchContentType is the content type of the batches of events, in the JSON batch format of the
+	// CloudEvents HTTP protocol binding.
+	batchContentType = "application/cloudevents-batch+json"
+
+	// defaultBatchMaxLatency is the longest an event waits for its batch if the batch policy of the
+	// target doesn't say.
+	defaultBatchMaxLatency = time.Second
+)
+
+// Batchers accumulates the events delivered to the targets with a batch policy into batches. The
+// events of a batch are delivered in a single request, and the delivery of each event fails if the
+// delivery of its batch fails.
+type Batchers struct {
+	// ctx is the parent of the contexts the batches are delivered with. It is cancelled by Stop.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// timeout is the timeout of the delivery of each batch, if not zero.
+	timeout time.Duration
+
+	mu sync.Mutex
+	// pending is a map from the target key to the batch of the target that is still filling up.
+	pending map[config.TargetKey]*batch
+}
+
+// NewBatchers creates a new Batchers delivering each batch within the timeout, if not zero.
+func NewBatchers(timeout time.Duration) *Batchers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Batchers{
+		ctx:     ctx,
+		cancel:  cancel,
+		timeout: timeout,
+		pending: make(map[config.TargetKey]*batch),
+	}
+}
+
+// Stop cancels the deliveries of the batches in progress. It is meant to be called on shutdown,
+// once the handlers have drained.
+func (bs *Batchers) Stop() {
+	bs.cancel()
+}
+
+// deliveryContext returns the context the batch is delivered with, and its cancel function. It
+// carries the values of the context of the first event of the batch, such as its logger and metric
+// tags, but it is cancelled with the Batchers or after their timeout, rather than with the first
+// event.
+func (bs *Batchers) deliveryContext(b *batch) (context.Context, context.CancelFunc) {
+	ctx := valuesContext{Context: bs.ctx, values: b.values}
+	if bs.timeout > 0 {
+		return context.WithTimeout(ctx, bs.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// valuesContext is a context whose values come from another context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// batch is a batch of events to a target.
+type batch struct {
+	// values is the context of the first event of the batch, whose values are used to deliver the
+	// batch.
+	values context.Context
+	// events are the events of the batch, encoded in the JSON format.
+	events []json.RawMessage
+	size   int64
+	timer  *time.Timer
+	// done is closed once the batch is delivered, err being the result of the delivery.
+	done chan struct{}
+	err  error
+}
+
+// add adds the encoded event to the pending batch of the target and returns the batch. send is
+// called in a separate goroutine with each batch that is complete: when it reaches the maximum count
+// or size of the batch policy of the target, or the maximum latency after its first event.
+func (bs *Batchers) add(ctx context.Context, target *config.Target, e json.RawMessage, send func(*batch)) *batch {
+	policy := target.BatchPolicy
+	key := *target.Key()
+	size := int64(len(e))
+	var complete []*batch
+
+	bs.mu.Lock()
+	b := bs.pending[key]
+	if b != nil && policy.MaxBytes > 0 && b.size+size > policy.MaxBytes {
+		// The event doesn't fit in the pending batch.
+		bs.detachLocked(key, b)
+		complete = append(complete, b)
+		b = nil
+	}
+	if b == nil {
+		latency := defaultBatchMaxLatency
+		if ms := policy.MaxLatencyMillis; ms > 0 {
+			latency = time.Duration(ms) * time.Millisecond
+		}
+		b = &batch{values: ctx, done: make(chan struct{})}
+		pending := b
+		b.timer = time.AfterFunc(latency, func() {
+			if bs.detach(key, pending) {
+				send(pending)
+			}
+		})
+		bs.pending[key] = b
+	}
+	b.events = append(b.events, e)
+	b.size += size
+	if len(b.events) >= int(policy.MaxCount) || (policy.MaxBytes > 0 && b.size >= policy.MaxBytes) {
+		bs.detachLocked(key, b)
+		complete = append(complete, b)
+	}
+	bs.mu.Unlock()
+
+	for _, c := range complete {
+		go send(c)
+	}
+	return b
+}
+
+// detach removes the batch from the pending batches, and reports whether it was still pending.
+func (bs *Batchers) detach(key config.TargetKey, b *batch) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.pending[key] != b {
+		return false
+	}
+	bs.detachLocked(key, b)
+	return true
+}
+
+func (bs *Batchers) detachLocked(key config.TargetKey, b *batch) {
+	delete(bs.pending, key)
+	b.timer.Stop()
+}
+
+// body returns the body of the request delivering the batch.
+func (b *batch) body() []byte {
+	var buf bytes.Buffer
+	buf.Grow(int(b.size) + len(b.events) + 1)
+	buf.WriteByte('[')
+	for i, e := range b.events {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(e)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// deliverBatched adds the event to the pending batch of the target and waits for the batch to be
+// delivered. Replies to batches are not supported, the responses of the target are ignored.
+func (p *Processor) deliverBatched(ctx context.Context, target *config.Target, e *event.Event) error {
+	delivered, err := p.rehydrate(ctx, target.Key().ParentKey(), e)
+	if err != nil {
+		return err
+	}
+	// Hops are local to the broker. Do not modify the original event as it is sent to the retry
+	// topic on failure.
+	batched := delivered.Clone()
+	batched.SetExtension(eventutil.HopsAttribute, nil)
+	encoded, err := json.Marshal(batched)
+	if err != nil {
+		return err
+	}
+
+	b := p.Batchers.add(ctx, target, encoded, func(b *batch) {
+		p.sendBatch(target, b)
+	})
+	select {
+	case <-b.done:
+		return b.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendBatch delivers the batch to target and records the result.
+func (p *Processor) sendBatch(target *config.Target, b *batch) {
+	defer close(b.done)
+	ctx, cancel := p.Batchers.deliveryContext(b)
+	defer cancel()
+	b.err = p.withinLimit(target, func() error {
+		return p.withBreaker(target, p.withBackpressure(target, func() error {
+			return p.deliverBatch(ctx, target, b)
+		}))
+	})
+	if b.err != nil {
+		logging.FromContext(ctx).Debug("batch delivery failed",
+			zap.Stringer("target", target.Key()), zap.Int("events", len(b.events)), zap.Error(b.err))
+	}
+}
+
+// deliverBatch sends the batch to target in a single request.
+func (p *Processor) deliverBatch(ctx context.Context, target *config.Target, b *batch) error {
+	auth, err := p.authorization(target.GetSubscriberAuth().GetAudience())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Address, bytes.NewReader(b.body()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", batchContentType)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	startTime := time.Now()
+	resp, err := p.DeliverClient.Do(req)
+	if err != nil {
+		var result *url.Error
+		if errors.As(err, &result) && result.Timeout() {
+			p.StatsReporter.ReportEventDispatchTime(ctx, time.Since(startTime))
+		}
+		return &targetError{err: err}
+	}
+	defer func() {
+		// Drain the response, which is ignored, so that the connection can be reused.
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxErrorDataLength))
+		if err := resp.Body.Close(); err != nil {
+			logging.FromContext(ctx).Warn("failed to close response body", zap.Error(err))
+		}
+	}()
+
+	cctx, err := metrics.AddRespStatusCodeTags(ctx, resp.StatusCode)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to add status code tags to context", zap.Error(err))
+	}
+	p.StatsReporter.ReportEventDispatchTime(cctx, time.Since(startTime))
+	return responseError(resp)
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+// newBatchEvent returns an event whose JSON encoding has the same size for all i < 10.
+func newBatchEvent(i int) *event.Event {
+	e := newSampleEvent()
+	e.SetID(fmt.Sprintf("id-%d", i))
+	e.SetTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	e.SetExtension(eventutil.HopsAttribute, 10)
+	return e
+}
+
+func TestDeliverBatch(t *testing.T) {
+	// The events are batched without the hops extension.
+	sized := newBatchEvent(0)
+	sized.SetExtension(eventutil.HopsAttribute, nil)
+	encoded, err := json.Marshal(sized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventSize := int64(len(encoded))
+
+	cases := []struct {
+		name        string
+		policy      *config.BatchPolicy
+		events      int
+		statusCode  int
+		wantBatches []int
+		wantErr     bool
+	}{{
+		name:        "full batch",
+		policy:      &config.BatchPolicy{MaxCount: 3, MaxLatencyMillis: 60000},
+		events:      3,
+		statusCode:  http.StatusOK,
+		wantBatches: []int{3},
+	}, {
+		name:        "max latency",
+		policy:      &config.BatchPolicy{MaxCount: 10, MaxLatencyMillis: 200},
+		events:      2,
+		statusCode:  http.StatusOK,
+		wantBatches: []int{2},
+	}, {
+		name:        "max bytes",
+		policy:      &config.BatchPolicy{MaxCount: 10, MaxBytes: 2 * eventSize, MaxLatencyMillis: 60000},
+		events:      4,
+		statusCode:  http.StatusOK,
+		wantBatches: []int{2, 2},
+	}, {
+		name:        "failed batch",
+		policy:      &config.BatchPolicy{MaxCount: 2, MaxLatencyMillis: 60000},
+		events:      2,
+		statusCode:  http.StatusInternalServerError,
+		wantBatches: []int{2},
+		wantErr:     true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+
+			var mu sync.Mutex
+			var gotBatches []int
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if got := req.Header.Get("Content-Type"); got != "application/cloudevents-batch+json" {
+					t.Errorf("batch Content-Type got=%q, want application/cloudevents-batch+json", got)
+				}
+				var events []event.Event
+				if err := json.NewDecoder(req.Body).Decode(&events); err != nil {
+					t.Errorf("failed to decode batch: %v", err)
+				}
+				for _, e := range events {
+					if _, ok := e.Extensions()[eventutil.HopsAttribute]; ok {
+						t.Errorf("batched event %s has the hops extension", e.ID())
+					}
+				}
+				mu.Lock()
+				gotBatches = append(gotBatches, len(events))
+				mu.Unlock()
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer targetSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				BatchPolicy:    tc.policy,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+				Batchers:      NewBatchers(time.Second),
+			}
+
+			errs := make(chan error, tc.events)
+			for i := 0; i < tc.events; i++ {
+				e := newBatchEvent(i)
+				go func() {
+					errs <- p.Process(ctx, e)
+				}()
+			}
+			for i := 0; i < tc.events; i++ {
+				if err := <-errs; (err != nil) != tc.wantErr {
+					t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Ints(gotBatches)
+			if diff := cmp.Diff(tc.wantBatches, gotBatches); diff != "" {
+				t.Errorf("unexpected batch sizes (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestBatchersAdd(t *testing.T) {
+	target := &config.Target{
+		Namespace:   "ns",
+		Name:        "target",
+		BatchPolicy: &config.BatchPolicy{MaxCount: 2, MaxLatencyMillis: 60000},
+	}
+	sent := make(chan *batch, 2)
+	send := func(b *batch) { sent <- b }
+	bs := NewBatchers(time.Second)
+	ctx := logtest.TestContextWithLogger(t)
+
+	first := bs.add(ctx, target, json.RawMessage(`{"id":"1"}`), send)
+	if got := bs.add(ctx, target, json.RawMessage(`{"id":"2"}`), send); got != first {
+		t.Error("the second event wasn't added to the pending batch")
+	}
+	if got := <-sent; got != first {
+		t.Error("the complete batch wasn't sent")
+	}
+	if got, want := string(first.body()), `[{"id":"1"},{"id":"2"}]`; got != want {
+		t.Errorf("batch body got=%s, want=%s", got, want)
+	}
+	if third := bs.add(ctx, target, json.RawMessage(`{"id":"3"}`), send); third == first {
+		t.Error("an event was added to a batch that was already sent")
+	}
+}
+
+func TestBatchDeliveryContext(t *testing.T) {
+	cases := []struct {
+		name     string
+		delay    time.Duration
+		wantSent int
+		wantErr  bool
+	}{{
+		name:     "first event cancelled",
+		wantSent: 2,
+	}, {
+		name:     "batch timeout",
+		delay:    time.Second,
+		wantSent: 2,
+		wantErr:  true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+
+			sent := make(chan int, 1)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				var events []event.Event
+				if err := json.NewDecoder(req.Body).Decode(&events); err != nil {
+					t.Errorf("failed to decode batch: %v", err)
+				}
+				sent <- len(events)
+				time.Sleep(tc.delay)
+			}))
+			defer targetSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				BatchPolicy:    &config.BatchPolicy{MaxCount: 2, MaxLatencyMillis: 60000},
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs := NewBatchers(500 * time.Millisecond)
+			defer bs.Stop()
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+				Batchers:      bs,
+			}
+
+			// The first event gives up on the batch before the batch is complete.
+			firstCtx, cancelFirst := context.WithCancel(ctx)
+			firstErr := make(chan error, 1)
+			go func() {
+				firstErr <- p.Process(firstCtx, newBatchEvent(0))
+			}()
+			for pending := false; !pending; {
+				bs.mu.Lock()
+				pending = bs.pending[*target.Key()] != nil
+				bs.mu.Unlock()
+				time.Sleep(time.Millisecond)
+			}
+			cancelFirst()
+			if err := <-firstErr; !errors.Is(err, context.Canceled) {
+				t.Errorf("processing the first event got error=%v, want %v", err, context.Canceled)
+			}
+
+			err = p.Process(ctx, newBatchEvent(1))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing the second event got error=%v, want=%v", err, tc.wantErr)
+			}
+			if got := <-sent; got != tc.wantSent {
+				t.Errorf("batch size got=%d, want=%d", got, tc.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/binding/transformer"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// The extensions added to the events sent to a dead letter sink, following the Knative eventing
+// conventions.
+const (
+	// errorDestExtension is the address of the target the event failed to be delivered to.
+	errorDestExtension = "knativeerrordest"
+	// errorCodeExtension is the HTTP status code of the last response of the target, if any.
+	errorCodeExtension = "knativeerrorcode"
+	// errorDataExtension is the base64 encoded body of the last response of the target, or of the
+	// error of the last delivery if the target didn't respond.
+	errorDataExtension = "knativeerrordata"
+
+	// maxErrorDataLength is the maximum length of the data encoded in the errorDataExtension.
+	maxErrorDataLength = 1024
+)
+
+// shouldDeadLetter reports whether the event should be sent to the dead letter sink of the broker
+// after err: either the target rejected it with a non-retryable status code, or the failed delivery
+// was the last attempt allowed. Deliveries that were never attempted, because the target has
+// reached its delivery limit or its circuit breaker is open, don't exhaust the retries.
+func (p *Processor) shouldDeadLetter(ctx context.Context, broker *config.CellTenant, err error) bool {
+	if broker.DeadLetter == nil || broker.DeadLetter.Address == "" {
+		return false
+	}
+	if errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrSuspended) {
+		return false
+	}
+	var te *targetError
+	if errors.As(err, &te) && p.NonRetryableStatusCodes[te.statusCode] {
+		return true
+	}
+	attempt, ok := handlerctx.GetDeliveryAttempt(ctx)
+	return ok && attempt >= int(broker.DeadLetter.MaxDeliveryAttempts)
+}
+
+// sendToDeadLetterSink sends the event to the dead letter sink of the broker, with extensions
+// describing deliveryErr, the last failure to deliver the event to target or the reason why it
+// wasn't delivered.
+func (p *Processor) sendToDeadLetterSink(ctx context.Context, target *config.Target, broker *config.CellTenant, e *event.Event, deliveryErr error) error {
+	logging.FromContext(ctx).Warn("sending event to dead letter sink",
+		zap.Stringer("target", target.Key()), zap.Error(deliveryErr))
+	trace.FromContext(ctx).Annotate(
+		[]trace.Attribute{trace.StringAttribute("error_message", deliveryErr.Error())},
+		"sending to dead letter sink",
+	)
+
+	dead, err := p.rehydrate(ctx, broker.Key(), e)
+	if err != nil {
+		// The dead letter sink can still read the payload back with the claim check.
+		logging.FromContext(ctx).Warn("failed to read back the offloaded event payload", zap.Error(err))
+		dead = e
+	}
+	transformers := []binding.Transformer{
+		transformer.DeleteExtension(eventutil.HopsAttribute),
+		transformer.AddExtension(errorDestExtension, target.Address),
+		transformer.AddExtension(errorDataExtension, errorData(deliveryErr)),
+	}
+	var te *targetError
+	if errors.As(deliveryErr, &te) && te.statusCode != 0 {
+		transformers = append(transformers, transformer.AddExtension(errorCodeExtension, te.statusCode))
+	}
+
+	resp, err := p.sendMsg(ctx, broker.DeadLetter.Address, "", eventutil.NewImmutableEventMessage(dead), transformers...)
+	if err != nil {
+		return fmt.Errorf("failed to send event to dead letter sink: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		logging.FromContext(ctx).Warn("failed to close dead letter sink response body", zap.Error(err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send event to dead letter sink: HTTP status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// errorData returns the value of the errorDataExtension for the delivery error: the body of the
+// response of the target if it has one, or the error message otherwise, truncated and base64
+// encoded.
+func errorData(err error) string {
+	data := []byte(err.Error())
+	var te *targetError
+	if errors.As(err, &te) && len(te.body) > 0 {
+		data = te.body
+	}
+	if len(data) > maxErrorDataLength {
+		data = data[:maxErrorDataLength]
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// DeliveryError is a failed delivery to a target.
+type DeliveryError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// ErrorLog keeps the most recent delivery errors of each target, for introspection.
+type ErrorLog struct {
+	size int
+	now  func() time.Time
+	// logs is a map from the target key to its *targetErrors.
+	logs sync.Map
+}
+
+type targetErrors struct {
+	mu sync.Mutex
+	// errs is a ring buffer of at most size errors, next being the index of the oldest one once
+	// it is full.
+	errs []DeliveryError
+	next int
+}
+
+// NewErrorLog creates an ErrorLog keeping the last size errors of each target.
+func NewErrorLog(size int) *ErrorLog {
+	return &ErrorLog{size: size, now: time.Now}
+}
+
+// Record records a failed delivery to the target.
+func (l *ErrorLog) Record(key *config.TargetKey, err error) {
+	if l.size <= 0 {
+		return
+	}
+	v, ok := l.logs.Load(*key)
+	if !ok {
+		v, _ = l.logs.LoadOrStore(*key, &targetErrors{})
+	}
+	te := v.(*targetErrors)
+	e := DeliveryError{Time: l.now(), Message: err.Error()}
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if len(te.errs) < l.size {
+		te.errs = append(te.errs, e)
+		return
+	}
+	te.errs[te.next] = e
+	te.next = (te.next + 1) % l.size
+}
+
+// Recent returns the recorded errors of the target, oldest first.
+func (l *ErrorLog) Recent(key *config.TargetKey) []DeliveryError {
+	v, ok := l.logs.Load(*key)
+	if !ok {
+		return nil
+	}
+	return v.(*targetErrors).recent()
+}
+
+func (te *targetErrors) recent() []DeliveryError {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	errs := make([]DeliveryError, 0, len(te.errs))
+	errs = append(errs, te.errs[te.next:]...)
+	return append(errs, te.errs[:te.next]...)
+}
+
+// Range calls f sequentially with the recorded errors of each target, oldest first. If f returns
+// false, Range stops the iteration.
+func (l *ErrorLog) Range(f func(key *config.TargetKey, errs []DeliveryError) bool) {
+	l.logs.Range(func(key, value interface{}) bool {
+		k := key.(config.TargetKey)
+		return f(&k, value.(*targetErrors).recent())
+	})
+}
+
+// Prune forgets the errors of the targets that are no longer in the config.
+func (l *ErrorLog) Prune(targets config.ReadonlyTargets) {
+	l.logs.Range(func(key, _ interface{}) bool {
+		k := key.(config.TargetKey)
+		if _, ok := targets.GetTargetByKey(&k); !ok {
+			l.logs.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestErrorLog(t *testing.T) {
+	l := NewErrorLog(3)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	target := limitedTarget(nil)
+	other := limitedTarget(nil)
+	other.Name = "other"
+	if errs := l.Recent(target.Key()); errs != nil {
+		t.Errorf("Recent() = %v before any error, want nil", errs)
+	}
+
+	var want []DeliveryError
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		msg := fmt.Sprintf("error %d", i)
+		l.Record(target.Key(), errors.New(msg))
+		want = append(want, DeliveryError{Time: now, Message: msg})
+	}
+	l.Record(other.Key(), errors.New("other error"))
+
+	// Only the last 3 errors are kept, oldest first.
+	if diff := cmp.Diff(want[2:], l.Recent(target.Key())); diff != "" {
+		t.Errorf("Recent() (-want,+got): %v", diff)
+	}
+
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(target.Key().ParentKey(), func(m config.CellTenantMutation) {
+		m.UpsertTargets(target)
+	})
+	l.Prune(targets)
+	got := map[string][]DeliveryError{}
+	l.Range(func(key *config.TargetKey, errs []DeliveryError) bool {
+		got[key.String()] = errs
+		return true
+	})
+	if diff := cmp.Diff(map[string][]DeliveryError{target.Key().String(): want[2:]}, got); diff != "" {
+		t.Errorf("Range() after Prune (-want,+got): %v", diff)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// ErrLimitExceeded is the error of a delivery rejected because the target has reached its
+// delivery limit.
+var ErrLimitExceeded = errors.New("target delivery limit exceeded")
+
+// TargetLimiter enforces the per target delivery limits from the targets config. Each target with a
+// delivery limit has a counter of the deliveries in flight and a token bucket for requests.
+type TargetLimiter struct {
+	// limiters is a map from the target key to its *targetLimiter.
+	limiters sync.Map
+}
+
+type targetLimiter struct {
+	limit    *config.DeliveryLimit
+	inFlight int32
+	requests *rate.Limiter
+}
+
+// NewTargetLimiter creates a new TargetLimiter.
+func NewTargetLimiter() *TargetLimiter {
+	return &TargetLimiter{}
+}
+
+// Acquire reports whether a delivery to the target can be started now. If it can, the returned
+// function must be called once the delivery is done. Nothing is consumed from the limits if the
+// delivery can't be started.
+func (l *TargetLimiter) Acquire(target *config.Target) (func(), bool) {
+	tl := l.limiterFor(target)
+	if tl == nil {
+		return func() {}, true
+	}
+	if max := tl.limit.MaxConcurrency; max > 0 {
+		if atomic.AddInt32(&tl.inFlight, 1) > max {
+			atomic.AddInt32(&tl.inFlight, -1)
+			return nil, false
+		}
+	}
+	if !tl.requests.Allow() {
+		tl.release()
+		return nil, false
+	}
+	return tl.release, true
+}
+
+func (tl *targetLimiter) release() {
+	if tl.limit.MaxConcurrency > 0 {
+		atomic.AddInt32(&tl.inFlight, -1)
+	}
+}
+
+// limiterFor returns the limiter of the target, or nil if the target has no delivery limit. The
+// limiter is recreated when the delivery limit of the target changes.
+func (l *TargetLimiter) limiterFor(target *config.Target) *targetLimiter {
+	key := *target.Key()
+	if target.DeliveryLimit == nil {
+		l.limiters.Delete(key)
+		return nil
+	}
+	if v, ok := l.limiters.Load(key); ok {
+		if tl := v.(*targetLimiter); proto.Equal(tl.limit, target.DeliveryLimit) {
+			return tl
+		}
+	}
+	tl := &targetLimiter{
+		limit:    target.DeliveryLimit,
+		requests: newRequestLimiter(target.DeliveryLimit.MaxRequestsPerSecond),
+	}
+	l.limiters.Store(key, tl)
+	return tl
+}
+
+// Prune forgets the limiters of the targets that are no longer in the config or no longer have a
+// delivery limit.
+func (l *TargetLimiter) Prune(targets config.ReadonlyTargets) {
+	l.limiters.Range(func(key, _ interface{}) bool {
+		k := key.(config.TargetKey)
+		if target, ok := targets.GetTargetByKey(&k); !ok || target.DeliveryLimit == nil {
+			l.limiters.Delete(key)
+		}
+		return true
+	})
+}
+
+// newRequestLimiter creates a token bucket allowing a second worth of requests at once. A zero
+// rate means no limit.
+func newRequestLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(math.Ceil(perSecond)))
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func limitedTarget(limit *config.DeliveryLimit) *config.Target {
+	return &config.Target{
+		Namespace:      "ns",
+		Name:           "target",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "broker",
+		DeliveryLimit:  limit,
+	}
+}
+
+func TestTargetLimiterNoLimit(t *testing.T) {
+	l := NewTargetLimiter()
+	target := limitedTarget(nil)
+	for i := 0; i < 100; i++ {
+		if _, ok := l.Acquire(target); !ok {
+			t.Fatalf("delivery %d rejected without a limit", i)
+		}
+	}
+}
+
+func TestTargetLimiterMaxConcurrency(t *testing.T) {
+	l := NewTargetLimiter()
+	target := limitedTarget(&config.DeliveryLimit{MaxConcurrency: 2})
+
+	release1, ok := l.Acquire(target)
+	if !ok {
+		t.Fatal("first delivery rejected")
+	}
+	if _, ok := l.Acquire(target); !ok {
+		t.Fatal("second delivery rejected")
+	}
+	if _, ok := l.Acquire(target); ok {
+		t.Fatal("delivery above max concurrency allowed")
+	}
+	release1()
+	if _, ok := l.Acquire(target); !ok {
+		t.Error("delivery rejected after another one is done")
+	}
+}
+
+func TestTargetLimiterMaxRequestsPerSecond(t *testing.T) {
+	l := NewTargetLimiter()
+	target := limitedTarget(&config.DeliveryLimit{MaxRequestsPerSecond: 2})
+
+	for i := 0; i < 2; i++ {
+		release, ok := l.Acquire(target)
+		if !ok {
+			t.Fatalf("delivery %d rejected within the rate", i)
+		}
+		release()
+	}
+	if _, ok := l.Acquire(target); ok {
+		t.Error("delivery above max requests per second allowed")
+	}
+}
+
+func TestTargetLimiterLimitChange(t *testing.T) {
+	l := NewTargetLimiter()
+	if _, ok := l.Acquire(limitedTarget(&config.DeliveryLimit{MaxConcurrency: 1})); !ok {
+		t.Fatal("first delivery rejected")
+	}
+	if _, ok := l.Acquire(limitedTarget(&config.DeliveryLimit{MaxConcurrency: 1})); ok {
+		t.Fatal("delivery above max concurrency allowed")
+	}
+	if _, ok := l.Acquire(limitedTarget(&config.DeliveryLimit{MaxConcurrency: 2})); !ok {
+		t.Error("delivery rejected after the limit was raised")
+	}
+	if _, ok := l.Acquire(limitedTarget(nil)); !ok {
+		t.Error("delivery rejected after the limit was removed")
+	}
+}
+
+func TestTargetLimiterPrune(t *testing.T) {
+	targets := memory.NewEmptyTargets()
+	kept := limitedTarget(&config.DeliveryLimit{MaxConcurrency: 1})
+	kept.Name = "kept"
+	unlimited := limitedTarget(&config.DeliveryLimit{MaxConcurrency: 1})
+	unlimited.Name = "unlimited"
+	deleted := limitedTarget(&config.DeliveryLimit{MaxConcurrency: 1})
+	deleted.Name = "deleted"
+
+	l := NewTargetLimiter()
+	for _, target := range []*config.Target{kept, unlimited, deleted} {
+		if _, ok := l.Acquire(target); !ok {
+			t.Fatalf("first delivery to %s rejected", target.Name)
+		}
+	}
+	// The delivery limit of one target is removed, and another target is deleted.
+	unlimitedNow := limitedTarget(nil)
+	unlimitedNow.Name = "unlimited"
+	targets.MutateCellTenant(config.TestOnlyBrokerKey("ns", "broker"), func(m config.CellTenantMutation) {
+		m.UpsertTargets(kept, unlimitedNow)
+	})
+
+	l.Prune(targets)
+	var got []string
+	l.limiters.Range(func(key, _ interface{}) bool {
+		k := key.(config.TargetKey)
+		got = append(got, k.String())
+		return true
+	})
+	if len(got) != 1 || got[0] != kept.Key().String() {
+		t.Errorf("limiters after Prune got=%v, want [%v]", got, kept.Key())
+	}
+}
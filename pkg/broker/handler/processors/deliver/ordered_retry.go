@@ -0,0 +1,126 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+// OrderedRetryPublisher publishes the events of ordered brokers to the retry topics of the targets
+// with their ordering key, so that the retry subscriptions, which have message ordering enabled,
+// deliver them in order.
+//
+// It also remembers the ordering keys it published to each topic. While a key is pending in the
+// retry topic of a target, the next events with the key must be sent to the retry topic as well,
+// rather than delivered to the target ahead of it.
+type OrderedRetryPublisher struct {
+	client *pubsub.Client
+	// pendingTimeout is how long an ordering key stays pending after the last event with the key
+	// was published to a topic. The fanout can't tell when the retry pods delivered the event, so
+	// it must be long enough for the retry subscription to deliver it.
+	pendingTimeout time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+	// pending holds the time each ordering key was last published to each topic.
+	pending map[pendingKey]time.Time
+}
+
+type pendingKey struct {
+	topic       string
+	orderingKey string
+}
+
+// defaultPendingTimeout is the time an ordering key stays pending in a retry topic.
+const defaultPendingTimeout = time.Hour
+
+// NewOrderedRetryPublisher creates an OrderedRetryPublisher publishing with the given client.
+func NewOrderedRetryPublisher(client *pubsub.Client) *OrderedRetryPublisher {
+	return &OrderedRetryPublisher{
+		client:         client,
+		pendingTimeout: defaultPendingTimeout,
+		topics:         make(map[string]*pubsub.Topic),
+		pending:        make(map[pendingKey]time.Time),
+	}
+}
+
+// Pending reports whether an event with the ordering key was recently published to the topic, and
+// may not be delivered yet.
+func (p *OrderedRetryPublisher) Pending(topicID, orderingKey string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k := pendingKey{topic: topicID, orderingKey: orderingKey}
+	published, ok := p.pending[k]
+	if !ok {
+		return false
+	}
+	if time.Since(published) >= p.pendingTimeout {
+		delete(p.pending, k)
+		return false
+	}
+	return true
+}
+
+// Prune forgets the ordering keys that are no longer pending.
+func (p *OrderedRetryPublisher) Prune() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, published := range p.pending {
+		if time.Since(published) >= p.pendingTimeout {
+			delete(p.pending, k)
+		}
+	}
+}
+
+// Publish publishes the event to the topic with the ordering key and waits for the result.
+func (p *OrderedRetryPublisher) Publish(ctx context.Context, topicID, orderingKey string, e *event.Event) error {
+	msg := new(pubsub.Message)
+	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(e), msg); err != nil {
+		return err
+	}
+	msg.OrderingKey = orderingKey
+	topic := p.topic(topicID)
+	if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+		// The topic stops publishing messages with the ordering key after an error. Resume it so
+		// that the event can be sent again when it is redelivered.
+		topic.ResumePublish(orderingKey)
+		return err
+	}
+	p.mu.Lock()
+	p.pending[pendingKey{topic: topicID, orderingKey: orderingKey}] = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *OrderedRetryPublisher) topic(id string) *pubsub.Topic {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topic, ok := p.topics[id]
+	if !ok {
+		topic = p.client.Topic(id)
+		topic.EnableMessageOrdering = true
+		p.topics[id] = topic
+	}
+	return topic
+}
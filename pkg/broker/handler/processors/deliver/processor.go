@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -35,15 +37,23 @@ import (
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/idtoken"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
 const defaultEventHopsLimit int32 = 255
 
+// ErrSuspended is the error of a delivery skipped because the target is suspended. The fanout
+// sends the event to the retry topic of the target, where it's held until the target is resumed.
+var ErrSuspended = errors.New("target is suspended")
+
 // Processor delivers events based on the broker/target in the context.
 type Processor struct {
 	processors.BaseProcessor
@@ -62,12 +72,84 @@ type Processor struct {
 	// to the retry topic.
 	DeliverRetryClient ceclient.Client
 
+	// OrderedRetryPublisher sends the events with an ordering key to the retry topic. If nil, they
+	// are sent by the DeliverRetryClient without their ordering key.
+	OrderedRetryPublisher *OrderedRetryPublisher
+
 	// DeliverTimeout is the timeout applied to cancel delivery.
 	// If zero, not additional timeout is applied.
 	DeliverTimeout time.Duration
 
 	// StatsReporter is used to report delivery metrics.
 	StatsReporter *metrics.DeliveryReporter
+
+	// ClaimCheck reads back the event payloads offloaded to GCS by the ingress. Events with an
+	// offloaded payload fail to be delivered if nil.
+	ClaimCheck *claimcheck.Reader
+
+	// Breakers are the circuit breakers of the targets. While the breaker of a target is open,
+	// deliveries to the target fail without sending a request. Disabled if nil.
+	Breakers *circuitbreaker.Breakers
+
+	// Limiter enforces the delivery limits of the targets. Deliveries above the limit of a target
+	// fail without sending a request. Disabled if nil.
+	Limiter *TargetLimiter
+
+	// RetryBackoff decides how long failed events are held before being returned as failures, if
+	// RetryOnFailure is false. Failures are returned right away if nil.
+	RetryBackoff *RetryBackoff
+
+	// NonRetryableStatusCodes are the target response status codes after which an event is sent
+	// to the dead letter sink of the broker without further retries, if RetryOnFailure is false.
+	NonRetryableStatusCodes map[int]bool
+
+	// IDTokens mints the ID tokens sent to the targets that require authentication, and with the
+	// replies to the brokers that require authentication. Such deliveries fail if nil.
+	IDTokens *idtoken.Minter
+
+	// Batchers accumulates the events to the targets with a batch policy into batches. Events are
+	// delivered one at a time if nil.
+	Batchers *Batchers
+
+	// Backpressure records the results and latencies of the deliveries to the targets, from which
+	// the number of events pulled for each target is adapted. Disabled if nil.
+	Backpressure *backpressure.Controller
+
+	// Errors records the recent delivery errors of the targets, for introspection. Disabled if nil.
+	Errors *ErrorLog
+}
+
+// targetError is the failure of a request to the target, as opposed to e.g. a failure to send the
+// reply of the target.
+type targetError struct {
+	// statusCode is the status code of the response of the target, or zero if there was none.
+	statusCode int
+	// body is the beginning of the body of the error response of the target.
+	body []byte
+	// retryAfter is the delay requested by the Retry-After header of the response, if any.
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *targetError) Error() string {
+	return e.err.Error()
+}
+
+func (e *targetError) Unwrap() error {
+	return e.err
+}
+
+// isTargetUnavailable reports whether err shows that the target is unavailable, i.e. it didn't
+// respond in time or it responded with a server error or a throttling status code.
+func isTargetUnavailable(err error) bool {
+	var te *targetError
+	if !errors.As(err, &te) {
+		return false
+	}
+	return te.statusCode == 0 ||
+		te.statusCode >= 500 ||
+		te.statusCode == http.StatusRequestTimeout ||
+		te.statusCode == http.StatusTooManyRequests
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -103,6 +185,10 @@ func (p *Processor) Process(ctx context.Context, e *event.Event) error {
 		return nil
 	}
 
+	if expired(target, e, time.Now()) {
+		return p.handleExpired(ctx, target, broker, e)
+	}
+
 	// Hops is a broker local counter so remove any hops value before forwarding.
 	// Do not modify the original event as we need to send the original
 	// event to retry queue on failure.
@@ -121,6 +207,14 @@ func (p *Processor) Process(ctx context.Context, e *event.Event) error {
 
 	p.StatsReporter.FinishEventProcessing(ctx)
 
+	key := eventutil.OrderingKey(broker, e)
+	if key != "" && p.RetryOnFailure && p.OrderedRetryPublisher != nil && p.OrderedRetryPublisher.Pending(target.RetryQueue.Topic, key) {
+		// An earlier event with the same ordering key is still in the retry topic of the target.
+		// Send this one after it, so that the target receives them in order.
+		logging.FromContext(ctx).Debug("ordering key pending in the retry topic", zap.Stringer("target", tk), zap.String("orderingKey", key))
+		return p.sendToRetryTopic(ctx, target, e, key)
+	}
+
 	dctx := ctx
 	if p.DeliverTimeout > 0 {
 		var cancel context.CancelFunc
@@ -128,35 +222,152 @@ func (p *Processor) Process(ctx context.Context, e *event.Event) error {
 		defer cancel()
 	}
 
-	if err := p.deliver(dctx, target, broker, eventutil.NewImmutableEventMessage(e), hops); err != nil {
+	if err := p.deliverEvent(dctx, target, broker, e, hops); err != nil {
+		if p.Errors != nil && !errors.Is(err, ErrSuspended) {
+			p.Errors.Record(tk, err)
+		}
 		if !p.RetryOnFailure {
+			if p.shouldDeadLetter(ctx, broker, err) {
+				return p.sendToDeadLetterSink(ctx, target, broker, e, err)
+			}
+			p.holdBeforeRetry(ctx, err)
 			return err
 		}
-
-		logging.FromContext(ctx).Warn("target delivery failed", zap.Stringer("target", tk), zap.Error(err))
+		// The events of ordered brokers are sent to the retry topic with their ordering key. The
+		// next events with the key are then sent after them until the key is no longer pending.
+		if errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrSuspended) {
+			logging.FromContext(ctx).Debug("target delivery skipped", zap.Stringer("target", tk), zap.Error(err))
+		} else {
+			logging.FromContext(ctx).Warn("target delivery failed", zap.Stringer("target", tk), zap.Error(err))
+		}
 		trace.FromContext(ctx).Annotate(
 			[]trace.Attribute{trace.StringAttribute("error_message", err.Error())},
 			"enqueueing for retry",
 		)
 
-		return p.sendToRetryTopic(ctx, target, e)
+		return p.sendToRetryTopic(ctx, target, e, key)
 	}
 	// For post-delivery processing.
 	return p.Next().Process(ctx, e)
 }
 
+// holdBeforeRetry blocks for the backoff delay of the failed delivery, or until ctx is done. The
+// Pub/Sub client keeps extending the lease of the event in the meantime.
+func (p *Processor) holdBeforeRetry(ctx context.Context, err error) {
+	if p.RetryBackoff == nil {
+		return
+	}
+	attempt, _ := handlerctx.GetDeliveryAttempt(ctx)
+	d := p.RetryBackoff.Delay(err, attempt)
+	if d <= 0 {
+		return
+	}
+	logging.FromContext(ctx).Debug("holding event before retry", zap.Duration("delay", d), zap.Error(err))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// rehydrate returns the event of the broker with its payload read back from GCS if it was offloaded
+// by the ingress, or the event itself otherwise.
+func (p *Processor) rehydrate(ctx context.Context, broker *config.CellTenantKey, e *event.Event) (*event.Event, error) {
+	if !claimcheck.HasReference(e) {
+		return e, nil
+	}
+	if p.ClaimCheck == nil {
+		return nil, errors.New("event payload was offloaded but claim check is not enabled")
+	}
+	return p.ClaimCheck.Rehydrate(ctx, broker, e)
+}
+
+// deliverEvent delivers the event to target, either on its own or in a batch with other events if
+// the target has a batch policy. Nothing is delivered to a suspended target.
+func (p *Processor) deliverEvent(ctx context.Context, target *config.Target, broker *config.CellTenant, e *event.Event, hops int32) error {
+	if target.State == config.State_SUSPENDED {
+		return fmt.Errorf("delivery to %q skipped: %w", target.Name, ErrSuspended)
+	}
+	if target.BatchPolicy != nil && p.Batchers != nil {
+		return p.deliverBatched(ctx, target, e)
+	}
+	return p.deliverWithinLimit(ctx, target, broker, e, hops)
+}
+
+// deliverWithinLimit delivers the event to target unless the target has reached its delivery
+// limit.
+func (p *Processor) deliverWithinLimit(ctx context.Context, target *config.Target, broker *config.CellTenant, e *event.Event, hops int32) error {
+	return p.withinLimit(target, func() error {
+		// The original event, which may only hold a claim check, is kept for the retry topic.
+		delivered, err := p.rehydrate(ctx, broker.Key(), e)
+		if err != nil {
+			return err
+		}
+		msg := eventutil.NewImmutableEventMessage(delivered)
+		return p.withBreaker(target, p.withBackpressure(target, func() error {
+			return p.deliver(ctx, target, broker, msg, hops)
+		}))
+	})
+}
+
+// withinLimit calls deliver unless the target has reached its delivery limit.
+func (p *Processor) withinLimit(target *config.Target, deliver func() error) error {
+	if p.Limiter != nil {
+		release, ok := p.Limiter.Acquire(target)
+		if !ok {
+			return fmt.Errorf("delivery to %q rejected: %w", target.Name, ErrLimitExceeded)
+		}
+		defer release()
+	}
+	return deliver()
+}
+
+// withBreaker calls deliver through the circuit breaker of the target, if any.
+func (p *Processor) withBreaker(target *config.Target, deliver func() error) error {
+	if p.Breakers == nil {
+		return deliver()
+	}
+	b := p.Breakers.Get(target.Key())
+	attempt, ok := b.Allow()
+	if !ok {
+		return fmt.Errorf("delivery to %q rejected: %w", target.Name, circuitbreaker.ErrOpen)
+	}
+	err := deliver()
+	b.Record(attempt, !isTargetUnavailable(err))
+	return err
+}
+
+// withBackpressure returns deliver recording its result and latency for the backpressure of the
+// target, if any.
+func (p *Processor) withBackpressure(target *config.Target, deliver func() error) func() error {
+	if p.Backpressure == nil {
+		return deliver
+	}
+	return func() error {
+		startTime := time.Now()
+		err := deliver()
+		p.Backpressure.Record(target.Key(), !isTargetUnavailable(err), time.Since(startTime))
+		return err
+	}
+}
+
 // deliver delivers msg to target and sends the target's reply to the broker ingress.
 func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *config.CellTenant, msg binding.Message, hops int32) error {
+	auth, err := p.authorization(target.GetSubscriberAuth().GetAudience())
+	if err != nil {
+		return err
+	}
 	startTime := time.Now()
 	// Remove hops from forwarded event.
-	resp, err := p.sendMsg(ctx, target.Address, msg, transformer.DeleteExtension(eventutil.HopsAttribute))
+	resp, err := p.sendMsg(ctx, target.Address, auth, msg, transformer.DeleteExtension(eventutil.HopsAttribute))
 	if err != nil {
 		var result *url.Error
 		if errors.As(err, &result) && result.Timeout() {
 			// If the delivery is cancelled because of timeout, report event dispatch time without resp status code.
 			p.StatsReporter.ReportEventDispatchTime(ctx, time.Since(startTime))
 		}
-		return err
+		return &targetError{err: err}
 	}
 
 	defer func() {
@@ -173,8 +384,8 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 	// Report event dispatch time with resp status code.
 	p.StatsReporter.ReportEventDispatchTime(cctx, time.Since(startTime))
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("event delivery failed: HTTP status code %d", resp.StatusCode)
+	if err := responseError(resp); err != nil {
+		return err
 	}
 
 	// Pre-check the reply response header, if it's not in structured mode/batched mode or binary mode,
@@ -234,8 +445,13 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 		return nil
 	}
 
-	// Attach the previous hops for the reply.
-	replyResp, err := p.sendMsg(ctx, broker.Address, respMsg, eventutil.SetRemainingHopsTransformer(hops))
+	replyAuth, err := p.authorization(ingressAudience(broker))
+	if err != nil {
+		return err
+	}
+	// Attach the previous hops for the reply. Claim checks can only be set by the ingress.
+	replyResp, err := p.sendMsg(ctx, broker.Address, replyAuth, respMsg,
+		eventutil.SetRemainingHopsTransformer(hops), transformer.DeleteExtension(claimcheck.ReferenceExtension))
 	if err != nil {
 		return err
 	}
@@ -251,18 +467,86 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 	return nil
 }
 
-func (p *Processor) sendMsg(ctx context.Context, address string, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
+// responseError returns the error of a delivery whose response has a non-2xx status code, or nil if
+// the delivery succeeded.
+func responseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorDataLength))
+	return &targetError{
+		statusCode: resp.StatusCode,
+		body:       body,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		err:        fmt.Errorf("event delivery failed: HTTP status code %d", resp.StatusCode),
+	}
+}
+
+// authorization returns the Authorization header of the requests to the audience, or an empty
+// string if the requests are not authenticated.
+func (p *Processor) authorization(audience string) (string, error) {
+	if audience == "" {
+		return "", nil
+	}
+	if p.IDTokens == nil {
+		return "", fmt.Errorf("ID token required for audience %q but ID tokens are not enabled", audience)
+	}
+	token, err := p.IDTokens.Token(audience)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// Audiences returns the audiences of the ID tokens sent to the targets and with the replies to the
+// brokers of the config.
+func Audiences(targets config.ReadonlyTargets) map[string]bool {
+	audiences := make(map[string]bool)
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		if audience := t.GetSubscriberAuth().GetAudience(); audience != "" {
+			audiences[audience] = true
+		}
+		return true
+	})
+	targets.RangeCellTenants(func(b *config.CellTenant) bool {
+		if audience := ingressAudience(b); audience != "" {
+			audiences[audience] = true
+		}
+		return true
+	})
+	return audiences
+}
+
+// ingressAudience returns the audience of the ID tokens accepted by the broker ingress, or an empty
+// string if the broker doesn't require authentication.
+func ingressAudience(broker *config.CellTenant) string {
+	if audiences := broker.GetIngressAuth().GetAudiences(); len(audiences) > 0 {
+		return audiences[0]
+	}
+	return ""
+}
+
+func (p *Processor) sendMsg(ctx context.Context, address, auth string, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, nil)
 	if err != nil {
 		return nil, err
 	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
 	if err := cehttp.WriteRequest(ctx, msg, req, transformers...); err != nil {
 		return nil, err
 	}
 	return p.DeliverClient.Do(req)
 }
 
-func (p *Processor) sendToRetryTopic(ctx context.Context, target *config.Target, event *event.Event) error {
+func (p *Processor) sendToRetryTopic(ctx context.Context, target *config.Target, event *event.Event, orderingKey string) error {
+	if orderingKey != "" && p.OrderedRetryPublisher != nil {
+		if err := p.OrderedRetryPublisher.Publish(ctx, target.RetryQueue.Topic, orderingKey, event); err != nil {
+			return fmt.Errorf("failed to send event to retry topic: %w", err)
+		}
+		return nil
+	}
 	pctx := cecontext.WithTopic(ctx, target.RetryQueue.Topic)
 	if err := p.DeliverRetryClient.Send(pctx, *event); err != nil {
 		return fmt.Errorf("failed to send event to retry topic: %w", err)
@@ -19,12 +19,15 @@ package deliver
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -40,15 +43,21 @@ import (
 	kgcptesting "github.com/google/knative-gcp/pkg/testing"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"knative.dev/pkg/logging"
 	logtest "knative.dev/pkg/logging/testing"
 
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
+	"github.com/google/knative-gcp/pkg/broker/circuitbreaker"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/idtoken"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage/testing"
 	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 
@@ -90,6 +99,14 @@ func TestDeliverSuccess(t *testing.T) {
 	sampleReply := sampleEvent.Clone()
 	sampleReply.SetID("reply")
 
+	largeEvent := sampleEvent.Clone()
+	largeEvent.SetDataContentType("application/octet-stream")
+	largeEvent.DataEncoded = []byte("large payload")
+	claimCheckEvent := largeEvent.Clone()
+	claimCheckEvent.DataEncoded = nil
+	claimCheckEvent.SetExtension(claimcheck.ReferenceExtension, "gs://bucket/ns/broker/object")
+	claimCheckObjects := map[string][]byte{"ns/broker/object": []byte("large payload")}
+
 	cases := []struct {
 		name       string
 		origin     *event.Event
@@ -115,6 +132,16 @@ func TestDeliverSuccess(t *testing.T) {
 		}(),
 		wantOrigin: sampleEvent,
 		reply:      &sampleReply,
+	}, {
+		name:       "success with claim check",
+		origin:     &claimCheckEvent,
+		wantOrigin: &largeEvent,
+		reply:      &sampleReply,
+		wantReply: func() *event.Event {
+			copy := sampleReply.Clone()
+			eventutil.UpdateRemainingHops(context.Background(), &copy, defaultEventHopsLimit)
+			return &copy
+		}(),
 	}}
 
 	for _, tc := range cases {
@@ -122,18 +149,1099 @@ func TestDeliverSuccess(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			reportertest.ResetDeliveryMetrics()
 			ctx := logtest.TestContextWithLogger(t)
-			targetClient, err := cehttp.New()
-			if err != nil {
-				t.Fatalf("failed to create target cloudevents client: %v", err)
-			}
-			ingressClient, err := cehttp.New()
-			if err != nil {
-				t.Fatalf("failed to create ingress cloudevents client: %v", err)
-			}
-			targetSvr := httptest.NewServer(targetClient)
+			targetClient, err := cehttp.New()
+			if err != nil {
+				t.Fatalf("failed to create target cloudevents client: %v", err)
+			}
+			ingressClient, err := cehttp.New()
+			if err != nil {
+				t.Fatalf("failed to create ingress cloudevents client: %v", err)
+			}
+			targetSvr := httptest.NewServer(targetClient)
+			defer targetSvr.Close()
+			ingressSvr := httptest.NewServer(ingressClient)
+			defer ingressSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.SetAddress(ingressSvr.URL)
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+				ClaimCheck: claimcheck.NewReader(gstorage.TestClientCreator(gstorage.TestClientData{
+					BucketData: gstorage.TestBucketData{Objects: claimCheckObjects},
+				}), "bucket"),
+			}
+
+			rctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			go func() {
+				msg, resp, err := targetClient.Respond(rctx)
+				if err != nil && err != io.EOF {
+					t.Errorf("unexpected error from target receiving event: %v", err)
+				}
+				if err := resp(rctx, binding.ToMessage(tc.reply), protocol.ResultACK); err != nil {
+					t.Errorf("unexpected error from target responding event: %v", err)
+				}
+				defer msg.Finish(nil)
+				gotEvent, err := binding.ToEvent(rctx, msg)
+				if err != nil {
+					t.Errorf("target received message cannot be converted to an event: %v", err)
+				}
+				if diff := cmp.Diff(tc.wantOrigin, gotEvent); diff != "" {
+					t.Errorf("target received event (-want,+got): %v", diff)
+				}
+			}()
+
+			go func() {
+				msg, err := ingressClient.Receive(rctx)
+				if err != nil && err != io.EOF {
+					t.Errorf("unexpected error from ingress receiving event: %v", err)
+				}
+				var gotEvent *event.Event
+				if msg != nil {
+					defer msg.Finish(nil)
+					var err error
+					gotEvent, err = binding.ToEvent(rctx, msg)
+					if err != nil {
+						t.Errorf("ingress received message cannot be converted to an event: %v", err)
+					}
+					// Get and set the hops if it presents.
+					// HTTP transport changes the internal type of the hops from int32 to string.
+					if hops, ok := eventutil.GetRemainingHops(rctx, gotEvent); ok {
+						eventutil.DeleteRemainingHops(rctx, gotEvent)
+						eventutil.UpdateRemainingHops(rctx, gotEvent, hops)
+					}
+				}
+				if diff := cmp.Diff(tc.wantReply, gotEvent); diff != "" {
+					t.Errorf("ingress received event (-want,+got): %v", diff)
+				}
+			}()
+
+			if err := p.Process(ctx, tc.origin); err != nil {
+				t.Errorf("unexpected error from processing: %v", err)
+			}
+
+			<-rctx.Done()
+		})
+	}
+}
+
+type targetWithFailureHandler struct {
+	t                     *testing.T
+	delay                 time.Duration
+	structuredContentMode bool
+	nonCloudEventReply    bool
+	respCode              int
+	respBody              string
+}
+
+func (h *targetWithFailureHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.t.Helper()
+	_, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		h.t.Errorf("Failed to read request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	time.Sleep(h.delay)
+
+	w.Header().Set("ce-specversion", "1.0")
+	if h.nonCloudEventReply {
+		// missing ce-specversion header
+		w.Header().Del("ce-specversion")
+		// Content-Type does not start with `application/cloudevents`
+		w.Header().Set("Content-Type", "text/html")
+	} else if h.structuredContentMode {
+		w.Header().Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	}
+	w.WriteHeader(h.respCode)
+	w.Write([]byte(h.respBody))
+}
+
+// statusCodeReplyHandler is intended to be used as the handler that replies are sent to. It will
+// respond with `responseCode`. If `responseCode` is not set, then the handler asserts that it
+// should not have been called (i.e. no reply event was expected to be sent).
+type statusCodeReplyHandler struct {
+	t            *testing.T
+	responseCode int
+	eventsSeen   int
+}
+
+func (h *statusCodeReplyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	io.Copy(ioutil.Discard, req.Body)
+	h.eventsSeen += 1
+	if h.responseCode == 0 {
+		h.t.Errorf("Reply Handler was not configured, no event should have been seen")
+	} else {
+		w.WriteHeader(h.responseCode)
+	}
+}
+
+func TestDeliverFailure(t *testing.T) {
+	cases := []struct {
+		name                string
+		withRetry           bool
+		targetHandler       *targetWithFailureHandler
+		replyHandler        statusCodeReplyHandler
+		expectedReplyEvents int
+		failRetry           bool
+		ordered             bool
+		wantErr             bool
+	}{{
+		name:          "delivery error no retry",
+		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
+		wantErr:       true,
+	}, {
+		name:          "delivery error retry success",
+		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
+		withRetry:     true,
+		wantErr:       false,
+	}, {
+		name:          "delivery error retry failure",
+		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
+		withRetry:     true,
+		failRetry:     true,
+		wantErr:       true,
+	}, {
+		name:          "ordered delivery error retry success",
+		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
+		withRetry:     true,
+		ordered:       true,
+		wantErr:       false,
+	}, {
+		name:          "delivery timeout no retry",
+		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
+		wantErr:       true,
+	}, {
+		name:          "delivery timeout retry success",
+		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
+		withRetry:     true,
+		wantErr:       false,
+	}, {
+		name:          "delivery timeout retry failure",
+		withRetry:     true,
+		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
+		failRetry:     true,
+		wantErr:       true,
+	}, {
+		name: "malformed CloudEvent reply failure",
+		// Return 2xx but with a malformed event should be considered error.
+		targetHandler: &targetWithFailureHandler{
+			respCode:              http.StatusOK,
+			respBody:              "not a valid structured cloud event",
+			structuredContentMode: true,
+		},
+		wantErr: true,
+	}, {
+		name: "non-CloudEvent reply success",
+		// a non-CloudEvent reply with 2xx status code should be considered delivery success.
+		targetHandler: &targetWithFailureHandler{
+			respCode:           http.StatusOK,
+			respBody:           "reply body",
+			nonCloudEventReply: true,
+		},
+		wantErr: false,
+	}, {
+		name: "non-CloudEvent reply failure",
+		// a non-CloudEvent reply with non-2xx status code should be considered delivery failure.
+		targetHandler: &targetWithFailureHandler{
+			respCode:           http.StatusBadRequest,
+			respBody:           "reply body",
+			nonCloudEventReply: true,
+		},
+		wantErr: true,
+	}, {
+		name: "reply server failure",
+		targetHandler: &targetWithFailureHandler{
+			respCode: http.StatusAccepted,
+			respBody: ceBody,
+		},
+		replyHandler: statusCodeReplyHandler{
+			responseCode: http.StatusBadRequest,
+		},
+		expectedReplyEvents: 1,
+		wantErr:             true,
+	}, {
+		name: "reply server success",
+		targetHandler: &targetWithFailureHandler{
+			respCode: http.StatusAccepted,
+			respBody: ceBody,
+		},
+		replyHandler: statusCodeReplyHandler{
+			responseCode: http.StatusOK,
+		},
+		expectedReplyEvents: 1,
+		wantErr:             false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			tc.targetHandler.t = t
+			targetSvr := httptest.NewServer(tc.targetHandler)
+			defer targetSvr.Close()
+			tc.replyHandler.t = t
+			replySvr := httptest.NewServer(&tc.replyHandler)
+			defer replySvr.Close()
+
+			_, c, close := testPubsubClient(ctx, t, "test-project")
+			defer close()
+
+			// Don't create the retry topic to make it fail.
+			if !tc.failRetry {
+				if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+					t.Fatalf("failed to create test pubsub topc: %v", err)
+				}
+			}
+
+			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatalf("failed to create pubsub protocol: %v", err)
+			}
+			deliverRetryClient, err := ceclient.New(ps)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents client: %v", err)
+			}
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+				Address:   replySvr.URL,
+			}
+			if tc.ordered {
+				broker.OrderingKeyExtension = "partitionkey"
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				RetryQueue: &config.Queue{
+					Topic: "test-retry-topic",
+				},
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.SetAddress(broker.Address)
+				bm.SetOrderingKeyExtension(broker.OrderingKeyExtension)
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient:      http.DefaultClient,
+				Targets:            testTargets,
+				RetryOnFailure:     tc.withRetry,
+				DeliverRetryClient: deliverRetryClient,
+				DeliverTimeout:     500 * time.Millisecond,
+				StatsReporter:      r,
+			}
+
+			origin := newSampleEvent()
+			origin.SetExtension("partitionkey", "key-1")
+			err = p.Process(ctx, origin)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+			}
+			if want, got := tc.expectedReplyEvents, tc.replyHandler.eventsSeen; want != got {
+				t.Errorf("Unexpected number of reply events. Want %d, Got %d", want, got)
+			}
+		})
+	}
+}
+
+// countingHandler responds to requests with a status code and counts them.
+type countingHandler struct {
+	respCode int
+	requests int32
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	io.Copy(ioutil.Discard, req.Body)
+	atomic.AddInt32(&h.requests, 1)
+	w.WriteHeader(h.respCode)
+}
+
+func TestDeliverCircuitBreaker(t *testing.T) {
+	cases := []struct {
+		name         string
+		respCode     int
+		wantRequests int32
+		wantState    circuitbreaker.State
+	}{{
+		name:         "unavailable target",
+		respCode:     http.StatusServiceUnavailable,
+		wantRequests: 2,
+		wantState:    circuitbreaker.StateOpen,
+	}, {
+		name:         "throttling target",
+		respCode:     http.StatusTooManyRequests,
+		wantRequests: 2,
+		wantState:    circuitbreaker.StateOpen,
+	}, {
+		name:         "target rejecting events",
+		respCode:     http.StatusBadRequest,
+		wantRequests: 4,
+		wantState:    circuitbreaker.StateClosed,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetHandler := &countingHandler{respCode: tc.respCode}
+			targetSvr := httptest.NewServer(targetHandler)
+			defer targetSvr.Close()
+
+			psSrv, c, close := testPubsubClient(ctx, t, "test-project")
+			defer close()
+			if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+				t.Fatalf("failed to create test pubsub topc: %v", err)
+			}
+			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatalf("failed to create pubsub protocol: %v", err)
+			}
+			deliverRetryClient, err := ceclient.New(ps)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents client: %v", err)
+			}
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				RetryQueue: &config.Queue{
+					Topic: "test-retry-topic",
+				},
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			breakers := circuitbreaker.NewBreakers(circuitbreaker.Settings{
+				Window:         time.Minute,
+				MinRequests:    2,
+				FailureRatio:   0.5,
+				OpenTimeout:    time.Hour,
+				HalfOpenProbes: 1,
+			}, nil)
+			p := &Processor{
+				DeliverClient:      http.DefaultClient,
+				Targets:            testTargets,
+				RetryOnFailure:     true,
+				DeliverRetryClient: deliverRetryClient,
+				StatsReporter:      r,
+				Breakers:           breakers,
+			}
+
+			for i := 0; i < 4; i++ {
+				if err := p.Process(ctx, newSampleEvent()); err != nil {
+					t.Errorf("processing got unexpected error: %v", err)
+				}
+			}
+			if got := atomic.LoadInt32(&targetHandler.requests); got != tc.wantRequests {
+				t.Errorf("target requests got=%d, want=%d", got, tc.wantRequests)
+			}
+			if got := len(psSrv.Messages()); got != 4 {
+				t.Errorf("events sent to the retry topic got=%d, want=4", got)
+			}
+			if got := breakers.Get(target.Key()).State(); got != tc.wantState {
+				t.Errorf("breaker state got=%v, want=%v", got, tc.wantState)
+			}
+		})
+	}
+}
+
+func TestDeliverBackpressure(t *testing.T) {
+	cases := []struct {
+		name      string
+		respCode  int
+		wantLimit int
+	}{{
+		name:      "unavailable target",
+		respCode:  http.StatusServiceUnavailable,
+		wantLimit: 4,
+	}, {
+		name:      "target rejecting events",
+		respCode:  http.StatusBadRequest,
+		wantLimit: 8,
+	}, {
+		name:      "healthy target",
+		respCode:  http.StatusAccepted,
+		wantLimit: 8,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetSvr := httptest.NewServer(&countingHandler{respCode: tc.respCode})
+			defer targetSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Adjust the limit on every read.
+			controller := backpressure.NewController(backpressure.Settings{
+				MinRequests:   2,
+				FailureRatio:  0.5,
+				PauseDuration: time.Hour,
+			})
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+				Backpressure:  controller,
+			}
+
+			for i := 0; i < 2; i++ {
+				p.Process(ctx, newSampleEvent())
+			}
+			if got := controller.Limit(target.Key(), 8); got != tc.wantLimit {
+				t.Errorf("target limit got=%d, want=%d", got, tc.wantLimit)
+			}
+		})
+	}
+}
+
+func TestDeliverErrorLog(t *testing.T) {
+	cases := []struct {
+		name       string
+		respCode   int
+		wantErrors int
+	}{{
+		name:       "unavailable target",
+		respCode:   http.StatusServiceUnavailable,
+		wantErrors: 2,
+	}, {
+		name:       "healthy target",
+		respCode:   http.StatusAccepted,
+		wantErrors: 0,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetSvr := httptest.NewServer(&countingHandler{respCode: tc.respCode})
+			defer targetSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+				Errors:        NewErrorLog(10),
+			}
+
+			for i := 0; i < 2; i++ {
+				p.Process(ctx, newSampleEvent())
+			}
+			if got := len(p.Errors.Recent(target.Key())); got != tc.wantErrors {
+				t.Errorf("recorded errors got=%d, want=%d", got, tc.wantErrors)
+			}
+		})
+	}
+}
+
+// blockingHandler blocks requests until unblock is closed.
+type blockingHandler struct {
+	received chan struct{}
+	unblock  chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	io.Copy(ioutil.Discard, req.Body)
+	h.received <- struct{}{}
+	<-h.unblock
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestDeliverLimitExceeded(t *testing.T) {
+	cases := []struct {
+		name      string
+		withRetry bool
+		wantErr   bool
+	}{{
+		name:    "no retry",
+		wantErr: true,
+	}, {
+		name:      "retry",
+		withRetry: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetHandler := &blockingHandler{received: make(chan struct{}, 2), unblock: make(chan struct{})}
+			targetSvr := httptest.NewServer(targetHandler)
+			defer targetSvr.Close()
+
+			psSrv, c, closePubsub := testPubsubClient(ctx, t, "test-project")
+			defer closePubsub()
+			if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+				t.Fatalf("failed to create test pubsub topc: %v", err)
+			}
+			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatalf("failed to create pubsub protocol: %v", err)
+			}
+			deliverRetryClient, err := ceclient.New(ps)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents client: %v", err)
+			}
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				RetryQueue: &config.Queue{
+					Topic: "test-retry-topic",
+				},
+				DeliveryLimit: &config.DeliveryLimit{MaxConcurrency: 1},
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient:      http.DefaultClient,
+				Targets:            testTargets,
+				RetryOnFailure:     tc.withRetry,
+				DeliverRetryClient: deliverRetryClient,
+				StatsReporter:      r,
+				Limiter:            NewTargetLimiter(),
+			}
+
+			firstErr := make(chan error)
+			go func() {
+				firstErr <- p.Process(ctx, newSampleEvent())
+			}()
+			<-targetHandler.received
+
+			err = p.Process(ctx, newSampleEvent())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing above the limit got error=%v, want=%v", err, tc.wantErr)
+			}
+			if len(targetHandler.received) != 0 {
+				t.Error("the target received an event above the limit")
+			}
+			wantRetried := 0
+			if tc.withRetry {
+				wantRetried = 1
+			}
+			if got := len(psSrv.Messages()); got != wantRetried {
+				t.Errorf("events sent to the retry topic got=%d, want=%d", got, wantRetried)
+			}
+
+			close(targetHandler.unblock)
+			if err := <-firstErr; err != nil {
+				t.Errorf("processing within the limit got unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeliverSuspended(t *testing.T) {
+	cases := []struct {
+		name      string
+		withRetry bool
+		ordered   bool
+		wantErr   bool
+	}{{
+		name:    "no retry",
+		wantErr: true,
+	}, {
+		name:      "retry",
+		withRetry: true,
+	}, {
+		name:      "ordered broker",
+		withRetry: true,
+		ordered:   true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			received := make(chan struct{}, 1)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				received <- struct{}{}
+			}))
+			defer targetSvr.Close()
+
+			psSrv, c, closePubsub := testPubsubClient(ctx, t, "test-project")
+			defer closePubsub()
+			if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+				t.Fatalf("failed to create test pubsub topc: %v", err)
+			}
+			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatalf("failed to create pubsub protocol: %v", err)
+			}
+			deliverRetryClient, err := ceclient.New(ps)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents client: %v", err)
+			}
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			if tc.ordered {
+				broker.OrderingKeyExtension = "partitionkey"
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+				RetryQueue: &config.Queue{
+					Topic: "test-retry-topic",
+				},
+				State: config.State_SUSPENDED,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.SetOrderingKeyExtension(broker.OrderingKeyExtension)
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient:         http.DefaultClient,
+				Targets:               testTargets,
+				RetryOnFailure:        tc.withRetry,
+				DeliverRetryClient:    deliverRetryClient,
+				OrderedRetryPublisher: NewOrderedRetryPublisher(c),
+				StatsReporter:         r,
+			}
+
+			e := newSampleEvent()
+			e.SetExtension("partitionkey", "key-1")
+			err = p.Process(ctx, e)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+			}
+			if tc.wantErr && !errors.Is(err, ErrSuspended) {
+				t.Errorf("processing got error=%v, want %v", err, ErrSuspended)
+			}
+			if len(received) != 0 {
+				t.Error("the suspended target received an event")
+			}
+			wantRetried := 0
+			if tc.withRetry {
+				wantRetried = 1
+			}
+			msgs := psSrv.Messages()
+			if got := len(msgs); got != wantRetried {
+				t.Fatalf("events sent to the retry topic got=%d, want=%d", got, wantRetried)
+			}
+			if tc.ordered && msgs[0].OrderingKey != "key-1" {
+				t.Errorf("event sent to the retry topic with ordering key %q, want %q", msgs[0].OrderingKey, "key-1")
+			}
+		})
+	}
+}
+
+func TestDeliverOrderedPendingRetry(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetHandler := &countingHandler{respCode: http.StatusInternalServerError}
+	targetSvr := httptest.NewServer(targetHandler)
+	defer targetSvr.Close()
+
+	psSrv, c, closePubsub := testPubsubClient(ctx, t, "test-project")
+	defer closePubsub()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topc: %v", err)
+	}
+
+	broker := &config.CellTenant{
+		Type:                 config.CellTenantType_BROKER,
+		Namespace:            "ns",
+		Name:                 "broker",
+		OrderingKeyExtension: "partitionkey",
+	}
+	target := &config.Target{
+		Namespace:      "ns",
+		Name:           "target",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "broker",
+		Address:        targetSvr.URL,
+		RetryQueue: &config.Queue{
+			Topic: "test-retry-topic",
+		},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+		bm.SetOrderingKeyExtension(broker.OrderingKeyExtension)
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderedRetry := NewOrderedRetryPublisher(c)
+	p := &Processor{
+		DeliverClient:         http.DefaultClient,
+		Targets:               testTargets,
+		RetryOnFailure:        true,
+		OrderedRetryPublisher: orderedRetry,
+		StatsReporter:         r,
+	}
+
+	process := func(key string) {
+		t.Helper()
+		e := newSampleEvent()
+		e.SetExtension("partitionkey", key)
+		if err := p.Process(ctx, e); err != nil {
+			t.Errorf("processing event with ordering key %q got unexpected error: %v", key, err)
+		}
+	}
+
+	// The failed event is sent to the retry topic rather than nacked.
+	process("key-1")
+	if got := atomic.LoadInt32(&targetHandler.requests); got != 1 {
+		t.Fatalf("target requests got=%d, want=1", got)
+	}
+	if got := len(psSrv.Messages()); got != 1 {
+		t.Fatalf("events sent to the retry topic got=%d, want=1", got)
+	}
+
+	// The next event with the same key follows it through the retry topic, even though the
+	// target would accept it.
+	targetHandler.respCode = http.StatusOK
+	process("key-1")
+	if got := atomic.LoadInt32(&targetHandler.requests); got != 1 {
+		t.Errorf("target requests got=%d, want=1", got)
+	}
+	msgs := psSrv.Messages()
+	if got := len(msgs); got != 2 {
+		t.Fatalf("events sent to the retry topic got=%d, want=2", got)
+	}
+	for _, msg := range msgs {
+		if msg.OrderingKey != "key-1" {
+			t.Errorf("event sent to the retry topic with ordering key %q, want %q", msg.OrderingKey, "key-1")
+		}
+	}
+
+	// Events with other keys are delivered to the target.
+	process("key-2")
+	if got := atomic.LoadInt32(&targetHandler.requests); got != 2 {
+		t.Errorf("target requests got=%d, want=2", got)
+	}
+
+	// Once the key is no longer pending, its events are delivered to the target again.
+	orderedRetry.pendingTimeout = 0
+	process("key-1")
+	if got := atomic.LoadInt32(&targetHandler.requests); got != 3 {
+		t.Errorf("target requests got=%d, want=3", got)
+	}
+	if got := len(psSrv.Messages()); got != 2 {
+		t.Errorf("events sent to the retry topic got=%d, want=2", got)
+	}
+}
+
+// deadLetterHandler records the events it receives and responds with a status code.
+type deadLetterHandler struct {
+	t        *testing.T
+	respCode int
+	received chan *event.Event
+}
+
+func (h *deadLetterHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	e, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		h.t.Errorf("dead letter sink received an invalid event: %v", err)
+	}
+	h.received <- e
+	w.WriteHeader(h.respCode)
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	cases := []struct {
+		name         string
+		attempt      int
+		nonRetryable map[int]bool
+		dlsRespCode  int
+		wantErr      bool
+		wantDeadLast bool
+	}{{
+		name:    "no delivery attempt",
+		wantErr: true,
+	}, {
+		name:         "non-retryable status code",
+		attempt:      1,
+		nonRetryable: map[int]bool{http.StatusServiceUnavailable: true},
+		dlsRespCode:  http.StatusAccepted,
+		wantDeadLast: true,
+	}, {
+		name:        "attempts remaining",
+		attempt:     2,
+		dlsRespCode: http.StatusAccepted,
+		wantErr:     true,
+	}, {
+		name:         "attempts exhausted",
+		attempt:      3,
+		dlsRespCode:  http.StatusAccepted,
+		wantDeadLast: true,
+	}, {
+		name:         "dead letter sink failure",
+		attempt:      3,
+		dlsRespCode:  http.StatusInternalServerError,
+		wantErr:      true,
+		wantDeadLast: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				io.Copy(ioutil.Discard, req.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte("target unavailable"))
+			}))
+			defer targetSvr.Close()
+			dlsHandler := &deadLetterHandler{t: t, respCode: tc.dlsRespCode, received: make(chan *event.Event, 1)}
+			dlsSvr := httptest.NewServer(dlsHandler)
+			defer dlsSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:      "ns",
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Address:        targetSvr.URL,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				bm.SetDeadLetter(&config.DeadLetter{Address: dlsSvr.URL, MaxDeliveryAttempts: 3})
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			if tc.attempt > 0 {
+				ctx = handlerctx.WithDeliveryAttempt(ctx, tc.attempt)
+			}
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient:           http.DefaultClient,
+				Targets:                 testTargets,
+				StatsReporter:           r,
+				NonRetryableStatusCodes: tc.nonRetryable,
+			}
+
+			origin := newSampleEvent()
+			err = p.Process(ctx, origin)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+			}
+			if !tc.wantDeadLast {
+				if len(dlsHandler.received) != 0 {
+					t.Error("the dead letter sink received an event before the attempts were exhausted")
+				}
+				return
+			}
+			dead := <-dlsHandler.received
+			if dead.ID() != origin.ID() {
+				t.Errorf("dead letter event id got=%q, want=%q", dead.ID(), origin.ID())
+			}
+			wantExtensions := map[string]string{
+				errorDestExtension: targetSvr.URL,
+				errorCodeExtension: "503",
+				errorDataExtension: base64.StdEncoding.EncodeToString([]byte("target unavailable")),
+			}
+			for name, want := range wantExtensions {
+				if got := fmt.Sprint(dead.Extensions()[name]); got != want {
+					t.Errorf("dead letter event extension %q got=%q, want=%q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorData(t *testing.T) {
+	long := bytes.Repeat([]byte{0xff}, maxErrorDataLength+1)
+	tests := []struct {
+		name string
+		err  error
+		want []byte
+	}{{
+		name: "no response",
+		err:  &targetError{err: errors.New("connection refused")},
+		want: []byte("connection refused"),
+	}, {
+		name: "response body",
+		err:  &targetError{statusCode: http.StatusBadRequest, body: []byte("invalid event"), err: errors.New("bad request")},
+		want: []byte("invalid event"),
+	}, {
+		name: "truncated binary response body",
+		err:  &targetError{statusCode: http.StatusBadRequest, body: long, err: errors.New("bad request")},
+		want: long[:maxErrorDataLength],
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := base64.StdEncoding.DecodeString(errorData(tc.err))
+			if err != nil {
+				t.Fatalf("errorData() is not base64 encoded: %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("errorData() decoded got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeliverRetryBackoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		timeout  time.Duration
+		wantHold time.Duration
+	}{{
+		name:     "hold until retry after",
+		timeout:  time.Minute,
+		wantHold: time.Second,
+	}, {
+		name:    "hold until timeout",
+		timeout: 100 * time.Millisecond,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				io.Copy(ioutil.Discard, req.Body)
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
 			defer targetSvr.Close()
-			ingressSvr := httptest.NewServer(ingressClient)
-			defer ingressSvr.Close()
 
 			broker := &config.CellTenant{
 				Type:      config.CellTenantType_BROKER,
@@ -149,11 +1257,12 @@ func TestDeliverSuccess(t *testing.T) {
 			}
 			testTargets := memory.NewEmptyTargets()
 			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
-				bm.SetAddress(ingressSvr.URL)
 				bm.UpsertTargets(target)
 			})
 			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
 			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			ctx, cancel := context.WithTimeout(ctx, tc.timeout)
+			defer cancel()
 
 			r, err := metrics.NewDeliveryReporter("pod", "container")
 			if err != nil {
@@ -163,240 +1272,71 @@ func TestDeliverSuccess(t *testing.T) {
 				DeliverClient: http.DefaultClient,
 				Targets:       testTargets,
 				StatsReporter: r,
+				RetryBackoff:  &RetryBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Minute},
 			}
 
-			rctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-
-			go func() {
-				msg, resp, err := targetClient.Respond(rctx)
-				if err != nil && err != io.EOF {
-					t.Errorf("unexpected error from target receiving event: %v", err)
-				}
-				if err := resp(rctx, binding.ToMessage(tc.reply), protocol.ResultACK); err != nil {
-					t.Errorf("unexpected error from target responding event: %v", err)
-				}
-				defer msg.Finish(nil)
-				gotEvent, err := binding.ToEvent(rctx, msg)
-				if err != nil {
-					t.Errorf("target received message cannot be converted to an event: %v", err)
-				}
-				if diff := cmp.Diff(tc.wantOrigin, gotEvent); diff != "" {
-					t.Errorf("target received event (-want,+got): %v", diff)
-				}
-			}()
-
-			go func() {
-				msg, err := ingressClient.Receive(rctx)
-				if err != nil && err != io.EOF {
-					t.Errorf("unexpected error from ingress receiving event: %v", err)
-				}
-				var gotEvent *event.Event
-				if msg != nil {
-					defer msg.Finish(nil)
-					var err error
-					gotEvent, err = binding.ToEvent(rctx, msg)
-					if err != nil {
-						t.Errorf("ingress received message cannot be converted to an event: %v", err)
-					}
-					// Get and set the hops if it presents.
-					// HTTP transport changes the internal type of the hops from int32 to string.
-					if hops, ok := eventutil.GetRemainingHops(rctx, gotEvent); ok {
-						eventutil.DeleteRemainingHops(rctx, gotEvent)
-						eventutil.UpdateRemainingHops(rctx, gotEvent, hops)
-					}
-				}
-				if diff := cmp.Diff(tc.wantReply, gotEvent); diff != "" {
-					t.Errorf("ingress received event (-want,+got): %v", diff)
-				}
-			}()
-
-			if err := p.Process(ctx, tc.origin); err != nil {
-				t.Errorf("unexpected error from processing: %v", err)
+			start := time.Now()
+			if err := p.Process(ctx, newSampleEvent()); err == nil {
+				t.Error("processing a throttled event got no error")
+			}
+			held := time.Since(start)
+			if held < tc.wantHold {
+				t.Errorf("event held for %v, want at least %v", held, tc.wantHold)
+			}
+			if held >= tc.timeout+time.Second {
+				t.Errorf("event held for %v past the timeout %v", held, tc.timeout)
 			}
-
-			<-rctx.Done()
 		})
 	}
 }
 
-type targetWithFailureHandler struct {
-	t                     *testing.T
-	delay                 time.Duration
-	structuredContentMode bool
-	nonCloudEventReply    bool
-	respCode              int
-	respBody              string
-}
-
-func (h *targetWithFailureHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	h.t.Helper()
-	_, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		h.t.Errorf("Failed to read request: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	time.Sleep(h.delay)
-
-	w.Header().Set("ce-specversion", "1.0")
-	if h.nonCloudEventReply {
-		// missing ce-specversion header
-		w.Header().Del("ce-specversion")
-		// Content-Type does not start with `application/cloudevents`
-		w.Header().Set("Content-Type", "text/html")
-	} else if h.structuredContentMode {
-		w.Header().Set("Content-Type", "application/cloudevents+json; charset=utf-8")
-	}
-	w.WriteHeader(h.respCode)
-	w.Write([]byte(h.respBody))
-}
-
-// statusCodeReplyHandler is intended to be used as the handler that replies are sent to. It will
-// respond with `responseCode`. If `responseCode` is not set, then the handler asserts that it
-// should not have been called (i.e. no reply event was expected to be sent).
-type statusCodeReplyHandler struct {
-	t            *testing.T
-	responseCode int
-	eventsSeen   int
-}
-
-func (h *statusCodeReplyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	io.Copy(ioutil.Discard, req.Body)
-	h.eventsSeen += 1
-	if h.responseCode == 0 {
-		h.t.Errorf("Reply Handler was not configured, no event should have been seen")
-	} else {
-		w.WriteHeader(h.responseCode)
+func TestDeliverIDToken(t *testing.T) {
+	fakeTokens := func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token-for-" + audience}), nil
 	}
-}
-
-func TestDeliverFailure(t *testing.T) {
 	cases := []struct {
-		name                string
-		withRetry           bool
-		targetHandler       *targetWithFailureHandler
-		replyHandler        statusCodeReplyHandler
-		expectedReplyEvents int
-		failRetry           bool
-		wantErr             bool
+		name           string
+		idTokens       bool
+		wantErr        bool
+		wantTargetAuth string
+		wantReplyAuth  string
 	}{{
-		name:          "delivery error no retry",
-		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
-		wantErr:       true,
-	}, {
-		name:          "delivery error retry success",
-		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
-		withRetry:     true,
-		wantErr:       false,
-	}, {
-		name:          "delivery error retry failure",
-		targetHandler: &targetWithFailureHandler{respCode: http.StatusInternalServerError},
-		withRetry:     true,
-		failRetry:     true,
-		wantErr:       true,
-	}, {
-		name:          "delivery timeout no retry",
-		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
-		wantErr:       true,
-	}, {
-		name:          "delivery timeout retry success",
-		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
-		withRetry:     true,
-		wantErr:       false,
-	}, {
-		name:          "delivery timeout retry failure",
-		withRetry:     true,
-		targetHandler: &targetWithFailureHandler{delay: time.Second, respCode: http.StatusOK},
-		failRetry:     true,
-		wantErr:       true,
-	}, {
-		name: "malformed CloudEvent reply failure",
-		// Return 2xx but with a malformed event should be considered error.
-		targetHandler: &targetWithFailureHandler{
-			respCode:              http.StatusOK,
-			respBody:              "not a valid structured cloud event",
-			structuredContentMode: true,
-		},
-		wantErr: true,
-	}, {
-		name: "non-CloudEvent reply success",
-		// a non-CloudEvent reply with 2xx status code should be considered delivery success.
-		targetHandler: &targetWithFailureHandler{
-			respCode:           http.StatusOK,
-			respBody:           "reply body",
-			nonCloudEventReply: true,
-		},
-		wantErr: false,
+		name:           "tokens attached",
+		idTokens:       true,
+		wantTargetAuth: "Bearer token-for-https://target.example.com",
+		wantReplyAuth:  "Bearer token-for-broker-audience",
 	}, {
-		name: "non-CloudEvent reply failure",
-		// a non-CloudEvent reply with non-2xx status code should be considered delivery failure.
-		targetHandler: &targetWithFailureHandler{
-			respCode:           http.StatusBadRequest,
-			respBody:           "reply body",
-			nonCloudEventReply: true,
-		},
+		name:    "tokens not enabled",
 		wantErr: true,
-	}, {
-		name: "reply server failure",
-		targetHandler: &targetWithFailureHandler{
-			respCode: http.StatusAccepted,
-			respBody: ceBody,
-		},
-		replyHandler: statusCodeReplyHandler{
-			responseCode: http.StatusBadRequest,
-		},
-		expectedReplyEvents: 1,
-		wantErr:             true,
-	}, {
-		name: "reply server success",
-		targetHandler: &targetWithFailureHandler{
-			respCode: http.StatusAccepted,
-			respBody: ceBody,
-		},
-		replyHandler: statusCodeReplyHandler{
-			responseCode: http.StatusOK,
-		},
-		expectedReplyEvents: 1,
-		wantErr:             false,
 	}}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			reportertest.ResetDeliveryMetrics()
 			ctx := logtest.TestContextWithLogger(t)
-			tc.targetHandler.t = t
-			targetSvr := httptest.NewServer(tc.targetHandler)
+			targetAuth := make(chan string, 1)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				io.Copy(ioutil.Discard, req.Body)
+				targetAuth <- req.Header.Get("Authorization")
+				w.Header().Set("ce-specversion", "1.0")
+				w.Header().Set("ce-id", "reply")
+				w.Header().Set("ce-type", "com.example.reply")
+				w.Header().Set("ce-source", "target")
+				w.WriteHeader(http.StatusOK)
+			}))
 			defer targetSvr.Close()
-			tc.replyHandler.t = t
-			replySvr := httptest.NewServer(&tc.replyHandler)
-			defer replySvr.Close()
-
-			_, c, close := testPubsubClient(ctx, t, "test-project")
-			defer close()
-
-			// Don't create the retry topic to make it fail.
-			if !tc.failRetry {
-				if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
-					t.Fatalf("failed to create test pubsub topc: %v", err)
-				}
-			}
-
-			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
-			if err != nil {
-				t.Fatalf("failed to create pubsub protocol: %v", err)
-			}
-			deliverRetryClient, err := ceclient.New(ps)
-			if err != nil {
-				t.Fatalf("failed to create cloudevents client: %v", err)
-			}
+			replyAuth := make(chan string, 1)
+			ingressSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				io.Copy(ioutil.Discard, req.Body)
+				replyAuth <- req.Header.Get("Authorization")
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer ingressSvr.Close()
 
 			broker := &config.CellTenant{
 				Type:      config.CellTenantType_BROKER,
 				Namespace: "ns",
 				Name:      "broker",
-				Address:   replySvr.URL,
 			}
 			target := &config.Target{
 				Namespace:      "ns",
@@ -404,13 +1344,12 @@ func TestDeliverFailure(t *testing.T) {
 				CellTenantType: config.CellTenantType_BROKER,
 				CellTenantName: "broker",
 				Address:        targetSvr.URL,
-				RetryQueue: &config.Queue{
-					Topic: "test-retry-topic",
-				},
+				SubscriberAuth: &config.SubscriberAuth{Audience: "https://target.example.com"},
 			}
 			testTargets := memory.NewEmptyTargets()
 			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
-				bm.SetAddress(broker.Address)
+				bm.SetAddress(ingressSvr.URL)
+				bm.SetIngressAuth(&config.IngressAuth{Audiences: []string{"broker-audience"}})
 				bm.UpsertTargets(target)
 			})
 			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
@@ -421,21 +1360,29 @@ func TestDeliverFailure(t *testing.T) {
 				t.Fatal(err)
 			}
 			p := &Processor{
-				DeliverClient:      http.DefaultClient,
-				Targets:            testTargets,
-				RetryOnFailure:     tc.withRetry,
-				DeliverRetryClient: deliverRetryClient,
-				DeliverTimeout:     500 * time.Millisecond,
-				StatsReporter:      r,
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+			}
+			if tc.idTokens {
+				p.IDTokens = idtoken.NewMinter(ctx, fakeTokens)
 			}
 
-			origin := newSampleEvent()
-			err = p.Process(ctx, origin)
+			err = p.Process(ctx, newSampleEvent())
 			if (err != nil) != tc.wantErr {
-				t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+				t.Fatalf("processing got error=%v, want=%v", err, tc.wantErr)
 			}
-			if want, got := tc.expectedReplyEvents, tc.replyHandler.eventsSeen; want != got {
-				t.Errorf("Unexpected number of reply events. Want %d, Got %d", want, got)
+			if tc.wantErr {
+				if len(targetAuth) != 0 {
+					t.Error("the target received an unauthenticated request")
+				}
+				return
+			}
+			if got := <-targetAuth; got != tc.wantTargetAuth {
+				t.Errorf("target Authorization header got=%q, want=%q", got, tc.wantTargetAuth)
+			}
+			if got := <-replyAuth; got != tc.wantReplyAuth {
+				t.Errorf("reply Authorization header got=%q, want=%q", got, tc.wantReplyAuth)
 			}
 		})
 	}
@@ -776,3 +1723,31 @@ func newSampleEvent() *event.Event {
 	sampleEvent.SetTime(time.Now())
 	return &sampleEvent
 }
+
+func TestAudiences(t *testing.T) {
+	targets := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"ns/broker": {
+				Type:        config.CellTenantType_BROKER,
+				Namespace:   "ns",
+				Name:        "broker",
+				IngressAuth: &config.IngressAuth{Audiences: []string{"broker-aud", "other-broker-aud"}},
+				Targets: map[string]*config.Target{
+					"authenticated": {
+						Namespace:      "ns",
+						Name:           "authenticated",
+						SubscriberAuth: &config.SubscriberAuth{Audience: "target-aud"},
+					},
+					"unauthenticated": {
+						Namespace: "ns",
+						Name:      "unauthenticated",
+					},
+				},
+			},
+		},
+	})
+	want := map[string]bool{"broker-aud": true, "target-aud": true}
+	if diff := cmp.Diff(want, Audiences(targets)); diff != "" {
+		t.Errorf("unexpected audiences (-want, +got): %s", diff)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// errEventExpired is the error with which the expired events are sent to the dead letter sink.
+var errEventExpired = errors.New("event exceeded the maximum event age of the target")
+
+// expired reports whether the event arrived at the broker longer ago than the maximum event age of
+// the target. Events without an arrival time never expire.
+func expired(target *config.Target, e *event.Event, now time.Time) bool {
+	if target.MaxEventAgeMillis <= 0 {
+		return false
+	}
+	arrival, ok := eventutil.GetArrivalTime(e)
+	if !ok {
+		return false
+	}
+	return now.Sub(arrival) > time.Duration(target.MaxEventAgeMillis)*time.Millisecond
+}
+
+// handleExpired sends the expired event to the dead letter sink of the broker if it has one, or
+// drops it otherwise.
+func (p *Processor) handleExpired(ctx context.Context, target *config.Target, broker *config.CellTenant, e *event.Event) error {
+	p.StatsReporter.ReportExpiredEvent(ctx)
+	if broker.DeadLetter != nil && broker.DeadLetter.Address != "" {
+		return p.sendToDeadLetterSink(ctx, target, broker, e, errEventExpired)
+	}
+	logging.FromContext(ctx).Debug("dropping expired event", zap.Stringer("target", target.Key()), zap.String("event.id", e.ID()))
+	trace.FromContext(ctx).Annotate(
+		ceclient.EventTraceAttributes(e),
+		"event dropped: maximum event age exceeded",
+	)
+	return nil
+}
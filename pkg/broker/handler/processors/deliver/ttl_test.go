@@ -0,0 +1,165 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	logtest "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		maxEventAge time.Duration
+		arrival     interface{}
+		want        bool
+	}{{
+		name:    "no max event age",
+		arrival: cetypes.Timestamp{Time: now.Add(-24 * time.Hour)},
+	}, {
+		name:        "no arrival time",
+		maxEventAge: time.Hour,
+	}, {
+		name:        "within max event age",
+		maxEventAge: time.Hour,
+		arrival:     now.Add(-time.Hour).Format(time.RFC3339Nano),
+	}, {
+		name:        "older than max event age",
+		maxEventAge: time.Hour,
+		arrival:     now.Add(-time.Hour - time.Millisecond).Format(time.RFC3339Nano),
+		want:        true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := &config.Target{MaxEventAgeMillis: tc.maxEventAge.Milliseconds()}
+			e := newSampleEvent()
+			if tc.arrival != nil {
+				e.SetExtension(eventutil.ArrivalTimeAttribute, tc.arrival)
+			}
+			if got := expired(target, e, now); got != tc.want {
+				t.Errorf("expired() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeliverExpired(t *testing.T) {
+	cases := []struct {
+		name           string
+		withDeadLetter bool
+	}{{
+		name: "dropped",
+	}, {
+		name:           "dead lettered",
+		withDeadLetter: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			received := make(chan struct{}, 1)
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				received <- struct{}{}
+			}))
+			defer targetSvr.Close()
+			dlsHandler := &deadLetterHandler{t: t, respCode: http.StatusOK, received: make(chan *event.Event, 1)}
+			dlsSvr := httptest.NewServer(dlsHandler)
+			defer dlsSvr.Close()
+
+			broker := &config.CellTenant{
+				Type:      config.CellTenantType_BROKER,
+				Namespace: "ns",
+				Name:      "broker",
+			}
+			target := &config.Target{
+				Namespace:         "ns",
+				Name:              "target",
+				CellTenantType:    config.CellTenantType_BROKER,
+				CellTenantName:    "broker",
+				Address:           targetSvr.URL,
+				MaxEventAgeMillis: time.Hour.Milliseconds(),
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+				if tc.withDeadLetter {
+					bm.SetDeadLetter(&config.DeadLetter{Address: dlsSvr.URL, MaxDeliveryAttempts: 3})
+				}
+				bm.UpsertTargets(target)
+			})
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, err = r.AddTags(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			p := &Processor{
+				DeliverClient: http.DefaultClient,
+				Targets:       testTargets,
+				StatsReporter: r,
+			}
+
+			origin := newSampleEvent()
+			origin.SetExtension(eventutil.ArrivalTimeAttribute, cetypes.Timestamp{Time: time.Now().Add(-2 * time.Hour)})
+			if err := p.Process(ctx, origin); err != nil {
+				t.Errorf("processing the expired event got unexpected error: %v", err)
+			}
+			if len(received) != 0 {
+				t.Error("the target received the expired event")
+			}
+			metricstest.CheckCountData(t, "expired_event_count", map[string]string{
+				metricskey.PodName:       "pod",
+				metricskey.ContainerName: "container",
+			}, 1)
+
+			if !tc.withDeadLetter {
+				return
+			}
+			dead := <-dlsHandler.received
+			if dead.ID() != origin.ID() {
+				t.Errorf("dead letter event id got=%q, want=%q", dead.ID(), origin.ID())
+			}
+			if got, want := fmt.Sprint(dead.Extensions()[errorDataExtension]), base64.StdEncoding.EncodeToString([]byte(errEventExpired.Error())); got != want {
+				t.Errorf("dead letter event extension %q got=%q, want=%q", errorDataExtension, got, want)
+			}
+		})
+	}
+}
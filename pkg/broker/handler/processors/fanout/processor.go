@@ -57,22 +57,28 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 	if err != nil {
 		return err
 	}
-	broker, ok := p.Targets.GetCellTenantByKey(bk)
-	if !ok {
+	if _, ok := p.Targets.GetCellTenantByKey(bk); !ok {
 		// If the broker no longer exists, then there is nothing to process.
 		logging.FromContext(ctx).Warn("broker no longer exist in the config", zap.Stringer("broker", bk))
 		return nil
 	}
 
+	// Only the targets that may accept the event are passed to the next processors.
+	var targets []*config.Target
+	p.Targets.RangeCandidateTargets(bk, event, func(t *config.Target) bool {
+		targets = append(targets, t)
+		return true
+	})
+
 	tc := make(chan *config.Target)
 	go func() {
 		defer close(tc)
-		for _, target := range broker.Targets {
+		for _, target := range targets {
 			tc <- target
 		}
 	}()
 
-	curr := len(broker.Targets)
+	curr := len(targets)
 	if curr > p.MaxConcurrency {
 		curr = p.MaxConcurrency
 	}
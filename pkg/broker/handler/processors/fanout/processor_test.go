@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/cloudevents/sdk-go/v2/event"
@@ -155,6 +156,54 @@ func TestFanoutPartialFailure(t *testing.T) {
 	close(ch)
 }
 
+func TestFanoutSkipsNonCandidateTargets(t *testing.T) {
+	ch := make(chan *event.Event, 3)
+	bk := config.TestOnlyBrokerKey("ns", "broker")
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(bk, func(bm config.CellTenantMutation) {
+		bm.UpsertTargets(
+			&config.Target{Name: "matching-type", Id: "matching-type", FilterAttributes: map[string]string{"type": "type"}},
+			&config.Target{Name: "other-type", Id: "other-type", FilterAttributes: map[string]string{"type": "other"}},
+			&config.Target{Name: "no-filter", Id: "no-filter"},
+		)
+	})
+	var wantTargets []*config.TargetKey
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		if t.Name != "other-type" {
+			wantTargets = append(wantTargets, t.Key())
+		}
+		return true
+	})
+	var gotTargets []*config.TargetKey
+	var mux sync.Mutex
+	next := &processors.FakeProcessor{
+		PrevEventsCh: ch,
+		InterceptFunc: func(ctx context.Context, e *event.Event) *event.Event {
+			t, _ := handlerctx.GetTargetKey(ctx)
+			mux.Lock()
+			defer mux.Unlock()
+			gotTargets = append(gotTargets, t)
+			return e
+		},
+	}
+	p := &Processor{MaxConcurrency: 2, Targets: targets}
+	p.WithNext(next)
+
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	ctx := handlerctx.WithBrokerKey(context.Background(), bk)
+	if err := p.Process(ctx, &e); err != nil {
+		t.Errorf("unexpected error from processing: %v", err)
+	}
+	close(ch)
+
+	if diff := cmp.Diff(wantTargets, gotTargets, diffTargetKeySlice); diff != "" {
+		t.Errorf("got target keys (-want,+got): %v", diff)
+	}
+}
+
 func newTestTargets(key *config.CellTenantKey, num int) config.ReadonlyTargets {
 	targets := memory.NewEmptyTargets()
 	targets.MutateCellTenant(key, func(bm config.CellTenantMutation) {
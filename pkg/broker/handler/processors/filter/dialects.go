@@ -0,0 +1,145 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// PassTarget checks the event against both the attribute filter and the Subscriptions API filters
+// of the target. The CESQL expressions are compiled with the cache x.
+func (x *Expressions) PassTarget(ctx context.Context, target *config.Target, event *event.Event) bool {
+	if target.FilterAttributes != nil && !PassFilter(ctx, target.FilterAttributes, event) {
+		return false
+	}
+	return x.PassFilters(ctx, target.Filters, event)
+}
+
+// PassFilters checks the event against the filters in the dialects of the CloudEvents Subscriptions
+// API. The event passes if it passes all of them. A CESQL expression that can't be compiled or
+// evaluated doesn't pass.
+func (x *Expressions) PassFilters(ctx context.Context, filters []*config.SubscriptionsAPIFilter, event *event.Event) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	attrs := attributeStrings(event)
+	for _, f := range filters {
+		if !x.passFilter(ctx, f, event, attrs) {
+			logging.FromContext(ctx).Debug("Event does not pass filter", zap.Stringer("filter", f))
+			trace.FromContext(ctx).Annotatef(nil, "event does not pass filter %v", f)
+			return false
+		}
+	}
+	return true
+}
+
+func (x *Expressions) passFilter(ctx context.Context, f *config.SubscriptionsAPIFilter, event *event.Event, attrs map[string]string) bool {
+	switch {
+	case len(f.All) > 0:
+		for _, nested := range f.All {
+			if !x.passFilter(ctx, nested, event, attrs) {
+				return false
+			}
+		}
+		return true
+	case len(f.Any) > 0:
+		for _, nested := range f.Any {
+			if x.passFilter(ctx, nested, event, attrs) {
+				return true
+			}
+		}
+		return false
+	case f.Not != nil:
+		return !x.passFilter(ctx, f.Not, event, attrs)
+	case len(f.Exact) > 0:
+		return matchAttributes(f.Exact, attrs, func(value, want string) bool { return value == want })
+	case len(f.Prefix) > 0:
+		return matchAttributes(f.Prefix, attrs, strings.HasPrefix)
+	case len(f.Suffix) > 0:
+		return matchAttributes(f.Suffix, attrs, strings.HasSuffix)
+	case f.Sql != "":
+		return x.passSQL(ctx, f.Sql, event)
+	default:
+		// An empty filter matches all events.
+		return true
+	}
+}
+
+// passSQL evaluates the CESQL expression against the event.
+func (x *Expressions) passSQL(ctx context.Context, sql string, event *event.Event) bool {
+	c := x.compile(sql)
+	if c.err != nil {
+		logging.FromContext(ctx).Error("Invalid CESQL expression in filter", zap.String("sql", sql), zap.Error(c.err))
+		return false
+	}
+	pass, err := c.expr.Match(event)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to evaluate CESQL expression", zap.String("sql", sql), zap.Error(err))
+		return false
+	}
+	return pass
+}
+
+// matchAttributes returns true if each attribute of the filter exists in the event and matches.
+func matchAttributes(filter, attrs map[string]string, match func(value, want string) bool) bool {
+	for k, want := range filter {
+		value, ok := attrs[k]
+		if !ok || !match(value, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// attributeStrings returns the context attributes and extensions of the event that are set, in
+// their canonical string representation.
+func attributeStrings(event *event.Event) map[string]string {
+	attrs := map[string]string{
+		"specversion": event.SpecVersion(),
+		"type":        event.Type(),
+		"source":      event.Source(),
+		"id":          event.ID(),
+	}
+	optional := map[string]string{
+		"subject":         event.Subject(),
+		"dataschema":      event.DataSchema(),
+		"datacontenttype": event.DataContentType(),
+	}
+	for k, v := range optional {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	if !event.Time().IsZero() {
+		attrs["time"] = cetypes.FormatTime(event.Time())
+	}
+	for k, v := range event.Extensions() {
+		if s, err := cetypes.Format(v); err == nil {
+			attrs[k] = s
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestPassFilters(t *testing.T) {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("https://example.com/orders")
+	e.SetType("com.example.orders.created")
+	e.SetTime(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	e.SetExtension("region", "us-central1")
+	e.SetExtension("priority", 3)
+
+	exact := func(k, v string) *config.SubscriptionsAPIFilter {
+		return &config.SubscriptionsAPIFilter{Exact: map[string]string{k: v}}
+	}
+	prefix := func(k, v string) *config.SubscriptionsAPIFilter {
+		return &config.SubscriptionsAPIFilter{Prefix: map[string]string{k: v}}
+	}
+	suffix := func(k, v string) *config.SubscriptionsAPIFilter {
+		return &config.SubscriptionsAPIFilter{Suffix: map[string]string{k: v}}
+	}
+
+	cases := []struct {
+		name    string
+		filters []*config.SubscriptionsAPIFilter
+		want    bool
+	}{{
+		name: "no filters",
+		want: true,
+	}, {
+		name:    "empty filter",
+		filters: []*config.SubscriptionsAPIFilter{{}},
+		want:    true,
+	}, {
+		name:    "exact match",
+		filters: []*config.SubscriptionsAPIFilter{exact("type", "com.example.orders.created")},
+		want:    true,
+	}, {
+		name:    "exact mismatch",
+		filters: []*config.SubscriptionsAPIFilter{exact("type", "com.example.orders")},
+	}, {
+		name:    "exact on missing attribute",
+		filters: []*config.SubscriptionsAPIFilter{exact("subject", "")},
+	}, {
+		name:    "exact on non-string extension",
+		filters: []*config.SubscriptionsAPIFilter{exact("priority", "3")},
+		want:    true,
+	}, {
+		name:    "exact on time",
+		filters: []*config.SubscriptionsAPIFilter{exact("time", "2021-01-02T03:04:05Z")},
+		want:    true,
+	}, {
+		name:    "prefix match",
+		filters: []*config.SubscriptionsAPIFilter{prefix("type", "com.example.orders.")},
+		want:    true,
+	}, {
+		name:    "prefix mismatch",
+		filters: []*config.SubscriptionsAPIFilter{prefix("type", "com.example.payments.")},
+	}, {
+		name:    "suffix match",
+		filters: []*config.SubscriptionsAPIFilter{suffix("region", "-central1")},
+		want:    true,
+	}, {
+		name:    "suffix mismatch",
+		filters: []*config.SubscriptionsAPIFilter{suffix("region", "-east1")},
+	}, {
+		name: "all attributes of a dialect must match",
+		filters: []*config.SubscriptionsAPIFilter{{Prefix: map[string]string{
+			"type":   "com.example.",
+			"source": "https://other.com",
+		}}},
+	}, {
+		name: "all",
+		filters: []*config.SubscriptionsAPIFilter{{All: []*config.SubscriptionsAPIFilter{
+			prefix("type", "com.example."),
+			suffix("type", ".created"),
+		}}},
+		want: true,
+	}, {
+		name: "all with one mismatch",
+		filters: []*config.SubscriptionsAPIFilter{{All: []*config.SubscriptionsAPIFilter{
+			prefix("type", "com.example."),
+			suffix("type", ".deleted"),
+		}}},
+	}, {
+		name: "any",
+		filters: []*config.SubscriptionsAPIFilter{{Any: []*config.SubscriptionsAPIFilter{
+			exact("region", "europe-west1"),
+			exact("region", "us-central1"),
+		}}},
+		want: true,
+	}, {
+		name: "any without match",
+		filters: []*config.SubscriptionsAPIFilter{{Any: []*config.SubscriptionsAPIFilter{
+			exact("region", "europe-west1"),
+			exact("region", "asia-east1"),
+		}}},
+	}, {
+		name:    "not",
+		filters: []*config.SubscriptionsAPIFilter{{Not: exact("region", "europe-west1")}},
+		want:    true,
+	}, {
+		name:    "not with match",
+		filters: []*config.SubscriptionsAPIFilter{{Not: exact("region", "us-central1")}},
+	}, {
+		name:    "sql",
+		filters: []*config.SubscriptionsAPIFilter{{Sql: "type LIKE 'com.example.%' AND priority > 2"}},
+		want:    true,
+	}, {
+		name:    "sql without match",
+		filters: []*config.SubscriptionsAPIFilter{{Sql: "region IN ('europe-west1', 'asia-east1')"}},
+	}, {
+		name:    "sql with evaluation error",
+		filters: []*config.SubscriptionsAPIFilter{{Sql: "subject = 'a'"}},
+	}, {
+		name:    "invalid sql",
+		filters: []*config.SubscriptionsAPIFilter{{Sql: "type LIKE"}},
+	}, {
+		name:    "nested sql",
+		filters: []*config.SubscriptionsAPIFilter{{Not: &config.SubscriptionsAPIFilter{Sql: "region = 'us-central1'"}}},
+	}, {
+		name: "all top level filters must pass",
+		filters: []*config.SubscriptionsAPIFilter{
+			prefix("type", "com.example."),
+			exact("region", "europe-west1"),
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := (&Expressions{}).PassFilters(context.Background(), tc.filters, &e); got != tc.want {
+				t.Errorf("PassFilters() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileTargets(t *testing.T) {
+	targets := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"ns/broker": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker",
+				Namespace: "ns",
+				Targets: map[string]*config.Target{
+					"t1": {Filters: []*config.SubscriptionsAPIFilter{{Sql: "priority > 2"}}},
+					"t2": {Filters: []*config.SubscriptionsAPIFilter{{Any: []*config.SubscriptionsAPIFilter{{Sql: "type LIKE"}}}}},
+				},
+			},
+		},
+	})
+	x := &Expressions{}
+	x.compile("stale = 1")
+	other := &Expressions{}
+	other.compile("stale = 1")
+
+	x.CompileTargets(context.Background(), targets)
+
+	if _, ok := x.cache.Load("stale = 1"); ok {
+		t.Error("CompileTargets() didn't forget an unused expression")
+	}
+	if c, ok := x.cache.Load("priority > 2"); !ok || c.(*compiledSQL).err != nil {
+		t.Errorf("CompileTargets() didn't compile a valid expression: %v", c)
+	}
+	if c, ok := x.cache.Load("type LIKE"); !ok || c.(*compiledSQL).err == nil {
+		t.Errorf("CompileTargets() didn't record the error of an invalid expression: %v", c)
+	}
+	if _, ok := other.cache.Load("stale = 1"); !ok {
+		t.Error("CompileTargets() forgot an expression of another cache")
+	}
+}
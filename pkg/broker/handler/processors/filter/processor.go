@@ -39,6 +39,9 @@ type Processor struct {
 
 	// Targets is the targets from config.
 	Targets config.ReadonlyTargets
+
+	// Expressions caches the compiled CESQL expressions of the targets.
+	Expressions *Expressions
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -64,11 +67,7 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 	ctx, span := startSpan(ctx, trigger, event)
 	defer span.End()
 
-	if target.FilterAttributes == nil {
-		return p.Next().Process(ctx, event)
-	}
-
-	if PassFilter(ctx, target.FilterAttributes, event) {
+	if p.Expressions.PassTarget(ctx, target, event) {
 		return p.Next().Process(ctx, event)
 	}
 	logging.FromContext(ctx).Debug("event does not pass filter for target", zap.Any("target", target))
@@ -84,6 +84,7 @@ func TestFilterProcessor(t *testing.T) {
 		name       string
 		e          event.Event
 		filter     map[string]string
+		filters    []*config.SubscriptionsAPIFilter
 		shouldPass bool
 	}{{
 		name: "no filter pass",
@@ -278,11 +279,43 @@ func TestFilterProcessor(t *testing.T) {
 			"source":  "unknown",
 		},
 		shouldPass: false,
+	}, {
+		name: "prefix filter pass",
+		e: func() event.Event {
+			e := event.New()
+			e.SetID("id")
+			e.SetSource("foo")
+			e.SetType("com.example.orders.created")
+			return e
+		}(),
+		filters: []*config.SubscriptionsAPIFilter{
+			{Prefix: map[string]string{"type": "com.example.orders."}},
+		},
+		shouldPass: true,
+	}, {
+		name: "attributes pass but dialect filter not pass",
+		e: func() event.Event {
+			e := event.New()
+			e.SetID("id")
+			e.SetSource("foo")
+			e.SetType("com.example.orders.created")
+			return e
+		}(),
+		filter: map[string]string{
+			"source": "foo",
+		},
+		filters: []*config.SubscriptionsAPIFilter{
+			{Any: []*config.SubscriptionsAPIFilter{
+				{Suffix: map[string]string{"type": ".deleted"}},
+				{Exact: map[string]string{"source": "bar"}},
+			}},
+		},
+		shouldPass: false,
 	}}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, testTargets := newTestTargets(tc.filter)
+			ctx, testTargets := newTestTargets(tc.filter, tc.filters...)
 			next := &processors.FakeProcessor{}
 			p := &Processor{Targets: testTargets}
 			p.WithNext(next)
@@ -311,13 +344,14 @@ func TestFilterProcessor(t *testing.T) {
 	}
 }
 
-func newTestTargets(filter map[string]string) (context.Context, config.Targets) {
+func newTestTargets(filter map[string]string, filters ...*config.SubscriptionsAPIFilter) (context.Context, config.Targets) {
 	testTarget := &config.Target{
 		Name:             "target",
 		CellTenantType:   config.CellTenantType_BROKER,
 		CellTenantName:   "broker",
 		Namespace:        "ns",
 		FilterAttributes: filter,
+		Filters:          filters,
 	}
 	testTargets := memory.NewEmptyTargets()
 	testTargets.MutateCellTenant(testTarget.Key().ParentKey(), func(bm config.CellTenantMutation) {
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/utils/cesql"
+)
+
+// compiledSQL is the result of compiling a CESQL expression.
+type compiledSQL struct {
+	expr *cesql.Expression
+	err  error
+}
+
+// Expressions caches the compiled CESQL expressions of a set of targets, keyed by their source.
+// Each set of targets, e.g. each handler pool, owns its cache, so that pruning it for one set
+// doesn't evict the expressions of another. The zero value is an empty cache. A nil cache compiles
+// the expressions each time they are evaluated.
+type Expressions struct {
+	cache sync.Map
+}
+
+// CompileTargets compiles the CESQL expressions of all targets, and forgets the expressions that
+// are no longer used. It is called whenever the targets config is loaded, so that each expression
+// is compiled once rather than for every event. Expressions that are not compiled yet, e.g. before
+// the first call, are compiled when they are first evaluated.
+func (x *Expressions) CompileTargets(ctx context.Context, targets config.ReadonlyTargets) {
+	used := make(map[string]bool)
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		for _, f := range t.Filters {
+			collectSQL(f, used)
+		}
+		return true
+	})
+	for sql := range used {
+		if c := x.compile(sql); c.err != nil {
+			logging.FromContext(ctx).Error("Invalid CESQL expression in filter", zap.String("sql", sql), zap.Error(c.err))
+		}
+	}
+	x.cache.Range(func(key, _ interface{}) bool {
+		if !used[key.(string)] {
+			x.cache.Delete(key)
+		}
+		return true
+	})
+}
+
+func collectSQL(f *config.SubscriptionsAPIFilter, used map[string]bool) {
+	if f == nil {
+		return
+	}
+	if f.Sql != "" {
+		used[f.Sql] = true
+	}
+	for _, nested := range f.All {
+		collectSQL(nested, used)
+	}
+	for _, nested := range f.Any {
+		collectSQL(nested, used)
+	}
+	collectSQL(f.Not, used)
+}
+
+// compile returns the compiled expression from the cache, or compiles it.
+func (x *Expressions) compile(sql string) *compiledSQL {
+	if x == nil {
+		expr, err := cesql.Parse(sql)
+		return &compiledSQL{expr: expr, err: err}
+	}
+	if c, ok := x.cache.Load(sql); ok {
+		return c.(*compiledSQL)
+	}
+	expr, err := cesql.Parse(sql)
+	c, _ := x.cache.LoadOrStore(sql, &compiledSQL{expr: expr, err: err})
+	return c.(*compiledSQL)
+}
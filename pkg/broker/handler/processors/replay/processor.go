@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replay implements the processor of the events replayed to a target.
+package replay
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// Processor is the processor to only replay the events published before the replay of the target
+// started. The replay queue of a target also receives the events published after that, which are
+// delivered from the decouple queue.
+type Processor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+}
+
+var _ processors.Interface = (*Processor)(nil)
+
+// Process passes the event to the next processor if it was published before the replay started.
+// Otherwise it simply returns.
+func (p *Processor) Process(ctx context.Context, e *event.Event) error {
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		return err
+	}
+	target, ok := p.Targets.GetTargetByKey(tk)
+	if !ok || target.Replay == nil {
+		// If the target or its replay no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Debug("target replay no longer exists in the config", zap.Stringer("target", tk))
+		return nil
+	}
+	until := time.Unix(0, target.Replay.UntilMillis*int64(time.Millisecond))
+	if published, ok := handlerctx.GetPublishTime(ctx); ok && !published.Before(until) {
+		return nil
+	}
+	return p.Next().Process(ctx, e)
+}
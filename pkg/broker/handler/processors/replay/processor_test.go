@@ -0,0 +1,94 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+func TestInvalidContext(t *testing.T) {
+	p := &Processor{}
+	e := event.New()
+	err := p.Process(context.Background(), &e)
+	if err != handlerctx.ErrTargetKeyNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrTargetKeyNotPresent)
+	}
+}
+
+func TestReplayUntil(t *testing.T) {
+	until := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	replay := &config.Replay{
+		Queue:       &config.Queue{Topic: "topic", Subscription: "replay"},
+		UntilMillis: until.UnixNano() / int64(time.Millisecond),
+	}
+	cases := []struct {
+		name        string
+		replay      *config.Replay
+		publishTime time.Time
+		wantPass    bool
+	}{{
+		name:        "published before the replay started",
+		replay:      replay,
+		publishTime: until.Add(-time.Millisecond),
+		wantPass:    true,
+	}, {
+		name:        "published after the replay started",
+		replay:      replay,
+		publishTime: until,
+	}, {
+		name:        "replay stopped",
+		publishTime: until.Add(-time.Hour),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := &config.Target{
+				Name:           "target",
+				Namespace:      "ns",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Replay:         tc.replay,
+			}
+			targets := memory.NewEmptyTargets()
+			targets.MutateCellTenant(target.Key().ParentKey(), func(m config.CellTenantMutation) {
+				m.UpsertTargets(target)
+			})
+			ctx := handlerctx.WithTargetKey(context.Background(), target.Key())
+			ctx = handlerctx.WithPublishTime(ctx, tc.publishTime)
+
+			ch := make(chan *event.Event, 1)
+			p := &Processor{Targets: targets}
+			p.WithNext(&processors.FakeProcessor{PrevEventsCh: ch})
+			e := event.New()
+			if err := p.Process(ctx, &e); err != nil {
+				t.Fatalf("Process got error: %v", err)
+			}
+			if gotPass := len(ch) == 1; gotPass != tc.wantPass {
+				t.Errorf("event passed got=%v, want=%v", gotPass, tc.wantPass)
+			}
+		})
+	}
+}
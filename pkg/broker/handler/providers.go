@@ -18,12 +18,16 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
+	"github.com/google/knative-gcp/pkg/gclient/storage"
 	"github.com/google/knative-gcp/pkg/utils/clients"
 	"github.com/google/wire"
 	"go.opencensus.io/plugin/ochttp"
@@ -57,6 +61,7 @@ var (
 		NewRetryPool,
 		clients.NewPubsubClient,
 		NewRetryClient,
+		NewClaimCheckReader,
 		wire.Value(DefaultHTTPClient),
 		wire.Value(DefaultCEClientOpts),
 	)
@@ -73,3 +78,43 @@ func NewRetryClient(ctx context.Context, client *pubsub.Client, opts ...ceclient
 
 	return ceclient.NewObserved(rps, opts...)
 }
+
+// ClaimCheckBucket is the GCS bucket the ingress offloads large event payloads to.
+type ClaimCheckBucket string
+
+// NewClaimCheckReader provides a claim check reader of the given bucket backed by a GCS client, which
+// is only created when an offloaded event payload needs to be read.
+func NewClaimCheckReader(bucket ClaimCheckBucket) *claimcheck.Reader {
+	return claimcheck.NewReader(storage.NewClient, string(bucket))
+}
+
+// deliverClientWithTLS returns a copy of the deliver client whose connections use the TLS
+// configuration kept up to date by r.
+func deliverClientWithTLS(c *http.Client, r *tlsconfig.Reloader) (*http.Client, error) {
+	rt, err := transportWithTLS(c.Transport, r)
+	if err != nil {
+		return nil, err
+	}
+	withTLS := *c
+	withTLS.Transport = rt
+	return &withTLS, nil
+}
+
+func transportWithTLS(rt http.RoundTripper, r *tlsconfig.Reloader) (http.RoundTripper, error) {
+	switch t := rt.(type) {
+	case nil:
+		return r.Transport(http.DefaultTransport.(*http.Transport)), nil
+	case *http.Transport:
+		return r.Transport(t), nil
+	case *ochttp.Transport:
+		base, err := transportWithTLS(t.Base, r)
+		if err != nil {
+			return nil, err
+		}
+		withTLS := *t
+		withTLS.Base = base
+		return &withTLS, nil
+	default:
+		return nil, fmt.Errorf("deliver client transport %T doesn't support TLS configuration", rt)
+	}
+}
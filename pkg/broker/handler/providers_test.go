@@ -0,0 +1,59 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"go.opencensus.io/plugin/ochttp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/tlsconfig"
+)
+
+type unsupportedTransport struct{}
+
+func (unsupportedTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func TestDeliverClientWithTLS(t *testing.T) {
+	r, err := tlsconfig.NewReloader(logtest.TestContextWithLogger(t), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := deliverClientWithTLS(DefaultHTTPClient, r)
+	if err != nil {
+		t.Fatalf("deliverClientWithTLS got error: %v", err)
+	}
+	oc, ok := c.Transport.(*ochttp.Transport)
+	if !ok {
+		t.Fatalf("deliver client transport got=%T, want the traced transport", c.Transport)
+	}
+	if _, ok := oc.Base.(*http.Transport); ok {
+		t.Error("deliver client base transport wasn't replaced")
+	}
+	if _, ok := DefaultHTTPClient.Transport.(*ochttp.Transport).Base.(*http.Transport); !ok {
+		t.Error("default deliver client was modified")
+	}
+
+	if _, err := deliverClientWithTLS(&http.Client{Transport: unsupportedTransport{}}, r); err == nil {
+		t.Error("deliverClientWithTLS with an unsupported transport got no error")
+	}
+}
@@ -20,17 +20,21 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/knative-gcp/pkg/logging"
 	"go.uber.org/zap"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/replay"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
@@ -42,12 +46,28 @@ type RetryPool struct {
 	options *Options
 	targets config.ReadonlyTargets
 	pool    *syncMapTargetKey
+	// replays holds the handlers pulling the replay queues of the targets that replay events.
+	replays *syncMapTargetKey
 	// Pubsub client used to pull events from decoupling topics.
 	pubsubClient *pubsub.Client
 	// For initial events delivery. We only need a shared client.
 	// And we can set target address dynamically.
 	deliverClient *http.Client
 	statsReporter *metrics.DeliveryReporter
+	// For reading back the event payloads offloaded to GCS by the ingress.
+	claimCheck *claimcheck.Reader
+	// For enforcing the delivery limits of the targets.
+	limiter *deliver.TargetLimiter
+	// For batching the deliveries to the targets with a batch policy.
+	batchers *deliver.Batchers
+	// The target response status codes after which events are dead lettered right away.
+	nonRetryableStatusCodes map[int]bool
+	// For adapting the number of events pulled for each target to the health of the target.
+	backpressure *backpressure.Controller
+	// The recent delivery errors of the targets, for introspection.
+	deliveryErrors *deliver.ErrorLog
+	// The compiled CESQL expressions of the targets' filters.
+	expressions *filter.Expressions
 }
 
 type retryHandlerCache struct {
@@ -66,6 +86,10 @@ func (hc *retryHandlerCache) shouldRenew(t *config.Target) bool {
 	if t == nil || t.RetryQueue == nil {
 		return true
 	}
+	// Stop pulling the retry queue while the target is suspended.
+	if t.State == config.State_SUSPENDED {
+		return true
+	}
 	if t.RetryQueue.Topic != hc.t.RetryQueue.Topic ||
 		t.RetryQueue.Subscription != hc.t.RetryQueue.Subscription {
 		return true
@@ -73,25 +97,62 @@ func (hc *retryHandlerCache) shouldRenew(t *config.Target) bool {
 	return false
 }
 
+// shouldRenewReplay returns true if the replay handler needs to be renewed, e.g. because the replay
+// was restarted.
+func (hc *retryHandlerCache) shouldRenewReplay(t *config.Target) bool {
+	if !hc.IsAlive() {
+		return true
+	}
+	if t.Replay == nil || t.Replay.Queue == nil {
+		return true
+	}
+	if t.State == config.State_SUSPENDED {
+		return true
+	}
+	return t.Replay.Queue.Subscription != hc.t.Replay.Queue.Subscription ||
+		t.Replay.UntilMillis != hc.t.Replay.UntilMillis
+}
+
 // NewRetryPool creates a new retry handler pool.
 func NewRetryPool(
 	targets config.ReadonlyTargets,
 	pubsubClient *pubsub.Client,
 	deliverClient *http.Client,
 	statsReporter *metrics.DeliveryReporter,
+	claimCheck *claimcheck.Reader,
 	opts ...Option) (*RetryPool, error) {
 	options, err := NewOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	if options.DeliveryTLS != nil {
+		if deliverClient, err = deliverClientWithTLS(deliverClient, options.DeliveryTLS); err != nil {
+			return nil, err
+		}
+	}
 	p := &RetryPool{
-		targets:       targets,
-		options:       options,
-		pool:          &syncMapTargetKey{},
-		pubsubClient:  pubsubClient,
-		deliverClient: deliverClient,
-		statsReporter: statsReporter,
+		targets:        targets,
+		options:        options,
+		pool:           &syncMapTargetKey{},
+		replays:        &syncMapTargetKey{},
+		pubsubClient:   pubsubClient,
+		deliverClient:  deliverClient,
+		statsReporter:  statsReporter,
+		claimCheck:     claimCheck,
+		limiter:        deliver.NewTargetLimiter(),
+		batchers:       deliver.NewBatchers(options.TimeoutPerEvent),
+		deliveryErrors: deliver.NewErrorLog(maxRecentErrors),
+		expressions:    &filter.Expressions{},
+	}
+	if options.Backpressure != nil {
+		p.backpressure = backpressure.NewController(*options.Backpressure)
+	}
+	if len(options.NonRetryableStatusCodes) > 0 {
+		p.nonRetryableStatusCodes = make(map[int]bool, len(options.NonRetryableStatusCodes))
+		for _, code := range options.NonRetryableStatusCodes {
+			p.nonRetryableStatusCodes[code] = true
+		}
 	}
 	return p, nil
 }
@@ -103,75 +164,209 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 		logging.FromContext(ctx).Error("failed to add tags to context", zap.Error(err))
 	}
 
+	p.expressions.CompileTargets(ctx, p.targets)
+	if p.backpressure != nil {
+		p.backpressure.Prune(p.targets)
+	}
+	p.deliveryErrors.Prune(p.targets)
+	p.limiter.Prune(p.targets)
+	if p.options.IDTokens != nil {
+		p.options.IDTokens.Prune(deliver.Audiences(p.targets))
+	}
+
 	p.pool.Range(func(key config.TargetKey, value *retryHandlerCache) bool {
 		// Each target represents a trigger.
 		if _, ok := p.targets.GetTargetByKey(&key); !ok {
-			value.Stop()
+			go value.Drain(p.options.DrainTimeout)
 			p.pool.Delete(key)
 		}
 		return true
 	})
 
-	p.targets.RangeAllTargets(func(t *config.Target) bool {
-		if value, ok := p.pool.Load(*t.Key()); ok {
-			// Skip if we don't need to renew the handler.
-			if !value.shouldRenew(t) {
-				return true
-			}
-			// Stop and clean up the old handler before we start a new one.
-			value.Stop()
-			p.pool.Delete(*t.Key())
+	p.replays.Range(func(key config.TargetKey, value *retryHandlerCache) bool {
+		if t, ok := p.targets.GetTargetByKey(&key); !ok || t.Replay == nil {
+			go value.Drain(p.options.DrainTimeout)
+			p.replays.Delete(key)
 		}
+		return true
+	})
+
+	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		p.syncRetryHandler(ctx, t)
+		p.syncReplayHandler(ctx, t)
+		return true
+	})
 
-		// Don't start the handler if the target is not ready.
-		// The retry topic/sub might not be ready at this point.
-		if t.State != config.State_READY {
-			return true
+	return nil
+}
+
+// syncRetryHandler starts the handler pulling the retry queue of the target, or renews it if its
+// settings changed.
+func (p *RetryPool) syncRetryHandler(ctx context.Context, t *config.Target) {
+	if value, ok := p.pool.Load(*t.Key()); ok {
+		// Skip if we don't need to renew the handler.
+		if !value.shouldRenew(t) {
+			return
 		}
+		// Drain and clean up the old handler while a new one starts.
+		go value.Drain(p.options.DrainTimeout)
+		p.pool.Delete(*t.Key())
+	}
+
+	// Don't start the handler if the target is not ready or is suspended.
+	// The retry topic/sub might not be ready at this point.
+	if t.State != config.State_READY {
+		return
+	}
+
+	hc := p.startHandler(ctx, t, t.RetryQueue.Subscription,
+		processors.ChainProcessors(
+			&filter.Processor{Targets: p.targets, Expressions: p.expressions},
+			p.deliverProcessor(),
+		),
+	)
+	p.pool.Store(*t.Key(), hc)
+}
 
-		sub := p.pubsubClient.Subscription(t.RetryQueue.Subscription)
-		sub.ReceiveSettings = p.options.PubsubReceiveSettings
-
-		h := NewHandler(
-			sub,
-			processors.ChainProcessors(
-				&filter.Processor{Targets: p.targets},
-				&deliver.Processor{
-					DeliverClient: p.deliverClient,
-					Targets:       p.targets,
-					StatsReporter: p.statsReporter,
-				},
-			),
-			p.options.TimeoutPerEvent,
-		)
-		hc := &retryHandlerCache{
-			Handler: *h,
-			t:       t,
+// syncReplayHandler starts the handler pulling the replay queue of the target if it replays events,
+// or renews it if the replay was restarted or its settings changed.
+func (p *RetryPool) syncReplayHandler(ctx context.Context, t *config.Target) {
+	if value, ok := p.replays.Load(*t.Key()); ok {
+		if !value.shouldRenewReplay(t) {
+			return
 		}
+		go value.Drain(p.options.DrainTimeout)
+		p.replays.Delete(*t.Key())
+	}
+
+	if t.Replay == nil || t.Replay.Queue == nil || t.State != config.State_READY {
+		return
+	}
+
+	hc := p.startHandler(ctx, t, t.Replay.Queue.Subscription,
+		processors.ChainProcessors(
+			&replay.Processor{Targets: p.targets},
+			&filter.Processor{Targets: p.targets, Expressions: p.expressions},
+			p.deliverProcessor(),
+		),
+	)
+	p.replays.Store(*t.Key(), hc)
+}
+
+func (p *RetryPool) deliverProcessor() *deliver.Processor {
+	return &deliver.Processor{
+		DeliverClient:           p.deliverClient,
+		Targets:                 p.targets,
+		StatsReporter:           p.statsReporter,
+		ClaimCheck:              p.claimCheck,
+		Limiter:                 p.limiter,
+		RetryBackoff:            p.options.RetryBackoff,
+		NonRetryableStatusCodes: p.nonRetryableStatusCodes,
+		IDTokens:                p.options.IDTokens,
+		Batchers:                p.batchers,
+		Backpressure:            p.backpressure,
+		Errors:                  p.deliveryErrors,
+	}
+}
+
+// startHandler starts a handler pulling the subscription for the target with the given processors.
+func (p *RetryPool) startHandler(ctx context.Context, t *config.Target, subscription string, processor processors.Interface) *retryHandlerCache {
+	sub := p.pubsubClient.Subscription(subscription)
+	sub.ReceiveSettings = p.options.PubsubReceiveSettings
 
-		ctx, err := metrics.AddTargetTags(ctx, t)
+	ctx, err := metrics.AddTargetTags(ctx, t)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+	}
+
+	h := NewHandler(sub, processor, p.options.TimeoutPerEvent)
+	h.Limit = p.backpressureLimit(ctx, t)
+	hc := &retryHandlerCache{
+		Handler: *h,
+		t:       t,
+	}
+
+	// Deliver processor needs the broker in the context for reply.
+	ctx = handlerctx.WithBrokerKey(ctx, t.Key().ParentKey())
+	ctx = handlerctx.WithTargetKey(ctx, t.Key())
+	// Start the handler with target in context.
+	hc.Start(ctx, func(err error) {
+		// We will anyway get an error because of https://github.com/cloudevents/sdk-go/issues/470
 		if err != nil {
-			logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+			logging.FromContext(ctx).Error("handler for trigger has stopped with error", zap.Stringer("trigger", t.Key()), zap.String("subscription", subscription), zap.Error(err))
+		} else {
+			logging.FromContext(ctx).Info("handler for trigger has stopped", zap.Stringer("trigger", t.Key()), zap.String("subscription", subscription))
 		}
+	})
+	return hc
+}
 
-		// Deliver processor needs the broker in the context for reply.
-		ctx = handlerctx.WithBrokerKey(ctx, t.Key().ParentKey())
-		ctx = handlerctx.WithTargetKey(ctx, t.Key())
-		// Start the handler with target in context.
-		hc.Start(ctx, func(err error) {
-			// We will anyway get an error because of https://github.com/cloudevents/sdk-go/issues/470
-			if err != nil {
-				logging.FromContext(ctx).Error("handler for trigger has stopped with error", zap.Stringer("trigger", t.Key()), zap.Error(err))
-			} else {
-				logging.FromContext(ctx).Info("handler for trigger has stopped", zap.Stringer("trigger", t.Key()))
-			}
-		})
-
-		p.pool.Store(*t.Key(), hc)
+// backpressureLimit returns the limit of the events processed at once by a handler of the target,
+// which is the MaxOutstandingMessages receive setting lowered by the backpressure of the target.
+// Zero means that processing is paused. It returns nil if the target has no backpressure.
+func (p *RetryPool) backpressureLimit(ctx context.Context, t *config.Target) func() int {
+	max := p.options.PubsubReceiveSettings.MaxOutstandingMessages
+	if max == 0 {
+		max = pubsub.DefaultReceiveSettings.MaxOutstandingMessages
+	}
+	// Backpressure can't lower an unlimited setting.
+	if p.backpressure == nil || max < 0 {
+		return nil
+	}
+	key := t.Key()
+	last := int64(max)
+	return func() int {
+		limit := p.backpressure.Limit(key, max)
+		if from := atomic.SwapInt64(&last, int64(limit)); from != int64(limit) {
+			logging.FromContext(ctx).Info("target backpressure changed",
+				zap.Stringer("target", key),
+				zap.Int64("fromLimit", from),
+				zap.Int("toLimit", limit),
+			)
+		}
+		return limit
+	}
+}
+
+// Drain drains all the handlers of the pool concurrently, and returns once they have stopped. It is
+// meant to be called on shutdown, after the pool stopped syncing.
+func (p *RetryPool) Drain() {
+	var handlers []*Handler
+	collect := func(_ config.TargetKey, value *retryHandlerCache) bool {
+		handlers = append(handlers, &value.Handler)
+		return true
+	}
+	p.pool.Range(collect)
+	p.replays.Range(collect)
+	drainHandlers(p.options.DrainTimeout, handlers)
+	p.batchers.Stop()
+}
+
+// Targets returns the targets config of the pool.
+func (p *RetryPool) Targets() config.ReadonlyTargets {
+	return p.targets
+}
+
+// Handlers returns the handlers of the targets, including those replaying events.
+func (p *RetryPool) Handlers() []HandlerInfo {
+	var handlers []HandlerInfo
+	p.pool.Range(func(key config.TargetKey, value *retryHandlerCache) bool {
+		handlers = append(handlers, newHandlerInfo(key.String(), &value.Handler))
 		return true
 	})
+	p.replays.Range(func(key config.TargetKey, value *retryHandlerCache) bool {
+		h := newHandlerInfo(key.String(), &value.Handler)
+		h.Replay = true
+		handlers = append(handlers, h)
+		return true
+	})
+	sortHandlers(handlers)
+	return handlers
+}
 
-	return nil
+// DeliveryErrors returns the recent delivery errors of the targets.
+func (p *RetryPool) DeliveryErrors() *deliver.ErrorLog {
+	return p.deliveryErrors
 }
 
 // syncMapTargetKey is a typed version of sync.Map.
@@ -21,10 +21,13 @@ import (
 	"testing"
 	"time"
 
+	"cloud.google.com/go/pubsub"
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/google/knative-gcp/pkg/broker/backpressure"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlertesting "github.com/google/knative-gcp/pkg/broker/handler/testing"
@@ -321,6 +324,187 @@ func TestRetrySyncPoolE2E(t *testing.T) {
 	})
 }
 
+func TestRetryBackpressure(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	rs := pubsub.DefaultReceiveSettings
+	rs.MaxOutstandingMessages = 4
+	// Adjust the limits on every sync.
+	syncPool, err := InitializeTestRetryPool(helper.Targets, retryPod, retryContainer, helper.PubsubClient,
+		WithPubsubReceiveSettings(rs),
+		WithBackpressure(&backpressure.Settings{
+			MinRequests:   1,
+			FailureRatio:  0.5,
+			PauseDuration: time.Hour,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	b := helper.GenerateBroker(ctx, t, "ns")
+	target := helper.GenerateTarget(ctx, t, b.Key(), nil)
+
+	if err := syncPool.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing the pool: %v", err)
+	}
+	hc, ok := syncPool.pool.Load(*target.Key())
+	if !ok {
+		t.Fatal("no handler for the target")
+	}
+	for _, want := range []int{4, 2, 1, 0} {
+		if got := hc.Limit(); got != want {
+			t.Errorf("handler limit got=%d, want=%d", got, want)
+		}
+		syncPool.backpressure.Record(target.Key(), false, time.Second)
+		if err := syncPool.SyncOnce(ctx); err != nil {
+			t.Fatalf("unexpected error from syncing the pool: %v", err)
+		}
+		// The limit is applied by the handler, which is not renewed when it changes.
+		if got, _ := syncPool.pool.Load(*target.Key()); got != hc {
+			t.Error("handler was renewed on a backpressure change")
+		}
+	}
+	syncPool.Drain()
+}
+
+func TestRetryReplay(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	syncPool, err := InitializeTestRetryPool(helper.Targets, retryPod, retryContainer, helper.PubsubClient)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	defer syncPool.Drain()
+	b := helper.GenerateBroker(ctx, t, "ns")
+	target := helper.GenerateTarget(ctx, t, b.Key(), nil)
+
+	replayQueue := &config.Queue{Topic: b.DecoupleQueue.Topic, Subscription: "replay-sub"}
+	if _, err := helper.PubsubClient.CreateSubscription(ctx, replayQueue.Subscription, pubsub.SubscriptionConfig{
+		Topic: helper.PubsubClient.Topic(replayQueue.Topic),
+	}); err != nil {
+		t.Fatalf("failed to create replay subscription: %v", err)
+	}
+	setReplay := func(replay *config.Replay) {
+		// The handlers read the stored target concurrently: update a copy.
+		updated := proto.Clone(target).(*config.Target)
+		updated.Replay = replay
+		helper.Targets.MutateCellTenant(b.Key(), func(m config.CellTenantMutation) {
+			m.UpsertTargets(updated)
+		})
+		if err := syncPool.SyncOnce(ctx); err != nil {
+			t.Fatalf("unexpected error from syncing the pool: %v", err)
+		}
+	}
+
+	t.Run("events published before the replay started are replayed", func(t *testing.T) {
+		e := genTestEvent("foo1", "bar1", "id1", "source1")
+		helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e)
+		setReplay(&config.Replay{
+			Queue:       replayQueue,
+			UntilMillis: time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond),
+		})
+		if _, ok := syncPool.replays.Load(*target.Key()); !ok {
+			t.Fatal("no replay handler for the target")
+		}
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		helper.VerifyNextTargetEvent(ctx, t, target.Key(), &e)
+	})
+
+	t.Run("events published after the replay started are not replayed", func(t *testing.T) {
+		setReplay(&config.Replay{
+			Queue:       replayQueue,
+			UntilMillis: time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond),
+		})
+		e := genTestEvent("foo2", "bar2", "id2", "source2")
+		helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e)
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		helper.VerifyNextTargetEvent(ctx, t, target.Key(), nil)
+	})
+
+	t.Run("replay handler is stopped with the replay", func(t *testing.T) {
+		setReplay(nil)
+		if _, ok := syncPool.replays.Load(*target.Key()); ok {
+			t.Error("replay handler still exists after the replay stopped")
+		}
+		if _, ok := syncPool.pool.Load(*target.Key()); !ok {
+			t.Error("retry handler was stopped with the replay")
+		}
+	})
+}
+
+func TestRetrySuspend(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	syncPool, err := InitializeTestRetryPool(helper.Targets, retryPod, retryContainer, helper.PubsubClient)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	defer syncPool.Drain()
+	b := helper.GenerateBroker(ctx, t, "ns")
+	target := helper.GenerateTarget(ctx, t, b.Key(), nil)
+	setState := func(state config.State) {
+		// The handlers read the stored target concurrently: update a copy.
+		updated := proto.Clone(target).(*config.Target)
+		updated.State = state
+		helper.Targets.MutateCellTenant(b.Key(), func(m config.CellTenantMutation) {
+			m.UpsertTargets(updated)
+		})
+		if err := syncPool.SyncOnce(ctx); err != nil {
+			t.Fatalf("unexpected error from syncing the pool: %v", err)
+		}
+	}
+
+	setState(config.State_READY)
+	if _, ok := syncPool.pool.Load(*target.Key()); !ok {
+		t.Fatal("no retry handler for the ready target")
+	}
+
+	e := genTestEvent("foo", "bar", "id", "source")
+	t.Run("suspended target doesn't receive events", func(t *testing.T) {
+		setState(config.State_SUSPENDED)
+		if _, ok := syncPool.pool.Load(*target.Key()); ok {
+			t.Error("retry handler still exists for the suspended target")
+		}
+		helper.SendEventToRetryQueue(ctx, t, target.Key(), &e)
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		helper.VerifyNextTargetEvent(ctx, t, target.Key(), nil)
+	})
+
+	t.Run("resumed target receives the held events", func(t *testing.T) {
+		setState(config.State_READY)
+		if _, ok := syncPool.pool.Load(*target.Key()); !ok {
+			t.Error("no retry handler for the resumed target")
+		}
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		helper.VerifyNextTargetEvent(ctx, t, target.Key(), &e)
+	})
+}
+
 func assertRetryHandlers(t *testing.T, p *RetryPool, targets config.Targets) {
 	t.Helper()
 	gotHandlers := make(map[config.TargetKey]bool)
@@ -341,6 +525,19 @@ func assertRetryHandlers(t *testing.T, p *RetryPool, targets config.Targets) {
 	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
 		t.Errorf("handlers map (-want,+got): %v", diff)
 	}
+
+	// The introspected handlers match the pool.
+	wantInfo := make(map[string]bool, len(wantHandlers))
+	for key := range wantHandlers {
+		wantInfo[key.String()] = true
+	}
+	gotInfo := make(map[string]bool)
+	for _, h := range p.Handlers() {
+		gotInfo[h.Key] = h.Alive
+	}
+	if diff := cmp.Diff(wantInfo, gotInfo); diff != "" {
+		t.Errorf("introspected handlers (-want,+got): %v", diff)
+	}
 }
 
 func genTestEvent(subject, t, id, source string) event.Event {
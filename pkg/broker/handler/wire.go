@@ -38,6 +38,8 @@ func InitializeTestFanoutPool(
 	panic(wire.Build(
 		NewFanoutPool,
 		NewRetryClient,
+		NewClaimCheckReader,
+		wire.Value(ClaimCheckBucket("")),
 		metrics.NewDeliveryReporter,
 		wire.Value(DefaultHTTPClient),
 		wire.Value(DefaultCEClientOpts),
@@ -53,6 +55,8 @@ func InitializeTestRetryPool(
 ) (*RetryPool, error) {
 	panic(wire.Build(
 		NewRetryPool,
+		NewClaimCheckReader,
+		wire.Value(ClaimCheckBucket("")),
 		metrics.NewDeliveryReporter,
 		wire.Value(DefaultHTTPClient),
 	))
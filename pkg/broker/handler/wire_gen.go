@@ -25,7 +25,9 @@ func InitializeTestFanoutPool(ctx context.Context, podName metrics.PodName, cont
 	if err != nil {
 		return nil, err
 	}
-	fanoutPool, err := NewFanoutPool(targets, pubsubClient, client, retryClient, deliveryReporter, opts...)
+	claimCheckBucket := _wireClaimCheckBucketValue
+	reader := NewClaimCheckReader(claimCheckBucket)
+	fanoutPool, err := NewFanoutPool(targets, pubsubClient, client, retryClient, deliveryReporter, reader, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +35,9 @@ func InitializeTestFanoutPool(ctx context.Context, podName metrics.PodName, cont
 }
 
 var (
-	_wireClientValue = DefaultHTTPClient
-	_wireValue       = DefaultCEClientOpts
+	_wireClientValue           = DefaultHTTPClient
+	_wireValue                 = DefaultCEClientOpts
+	_wireClaimCheckBucketValue = ClaimCheckBucket("")
 )
 
 func InitializeTestRetryPool(targets config.ReadonlyTargets, podName metrics.PodName, containerName metrics.ContainerName, pubsubClient *pubsub.Client, opts ...Option) (*RetryPool, error) {
@@ -43,7 +46,9 @@ func InitializeTestRetryPool(targets config.ReadonlyTargets, podName metrics.Pod
 	if err != nil {
 		return nil, err
 	}
-	retryPool, err := NewRetryPool(targets, pubsubClient, client, deliveryReporter, opts...)
+	claimCheckBucket := _wireHandlerClaimCheckBucketValue
+	reader := NewClaimCheckReader(claimCheckBucket)
+	retryPool, err := NewRetryPool(targets, pubsubClient, client, deliveryReporter, reader, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -51,5 +56,6 @@ func InitializeTestRetryPool(targets config.ReadonlyTargets, podName metrics.Pod
 }
 
 var (
-	_wireHttpClientValue = DefaultHTTPClient
+	_wireHttpClientValue              = DefaultHTTPClient
+	_wireHandlerClaimCheckBucketValue = ClaimCheckBucket("")
 )
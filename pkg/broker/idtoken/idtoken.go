@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package idtoken mints the OpenID Connect ID tokens sent to the subscribers that require
+// authentication, such as authenticated Cloud Run services or IAP-protected endpoints.
+package idtoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2"
+	gidtoken "google.golang.org/api/idtoken"
+)
+
+// SourceFunc creates a token source of ID tokens for the audience.
+type SourceFunc func(ctx context.Context, audience string) (oauth2.TokenSource, error)
+
+// Google creates a token source of Google-signed ID tokens for the identity of the pod: the
+// service account key from GOOGLE_APPLICATION_CREDENTIALS if set, or the service account of the
+// GKE workload from the metadata server otherwise.
+func Google(ctx context.Context, audience string) (oauth2.TokenSource, error) {
+	return gidtoken.NewTokenSource(ctx, audience)
+}
+
+// Minter mints ID tokens for any audience. Tokens are cached until they expire.
+type Minter struct {
+	// ctx is used by the token sources to fetch tokens, so it must outlive the requests.
+	ctx       context.Context
+	newSource SourceFunc
+	// sources is a map from the audience to its oauth2.TokenSource.
+	sources sync.Map
+}
+
+// NewMinter creates a Minter getting its tokens from the sources created by newSource. The context
+// is used to fetch the tokens for as long as the minter is used.
+func NewMinter(ctx context.Context, newSource SourceFunc) *Minter {
+	return &Minter{ctx: ctx, newSource: newSource}
+}
+
+// Token returns an ID token for the audience.
+func (m *Minter) Token(audience string) (string, error) {
+	if audience == "" {
+		return "", errors.New("ID token audience must not be empty")
+	}
+	ts, err := m.sourceFor(audience)
+	if err != nil {
+		return "", err
+	}
+	t, err := ts.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get ID token for audience %q: %w", audience, err)
+	}
+	return t.AccessToken, nil
+}
+
+func (m *Minter) sourceFor(audience string) (oauth2.TokenSource, error) {
+	if ts, ok := m.sources.Load(audience); ok {
+		return ts.(oauth2.TokenSource), nil
+	}
+	src, err := m.newSource(m.ctx, audience)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ID token source for audience %q: %w", audience, err)
+	}
+	ts, _ := m.sources.LoadOrStore(audience, oauth2.ReuseTokenSource(nil, src))
+	return ts.(oauth2.TokenSource), nil
+}
+
+// Prune forgets the token sources, and their cached tokens, of the audiences that are not in use.
+func (m *Minter) Prune(inUse map[string]bool) {
+	m.sources.Range(func(audience, _ interface{}) bool {
+		if !inUse[audience.(string)] {
+			m.sources.Delete(audience)
+		}
+		return true
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idtoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeSource mints tokens with the given lifetime and counts them.
+type fakeSource struct {
+	audience string
+	lifetime time.Duration
+	minted   int
+}
+
+func (s *fakeSource) Token() (*oauth2.Token, error) {
+	s.minted++
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("%s-%d", s.audience, s.minted),
+		Expiry:      time.Now().Add(s.lifetime),
+	}, nil
+}
+
+func TestMinterCachesTokens(t *testing.T) {
+	sources := make(map[string]*fakeSource)
+	m := NewMinter(context.Background(), func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+		s := &fakeSource{audience: audience, lifetime: time.Hour}
+		sources[audience] = s
+		return s, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		got, err := m.Token("https://a.example.com")
+		if err != nil {
+			t.Fatalf("Token() got unexpected error: %v", err)
+		}
+		if want := "https://a.example.com-1"; got != want {
+			t.Errorf("Token() got=%q, want=%q", got, want)
+		}
+	}
+	got, err := m.Token("https://b.example.com")
+	if err != nil {
+		t.Fatalf("Token() got unexpected error: %v", err)
+	}
+	if want := "https://b.example.com-1"; got != want {
+		t.Errorf("Token() got=%q, want=%q", got, want)
+	}
+	if len(sources) != 2 {
+		t.Errorf("token sources created got=%d, want=2", len(sources))
+	}
+}
+
+func TestMinterRefreshesExpiredTokens(t *testing.T) {
+	m := NewMinter(context.Background(), func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+		// Tokens expiring within the expiry delta of oauth2 are refreshed right away.
+		return &fakeSource{audience: audience, lifetime: time.Second}, nil
+	})
+	first, err := m.Token("aud")
+	if err != nil {
+		t.Fatalf("Token() got unexpected error: %v", err)
+	}
+	second, err := m.Token("aud")
+	if err != nil {
+		t.Fatalf("Token() got unexpected error: %v", err)
+	}
+	if first == second {
+		t.Errorf("Token() got the expired token %q again", second)
+	}
+}
+
+func TestMinterErrors(t *testing.T) {
+	m := NewMinter(context.Background(), func(context.Context, string) (oauth2.TokenSource, error) {
+		return nil, errors.New("no credentials")
+	})
+	if _, err := m.Token(""); err == nil {
+		t.Error("Token() with an empty audience got no error")
+	}
+	if _, err := m.Token("aud"); err == nil {
+		t.Error("Token() without a token source got no error")
+	}
+}
+
+func TestMinterPrune(t *testing.T) {
+	created := 0
+	m := NewMinter(context.Background(), func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+		created++
+		return &fakeSource{audience: audience, lifetime: time.Hour}, nil
+	})
+	for _, audience := range []string{"kept", "unused"} {
+		if _, err := m.Token(audience); err != nil {
+			t.Fatalf("Token() got unexpected error: %v", err)
+		}
+	}
+
+	m.Prune(map[string]bool{"kept": true})
+	var got []string
+	m.sources.Range(func(audience, _ interface{}) bool {
+		got = append(got, audience.(string))
+		return true
+	})
+	if len(got) != 1 || got[0] != "kept" {
+		t.Errorf("token sources after Prune got=%v, want [kept]", got)
+	}
+	if _, err := m.Token("kept"); err != nil {
+		t.Fatalf("Token() got unexpected error: %v", err)
+	}
+	if created != 2 {
+		t.Errorf("token sources created got=%d, want=2", created)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/utils/jwt"
+)
+
+// keySetFetchTimeout is the timeout of the requests fetching the keys of the trusted issuers.
+const keySetFetchTimeout = 10 * time.Second
+
+var (
+	// ErrUnauthenticated is the error when a request to a broker that requires authentication has
+	// a missing or invalid token.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is the error when a request is authenticated but its token's audience or subject
+	// is not allowed by the broker.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// TrustedIssuers maps the issuers whose tokens are accepted by the ingress to their verifiers.
+type TrustedIssuers map[string]jwt.Issuer
+
+// ParseTrustedIssuers parses a comma-separated list of trusted issuers. Each item is either an
+// issuer, whose key set is discovered through OpenID Connect Discovery, or "issuer=jwksURL". Google
+// is trusted if the list is empty, so that Google-signed ID tokens are accepted by default.
+func ParseTrustedIssuers(s string) (TrustedIssuers, error) {
+	client := &nethttp.Client{Timeout: keySetFetchTimeout}
+	issuers := make(TrustedIssuers)
+	if strings.TrimSpace(s) == "" {
+		s = strings.Join(jwt.GoogleIssuers, ",")
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		issuer, url := item, ""
+		if i := strings.Index(item, "="); i >= 0 {
+			issuer, url = item[:i], item[i+1:]
+			if issuer == "" || url == "" {
+				return nil, fmt.Errorf("invalid trusted issuer %q", item)
+			}
+		}
+		switch {
+		case url != "":
+			issuers[issuer] = jwt.NewRemoteIssuer(issuer, url, client)
+		case isGoogleIssuer(issuer):
+			google, err := jwt.NewGoogleIssuer(client)
+			if err != nil {
+				return nil, err
+			}
+			issuers[issuer] = google
+		default:
+			issuers[issuer] = jwt.NewDiscoveredIssuer(issuer, client)
+		}
+	}
+	return issuers, nil
+}
+
+func isGoogleIssuer(issuer string) bool {
+	for _, google := range jwt.GoogleIssuers {
+		if issuer == google {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates the requests to the brokers that require it. The audiences and
+// subjects allowed by each broker come from the broker config.
+type Authenticator struct {
+	brokerConfig config.ReadonlyTargets
+	verifier     *jwt.Verifier
+}
+
+// NewAuthenticator creates a new Authenticator that accepts the tokens of the trusted issuers.
+func NewAuthenticator(brokerConfig config.ReadonlyTargets, issuers TrustedIssuers) *Authenticator {
+	return &Authenticator{
+		brokerConfig: brokerConfig,
+		verifier:     jwt.NewVerifier(issuers),
+	}
+}
+
+// Authenticate authenticates the request to the broker. It returns nil claims and no error if the
+// broker doesn't require authentication. Otherwise, the error wraps ErrUnauthenticated or
+// ErrForbidden if the request is not allowed.
+func (a *Authenticator) Authenticate(ctx context.Context, broker *config.CellTenantKey, request *nethttp.Request) (*jwt.Claims, error) {
+	b, ok := a.brokerConfig.GetCellTenantByKey(broker)
+	if !ok || b.IngressAuth == nil || len(b.IngressAuth.Audiences) == 0 {
+		return nil, nil
+	}
+	token := bearerToken(request)
+	if token == "" {
+		return nil, fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if !containsAny(claims.Audience, b.IngressAuth.Audiences) {
+		return claims, fmt.Errorf("%w: audience %v is not allowed", ErrForbidden, claims.Audience)
+	}
+	if len(b.IngressAuth.Subjects) > 0 && !containsAny(b.IngressAuth.Subjects, []string{claims.Subject, claims.Email}) {
+		return claims, fmt.Errorf("%w: subject %q is not allowed", ErrForbidden, claims.Subject)
+	}
+	return claims, nil
+}
+
+// bearerToken returns the bearer token of the request's Authorization header.
+func bearerToken(request *nethttp.Request) string {
+	const prefix = "bearer "
+	h := request.Header.Get("Authorization")
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(h[len(prefix):])
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if w != "" && v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authenticate authenticates the request to the broker. If the request is not allowed, it responds
+// with 401 or 403 and returns false. Every decision for a broker that requires authentication is
+// logged for auditing.
+func (h *Handler) authenticate(ctx context.Context, response nethttp.ResponseWriter, request *nethttp.Request, broker *config.CellTenantKey) bool {
+	if h.authenticator == nil {
+		return true
+	}
+	claims, err := h.authenticator.Authenticate(ctx, broker, request)
+	if claims == nil && err == nil {
+		return true
+	}
+	fields := []zap.Field{zap.String("remoteAddr", request.RemoteAddr)}
+	if claims != nil {
+		fields = append(fields,
+			zap.String("issuer", claims.Issuer),
+			zap.String("subject", claims.Subject),
+			zap.Strings("audience", claims.Audience))
+		if claims.Email != "" {
+			fields = append(fields, zap.String("email", claims.Email))
+		}
+	}
+	logger := logging.FromContext(ctx).Named("audit")
+	if err == nil {
+		logger.Info("Request authenticated", append(fields, zap.String("decision", "allow"))...)
+		return true
+	}
+	logger.Info("Request denied", append(fields, zap.String("decision", "deny"), zap.Error(err))...)
+	if errors.Is(err, ErrForbidden) {
+		nethttp.Error(response, "Forbidden", nethttp.StatusForbidden)
+		return false
+	}
+	response.Header().Set("WWW-Authenticate", "Bearer")
+	nethttp.Error(response, "Unauthorized", nethttp.StatusUnauthorized)
+	return false
+}
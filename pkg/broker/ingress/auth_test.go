@@ -0,0 +1,198 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"knative.dev/pkg/logging"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+	jwttesting "github.com/google/knative-gcp/pkg/utils/jwt/testing"
+)
+
+func authTargets(auth *config.IngressAuth) config.Targets {
+	return memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"ns1/broker1": {
+				Type:        config.CellTenantType_BROKER,
+				Name:        "broker1",
+				Namespace:   "ns1",
+				IngressAuth: auth,
+			},
+		},
+	})
+}
+
+func TestHandlerAuthentication(t *testing.T) {
+	iss := jwttesting.NewIssuer(t)
+	untrusted := jwttesting.NewIssuer(t)
+	validClaims := func(aud, sub string) jwttesting.Claims {
+		return jwttesting.Claims{
+			Subject:  sub,
+			Audience: []string{aud},
+			Expiry:   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		auth          *config.IngressAuth
+		authorization func() string
+		wantCode      int
+	}{{
+		name:     "no auth required",
+		wantCode: nethttp.StatusAccepted,
+	}, {
+		name:          "no auth required ignores the token",
+		authorization: func() string { return "Bearer garbage" },
+		wantCode:      nethttp.StatusAccepted,
+	}, {
+		name:     "missing token",
+		auth:     &config.IngressAuth{Audiences: []string{"broker1"}},
+		wantCode: nethttp.StatusUnauthorized,
+	}, {
+		name:          "not a bearer token",
+		auth:          &config.IngressAuth{Audiences: []string{"broker1"}},
+		authorization: func() string { return "Basic dXNlcjpwYXNz" },
+		wantCode:      nethttp.StatusUnauthorized,
+	}, {
+		name: "invalid token",
+		auth: &config.IngressAuth{Audiences: []string{"broker1"}},
+		authorization: func() string {
+			c := validClaims("broker1", "sa")
+			c.Issuer = iss.URL
+			return "Bearer " + untrusted.Sign(t, c)
+		},
+		wantCode: nethttp.StatusUnauthorized,
+	}, {
+		name: "valid token",
+		auth: &config.IngressAuth{Audiences: []string{"other", "broker1"}},
+		authorization: func() string {
+			return "Bearer " + iss.Sign(t, validClaims("broker1", "sa"))
+		},
+		wantCode: nethttp.StatusAccepted,
+	}, {
+		name: "audience not allowed",
+		auth: &config.IngressAuth{Audiences: []string{"broker1"}},
+		authorization: func() string {
+			return "Bearer " + iss.Sign(t, validClaims("broker2", "sa"))
+		},
+		wantCode: nethttp.StatusForbidden,
+	}, {
+		name: "subject allowed",
+		auth: &config.IngressAuth{Audiences: []string{"broker1"}, Subjects: []string{"system:serviceaccount:ns1:sa"}},
+		authorization: func() string {
+			return "bearer " + iss.Sign(t, validClaims("broker1", "system:serviceaccount:ns1:sa"))
+		},
+		wantCode: nethttp.StatusAccepted,
+	}, {
+		name: "email of a token not signed by Google is not a subject",
+		auth: &config.IngressAuth{Audiences: []string{"broker1"}, Subjects: []string{"sa@project.iam.gserviceaccount.com"}},
+		authorization: func() string {
+			c := validClaims("broker1", "1234567890")
+			c.Email = "sa@project.iam.gserviceaccount.com"
+			return "Bearer " + iss.Sign(t, c)
+		},
+		wantCode: nethttp.StatusForbidden,
+	}, {
+		name: "subject not allowed",
+		auth: &config.IngressAuth{Audiences: []string{"broker1"}, Subjects: []string{"system:serviceaccount:ns1:sa"}},
+		authorization: func() string {
+			return "Bearer " + iss.Sign(t, validClaims("broker1", "system:serviceaccount:ns1:other"))
+		},
+		wantCode: nethttp.StatusForbidden,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logging.WithLogger(context.Background(), logtest.TestLogger(t))
+			reporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			authenticator := NewAuthenticator(authTargets(tc.auth), TrustedIssuers{iss.URL: iss.Remote()})
+			h := NewHandler(ctx, nil, &fakeAcceptingDecoupleSink{}, nil, authenticator, nil, reporter, "")
+
+			req := httptest.NewRequest("POST", "/ns1/broker1", nil)
+			message := binding.ToMessage(createTestEvent("test-event"))
+			defer message.Finish(nil)
+			http.WriteRequest(ctx, message, req)
+			if tc.authorization != nil {
+				req.Header.Set("Authorization", tc.authorization())
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			if res.StatusCode != tc.wantCode {
+				t.Errorf("StatusCode mismatch. got: %v, want: %v", res.StatusCode, tc.wantCode)
+			}
+			if tc.wantCode == nethttp.StatusUnauthorized && res.Header.Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func TestParseTrustedIssuers(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{{
+		name: "defaults to Google",
+		want: []string{"https://accounts.google.com", "accounts.google.com"},
+	}, {
+		name: "discovered and explicit key sets",
+		in:   "https://kubernetes.default.svc, https://issuer.example.com=https://issuer.example.com/keys",
+		want: []string{"https://kubernetes.default.svc", "https://issuer.example.com"},
+	}, {
+		name:    "missing key set URL",
+		in:      "https://issuer.example.com=",
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseTrustedIssuers(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseTrustedIssuers() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Errorf("ParseTrustedIssuers() = %v, want issuers %v", got, tc.want)
+			}
+			for _, issuer := range tc.want {
+				if _, ok := got[issuer]; !ok {
+					t.Errorf("ParseTrustedIssuers() is missing issuer %q", issuer)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	nethttp "net/http"
+	"sync"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/binding/transformer"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	kntracing "knative.dev/eventing/pkg/tracing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/tracing"
+)
+
+// maxBatchPublishConcurrency is the maximum number of events of a batched request that are published
+// at the same time.
+const maxBatchPublishConcurrency = 100
+
+// BatchEventResult is the result of publishing a single event of a batched request.
+type BatchEventResult struct {
+	// ID is the ID of the event.
+	ID string `json:"id"`
+	// Code is the HTTP status code the event would have received if it was sent individually.
+	Code int `json:"code"`
+	// Error is the error message if the event was not accepted.
+	Error string `json:"error,omitempty"`
+}
+
+// isBatchRequest returns true if the request carries events in the CloudEvents batched content mode.
+func isBatchRequest(request *nethttp.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	return err == nil && mediaType == cev2.ApplicationCloudEventsBatchJSON
+}
+
+// serveBatch publishes every event of a batched request to the decouple sink of the broker. The
+// response contains a BatchEventResult for each event. Its status code is 202 if all events were
+// accepted, 207 if only some of them were, and the status code of the first event otherwise.
+func (h *Handler) serveBatch(ctx context.Context, response nethttp.ResponseWriter, request *nethttp.Request, broker *config.CellTenantKey) {
+	events, err := h.toEvents(ctx, request)
+	if err != nil {
+		httpStatus := invalidEventStatusCode(err)
+		nethttp.Error(response, err.Error(), httpStatus)
+		h.reportMetrics(ctx, "_invalid_cloud_event_", httpStatus)
+		return
+	}
+
+	eventPtrs := make([]*cev2.Event, len(events))
+	for i := range events {
+		eventPtrs[i] = &events[i]
+	}
+	if !h.allow(ctx, response, broker, len(events), requestSize(request, eventPtrs...)) {
+		return
+	}
+
+	span := trace.FromContext(ctx)
+	span.SetName(broker.SpanMessagingDestination())
+	if span.IsRecordingEvents() {
+		span.AddAttributes(
+			kntracing.MessagingSystemAttribute,
+			tracing.PubSubProtocolAttribute,
+			broker.SpanMessagingDestinationAttribute(),
+			trace.Int64Attribute("cloudevents.batch_size", int64(len(events))),
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
+	defer cancel()
+
+	// Publish the events concurrently so that the Pub/Sub client can bundle them, with at most
+	// maxBatchPublishConcurrency goroutines however large the batch is.
+	results := make([]BatchEventResult, len(events))
+	indexes := make(chan int)
+	workers := len(events)
+	if workers > maxBatchPublishConcurrency {
+		workers = maxBatchPublishConcurrency
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				code, errMsg := h.publish(ctx, broker, &events[i])
+				results[i] = BatchEventResult{ID: events[i].ID(), Code: code, Error: errMsg}
+			}
+		}()
+	}
+	for i := range events {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	statusCode := batchStatusCode(results)
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	if err := json.NewEncoder(response).Encode(results); err != nil {
+		logging.FromContext(ctx).Warn("Failed to write batch response", zap.Error(err))
+	}
+}
+
+// toEvents converts a batched http request to events.
+func (h *Handler) toEvents(ctx context.Context, request *nethttp.Request) ([]cev2.Event, error) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var events []cev2.Event
+	if err := json.Unmarshal(body, &events); err != nil {
+		logging.FromContext(ctx).Debug("Failed to unmarshal batched events", zap.Error(err))
+		return nil, fmt.Errorf("malformed batch of events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("empty batch of events")
+	}
+	for i := range events {
+		if err := events[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid event at index %d: %w", i, err)
+		}
+		// Like the events of single requests, the events of a batch get a time if they have none.
+		if _, err := binding.ToEvent(ctx, binding.ToMessage(&events[i]), transformer.AddTimeNow); err != nil {
+			return nil, fmt.Errorf("invalid event at index %d: %w", i, err)
+		}
+	}
+	return events, nil
+}
+
+func batchStatusCode(results []BatchEventResult) int {
+	accepted := 0
+	for _, r := range results {
+		if r.Code == nethttp.StatusAccepted {
+			accepted++
+		}
+	}
+	switch accepted {
+	case len(results):
+		return nethttp.StatusAccepted
+	case 0:
+		return results[0].Code
+	default:
+		return nethttp.StatusMultiStatus
+	}
+}
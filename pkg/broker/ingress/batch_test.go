@@ -0,0 +1,86 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsBatchRequest(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "application/cloudevents-batch+json", want: true},
+		{contentType: "application/cloudevents-batch+json; charset=utf-8", want: true},
+		{contentType: "application/cloudevents+json", want: false},
+		{contentType: "application/json", want: false},
+		{contentType: "", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.contentType, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/ns1/broker1", nil)
+			req.Header.Set("Content-Type", tc.contentType)
+			if got := isBatchRequest(req); got != tc.want {
+				t.Errorf("isBatchRequest() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBatchStatusCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []BatchEventResult
+		want    int
+	}{
+		{
+			name:    "all accepted",
+			results: []BatchEventResult{{Code: nethttp.StatusAccepted}, {Code: nethttp.StatusAccepted}},
+			want:    nethttp.StatusAccepted,
+		},
+		{
+			name:    "some accepted",
+			results: []BatchEventResult{{Code: nethttp.StatusAccepted}, {Code: nethttp.StatusTooManyRequests}},
+			want:    nethttp.StatusMultiStatus,
+		},
+		{
+			name:    "none accepted",
+			results: []BatchEventResult{{Code: nethttp.StatusNotFound}, {Code: nethttp.StatusInternalServerError}},
+			want:    nethttp.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := batchStatusCode(tc.results); got != tc.want {
+				t.Errorf("batchStatusCode() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInvalidEventStatusCode(t *testing.T) {
+	if got, want := invalidEventStatusCode(errors.New("http: request body too large")), nethttp.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("invalidEventStatusCode() = %v, want %v", got, want)
+	}
+	if got, want := invalidEventStatusCode(errors.New("malformed batch of events")), nethttp.StatusBadRequest; got != want {
+		t.Errorf("invalidEventStatusCode() = %v, want %v", got, want)
+	}
+}
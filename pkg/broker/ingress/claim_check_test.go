@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"knative.dev/pkg/logging"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage/testing"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+// capturingDecoupleSink records the events it receives.
+type capturingDecoupleSink struct {
+	events []cev2.Event
+}
+
+func (m *capturingDecoupleSink) Send(_ context.Context, _ *config.CellTenantKey, e cev2.Event) protocol.Result {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func TestHandlerClaimCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		payloadSize    int
+		reference      string
+		writeErr       error
+		wantCode       int
+		wantOffloaded  bool
+		wantSentEvents int
+	}{{
+		name:           "small payload is published as is",
+		payloadSize:    10,
+		wantCode:       nethttp.StatusAccepted,
+		wantSentEvents: 1,
+	}, {
+		name:           "large payload is offloaded",
+		payloadSize:    maxRequestBodyBytes + 1,
+		wantCode:       nethttp.StatusAccepted,
+		wantOffloaded:  true,
+		wantSentEvents: 1,
+	}, {
+		name:           "forged reference is stripped",
+		payloadSize:    10,
+		reference:      "gs://other-bucket/other-ns/other-broker/object",
+		wantCode:       nethttp.StatusAccepted,
+		wantSentEvents: 1,
+	}, {
+		name:        "offload failure",
+		payloadSize: 2000,
+		writeErr:    errors.New("write error"),
+		wantCode:    nethttp.StatusInternalServerError,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logging.WithLogger(context.Background(), logtest.TestLogger(t))
+			reporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err := gstorage.TestClientCreator(gstorage.TestClientData{
+				BucketData: gstorage.TestBucketData{ObjectWriteErr: tc.writeErr},
+			})(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decouple := &capturingDecoupleSink{}
+			h := NewHandler(ctx, nil, decouple, nil, nil, claimcheck.NewStore(client, "bucket", 1000), reporter, "")
+
+			req := httptest.NewRequest("POST", "/ns1/broker1", nil)
+			e := createTestEventWithPayloadSize("test-event", tc.payloadSize)
+			if tc.reference != "" {
+				e.SetExtension(claimcheck.ReferenceExtension, tc.reference)
+			}
+			message := binding.ToMessage(e)
+			defer message.Finish(nil)
+			http.WriteRequest(ctx, message, req)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if res := rec.Result(); res.StatusCode != tc.wantCode {
+				t.Errorf("StatusCode mismatch. got: %v, want: %v", res.StatusCode, tc.wantCode)
+			}
+			if len(decouple.events) != tc.wantSentEvents {
+				t.Fatalf("Sent %d events, want %d", len(decouple.events), tc.wantSentEvents)
+			}
+			for _, e := range decouple.events {
+				if got := claimcheck.HasReference(&e); got != tc.wantOffloaded {
+					t.Errorf("HasReference() = %v, want %v", got, tc.wantOffloaded)
+				}
+			}
+		})
+	}
+}
@@ -19,10 +19,14 @@ package ingress
 import (
 	"context"
 	"errors"
+	"math"
 	nethttp "net/http"
+	"strconv"
 	"time"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 
 	cev2 "github.com/cloudevents/sdk-go/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
@@ -54,11 +58,14 @@ const (
 	// Limit for request payload in bytes (10Mb -- corresponds to message size limit on PubSub as of 09/2020)
 	maxRequestBodyBytes = 10000000
 
+	// Limit for request payload in bytes when large payloads are offloaded to GCS.
+	maxClaimCheckRequestBodyBytes = 100000000
+
 	// EventArrivalTime is used to access the metadata stored on a
 	// CloudEvent to measure the time difference between when an event is
 	// received on a broker and before it is dispatched to the trigger function.
 	// The format is an RFC3339 time in string format. For example: 2019-08-26T23:38:17.834384404Z.
-	EventArrivalTime = "knativearrivaltime"
+	EventArrivalTime = eventutil.ArrivalTimeAttribute
 
 	// for permission denied error msg
 	// TODO(cathyzhyi) point to official doc rather than github doc
@@ -73,6 +80,8 @@ var HandlerSet wire.ProviderSet = wire.NewSet(
 	wire.Bind(new(HttpMessageReceiver), new(*kncloudevents.HTTPMessageReceiver)),
 	NewMultiTopicDecoupleSink,
 	wire.Bind(new(DecoupleSink), new(*multiTopicDecoupleSink)),
+	NewRateLimiter,
+	NewAuthenticator,
 	clients.NewPubsubClient,
 	metrics.NewIngressReporter,
 )
@@ -94,19 +103,28 @@ type Handler struct {
 	httpReceiver HttpMessageReceiver
 	// decouple is the client to send events to a decouple sink.
 	decouple DecoupleSink
-	logger   *zap.Logger
-	reporter *metrics.IngressReporter
-	authType authcheck.AuthType
+	// limiter enforces the per broker rate limits.
+	limiter *RateLimiter
+	// authenticator authenticates the requests to the brokers that require it.
+	authenticator *Authenticator
+	// claimCheck offloads large event payloads to GCS. Payloads are not offloaded if nil.
+	claimCheck *claimcheck.Store
+	logger     *zap.Logger
+	reporter   *metrics.IngressReporter
+	authType   authcheck.AuthType
 }
 
 // NewHandler creates a new ingress handler.
-func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, reporter *metrics.IngressReporter, authType authcheck.AuthType) *Handler {
+func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, limiter *RateLimiter, authenticator *Authenticator, claimCheck *claimcheck.Store, reporter *metrics.IngressReporter, authType authcheck.AuthType) *Handler {
 	return &Handler{
-		httpReceiver: httpReceiver,
-		decouple:     decouple,
-		reporter:     reporter,
-		logger:       logging.FromContext(ctx),
-		authType:     authType,
+		httpReceiver:  httpReceiver,
+		decouple:      decouple,
+		limiter:       limiter,
+		authenticator: authenticator,
+		claimCheck:    claimCheck,
+		reporter:      reporter,
+		logger:        logging.FromContext(ctx),
+		authType:      authType,
 	}
 }
 
@@ -118,8 +136,10 @@ func (h *Handler) Start(ctx context.Context) error {
 // ServeHTTP implements net/http Handler interface method.
 // 1. Performs basic validation of the request.
 // 2. Parse request URL to get namespace and broker.
-// 3. Convert request to event.
-// 4. Send event to decouple sink.
+// 3. Authenticate the request if the broker requires it.
+// 4. Convert request to event, or to events if the request is in batched mode.
+// 5. Check the rate limit of the broker.
+// 6. Send event(s) to decouple sink.
 func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Request) {
 	ctx := request.Context()
 	ctx = logging.WithLogger(ctx, h.logger)
@@ -130,11 +150,15 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 		return
 	}
 
-	if request.ContentLength > maxRequestBodyBytes {
+	maxBodyBytes := int64(maxRequestBodyBytes)
+	if h.claimCheck != nil {
+		maxBodyBytes = maxClaimCheckRequestBodyBytes
+	}
+	if request.ContentLength > maxBodyBytes {
 		response.WriteHeader(nethttp.StatusRequestEntityTooLarge)
 		return
 	}
-	request.Body = nethttp.MaxBytesReader(nil, request.Body, maxRequestBodyBytes)
+	request.Body = nethttp.MaxBytesReader(nil, request.Body, maxBodyBytes)
 
 	broker, err := config.CellTenantKeyFromPersistenceString(request.URL.Path)
 	if err != nil {
@@ -145,18 +169,26 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 	ctx = logging.With(ctx, zap.Stringer("broker", broker))
 	ctx = metricskey.WithResource(ctx, broker.MetricsResource())
 
+	if !h.authenticate(ctx, response, request, broker) {
+		return
+	}
+
+	if isBatchRequest(request) {
+		h.serveBatch(ctx, response, request, broker)
+		return
+	}
+
 	event, err := h.toEvent(ctx, request)
 	if err != nil {
-		httpStatus := nethttp.StatusBadRequest
-		if err.Error() == "http: request body too large" {
-			httpStatus = nethttp.StatusRequestEntityTooLarge
-		}
+		httpStatus := invalidEventStatusCode(err)
 		nethttp.Error(response, err.Error(), httpStatus)
 		h.reportMetrics(ctx, "_invalid_cloud_event_", httpStatus)
 		return
 	}
 
-	event.SetExtension(EventArrivalTime, cev2.Timestamp{Time: time.Now()})
+	if !h.allow(ctx, response, broker, 1, requestSize(request, event)) {
+		return
+	}
 
 	span := trace.FromContext(ctx)
 	span.SetName(broker.SpanMessagingDestination())
@@ -172,13 +204,37 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 		)
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
+	defer cancel()
+	statusCode, errMsg := h.publish(ctx, broker, event)
+	if errMsg != "" {
+		nethttp.Error(response, errMsg, statusCode)
+		return
+	}
+	response.WriteHeader(statusCode)
+}
+
+// publish stamps the event with its arrival time and sends it to the decouple sink of the broker.
+// It returns the HTTP status code for the event and, if the event was not accepted, the error
+// message to return to the client. Metrics are reported for the event.
+func (h *Handler) publish(ctx context.Context, broker *config.CellTenantKey, event *cev2.Event) (int, string) {
+	event.SetExtension(EventArrivalTime, cev2.Timestamp{Time: time.Now()})
+
 	// Optimistically set status code to StatusAccepted. It will be updated if there is an error.
 	// According to the data plane spec (https://github.com/knative/eventing/blob/master/docs/spec/data-plane.md), a
 	// non-callable SINK (which broker is) MUST respond with 202 Accepted if the request is accepted.
 	statusCode := nethttp.StatusAccepted
-	ctx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
-	defer cancel()
 	defer func() { h.reportMetrics(ctx, event.Type(), statusCode) }()
+	// Only the claim check store sets claim check references: a reference set by the sender would
+	// make the data plane read any object it has access to.
+	claimcheck.StripReference(event)
+	if h.claimCheck != nil {
+		if err := h.claimCheck.Offload(ctx, broker, event); err != nil {
+			logging.FromContext(ctx).Error("Error offloading event payload", zap.Error(err))
+			statusCode = nethttp.StatusInternalServerError
+			return statusCode, "Failed to store event payload"
+		}
+	}
 	if res := h.decouple.Send(ctx, broker, *event); !cev2.IsACK(res) {
 		logging.FromContext(ctx).Error("Error publishing to PubSub", zap.Error(res))
 		statusCode = nethttp.StatusInternalServerError
@@ -191,14 +247,49 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 		case errors.Is(res, bundler.ErrOverflow):
 			statusCode = nethttp.StatusTooManyRequests
 		case grpcstatus.Code(res) == grpccode.PermissionDenied:
-			nethttp.Error(response, deniedErrMsg, statusCode)
-			return
+			return statusCode, deniedErrMsg
 		}
-		nethttp.Error(response, "Failed to publish to PubSub", statusCode)
-		return
+		return statusCode, "Failed to publish to PubSub"
 	}
+	return statusCode, ""
+}
 
-	response.WriteHeader(statusCode)
+// allow checks the rate limit of the broker for the given number of events and bytes. If the
+// request is over the limit, it responds with 429 and a Retry-After header and returns false.
+func (h *Handler) allow(ctx context.Context, response nethttp.ResponseWriter, broker *config.CellTenantKey, events, bytes int) bool {
+	if h.limiter == nil {
+		return true
+	}
+	ok, retryAfter := h.limiter.Allow(broker, events, bytes)
+	if ok {
+		return true
+	}
+	logging.FromContext(ctx).Debug("Rate limit exceeded", zap.Duration("retryAfter", retryAfter))
+	trace.FromContext(ctx).Annotate(nil, "rate limit exceeded")
+	args := metrics.IngressRejectArgs{
+		Namespace: broker.Namespace(),
+		Broker:    broker.Name(),
+		Count:     int64(events),
+	}
+	if err := h.reporter.ReportRejectedEventCount(ctx, args); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record metrics.", zap.Error(err))
+	}
+	response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	nethttp.Error(response, "Rate limit exceeded", nethttp.StatusTooManyRequests)
+	return false
+}
+
+// requestSize returns the size of the request body, or of the event data if the content length of
+// the request is unknown.
+func requestSize(request *nethttp.Request, events ...*cev2.Event) int {
+	if request.ContentLength >= 0 {
+		return int(request.ContentLength)
+	}
+	size := 0
+	for _, e := range events {
+		size += len(e.Data())
+	}
+	return size
 }
 
 // toEvent converts an http request to an event.
@@ -222,6 +313,15 @@ func (h *Handler) toEvent(ctx context.Context, request *nethttp.Request) (*cev2.
 	return event, nil
 }
 
+// invalidEventStatusCode returns the status code of a request whose events can't be read.
+func invalidEventStatusCode(err error) int {
+	// http.MaxBytesReader doesn't export the error it returns once the limit is reached.
+	if err.Error() == "http: request body too large" {
+		return nethttp.StatusRequestEntityTooLarge
+	}
+	return nethttp.StatusBadRequest
+}
+
 func (h *Handler) reportMetrics(ctx context.Context, eventType string, statusCode int) {
 	args := metrics.IngressReportArgs{
 		EventType:    eventType,
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	nethttp "net/http"
 	"net/http/httptest"
 	"testing"
@@ -38,6 +39,7 @@ import (
 	"github.com/cloudevents/sdk-go/v2/extensions"
 	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/metrics"
@@ -119,6 +121,8 @@ type testCase struct {
 	// in happy case, path should match the /<ns>/<broker> in the brokerConfig.
 	path  string
 	event *cloudevents.Event
+	// batch is sent in the batched content mode if specified.
+	batch []*cloudevents.Event
 	// If method is empty, POST will be used as default.
 	method string
 	// body and header can be specified if the client is making raw HTTP request instead of via cloudevents.
@@ -127,6 +131,8 @@ type testCase struct {
 	wantCode       int
 	wantMetricTags map[string]string
 	wantEventCount int64
+	// wantBatchResults is the expected response body of a batched request.
+	wantBatchResults []BatchEventResult
 	// additional assertions on the output event.
 	eventAssertions []eventAssertion
 	decouple        DecoupleSink
@@ -311,6 +317,57 @@ func TestHandler(t *testing.T) {
 			},
 			decouple: &fakeOverloadedDecoupleSink{},
 		},
+		{
+			name:           "batch of events",
+			path:           "/ns1/broker1",
+			batch:          []*cloudevents.Event{createTestEvent("test-event-1"), createTestEvent("test-event-2")},
+			wantCode:       nethttp.StatusAccepted,
+			wantEventCount: 2,
+			wantMetricTags: map[string]string{
+				metricskey.LabelEventType:         eventType,
+				metricskey.LabelResponseCode:      "202",
+				metricskey.LabelResponseCodeClass: "2xx",
+				metricskey.PodName:                pod,
+				metricskey.ContainerName:          container,
+			},
+			wantBatchResults: []BatchEventResult{
+				{ID: "test-event-1", Code: nethttp.StatusAccepted},
+				{ID: "test-event-2", Code: nethttp.StatusAccepted},
+			},
+			eventAssertions: []eventAssertion{assertExtensionsExist(EventArrivalTime), assertTimeExists},
+		},
+		{
+			name:           "batch of events to a broker that is not ready",
+			path:           "/ns4/broker4",
+			batch:          []*cloudevents.Event{createTestEvent("test-event-1"), createTestEvent("test-event-2")},
+			wantCode:       nethttp.StatusServiceUnavailable,
+			wantEventCount: 2,
+			wantMetricTags: map[string]string{
+				metricskey.LabelEventType:         eventType,
+				metricskey.LabelResponseCode:      "503",
+				metricskey.LabelResponseCodeClass: "5xx",
+				metricskey.PodName:                pod,
+				metricskey.ContainerName:          container,
+			},
+			wantBatchResults: []BatchEventResult{
+				{ID: "test-event-1", Code: nethttp.StatusServiceUnavailable, Error: "Failed to publish to PubSub"},
+				{ID: "test-event-2", Code: nethttp.StatusServiceUnavailable, Error: "Failed to publish to PubSub"},
+			},
+		},
+		{
+			name:           "batch with an invalid event",
+			path:           "/ns1/broker1",
+			batch:          []*cloudevents.Event{createTestEvent("test-event-1"), createTestEvent("")},
+			wantCode:       nethttp.StatusBadRequest,
+			wantEventCount: 1,
+			wantMetricTags: map[string]string{
+				metricskey.LabelEventType:         "_invalid_cloud_event_",
+				metricskey.LabelResponseCode:      "400",
+				metricskey.LabelResponseCodeClass: "4xx",
+				metricskey.PodName:                pod,
+				metricskey.ContainerName:          container,
+			},
+		},
 	}
 
 	client := nethttp.Client{}
@@ -349,8 +406,12 @@ func TestHandler(t *testing.T) {
 			}
 			verifyMetrics(t, tc)
 
+			if tc.wantBatchResults != nil {
+				verifyBatchResults(ctx, t, tc, res, rec)
+			}
+
 			// If event is accepted, check that it's stored in the decouple sink.
-			if res.StatusCode == nethttp.StatusAccepted {
+			if res.StatusCode == nethttp.StatusAccepted && tc.batch == nil {
 				m, err := rec.Receive(ctx)
 				if err != nil {
 					t.Fatal(err)
@@ -414,7 +475,7 @@ func runIngressHandlerBenchmark(b *testing.B, eventSize int, targetCounts int) {
 	if err != nil {
 		b.Fatal(err)
 	}
-	h := NewHandler(ctx, nil, decouple, statsReporter, "")
+	h := NewHandler(ctx, nil, decouple, NewRateLimiter(memory.NewTargets(brokerConfig)), nil, nil, statsReporter, "")
 
 	if _, err := psClient.CreateTopic(ctx, topicID); err != nil {
 		b.Fatal(err)
@@ -522,7 +583,7 @@ func createAndStartIngress(ctx context.Context, t testing.TB, psSrv *pstest.Serv
 	if err != nil {
 		t.Fatal(err)
 	}
-	h := NewHandler(ctx, receiver, decouple, statsReporter, "")
+	h := NewHandler(ctx, receiver, decouple, NewRateLimiter(memory.NewTargets(brokerConfig)), nil, nil, statsReporter, "")
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -569,9 +630,54 @@ func createRequest(tc testCase, url string) *nethttp.Request {
 		defer message.Finish(nil)
 		http.WriteRequest(context.Background(), message, request)
 	}
+	if tc.batch != nil {
+		body, _ := json.Marshal(tc.batch)
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		request.ContentLength = int64(len(body))
+		request.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+	}
 	return request
 }
 
+// verifyBatchResults verifies the response body of a batched request, and that every accepted
+// event is stored in the decouple sink.
+func verifyBatchResults(ctx context.Context, t *testing.T, tc testCase, res *nethttp.Response, rec *cepubsub.Protocol) {
+	t.Helper()
+	var got []BatchEventResult
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	if diff := cmp.Diff(tc.wantBatchResults, got); diff != "" {
+		t.Errorf("Unexpected batch results (-want, +got): %s", diff)
+	}
+
+	wantIDs := make(map[string]bool)
+	for _, r := range tc.wantBatchResults {
+		if r.Code == nethttp.StatusAccepted {
+			wantIDs[r.ID] = true
+		}
+	}
+	for len(wantIDs) > 0 {
+		m, err := rec.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		savedToSink, err := binding.ToEvent(ctx, m)
+		if err != nil {
+			m.Finish(err)
+			t.Fatal(err)
+		}
+		if !wantIDs[savedToSink.ID()] {
+			t.Errorf("Unexpected event in the decouple sink: %v", savedToSink.ID())
+		}
+		delete(wantIDs, savedToSink.ID())
+		for _, assertion := range tc.eventAssertions {
+			assertion(t, savedToSink)
+		}
+		m.Finish(nil)
+	}
+}
+
 // verifyMetrics verifies broker metrics are properly recorded (or not recorded)
 func verifyMetrics(t *testing.T, tc testCase) {
 	if tc.wantEventCount == 0 {
@@ -601,6 +707,12 @@ func assertTraceID(id string) eventAssertion {
 	}
 }
 
+func assertTimeExists(t *testing.T, e *cloudevents.Event) {
+	if e.Time().IsZero() {
+		t.Error("The event has no time.")
+	}
+}
+
 func assertExtensionsExist(extensions ...string) eventAssertion {
 	return func(t *testing.T, e *cloudevents.Event) {
 		for _, extension := range extensions {
@@ -32,6 +32,7 @@ import (
 	"github.com/cloudevents/sdk-go/v2/extensions"
 	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
 	"github.com/google/knative-gcp/pkg/logging"
 )
@@ -53,6 +54,7 @@ func NewMultiTopicDecoupleSink(
 		topics: make(map[config.CellTenantKey]*pubsub.Topic),
 		// TODO(#1804): remove this field when enabling the feature by default.
 		enableEventFiltering: enableEventFilterFunc(),
+		expressions:          &filter.Expressions{},
 	}
 }
 
@@ -70,6 +72,8 @@ type multiTopicDecoupleSink struct {
 	brokerConfig config.ReadonlyTargets
 	// TODO(#1804): remove this field when enabling the feature by default.
 	enableEventFiltering bool
+	// expressions caches the compiled CESQL expressions of the triggers' filters.
+	expressions *filter.Expressions
 }
 
 // Send sends incoming event to its corresponding pubsub topic based on which broker it belongs to.
@@ -88,7 +92,7 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.CellTe
 	// Check to see if there are any triggers interested in this event. If not, no need to send this
 	// to the decouple topic.
 	// TODO(#1804): remove first check when enabling the feature by default.
-	if m.enableEventFiltering && !m.hasTrigger(ctx, &event) {
+	if m.enableEventFiltering && !m.hasTrigger(ctx, broker, &event) {
 		logging.FromContext(ctx).Debug("Filtering target-less event at ingress", zap.String("Eventid", event.ID()))
 		return nil
 	}
@@ -98,14 +102,22 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.CellTe
 	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(&event), msg, dt.WriteTransformer()); err != nil {
 		return err
 	}
+	if b, ok := m.brokerConfig.GetCellTenantByKey(broker); ok && topic.EnableMessageOrdering {
+		msg.OrderingKey = eventutil.OrderingKey(b, &event)
+	}
 
 	_, err = topic.Publish(ctx, msg).Get(ctx)
+	if err != nil && msg.OrderingKey != "" {
+		// The topic stops publishing messages with the ordering key after an error. Resume it so
+		// that the event can be sent again by the client.
+		topic.ResumePublish(msg.OrderingKey)
+	}
 	return err
 }
 
 // eventFilterFunc is used to see if a target is interested in an event.
 // It is used as a vaiable to allow stubbing out in unit tests.
-var eventFilterFunc = filter.PassFilter
+var eventFilterFunc = (*filter.Expressions).PassTarget
 
 // enableEventFilterFunc is a temporary function to control enabling and
 // disabling trigger-less event filtering in ingress.
@@ -117,12 +129,12 @@ func isEventFilteringEnabled() bool {
 	return os.Getenv("ENABLE_INGRESS_EVENT_FILTERING") == "true"
 }
 
-// hasTrigger checks given event against all targets to see if it will pass any of their filters.
-// If one is fouund, hasTrigger returns true.
-func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Event) bool {
+// hasTrigger checks given event against the targets of the broker to see if it will pass any of
+// their filters. If one is found, hasTrigger returns true.
+func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, broker *config.CellTenantKey, event *cev2.Event) bool {
 	hasTrigger := false
-	m.brokerConfig.RangeAllTargets(func(target *config.Target) bool {
-		if eventFilterFunc(ctx, target.FilterAttributes, event) {
+	m.brokerConfig.RangeCandidateTargets(broker, event, func(target *config.Target) bool {
+		if eventFilterFunc(m.expressions, ctx, target, event) {
 			hasTrigger = true
 			return false
 		}
@@ -135,14 +147,14 @@ func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Eve
 
 // getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume.
 func (m *multiTopicDecoupleSink) getTopicForBroker(ctx context.Context, broker *config.CellTenantKey) (*pubsub.Topic, error) {
-	topicID, err := m.getTopicIDForBroker(ctx, broker)
+	topicID, ordered, err := m.getTopicIDForBroker(ctx, broker)
 	if err != nil {
 		return nil, err
 	}
 
 	if topic, ok := m.getExistingTopic(broker); ok {
-		// Check that the broker's topic ID hasn't changed.
-		if topic.ID() == topicID {
+		// Check that the broker's topic ID and ordering haven't changed.
+		if topic.ID() == topicID && topic.EnableMessageOrdering == ordered {
 			return topic, nil
 		}
 	}
@@ -155,13 +167,13 @@ func (m *multiTopicDecoupleSink) updateTopicForBroker(ctx context.Context, broke
 	m.topicsMut.Lock()
 	defer m.topicsMut.Unlock()
 	// Fetch latest decouple topic ID under lock.
-	topicID, err := m.getTopicIDForBroker(ctx, broker)
+	topicID, ordered, err := m.getTopicIDForBroker(ctx, broker)
 	if err != nil {
 		return nil, err
 	}
 
 	if topic, ok := m.topics[*broker]; ok {
-		if topic.ID() == topicID {
+		if topic.ID() == topicID && topic.EnableMessageOrdering == ordered {
 			// Topic already updated.
 			return topic, nil
 		}
@@ -170,28 +182,31 @@ func (m *multiTopicDecoupleSink) updateTopicForBroker(ctx context.Context, broke
 	}
 	topic := m.pubsub.Topic(topicID)
 	topic.PublishSettings = m.publishSettings
+	topic.EnableMessageOrdering = ordered
 	m.topics[*broker] = topic
 	return topic, nil
 }
 
-func (m *multiTopicDecoupleSink) getTopicIDForBroker(ctx context.Context, broker *config.CellTenantKey) (string, error) {
+// getTopicIDForBroker returns the decouple topic ID of the broker, and whether the broker's events
+// are published with ordering keys.
+func (m *multiTopicDecoupleSink) getTopicIDForBroker(ctx context.Context, broker *config.CellTenantKey) (string, bool, error) {
 	brokerConfig, ok := m.brokerConfig.GetCellTenantByKey(broker)
 	if !ok {
 		// There is an propagation delay between the controller reconciles the broker config and
 		// the config being pushed to the configmap volume in the ingress pod. So sometimes we return
 		// an error even if the request is valid.
 		logging.FromContext(ctx).Warn("config is not found for")
-		return "", fmt.Errorf("%q: %w", broker, ErrNotFound)
+		return "", false, fmt.Errorf("%q: %w", broker, ErrNotFound)
 	}
 	if brokerConfig.DecoupleQueue == nil || brokerConfig.DecoupleQueue.Topic == "" {
 		logging.FromContext(ctx).Error("DecoupleQueue or topic missing for broker, this should NOT happen.", zap.Any("brokerConfig", brokerConfig))
-		return "", fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
+		return "", false, fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
 	}
 	if brokerConfig.DecoupleQueue.State != config.State_READY {
 		logging.FromContext(ctx).Debug("decouple queue is not ready")
-		return "", fmt.Errorf("%q: %w", broker, ErrNotReady)
+		return "", false, fmt.Errorf("%q: %w", broker, ErrNotReady)
 	}
-	return brokerConfig.DecoupleQueue.Topic, nil
+	return brokerConfig.DecoupleQueue.Topic, brokerConfig.OrderingKeyExtension != "", nil
 }
 
 func (m *multiTopicDecoupleSink) getExistingTopic(broker *config.CellTenantKey) (*pubsub.Topic, bool) {
@@ -31,6 +31,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
 	logtest "knative.dev/pkg/logging/testing"
 )
 
@@ -458,14 +459,24 @@ func TestHasTrigger(t *testing.T) {
 	DecoupleQueue := &config.Queue{Topic: "test_topic", State: config.State_READY}
 
 	tests := []struct {
-		name          string
-		brokerTargets map[string]*config.Target
-		hasTrigger    bool
+		name               string
+		brokerTargets      map[string]*config.Target
+		otherBrokerTargets map[string]*config.Target
+		hasTrigger         bool
 	}{
 		{
 			name:       "broker with no target",
 			hasTrigger: false,
 		},
+		{
+			name: "only another broker with matching target",
+			otherBrokerTargets: map[string]*config.Target{
+				"target_1": {
+					CellTenantType: config.CellTenantType_BROKER,
+				},
+			},
+			hasTrigger: false,
+		},
 		{
 			name:          "broker with empty target",
 			brokerTargets: map[string]*config.Target{},
@@ -556,6 +567,33 @@ func TestHasTrigger(t *testing.T) {
 			},
 			hasTrigger: false,
 		},
+		{
+			name: "broker with target with matching prefix filter",
+			brokerTargets: map[string]*config.Target{
+				"target_1": {
+					CellTenantType: config.CellTenantType_BROKER,
+					Filters: []*config.SubscriptionsAPIFilter{
+						{Prefix: map[string]string{"source": "test-"}},
+					},
+				},
+			},
+			hasTrigger: true,
+		},
+		{
+			name: "broker with target with matching attributes and non-matching dialect filter",
+			brokerTargets: map[string]*config.Target{
+				"target_1": {
+					CellTenantType: config.CellTenantType_BROKER,
+					FilterAttributes: map[string]string{
+						"type": eventType,
+					},
+					Filters: []*config.SubscriptionsAPIFilter{
+						{Not: &config.SubscriptionsAPIFilter{Suffix: map[string]string{"source": "-source"}}},
+					},
+				},
+			},
+			hasTrigger: false,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -571,6 +609,11 @@ func TestHasTrigger(t *testing.T) {
 						DecoupleQueue: DecoupleQueue,
 						Targets:       test.brokerTargets,
 					},
+					"test_ns_1/test_broker_2": {
+						Type:          config.CellTenantType_BROKER,
+						DecoupleQueue: DecoupleQueue,
+						Targets:       test.otherBrokerTargets,
+					},
 				},
 			}
 
@@ -579,7 +622,7 @@ func TestHasTrigger(t *testing.T) {
 
 			event := createTestEvent(uuid.New().String())
 
-			hasTrigger := sink.hasTrigger(ctx, event)
+			hasTrigger := sink.hasTrigger(ctx, config.TestOnlyBrokerKey("test_ns_1", "test_broker_1"), event)
 			if hasTrigger != test.hasTrigger {
 				t.Errorf("Sink says event has trigger %t which should be %t", hasTrigger, test.hasTrigger)
 			}
@@ -598,7 +641,7 @@ func TestMultiTopicDecoupleSinkSendChecksFilter(t *testing.T) {
 	filterCalled := false
 	origEventFilterFunc := eventFilterFunc
 	defer func() { eventFilterFunc = origEventFilterFunc }()
-	eventFilterFunc = func(ctx context.Context, attrs map[string]string, event *event.Event) bool {
+	eventFilterFunc = func(_ *filter.Expressions, ctx context.Context, target *config.Target, event *event.Event) bool {
 		filterCalled = true
 		return true
 	}
@@ -654,7 +697,7 @@ func TestMultiTopicDecoupleSinkSendDoesNotChecksFilterWhenFeatureDisabled(t *tes
 	filterCalled := false
 	origEventFilterFunc := eventFilterFunc
 	defer func() { eventFilterFunc = origEventFilterFunc }()
-	eventFilterFunc = func(ctx context.Context, attrs map[string]string, event *event.Event) bool {
+	eventFilterFunc = func(_ *filter.Expressions, ctx context.Context, target *config.Target, event *event.Event) bool {
 		filterCalled = true
 		return true
 	}
@@ -751,3 +794,104 @@ func TestMultiTopicDecoupleSinkUsesPublishSettings(t *testing.T) {
 		t.Fatalf("Unexpected error, expected %q, actually %q", want, got)
 	}
 }
+
+func TestMultiTopicDecoupleSinkOrderingKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		want      string
+	}{{
+		name: "broker not ordered",
+	}, {
+		name:      "ordered broker",
+		extension: "partitionkey",
+		want:      "key-1",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := logtest.TestContextWithLogger(t)
+			psSrv := pstest.NewServer()
+			defer psSrv.Close()
+			psClient := createPubsubClient(ctx, t, psSrv)
+
+			brokerConfig := memory.NewTargets(&config.TargetsConfig{
+				CellTenants: map[string]*config.CellTenant{
+					"test_ns_1/test_broker_1": {
+						Type:                 config.CellTenantType_BROKER,
+						DecoupleQueue:        &config.Queue{Topic: "test_topic_1", State: config.State_READY},
+						OrderingKeyExtension: tc.extension,
+						Targets: map[string]*config.Target{"target_1": {
+							CellTenantType: config.CellTenantType_BROKER,
+						}},
+					},
+				},
+			})
+			if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+				t.Fatal(err)
+			}
+
+			ce := createTestEvent(uuid.New().String())
+			ce.SetExtension("partitionkey", "key-1")
+			sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+			if err := sink.Send(ctx, config.TestOnlyBrokerKey("test_ns_1", "test_broker_1"), *ce); err != nil {
+				t.Fatalf("Send() = %v", err)
+			}
+
+			msgs := psSrv.Messages()
+			if len(msgs) != 1 {
+				t.Fatalf("Got %d published messages, want 1", len(msgs))
+			}
+			if got := msgs[0].OrderingKey; got != tc.want {
+				t.Errorf("Published message ordering key = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// BenchmarkHasTrigger compares looking up the candidate targets of the broker through the index
+// with evaluating the filters of all targets in the cell.
+func BenchmarkHasTrigger(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		targets := make(map[string]*config.Target, n)
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("target_%d", i)
+			targets[name] = &config.Target{
+				Name:             name,
+				CellTenantType:   config.CellTenantType_BROKER,
+				FilterAttributes: map[string]string{"type": fmt.Sprintf("type_%d", i)},
+			}
+		}
+		brokerConfig := memory.NewTargets(&config.TargetsConfig{
+			CellTenants: map[string]*config.CellTenant{
+				"test_ns_1/test_broker_1": {
+					Type:      config.CellTenantType_BROKER,
+					Namespace: "test_ns_1",
+					Name:      "test_broker_1",
+					Targets:   targets,
+				},
+			},
+		})
+		broker := config.TestOnlyBrokerKey("test_ns_1", "test_broker_1")
+		event := createTestEvent("test-event")
+		// The only matching target is the last one, so that the linear scan sees most targets.
+		event.SetType(fmt.Sprintf("type_%d", n-1))
+		ctx := context.Background()
+		sink := &multiTopicDecoupleSink{brokerConfig: brokerConfig, expressions: &filter.Expressions{}}
+
+		b.Run(fmt.Sprintf("linear/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				brokerConfig.RangeAllTargets(func(target *config.Target) bool {
+					return !sink.expressions.PassTarget(ctx, target, event)
+				})
+			}
+		})
+		b.Run(fmt.Sprintf("indexed/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !sink.hasTrigger(ctx, broker, event) {
+					b.Fatal("hasTrigger() = false, want true")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// RateLimiter enforces the per broker ingress rate limits from the broker config. Each broker with
+// a rate limit has a token bucket for events and another one for bytes.
+type RateLimiter struct {
+	brokerConfig config.ReadonlyTargets
+	// limiters is a map from the broker key to its *brokerLimiter.
+	limiters sync.Map
+}
+
+type brokerLimiter struct {
+	limit  *config.RateLimit
+	events *rate.Limiter
+	bytes  *rate.Limiter
+}
+
+// NewRateLimiter creates a new RateLimiter.
+func NewRateLimiter(brokerConfig config.ReadonlyTargets) *RateLimiter {
+	return &RateLimiter{brokerConfig: brokerConfig}
+}
+
+// Allow reports whether the given number of events and bytes can be accepted for the broker now.
+// If not, it also returns how long the client should wait before retrying. Nothing is consumed
+// from the buckets if the request is not allowed.
+func (l *RateLimiter) Allow(broker *config.CellTenantKey, events, bytes int) (bool, time.Duration) {
+	bl := l.limiterFor(broker)
+	if bl == nil {
+		return true, 0
+	}
+	now := time.Now()
+	eventsRes := reserve(bl.events, now, events)
+	if delay := eventsRes.DelayFrom(now); delay > 0 {
+		eventsRes.CancelAt(now)
+		return false, delay
+	}
+	bytesRes := reserve(bl.bytes, now, bytes)
+	if delay := bytesRes.DelayFrom(now); delay > 0 {
+		bytesRes.CancelAt(now)
+		eventsRes.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// limiterFor returns the limiter of the broker, or nil if the broker has no rate limit. The limiter
+// is recreated when the rate limit of the broker changes.
+func (l *RateLimiter) limiterFor(broker *config.CellTenantKey) *brokerLimiter {
+	key := broker.PersistenceString()
+	b, ok := l.brokerConfig.GetCellTenantByKey(broker)
+	if !ok || b.RateLimit == nil {
+		l.limiters.Delete(key)
+		return nil
+	}
+	if v, ok := l.limiters.Load(key); ok {
+		if bl := v.(*brokerLimiter); proto.Equal(bl.limit, b.RateLimit) {
+			return bl
+		}
+	}
+	bl := &brokerLimiter{
+		limit:  b.RateLimit,
+		events: newLimiter(b.RateLimit.EventsPerSecond, b.RateLimit.EventsBurst),
+		bytes:  newLimiter(b.RateLimit.BytesPerSecond, b.RateLimit.BytesBurst),
+	}
+	l.limiters.Store(key, bl)
+	return bl
+}
+
+// newLimiter creates a token bucket. A zero rate means no limit.
+func newLimiter(perSecond float64, burst int64) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(burst))
+}
+
+// reserve reserves n tokens from the limiter. A request larger than the burst takes the whole
+// bucket instead of never being allowed.
+func reserve(limiter *rate.Limiter, now time.Time, n int) *rate.Reservation {
+	if limiter.Limit() != rate.Inf && n > limiter.Burst() {
+		n = limiter.Burst()
+	}
+	return limiter.ReserveN(now, n)
+}
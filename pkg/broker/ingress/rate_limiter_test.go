@@ -0,0 +1,191 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"knative.dev/pkg/logging"
+	logtest "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+	_ "knative.dev/pkg/metrics/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+type fakeAcceptingDecoupleSink struct{}
+
+func (m *fakeAcceptingDecoupleSink) Send(_ context.Context, _ *config.CellTenantKey, _ cev2.Event) protocol.Result {
+	return nil
+}
+
+func rateLimitedTargets(limit *config.RateLimit) config.Targets {
+	return memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"ns1/broker1": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker1",
+				Namespace: "ns1",
+				RateLimit: limit,
+			},
+			"ns2/broker2": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker2",
+				Namespace: "ns2",
+			},
+		},
+	})
+}
+
+func mustKey(t *testing.T, s string) *config.CellTenantKey {
+	t.Helper()
+	key, err := config.CellTenantKeyFromPersistenceString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit *config.RateLimit
+		// requests are the number of events and bytes of each request.
+		requests [][2]int
+		want     []bool
+	}{{
+		name:     "no limit",
+		requests: [][2]int{{100, 100}, {100, 100}},
+		want:     []bool{true, true},
+	}, {
+		name:     "events limit",
+		limit:    &config.RateLimit{EventsPerSecond: 1, EventsBurst: 2},
+		requests: [][2]int{{1, 0}, {1, 0}, {1, 0}},
+		want:     []bool{true, true, false},
+	}, {
+		name:     "bytes limit",
+		limit:    &config.RateLimit{BytesPerSecond: 1, BytesBurst: 100},
+		requests: [][2]int{{1, 60}, {1, 60}, {1, 40}},
+		want:     []bool{true, false, true},
+	}, {
+		name:     "request larger than the burst takes the whole bucket",
+		limit:    &config.RateLimit{BytesPerSecond: 1, BytesBurst: 100},
+		requests: [][2]int{{1, 1000}, {1, 1}},
+		want:     []bool{true, false},
+	}, {
+		name:     "rejected request does not consume events",
+		limit:    &config.RateLimit{EventsPerSecond: 1, EventsBurst: 2, BytesPerSecond: 1, BytesBurst: 10},
+		requests: [][2]int{{1, 100}, {1, 100}, {1, 0}, {1, 0}},
+		want:     []bool{true, false, true, false},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewRateLimiter(rateLimitedTargets(tc.limit))
+			for i, r := range tc.requests {
+				ok, retryAfter := l.Allow(mustKey(t, "/ns1/broker1"), r[0], r[1])
+				if ok != tc.want[i] {
+					t.Errorf("request %d: Allow() = %v, want %v", i, ok, tc.want[i])
+				}
+				if !ok && retryAfter <= 0 {
+					t.Errorf("request %d: Allow() retryAfter = %v, want > 0", i, retryAfter)
+				}
+			}
+			// Brokers without rate limit are not affected.
+			if ok, _ := l.Allow(mustKey(t, "/ns2/broker2"), 1000, 1000); !ok {
+				t.Error("Allow() = false for broker without rate limit")
+			}
+		})
+	}
+}
+
+func TestRateLimiterConfigUpdate(t *testing.T) {
+	targets := rateLimitedTargets(&config.RateLimit{EventsPerSecond: 1, EventsBurst: 1})
+	l := NewRateLimiter(targets)
+	key := mustKey(t, "/ns1/broker1")
+	if ok, _ := l.Allow(key, 1, 0); !ok {
+		t.Fatal("Allow() = false, want true")
+	}
+	if ok, _ := l.Allow(key, 1, 0); ok {
+		t.Fatal("Allow() = true, want false")
+	}
+
+	targets.MutateCellTenant(key, func(m config.CellTenantMutation) {
+		m.SetRateLimit(&config.RateLimit{EventsPerSecond: 1, EventsBurst: 5})
+	})
+	if ok, _ := l.Allow(key, 1, 0); !ok {
+		t.Error("Allow() = false after the rate limit was raised, want true")
+	}
+
+	targets.MutateCellTenant(key, func(m config.CellTenantMutation) {
+		m.SetRateLimit(nil)
+	})
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow(key, 1, 0); !ok {
+			t.Fatal("Allow() = false after the rate limit was removed, want true")
+		}
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+	ctx := logging.WithLogger(context.Background(), logtest.TestLogger(t))
+	reporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewRateLimiter(rateLimitedTargets(&config.RateLimit{EventsPerSecond: 0.5, EventsBurst: 1}))
+	h := NewHandler(ctx, nil, &fakeAcceptingDecoupleSink{}, limiter, nil, nil, reporter, "")
+
+	send := func() *nethttp.Response {
+		req := httptest.NewRequest("POST", "/ns1/broker1", nil)
+		message := binding.ToMessage(createTestEvent("test-event"))
+		defer message.Finish(nil)
+		http.WriteRequest(ctx, message, req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	if res := send(); res.StatusCode != nethttp.StatusAccepted {
+		t.Errorf("StatusCode mismatch. got: %v, want: %v", res.StatusCode, nethttp.StatusAccepted)
+	}
+	res := send()
+	if res.StatusCode != nethttp.StatusTooManyRequests {
+		t.Errorf("StatusCode mismatch. got: %v, want: %v", res.StatusCode, nethttp.StatusTooManyRequests)
+	}
+	if got, want := res.Header.Get("Retry-After"), "2"; got != want {
+		t.Errorf("Retry-After mismatch. got: %q, want: %q", got, want)
+	}
+	metricstest.CheckSumData(t, "rejected_event_count", map[string]string{
+		metricskey.LabelNamespaceName: "ns1",
+		metricskey.LabelBrokerName:    "broker1",
+		metricskey.PodName:            pod,
+		metricskey.ContainerName:      container,
+	}, 1)
+}
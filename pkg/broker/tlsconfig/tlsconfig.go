@@ -0,0 +1,173 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tlsconfig provides the TLS configuration of the connections to the subscribers, loaded
+// from PEM files and reloaded when the files change.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// Reloader keeps a TLS client configuration up to date with a CA bundle and a client certificate
+// and key stored in PEM files. The files are typically mounted from Kubernetes Secrets, which are
+// updated in place when the certificates are rotated.
+type Reloader struct {
+	caFile   string
+	certFile string
+	keyFile  string
+
+	mu sync.Mutex
+	// contents is the contents of the files the current configuration was loaded from.
+	contents []byte
+	// config holds the current *tls.Config.
+	config atomic.Value
+	// done is closed once the files are no longer watched.
+	done chan struct{}
+}
+
+// NewReloader loads the TLS configuration from the given files, and reloads it whenever the files
+// change until ctx is done. The CA bundle, if any, is trusted in addition to the system roots. The
+// client certificate and key are either both set or both empty.
+func NewReloader(ctx context.Context, caFile, certFile, keyFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("the client certificate and key files must be set together")
+	}
+	r := &Reloader{
+		caFile:   caFile,
+		certFile: certFile,
+		keyFile:  keyFile,
+		done:     make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Kubernetes updates Secret volumes by swapping a symlink in the directory of the files, so the
+	// directories are watched rather than the files.
+	dirs := make(map[string]bool)
+	for _, f := range []string{caFile, certFile, keyFile} {
+		if f == "" || dirs[filepath.Dir(f)] {
+			continue
+		}
+		dirs[filepath.Dir(f)] = true
+		if err := watcher.Add(filepath.Dir(f)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	go r.watch(ctx, watcher)
+	return r, nil
+}
+
+// Config returns the current TLS configuration. It must not be modified.
+func (r *Reloader) Config() *tls.Config {
+	return r.config.Load().(*tls.Config)
+}
+
+// Reload reads the files and replaces the current configuration if their contents changed. The
+// current configuration is kept if the files are invalid, e.g. while they are being rotated.
+func (r *Reloader) Reload() error {
+	ca, err := readFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	cert, err := readFile(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	key, err := readFile(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client key: %w", err)
+	}
+	contents := bytes.Join([][]byte{ca, cert, key}, []byte{0})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.config.Load() != nil && bytes.Equal(contents, r.contents) {
+		return nil
+	}
+	config := &tls.Config{}
+	if len(ca) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificate found in CA bundle %s", r.caFile)
+		}
+		config.RootCAs = pool
+	}
+	if len(cert) > 0 || len(key) > 0 {
+		c, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{c}
+	}
+	r.contents = contents
+	r.config.Store(config)
+	return nil
+}
+
+func (r *Reloader) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer close(r.done)
+	defer watcher.Close()
+	logger := logging.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if err := r.Reload(); err != nil {
+				logger.Warn("failed to reload the delivery TLS configuration", zap.Error(err))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("delivery TLS files watcher error", zap.Error(err))
+		}
+	}
+}
+
+func readFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return ioutil.ReadFile(path)
+}
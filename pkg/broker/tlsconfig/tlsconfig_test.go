@@ -0,0 +1,296 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logtest "knative.dev/pkg/logging/testing"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM encoded certificate and key, signed by the CA, for the local host.
+func (ca *testCA) issue(t *testing.T, name string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newTestServer starts a TLS server with a certificate issued by serverCA, which responds with the
+// common name of the client certificate issued by clientCA, if any.
+func newTestServer(t *testing.T, serverCA, clientCA *testCA) *httptest.Server {
+	t.Helper()
+	cert, key := serverCA.issue(t, "server")
+	serverCert, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(req.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	return srv
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	// Write the file next to the target and rename it, so that it's replaced atomically like a
+	// Secret volume.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+// stopReloader stops the watch of the files of the Reloader, so that it doesn't log once the test is
+// done.
+func stopReloader(cancel context.CancelFunc, r *Reloader) {
+	cancel()
+	<-r.done
+}
+
+func TestReloaderReloadsRotatedFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(logtest.TestContextWithLogger(t))
+	defer cancel()
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	oldCA := newTestCA(t, "old-ca")
+	newCA := newTestCA(t, "new-ca")
+	clientCA := newTestCA(t, "client-ca")
+	oldSrv := newTestServer(t, oldCA, clientCA)
+	defer oldSrv.Close()
+	newSrv := newTestServer(t, newCA, clientCA)
+	defer newSrv.Close()
+
+	writeFile(t, caFile, oldCA.pem)
+	cert, key := clientCA.issue(t, "old-client")
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+
+	r, err := NewReloader(ctx, caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewReloader got error: %v", err)
+	}
+	defer stopReloader(cancel, r)
+	client := &http.Client{Transport: r.Transport(&http.Transport{})}
+
+	if got, err := get(client, oldSrv.URL); err != nil || got != "old-client" {
+		t.Errorf("request to the server with the trusted CA got (%q, %v), want old-client", got, err)
+	}
+	if _, err := get(client, newSrv.URL); err == nil {
+		t.Error("request to the server with an untrusted CA unexpectedly succeeded")
+	}
+
+	writeFile(t, caFile, newCA.pem)
+	cert, key = clientCA.issue(t, "new-client")
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got, err := get(client, newSrv.URL)
+		if err == nil && got == "new-client" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request to the server with the rotated CA got (%q, %v), want new-client", got, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := get(client, oldSrv.URL); err == nil {
+		t.Error("request to the server with the CA rotated out unexpectedly succeeded")
+	}
+}
+
+func TestReloaderKeepsConfigOnInvalidFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(logtest.TestContextWithLogger(t))
+	defer cancel()
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, newTestCA(t, "ca").pem)
+
+	r, err := NewReloader(ctx, caFile, "", "")
+	if err != nil {
+		t.Fatalf("NewReloader got error: %v", err)
+	}
+	defer stopReloader(cancel, r)
+	config := r.Config()
+	if config.RootCAs == nil {
+		t.Error("the CA bundle wasn't loaded")
+	}
+	if len(config.Certificates) != 0 {
+		t.Errorf("unexpected client certificates: %v", config.Certificates)
+	}
+
+	writeFile(t, caFile, []byte("not a certificate"))
+	if err := r.Reload(); err == nil {
+		t.Error("Reload of an invalid CA bundle got no error")
+	}
+	if r.Config() != config {
+		t.Error("the configuration was replaced by an invalid one")
+	}
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t, "ca")
+	cert, key := ca.issue(t, "client")
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeFile(t, caFile, ca.pem)
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+
+	cases := []struct {
+		name     string
+		caFile   string
+		certFile string
+		keyFile  string
+	}{{
+		name:     "certificate without key",
+		certFile: certFile,
+	}, {
+		name:   "missing CA bundle",
+		caFile: filepath.Join(dir, "missing.crt"),
+	}, {
+		name:   "invalid CA bundle",
+		caFile: keyFile,
+	}, {
+		name:     "mismatched certificate and key",
+		certFile: caFile,
+		keyFile:  keyFile,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewReloader(ctx, tc.caFile, tc.certFile, tc.keyFile); err == nil {
+				t.Error("NewReloader got no error")
+			}
+		})
+	}
+}
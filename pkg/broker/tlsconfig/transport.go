@@ -0,0 +1,79 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Transport returns a round tripper sending requests with a clone of base using the current TLS
+// configuration of r. When the configuration is reloaded, new connections use the new
+// configuration and the idle connections made with the previous one are closed.
+func (r *Reloader) Transport(base *http.Transport) http.RoundTripper {
+	return &transport{reloader: r, base: base}
+}
+
+type transport struct {
+	reloader *Reloader
+	base     *http.Transport
+
+	mu sync.Mutex
+	// current holds the *configuredTransport of the current TLS configuration.
+	current atomic.Value
+}
+
+type configuredTransport struct {
+	*http.Transport
+	config *tls.Config
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport().RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections, so that http.Client.CloseIdleConnections
+// reaches the underlying transport.
+func (t *transport) CloseIdleConnections() {
+	if ct, ok := t.current.Load().(*configuredTransport); ok {
+		ct.CloseIdleConnections()
+	}
+}
+
+func (t *transport) transport() *http.Transport {
+	config := t.reloader.Config()
+	if ct, ok := t.current.Load().(*configuredTransport); ok && ct.config == config {
+		return ct.Transport
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous, _ := t.current.Load().(*configuredTransport)
+	if previous != nil && previous.config == config {
+		return previous.Transport
+	}
+	ct := &configuredTransport{Transport: t.base.Clone(), config: config}
+	// The transport may modify its TLS configuration, e.g. to enable HTTP/2.
+	ct.TLSClientConfig = config.Clone()
+	t.current.Store(ct)
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+	return ct.Transport
+}
@@ -45,3 +45,7 @@ func (b *storageBucket) DeleteNotification(ctx context.Context, id string) error
 func (b *storageBucket) Attrs(ctx context.Context) (attrs *storage.BucketAttrs, err error) {
 	return b.handle.Attrs(ctx)
 }
+
+func (b *storageBucket) Object(name string) Object {
+	return &storageObject{handle: b.handle.Object(name)}
+}
@@ -17,7 +17,11 @@ limitations under the License.
 package testing
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
+	"sync"
 
 	. "cloud.google.com/go/storage"
 	"github.com/google/knative-gcp/pkg/gclient/storage"
@@ -26,6 +30,17 @@ import (
 // testBucket is a test Storage bucket.
 type testBucket struct {
 	data TestBucketData
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newTestBucket(data TestBucketData) *testBucket {
+	objects := make(map[string][]byte, len(data.Objects))
+	for name, content := range data.Objects {
+		objects[name] = content
+	}
+	return &testBucket{data: data, objects: objects}
 }
 
 // TestBucketData is the data used to configure the test Bucket.
@@ -37,6 +52,10 @@ type TestBucketData struct {
 	DeleteErr          error
 	Attrs              *BucketAttrs
 	AttrsError         error
+	// Objects are the initial objects of the bucket, keyed by name.
+	Objects        map[string][]byte
+	ObjectWriteErr error
+	ObjectReadErr  error
 }
 
 // Verify that it satisfies the storage.Bucket interface.
@@ -65,3 +84,69 @@ func (b *testBucket) DeleteNotification(ctx context.Context, id string) error {
 func (b *testBucket) Attrs(ctx context.Context) (*BucketAttrs, error) {
 	return b.data.Attrs, b.data.AttrsError
 }
+
+// Object implements bucket.Object
+func (b *testBucket) Object(name string) storage.Object {
+	return &testObject{bucket: b, name: name}
+}
+
+// testObject is a test Storage object kept in memory by its bucket.
+type testObject struct {
+	bucket *testBucket
+	name   string
+}
+
+// Verify that it satisfies the storage.Object interface.
+var _ storage.Object = &testObject{}
+
+// NewWriter implements object.NewWriter. The object is stored when the writer is closed.
+func (o *testObject) NewWriter(ctx context.Context) io.WriteCloser {
+	return &testWriter{object: o}
+}
+
+// NewReader implements object.NewReader
+func (o *testObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	if o.bucket.data.ObjectReadErr != nil {
+		return nil, o.bucket.data.ObjectReadErr
+	}
+	o.bucket.mu.Lock()
+	defer o.bucket.mu.Unlock()
+	content, ok := o.bucket.objects[o.name]
+	if !ok {
+		return nil, ErrObjectNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Delete implements object.Delete
+func (o *testObject) Delete(ctx context.Context) error {
+	o.bucket.mu.Lock()
+	defer o.bucket.mu.Unlock()
+	if _, ok := o.bucket.objects[o.name]; !ok {
+		return ErrObjectNotExist
+	}
+	delete(o.bucket.objects, o.name)
+	return nil
+}
+
+type testWriter struct {
+	object *testObject
+	buf    bytes.Buffer
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	if err := w.object.bucket.data.ObjectWriteErr; err != nil {
+		return 0, err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *testWriter) Close() error {
+	if err := w.object.bucket.data.ObjectWriteErr; err != nil {
+		return err
+	}
+	w.object.bucket.mu.Lock()
+	defer w.object.bucket.mu.Unlock()
+	w.object.bucket.objects[w.object.name] = w.buf.Bytes()
+	return nil
+}
@@ -18,6 +18,7 @@ package testing
 
 import (
 	"context"
+	"sync"
 
 	"github.com/google/knative-gcp/pkg/gclient/storage"
 	"google.golang.org/api/option"
@@ -55,6 +56,10 @@ type TestClientData struct {
 // testClient is a test Storage client.
 type testClient struct {
 	data TestClientData
+
+	mu sync.Mutex
+	// buckets are kept so that the objects written to a bucket can be read back.
+	buckets map[string]*testBucket
 }
 
 // Verify that it satisfies the storage.Client interface.
@@ -67,5 +72,15 @@ func (c *testClient) Close() error {
 
 // Bucket implements client.Bucket
 func (c *testClient) Bucket(name string) storage.Bucket {
-	return &testBucket{data: c.data.BucketData}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.buckets[name]; ok {
+		return b
+	}
+	b := newTestBucket(c.data.BucketData)
+	if c.buckets == nil {
+		c.buckets = make(map[string]*testBucket)
+	}
+	c.buckets[name] = b
+	return b
 }
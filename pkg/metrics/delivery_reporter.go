@@ -45,6 +45,8 @@ type DeliveryReporter struct {
 	containerName         ContainerName
 	dispatchTimeInMsecM   *stats.Float64Measure
 	processingTimeInMsecM *stats.Float64Measure
+	circuitBreakerStateM  *stats.Int64Measure
+	expiredEventsM        *stats.Int64Measure
 }
 
 func (r *DeliveryReporter) register() error {
@@ -86,6 +88,26 @@ func (r *DeliveryReporter) register() error {
 				ContainerNameKey,
 			},
 		},
+		&view.View{
+			Name:        r.circuitBreakerStateM.Name(),
+			Description: r.circuitBreakerStateM.Description(),
+			Measure:     r.circuitBreakerStateM,
+			Aggregation: view.LastValue(),
+			TagKeys: []tag.Key{
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
+		&view.View{
+			Name:        "expired_event_count",
+			Description: r.expiredEventsM.Description(),
+			Measure:     r.expiredEventsM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -108,6 +130,20 @@ func NewDeliveryReporter(podName PodName, containerName ContainerName) (*Deliver
 			"The time spent processing an event before it is dispatched to a Trigger subscriber",
 			stats.UnitMilliseconds,
 		),
+		// circuitBreakerStateM records the state of the circuit breaker of a Trigger
+		// subscriber: 0 when closed, 1 when half-open and 2 when open.
+		circuitBreakerStateM: stats.Int64(
+			"circuit_breaker_state",
+			"The state of the circuit breaker of a Trigger subscriber, 0 when closed, 1 when half-open and 2 when open",
+			stats.UnitDimensionless,
+		),
+		// expiredEventsM records the events that exceeded the maximum event age of a Trigger,
+		// which are dropped or sent to the dead letter sink instead of being delivered.
+		expiredEventsM: stats.Int64(
+			"expired_events",
+			"Number of events dropped or dead lettered because they exceeded the maximum event age of a Trigger",
+			stats.UnitDimensionless,
+		),
 	}
 
 	if err := r.register(); err != nil {
@@ -123,6 +159,18 @@ func (r *DeliveryReporter) ReportEventDispatchTime(ctx context.Context, d time.D
 	metrics.Record(ctx, r.dispatchTimeInMsecM.M(float64(d/time.Millisecond)), stats.WithAttachments(attachments))
 }
 
+// ReportCircuitBreakerState captures the state of the circuit breaker of the target in the context.
+func (r *DeliveryReporter) ReportCircuitBreakerState(ctx context.Context, state int64) {
+	metrics.Record(ctx, r.circuitBreakerStateM.M(state))
+}
+
+// ReportExpiredEvent records an event that exceeded the maximum event age of the target in the
+// context.
+func (r *DeliveryReporter) ReportExpiredEvent(ctx context.Context) {
+	attachments := getSpanContextAttachments(ctx)
+	metrics.Record(ctx, r.expiredEventsM.M(1), stats.WithAttachments(attachments))
+}
+
 // StartEventProcessing records the start of event processing for delivery within the given context.
 func StartEventProcessing(ctx context.Context) context.Context {
 	return context.WithValue(ctx, startDeliveryProcessingTime, time.Now())
@@ -165,3 +165,65 @@ func TestMetricsWithEmptySourceAndTypeFilter(t *testing.T) {
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 1)
 }
+
+func TestReportCircuitBreakerState(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.PodName:       "testpod",
+		metricskey.ContainerName: "testcontainer",
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := r.AddTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = AddTargetTags(ctx, &config.Target{
+		Namespace:      "testns",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "testbroker",
+		Name:           "testtrigger",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportCircuitBreakerState(ctx, 2)
+	r.ReportCircuitBreakerState(ctx, 1)
+	metricstest.CheckLastValueData(t, "circuit_breaker_state", wantTags, 1)
+}
+
+func TestReportExpiredEvent(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.PodName:       "testpod",
+		metricskey.ContainerName: "testcontainer",
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := r.AddTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = AddTargetTags(ctx, &config.Target{
+		Namespace:      "testns",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "testbroker",
+		Name:           "testtrigger",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportExpiredEvent(ctx)
+	r.ReportExpiredEvent(ctx)
+	metricstest.CheckCountData(t, "expired_event_count", wantTags, 2)
+}
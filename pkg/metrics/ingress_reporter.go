@@ -37,6 +37,15 @@ type IngressReportArgs struct {
 	ResponseCode int
 }
 
+// IngressRejectArgs are the arguments to report events rejected by the ingress rate limit of a
+// broker.
+type IngressRejectArgs struct {
+	Namespace string
+	Broker    string
+	// Count is the number of rejected events.
+	Count int64
+}
+
 func (r *IngressReporter) register() error {
 	tagKeys := []tag.Key{
 		EventTypeKey,
@@ -55,6 +64,18 @@ func (r *IngressReporter) register() error {
 			Aggregation: view.Count(),
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Name:        r.rejectedEventCountM.Name(),
+			Description: r.rejectedEventCountM.Description(),
+			Measure:     r.rejectedEventCountM,
+			Aggregation: view.Sum(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -68,6 +89,11 @@ func NewIngressReporter(podName PodName, containerName ContainerName) (*IngressR
 			"Number of events received by a Broker",
 			stats.UnitDimensionless,
 		),
+		rejectedEventCountM: stats.Int64(
+			"rejected_event_count",
+			"Number of events rejected by the rate limit of a Broker",
+			stats.UnitDimensionless,
+		),
 	}
 	if err := r.register(); err != nil {
 		return nil, fmt.Errorf("failed to register ingress stats: %w", err)
@@ -80,6 +106,8 @@ type IngressReporter struct {
 	podName       PodName
 	containerName ContainerName
 	eventCountM   *stats.Int64Measure
+	// rejectedEventCountM is the number of events rejected by the rate limit of a broker.
+	rejectedEventCountM *stats.Int64Measure
 }
 
 func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressReportArgs) error {
@@ -98,3 +126,17 @@ func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressRepo
 	)
 	return nil
 }
+
+// ReportRejectedEventCount reports events rejected by the rate limit of a broker.
+func (r *IngressReporter) ReportRejectedEventCount(ctx context.Context, args IngressRejectArgs) error {
+	metrics.Record(
+		ctx, r.rejectedEventCountM.M(args.Count),
+		stats.WithTags(
+			tag.Insert(NamespaceNameKey, args.Namespace),
+			tag.Insert(BrokerNameKey, args.Broker),
+			tag.Insert(PodNameKey, string(r.podName)),
+			tag.Insert(ContainerNameKey, string(r.containerName)),
+		),
+	)
+	return nil
+}
@@ -86,3 +86,32 @@ func TestStatsReporterWithCustomEventType(t *testing.T) {
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 2)
 }
+
+func TestReportRejectedEventCount(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+
+	args := IngressRejectArgs{
+		Namespace: "testns",
+		Broker:    "testbroker",
+		Count:     3,
+	}
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		metricskey.ContainerName:      "testcontainer",
+		metricskey.PodName:            "testpod",
+	}
+
+	r, err := NewIngressReporter(PodName("testpod"), ContainerName("testcontainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRejectedEventCount(context.Background(), args)
+	})
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRejectedEventCount(context.Background(), args)
+	})
+	metricstest.CheckSumData(t, "rejected_event_count", wantTags, 6)
+}
@@ -27,9 +27,13 @@ import (
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/broker"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 )
 
 const (
@@ -40,6 +44,13 @@ const (
 
 type Reconciler struct {
 	celltenant.Reconciler
+
+	// targetsServer streams the targets config to the data plane, which reports the config it
+	// applied. It is nil if the data plane doesn't report it.
+	targetsServer *stream.Server
+
+	// uriResolver resolves the addressable dead letter sinks of the brokers.
+	uriResolver *resolver.URIResolver
 }
 
 // Check that Reconciler implements Interface
@@ -51,6 +62,10 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, b *brokerv1beta1.Broker)
 	logger.Debug("Reconciling Broker", zap.Any("broker", b))
 	b.Status.InitializeConditions()
 	b.Status.ObservedGeneration = b.Generation
+	r.checkDataPlane(b)
+	// The events are still fanned out while the dead letter sink isn't resolved, so the error is
+	// only returned once the rest of the broker is reconciled.
+	deadLetterErr := r.resolveDeadLetterSink(ctx, b)
 
 	bcs := celltenant.StatusableFromBroker(b)
 	if err := r.Reconciler.ReconcileGCPCellTenant(ctx, bcs); err != nil {
@@ -59,10 +74,61 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, b *brokerv1beta1.Broker)
 		//TODO instead of returning on error, update the data plane configmap with
 		// whatever info is available. or put this in a defer?
 	}
+	if deadLetterErr != nil {
+		return deadLetterErr
+	}
 
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, brokerReconciled, "Broker reconciled: \"%s/%s\"", b.Namespace, b.Name)
 }
 
+// checkDataPlane reports whether the data plane applied the targets config with the latest spec of
+// the broker.
+func (r *Reconciler) checkDataPlane(b *brokerv1beta1.Broker) {
+	if r.targetsServer == nil || !r.targetsServer.Serving() {
+		b.Status.MarkDataPlaneNotTracked()
+		return
+	}
+	bc := brokerresources.BrokerCellKey(b)
+	tc, ok := r.targetsServer.Applied(bc.Namespace, bc.Name)
+	if !ok {
+		// Keep the condition until the data plane reports the config it applied again, e.g. after
+		// the controller restarted, so that the ready brokers don't flap.
+		if !b.Status.GetCondition(brokerv1beta1.BrokerConditionDataPlane).IsTrue() {
+			b.Status.MarkDataPlaneUnknown("DataPlaneNotReported", "The data plane didn't report the targets config it applied")
+		}
+		return
+	}
+	checkAppliedConfig(b, tc)
+}
+
+// checkAppliedConfig reports whether the targets config applied by the data plane has the broker at
+// its latest generation.
+func checkAppliedConfig(b *brokerv1beta1.Broker, tc *config.TargetsConfig) {
+	if !tc.HasBrokerGeneration(b) {
+		b.Status.MarkDataPlaneUnknown("ConfigNotApplied", "The data plane didn't apply the generation %d of the Broker yet", b.Generation)
+		return
+	}
+	b.Status.MarkDataPlaneReady()
+}
+
+// resolveDeadLetterSink reports whether the dead letter sink the retry pool delivers the events to
+// once their retries are exhausted can be resolved. The BrokerCell resolves it again when it builds
+// the targets config, and doesn't dead letter the events of the broker until it is resolved.
+func (r *Reconciler) resolveDeadLetterSink(ctx context.Context, b *brokerv1beta1.Broker) error {
+	spec := b.Spec.Delivery
+	if spec == nil || spec.DeadLetterSink == nil || spec.Retry == nil || brokerv1beta1.IsPubsubDeadLetterSink(spec.DeadLetterSink) {
+		b.Status.MarkDeadLetterSinkNotNeeded()
+		return nil
+	}
+	if _, err := r.uriResolver.URIFromDestinationV1(ctx, *spec.DeadLetterSink, b); err != nil {
+		logging.FromContext(ctx).Error("Unable to get the dead letter sink's URI", zap.Error(err))
+		b.Status.MarkDeadLetterSinkFailed("DeadLetterSinkNotResolved", "Unable to get the dead letter sink's URI: %v", err)
+		return err
+	}
+	b.Status.MarkDeadLetterSinkResolved()
+	return nil
+}
+
 func (r *Reconciler) FinalizeKind(ctx context.Context, b *brokerv1beta1.Broker) pkgreconciler.Event {
 	logger := logging.FromContext(ctx)
 	logger.Debug("Finalizing Broker", zap.Any("broker", b))
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/knative-gcp/pkg/reconciler/celltenant"
 
@@ -29,6 +30,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
 
@@ -40,9 +42,12 @@ import (
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/network"
 	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/client/injection/ducks/duck/v1alpha1/resource"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/broker"
 	"github.com/google/knative-gcp/pkg/reconciler"
@@ -92,6 +97,30 @@ var (
 			},
 		},
 	}
+	brokerDeliverySpecWithHTTPDeadLetterSink = &eventingduckv1beta1.DeliverySpec{
+		BackoffDelay:  &backoffDelay,
+		BackoffPolicy: &backoffPolicy,
+		Retry:         &retry,
+		DeadLetterSink: &duckv1.Destination{
+			URI: &apis.URL{
+				Scheme: "http",
+				Host:   "dls.example.com",
+			},
+		},
+	}
+	brokerDeliverySpecWithMissingDeadLetterSink = &eventingduckv1beta1.DeliverySpec{
+		BackoffDelay:  &backoffDelay,
+		BackoffPolicy: &backoffPolicy,
+		Retry:         &retry,
+		DeadLetterSink: &duckv1.Destination{
+			Ref: &duckv1.KReference{
+				APIVersion: "serving.knative.dev/v1",
+				Kind:       "Service",
+				Namespace:  testNS,
+				Name:       "missing-dls",
+			},
+		},
+	}
 )
 
 func init() {
@@ -172,6 +201,8 @@ func TestAllCases(t *testing.T) {
 				WithBrokerUID(testUID),
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
 				WithBrokerSetDefaults,
 			),
 		}},
@@ -190,6 +221,206 @@ func TestAllCases(t *testing.T) {
 		PostConditions: []func(*testing.T, *TableRow){
 			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
 			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionHasMessageOrdering("cre-bkr_testnamespace_test-broker_abc123", false),
+		},
+	}, {
+		Name: "Create broker with an HTTP dead letter sink, dead letter sink is resolved",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpecWithHTTPDeadLetterSink),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpecWithHTTPDeadLetterSink),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+	}, {
+		Name: "Create broker with a missing dead letter sink, broker is created and the error is reported",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpecWithMissingDeadLetterSink),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpecWithMissingDeadLetterSink),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkFailed("DeadLetterSinkNotResolved", `Unable to get the dead letter sink's URI: services.serving.knative.dev "missing-dls" not found`),
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeWarning, "InternalError", `services.serving.knative.dev "missing-dls" not found`),
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		WantErr: true,
+	}, {
+		Name: "Create ordered broker, subscription is created with message ordering",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotationKey, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotationKey, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionHasMessageOrdering("cre-bkr_testnamespace_test-broker_abc123", true),
+		},
+	}, {
+		Name: "Create broker with retention, retention subscription is created",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.RetentionAnnotationKey, "24h"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.RetentionAnnotationKey, "24h"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr-ret_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions("cre-bkr_testnamespace_test-broker_abc123", "cre-bkr-ret_testnamespace_test-broker_abc123"),
+			SubscriptionHasRetentionDuration("cre-bkr-ret_testnamespace_test-broker_abc123", 24*time.Hour),
+		},
+	}, {
+		Name: "Retention removed, retention subscription is deleted",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionDeleted", `Deleted PubSub subscription "cre-bkr-ret_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				TopicAndSub("cre-bkr_testnamespace_test-broker_abc123", "cre-bkr-ret_testnamespace_test-broker_abc123"),
+			},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions("cre-bkr_testnamespace_test-broker_abc123"),
 		},
 	}, {
 		Name: "Create broker with unready brokercell, broker is created",
@@ -212,6 +443,8 @@ func TestAllCases(t *testing.T) {
 				WithBrokerUID(testUID),
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
 				WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell knative-testing/default is not ready"),
 				WithBrokerSetDefaults,
 			),
@@ -253,12 +486,14 @@ func TestAllCases(t *testing.T) {
 					WithBrokerUID(testUID),
 					WithBrokerDeliverySpec(brokerDeliverySpec),
 					WithInitBrokerConditions,
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
 					WithBrokerBrokerCellFailed("BrokerCellCreationFailed", "Failed to create BrokerCell knative-testing/default"),
 					WithBrokerSetDefaults,
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokercell doesn't require broker information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(types.NamespacedName{Namespace: systemNS, Name: resources.DefaultBrokerCellName})},
 		SkipNamespaceValidation: true, // The brokercell resource is created in a different namespace (system namespace) than the broker
 		WantEvents: []string{
 			brokerFinalizerUpdatedEvent,
@@ -286,12 +521,14 @@ func TestAllCases(t *testing.T) {
 					WithBrokerUID(testUID),
 					WithBrokerDeliverySpec(brokerDeliverySpec),
 					WithBrokerReadyURI(brokerAddress),
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
 					WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell knative-testing/default is not ready"),
 					WithBrokerSetDefaults,
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokercell doesn't require broker information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(types.NamespacedName{Namespace: systemNS, Name: resources.DefaultBrokerCellName})},
 		SkipNamespaceValidation: true, // The brokercell resource is created in a different namespace (system namespace) than the broker
 		WantEvents: []string{
 			brokerFinalizerUpdatedEvent,
@@ -314,6 +551,179 @@ func TestAllCases(t *testing.T) {
 			}),
 			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
 		},
+	}, {
+		Name: "Create broker with brokercell annotation, brokercell is created in the broker namespace",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "team-a"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults,
+			),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+			{
+				Object: NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "team-a"),
+					WithBrokerUID(testUID),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerReadyURI(&apis.URL{
+						Scheme: "http",
+						Host:   fmt.Sprintf("team-a-brokercell-ingress.%s.svc.%s", testNS, network.GetClusterDomainName()),
+						Path:   ingress.BrokerPath(testNS, brokerName),
+					}),
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
+					WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell testnamespace/team-a is not ready"),
+					WithBrokerSetDefaults,
+				),
+			},
+		},
+		WantCreates: []runtime.Object{resources.CreateBrokerCell(types.NamespacedName{Namespace: testNS, Name: "team-a"})},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "BrokerCellCreated", `Created BrokerCell testnamespace/team-a`),
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+		},
+	}, {
+		Name: "Broker bound to a brokercell of another namespace, brokercell is not allowed",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "other/team-a"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults,
+			),
+			NewBrokerCell("team-a", "other",
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+			{
+				Object: NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "other/team-a"),
+					WithBrokerUID(testUID),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithInitBrokerConditions,
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
+					WithBrokerBrokerCellFailed("BrokerCellNotAllowed", "BrokerCell other/team-a is neither in namespace testnamespace nor in the system namespace"),
+					WithBrokerSetDefaults,
+				),
+			},
+		},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			// The annotation is rejected by validation, including on status updates, so the
+			// reconcile error is only reported in this event.
+			Eventf(corev1.EventTypeWarning, "UpdateFailed", `Failed to update status for "test-broker": BrokerCell must be in the namespace of the Broker or in the system namespace: metadata.annotations.events.cloud.google.com/brokerCell`),
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		WantErr: true,
+	}, {
+		Name: "Broker bound to a missing brokercell of the system namespace, brokercell is not created",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, systemNS+"/team-a"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults,
+			),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+			{
+				Object: NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, systemNS+"/team-a"),
+					WithBrokerUID(testUID),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithInitBrokerConditions,
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
+					WithBrokerBrokerCellFailed("BrokerCellNotFound", "BrokerCell knative-testing/team-a doesn't exist"),
+					WithBrokerSetDefaults,
+				),
+			},
+		},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeWarning, "InternalError", `failed to reconcile broker: brokercell reconcile failed: brokercell knative-testing/team-a not found`),
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		WantErr: true,
+	}, {
+		Name: "Broker moving to a brokercell that is not ready keeps its address",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "team-a"),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerSetDefaults,
+			),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+			NewBrokerCell("team-a", testNS,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+			{
+				Object: NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "team-a"),
+					WithBrokerUID(testUID),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerReadyURI(brokerAddress),
+					WithBrokerDataPlaneNotTracked,
+					WithBrokerDeadLetterSinkNotNeeded,
+					WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell testnamespace/team-a is not ready"),
+					WithBrokerSetDefaults,
+				),
+			},
+		},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+		},
 	}, {
 		Name: "Check topic config with correct data residency and label",
 		Key:  testKey,
@@ -333,6 +743,8 @@ func TestAllCases(t *testing.T) {
 				WithBrokerUID(testUID),
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
 				WithBrokerSetDefaults,
 			),
 		}},
@@ -378,6 +790,8 @@ func TestAllCases(t *testing.T) {
 				WithBrokerUID(testUID),
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
 				WithBrokerSetDefaults,
 			),
 		}},
@@ -419,6 +833,8 @@ func TestAllCases(t *testing.T) {
 				WithBrokerUID(testUID),
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
+				WithBrokerDataPlaneNotTracked,
+				WithBrokerDeadLetterSinkNotNeeded,
 				WithBrokerSetDefaults,
 				WithBrokerTopicUnknown("FinalizeTopicPubSubClientCreationFailed", "Failed to create Pub/Sub client: Invoke time 0 reaches the max invoke time 0"),
 				WithBrokerSubscriptionUnknown("FinalizeSubscriptionPubSubClientCreationFailed", "Failed to create Pub/Sub client: Invoke time 0 reaches the max invoke time 0"),
@@ -484,11 +900,89 @@ func TestAllCases(t *testing.T) {
 				DataresidencyStore: drStore,
 				ClusterRegion:      testClusterRegion,
 			},
+			uriResolver: resolver.NewURIResolver(ctx, func(types.NamespacedName) {}),
 		}
 		return brokerreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, listers.GetBrokerLister(), r.Recorder, r, brokerv1beta1.BrokerClass)
 	}))
 }
 
+func TestCheckDataPlane(t *testing.T) {
+	newBroker := func(opts ...BrokerOption) *brokerv1beta1.Broker {
+		b := NewBroker(brokerName, testNS, append([]BrokerOption{WithBrokerUID(testUID), WithBrokerGeneration(2)}, opts...)...)
+		b.Status.InitializeConditions()
+		return b
+	}
+	applied := func(generation int64) *config.TargetsConfig {
+		return &config.TargetsConfig{CellTenants: map[string]*config.CellTenant{
+			config.TestOnlyBrokerKey(testNS, brokerName).PersistenceString(): {
+				Id:         testUID,
+				Type:       config.CellTenantType_BROKER,
+				Namespace:  testNS,
+				Name:       brokerName,
+				Generation: generation,
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name       string
+		notServing bool
+		applied    *config.TargetsConfig
+		broker     *brokerv1beta1.Broker
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{{
+		name:       "data plane not tracked",
+		notServing: true,
+		broker:     newBroker(),
+		wantStatus: corev1.ConditionTrue,
+		wantReason: "NotTracked",
+	}, {
+		name:       "data plane not reported",
+		broker:     newBroker(),
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "DataPlaneNotReported",
+	}, {
+		name:       "data plane ready until reported",
+		broker:     newBroker(WithBrokerDataPlaneReady),
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "older generation applied",
+		applied:    applied(1),
+		broker:     newBroker(WithBrokerDataPlaneReady),
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "ConfigNotApplied",
+	}, {
+		name:       "latest generation applied",
+		applied:    applied(2),
+		broker:     newBroker(),
+		wantStatus: corev1.ConditionTrue,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := stream.NewLocalServer(time.Second)
+			defer s.Close()
+			r := &Reconciler{targetsServer: s.Server}
+			if tc.notServing {
+				r.targetsServer = stream.NewServer()
+			}
+			if tc.applied != nil {
+				version, err := s.Publish(systemNS, resources.DefaultBrokerCellName, tc.applied)
+				if err != nil {
+					t.Fatalf("Failed to publish config: %v", err)
+				}
+				s.Report(systemNS, resources.DefaultBrokerCellName, "pod", version)
+			}
+
+			r.checkDataPlane(tc.broker)
+			got := tc.broker.Status.GetCondition(brokerv1beta1.BrokerConditionDataPlane)
+			if got.Status != tc.wantStatus || got.Reason != tc.wantReason {
+				t.Errorf("Unexpected data plane condition, got %v, want status %v and reason %q", got, tc.wantStatus, tc.wantReason)
+			}
+		})
+	}
+}
+
 func patchFinalizers(namespace, name, finalizer string) clientgotesting.PatchActionImpl {
 	action := clientgotesting.PatchActionImpl{}
 	action.Name = name
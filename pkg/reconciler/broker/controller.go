@@ -23,16 +23,19 @@ import (
 
 	"github.com/google/knative-gcp/pkg/apis/configs/brokerdelivery"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/google/knative-gcp/pkg/logging"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection"
+	"knative.dev/pkg/resolver"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
@@ -40,6 +43,7 @@ import (
 	brokercellinformer "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/broker"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"github.com/google/knative-gcp/pkg/utils"
 )
@@ -53,13 +57,13 @@ const (
 type Constructor injection.ControllerConstructor
 
 // NewConstructor creates a constructor to make a Broker controller.
-func NewConstructor(brokerdeliveryss *brokerdelivery.StoreSingleton, dataresidencyss *dataresidency.StoreSingleton) Constructor {
+func NewConstructor(brokerdeliveryss *brokerdelivery.StoreSingleton, dataresidencyss *dataresidency.StoreSingleton, targetsServer *stream.Server) Constructor {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-		return newController(ctx, cmw, brokerdeliveryss.Store(ctx, cmw), dataresidencyss.Store(ctx, cmw))
+		return newController(ctx, cmw, brokerdeliveryss.Store(ctx, cmw), dataresidencyss.Store(ctx, cmw), targetsServer)
 	}
 }
 
-func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdelivery.Store, drs *dataresidency.Store) *controller.Impl {
+func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdelivery.Store, drs *dataresidency.Store, targetsServer *stream.Server) *controller.Impl {
 	brokerInformer := brokerinformer.Get(ctx)
 	bcInformer := brokercellinformer.Get(ctx)
 
@@ -93,6 +97,7 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 			PubsubClient:       client,
 			DataresidencyStore: drs,
 		},
+		targetsServer: targetsServer,
 	}
 
 	impl := brokerreconciler.NewImpl(ctx, r, brokerv1beta1.BrokerClass,
@@ -102,6 +107,8 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 			}
 		})
 
+	r.uriResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
+
 	r.Logger.Info("Setting up event handlers")
 
 	brokerInformer.Informer().AddEventHandlerWithResyncPeriod(
@@ -115,21 +122,35 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 
 	bcInformer.Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
-			if _, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
-				// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-				// webhook assigns the brokercell label, i.e.,
-				// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+			if bc, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
 				brokers, err := brokerInformer.Lister().List(labels.Everything())
 				if err != nil {
 					r.Logger.Error("Failed to list brokers", zap.Error(err))
 					return
 				}
+				key := types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}
 				for _, broker := range brokers {
-					impl.Enqueue(broker)
+					if brokerresources.ServedByBrokerCell(broker, key) {
+						impl.Enqueue(broker)
+					}
 				}
 			}
 		},
 	))
 
+	// Watch the targets config applied by the data plane.
+	targetsServer.OnApplied(func(namespace, name string) {
+		tc, ok := targetsServer.Applied(namespace, name)
+		if !ok {
+			return
+		}
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		impl.FilteredGlobalResync(func(obj interface{}) bool {
+			b, ok := obj.(*brokerv1beta1.Broker)
+			return ok && reconcilerutils.BrokerClassFilter(b) && brokerresources.BrokerCellKey(b) == key &&
+				tc.HasBrokerGeneration(b) != b.Status.GetCondition(brokerv1beta1.BrokerConditionDataPlane).IsTrue()
+		}, brokerInformer.Informer())
+	})
+
 	return impl
 }
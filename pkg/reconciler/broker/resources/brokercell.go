@@ -19,24 +19,57 @@ package resources
 import (
 	"github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/network"
 	"knative.dev/pkg/system"
 
 	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	brokercellresources "github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 )
 
-// Right now we only support one brokercell in the system namespace in the cluster.
-// TODO(#866) Delete hard-coded brokercell once we can dynamically assign brokercell to brokers.
+// DefaultBrokerCellName is the name of the BrokerCell in the system namespace that serves the
+// brokers without a brokerCell annotation.
 const DefaultBrokerCellName = "default"
 
-// TODO Replace the Broker parameter with a generic CellTenant interface that has a method to get
-// the name of the BrokerCell to create.
-func CreateBrokerCell(_ *v1beta1.Broker) *inteventsv1alpha1.BrokerCell {
-	// TODO(#866) Get brokercell from the label (or annotation) from the broker.
+// BrokerCellKey returns the namespace and name of the BrokerCell the broker is bound to: the
+// BrokerCell selected by its brokerCell annotation, or the default BrokerCell in the system
+// namespace.
+func BrokerCellKey(b *v1beta1.Broker) types.NamespacedName {
+	if namespace, name, ok := b.BrokerCellRef(); ok {
+		return types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return DefaultBrokerCellKey()
+}
+
+// DefaultBrokerCellKey returns the namespace and name of the default BrokerCell.
+func DefaultBrokerCellKey() types.NamespacedName {
+	return types.NamespacedName{Namespace: system.Namespace(), Name: DefaultBrokerCellName}
+}
+
+// CreateBrokerCell returns the BrokerCell to create for the brokers bound to the given BrokerCell
+// key. It is garbage collected once no broker is bound to it.
+func CreateBrokerCell(key types.NamespacedName) *inteventsv1alpha1.BrokerCell {
 	return &inteventsv1alpha1.BrokerCell{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace:   system.Namespace(),
-			Name:        DefaultBrokerCellName,
+			Namespace:   key.Namespace,
+			Name:        key.Name,
 			Annotations: map[string]string{inteventsv1alpha1.CreatorKey: inteventsv1alpha1.Creator},
 		},
 	}
 }
+
+// ServedByBrokerCell returns true if the BrokerCell with the given key serves the broker, i.e. the
+// broker is bound to it, or the broker's address still points to its ingress while the broker
+// moves to another BrokerCell. Brokers are never bound to the BrokerCells of other namespaces than
+// their own and the system namespace.
+func ServedByBrokerCell(b *v1beta1.Broker, key types.NamespacedName) bool {
+	if BrokerCellKey(b) == key {
+		return v1beta1.IsBrokerCellNamespaceAllowed(b.Namespace, key.Namespace)
+	}
+	if b.Status.Address.URL == nil {
+		return false
+	}
+	//TODO(#1019) Use the IngressTemplate of brokercell.
+	ingressServiceName := brokercellresources.Name(key.Name, brokercellresources.IngressName)
+	return b.Status.Address.URL.Host == network.GetServiceHostname(ingressServiceName, key.Namespace)
+}
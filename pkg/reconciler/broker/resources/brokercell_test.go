@@ -19,10 +19,101 @@ package resources
 import (
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/system"
 	_ "knative.dev/pkg/system/testing"
+
+	"github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 )
 
-// This is already tested in broker_test.go, this test is just to make coverage tool happy.
-func TestBrokerCellCreation(t *testing.T) {
-	CreateBrokerCell(nil)
+func TestBrokerCellKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        types.NamespacedName
+	}{{
+		name: "default brokercell",
+		want: types.NamespacedName{Namespace: system.Namespace(), Name: DefaultBrokerCellName},
+	}, {
+		name:        "brokercell in the namespace of the broker",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "team-a"},
+		want:        types.NamespacedName{Namespace: "ns", Name: "team-a"},
+	}, {
+		name:        "brokercell in another namespace",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "shared/team-a"},
+		want:        types.NamespacedName{Namespace: "shared", Name: "team-a"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &v1beta1.Broker{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "broker", Annotations: tc.annotations}}
+			if got := BrokerCellKey(b); got != tc.want {
+				t.Errorf("BrokerCellKey() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCreateBrokerCell(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "team-a"}
+	bc := CreateBrokerCell(key)
+	if bc.Namespace != key.Namespace || bc.Name != key.Name {
+		t.Errorf("CreateBrokerCell() created %s/%s, want %v", bc.Namespace, bc.Name, key)
+	}
+	if bc.Annotations[inteventsv1alpha1.CreatorKey] != inteventsv1alpha1.Creator {
+		t.Errorf("CreateBrokerCell() annotations = %v, want the creator annotation", bc.Annotations)
+	}
+}
+
+func TestServedByBrokerCell(t *testing.T) {
+	defaultKey := types.NamespacedName{Namespace: system.Namespace(), Name: DefaultBrokerCellName}
+	teamKey := types.NamespacedName{Namespace: "ns", Name: "team-a"}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		address     *apis.URL
+		key         types.NamespacedName
+		want        bool
+	}{{
+		name: "bound to the default brokercell",
+		key:  defaultKey,
+		want: true,
+	}, {
+		name:        "bound to another brokercell",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "team-a"},
+		key:         defaultKey,
+		want:        false,
+	}, {
+		name:        "bound to the brokercell by annotation",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "team-a"},
+		key:         teamKey,
+		want:        true,
+	}, {
+		name:        "moving away from the brokercell",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "team-a"},
+		address:     &apis.URL{Scheme: "http", Host: "default-brokercell-ingress." + system.Namespace() + ".svc.cluster.local", Path: "/ns/broker"},
+		key:         defaultKey,
+		want:        true,
+	}, {
+		name:    "moving to the brokercell",
+		address: &apis.URL{Scheme: "http", Host: "team-a-brokercell-ingress.ns.svc.cluster.local", Path: "/ns/broker"},
+		key:     teamKey,
+		want:    true,
+	}, {
+		name:        "bound to a brokercell of another namespace",
+		annotations: map[string]string{v1beta1.BrokerCellAnnotationKey: "other/team-a"},
+		key:         types.NamespacedName{Namespace: "other", Name: "team-a"},
+		want:        false,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &v1beta1.Broker{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "broker", Annotations: tc.annotations}}
+			b.Status.SetAddress(tc.address)
+			if got := ServedByBrokerCell(b, tc.key); got != tc.want {
+				t.Errorf("ServedByBrokerCell() = %v, want %v", got, tc.want)
+			}
+		})
+	}
 }
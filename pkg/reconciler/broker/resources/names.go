@@ -60,3 +60,25 @@ func GenerateRetryTopicName(t *brokerv1beta1.Trigger) string {
 func GenerateRetrySubscriptionName(t *brokerv1beta1.Trigger) string {
 	return naming.TruncatedPubsubResourceName("cre-tgr", t.Namespace, t.Name, t.UID)
 }
+
+// GenerateRetentionSubscriptionName generates a deterministic name for the
+// subscription retaining the events of a Broker. If the subscription name
+// would be longer than allowed by PubSub, the Broker name is truncated to fit.
+func GenerateRetentionSubscriptionName(b *brokerv1beta1.Broker) string {
+	return naming.TruncatedPubsubResourceName("cre-bkr-ret", b.Namespace, b.Name, b.UID)
+}
+
+// GenerateReplaySubscriptionName generates a deterministic name for the
+// subscription replaying the retained events of a Broker to a Trigger. If the
+// subscription name would be longer than allowed by PubSub, the Trigger name is
+// truncated to fit.
+func GenerateReplaySubscriptionName(t *brokerv1beta1.Trigger) string {
+	return naming.TruncatedPubsubResourceName("cre-tgr-rpl", t.Namespace, t.Name, t.UID)
+}
+
+// GenerateReplaySnapshotName generates a deterministic name for the snapshot
+// seeding the replay subscription of a Trigger. If the snapshot name would be
+// longer than allowed by PubSub, the Trigger name is truncated to fit.
+func GenerateReplaySnapshotName(t *brokerv1beta1.Trigger) string {
+	return naming.TruncatedPubsubResourceName("cre-tgr-rpl", t.Namespace, t.Name, t.UID)
+}
@@ -193,6 +193,22 @@ func TestGenerateRetrySubscriptionName(t *testing.T) {
 	}
 }
 
+func TestGenerateReplayNames(t *testing.T) {
+	b := broker("default", "default", testUID)
+	if got, want := GenerateRetentionSubscriptionName(b), fmt.Sprintf("cre-bkr-ret_default_default_%s", testUID); got != want {
+		t.Errorf("retention subscription name got=%s, want=%s", got, want)
+	}
+	tr := trigger(maxNamespace, maxName, testUID)
+	for _, got := range []string{GenerateReplaySubscriptionName(tr), GenerateReplaySnapshotName(tr)} {
+		if len(got) > naming.PubsubMax {
+			t.Errorf("name length %d is greater than %d", len(got), naming.PubsubMax)
+		}
+		if !strings.HasPrefix(got, "cre-tgr-rpl_") || !strings.HasSuffix(got, testUID) {
+			t.Errorf("unexpected replay name %s", got)
+		}
+	}
+}
+
 func broker(ns, n, uid string) *brokerv1beta1.Broker {
 	return &brokerv1beta1.Broker{
 		ObjectMeta: metav1.ObjectMeta{
@@ -18,11 +18,20 @@ package brokercell
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/knative-gcp/pkg/logging"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/eventing/pkg/apis/eventing"
 
@@ -40,6 +49,10 @@ const (
 	configFailed = "BrokerTargetsConfigFailed"
 )
 
+// errDeadLetterNotResolved is returned when the dead letter sinks of some brokers can't be resolved.
+// The targets config is still updated, and the BrokerCell is reconciled again to resolve them.
+var errDeadLetterNotResolved = errors.New("unable to resolve the dead letter sinks of brokers")
+
 func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.BrokerCell) error {
 	// Start with a fresh config and add into it. This approach is straightforward and reliable,
 	// however not efficient if there are too many triggers/subscriptions. If performance becomes
@@ -47,9 +60,9 @@ func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.Broker
 	// update the config for updated triggers/subscriptions.
 	targets := memory.NewEmptyTargets()
 
-	err := r.addBrokersAndTriggersToTargets(ctx, bc, targets)
-	if err != nil {
-		return fmt.Errorf("unable to add Broker and Triggers to targets: %w", err)
+	deadLetterErr := r.addBrokersAndTriggersToTargets(ctx, bc, targets)
+	if deadLetterErr != nil && !errors.Is(deadLetterErr, errDeadLetterNotResolved) {
+		return fmt.Errorf("unable to add Broker and Triggers to targets: %w", deadLetterErr)
 	}
 
 	if err := r.updateTargetsConfig(ctx, bc, targets); err != nil {
@@ -58,23 +71,22 @@ func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.Broker
 		return err
 	}
 	bc.Status.MarkTargetsConfigReady()
-	return nil
+	return deadLetterErr
 }
 
 // addBrokersAndTriggersToTargets adds all Brokers that are associated with the `bc` BrokerCell to
-// `targets`, along with all Triggers that target those Brokers.
+// `targets`, along with all Triggers that target those Brokers. It returns errDeadLetterNotResolved
+// if the dead letter sinks of some brokers can't be resolved, once all brokers are added.
 func (r *Reconciler) addBrokersAndTriggersToTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets) error {
-	// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
 	brokers, err := r.brokerLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list brokers", zap.Error(err))
 		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list brokers: %v", err)
 		return err
 	}
+	var unresolved []string
 	for _, broker := range brokers {
-		if !utils.BrokerClassFilter(broker) {
+		if !utils.BrokerClassFilter(broker) || !brokerresources.ServedByBrokerCell(broker, types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}) {
 			continue
 		}
 		// Filter by `eventing.knative.dev/broker: <name>` here
@@ -86,13 +98,23 @@ func (r *Reconciler) addBrokersAndTriggersToTargets(ctx context.Context, bc *int
 			bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list triggers for broker %v: %v", broker.Name, err)
 			return err
 		}
-		addBrokerAndTriggersToConfig(ctx, broker, triggers, targets)
+		deadLetter, err := r.deadLetterFromBroker(ctx, broker)
+		if err != nil {
+			// The events of the broker are retried rather than dead lettered until the sink is
+			// resolved. The broker reports the error in its status.
+			logging.FromContext(ctx).Error("Failed to resolve the dead letter sink", zap.String("Broker", broker.Name), zap.Error(err))
+			unresolved = append(unresolved, fmt.Sprintf("%s/%s", broker.Namespace, broker.Name))
+		}
+		addBrokerAndTriggersToConfig(ctx, broker, triggers, deadLetter, targets)
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("%w: %s", errDeadLetterNotResolved, strings.Join(unresolved, ", "))
 	}
 	return nil
 }
 
 // addBrokerAndTriggersToConfig reconstructs the data entry for the given broker and adds it to targets-config.
-func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1beta1.Broker, triggers []*brokerv1beta1.Trigger, brokerTargets config.Targets) {
+func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1beta1.Broker, triggers []*brokerv1beta1.Trigger, deadLetter *config.DeadLetter, brokerTargets config.Targets) {
 	// TODO Maybe get rid of GCPCellAddressableMutation and add Delete() and Upsert(broker) methods to TargetsConfig. Now we always
 	//  delete or update the entire broker entry and we don't need partial updates per trigger.
 	// The code can be simplified to r.targetsConfig.Upsert(brokerConfigEntry)
@@ -114,11 +136,17 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1beta1.Broker, tr
 			Subscription: brokerresources.GenerateDecouplingSubscriptionName(b),
 			State:        brokerQueueState,
 		})
-		if b.Status.IsReady() {
+		// The data plane readiness of the broker waits for this config, so it must not affect its state.
+		if b.Status.IsControlPlaneReady() {
 			m.SetState(config.State_READY)
 		} else {
 			m.SetState(config.State_UNKNOWN)
 		}
+		m.SetRateLimit(rateLimitFromBroker(b))
+		m.SetOrderingKeyExtension(b.GetAnnotations()[brokerv1beta1.OrderingKeyExtensionAnnotationKey])
+		m.SetIngressAuth(ingressAuthFromBroker(b))
+		m.SetDeadLetter(deadLetter)
+		m.SetGeneration(b.Generation)
 
 		// Insert each Trigger to the config.
 		for _, t := range triggers {
@@ -138,22 +166,225 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1beta1.Broker, tr
 				if t.Spec.Filter != nil && t.Spec.Filter.Attributes != nil {
 					target.FilterAttributes = t.Spec.Filter.Attributes
 				}
-				// TODO(#939) May need to use "data plane readiness" for trigger in stead of the
-				//  overall status, see https://github.com/google/knative-gcp/issues/939#issuecomment-644337937
-				if t.Status.IsReady() {
-					target.State = config.State_READY
-				} else {
-					target.State = config.State_UNKNOWN
-				}
+				target.Filters = filtersFromTrigger(t)
+				target.DeliveryLimit = deliveryLimitFromTrigger(t)
+				target.SubscriberAuth = subscriberAuthFromTrigger(t)
+				target.BatchPolicy = batchPolicyFromTrigger(t)
+				target.Replay = replayFromTrigger(t, b)
+				target.MaxEventAgeMillis = maxEventAgeFromTrigger(t, b)
+				target.State = targetStateFromTrigger(t)
+				target.Generation = t.Generation
 				m.UpsertTargets(target)
 			}
 		}
 	})
 }
 
+// targetStateFromTrigger returns the state of the target of the trigger.
+func targetStateFromTrigger(t *brokerv1beta1.Trigger) config.State {
+	switch {
+	case t.IsSuspended():
+		// Even if the trigger isn't ready, its subscriber must not receive events while it's
+		// suspended.
+		return config.State_SUSPENDED
+	// The data plane readiness of the trigger waits for this config, so it must not affect its state.
+	case t.Status.IsControlPlaneReady():
+		return config.State_READY
+	default:
+		return config.State_UNKNOWN
+	}
+}
+
+// rateLimitFromBroker returns the ingress rate limit set through the annotations of the broker, or
+// nil if the broker has no rate limit. Invalid annotation values are rejected by the webhook and are
+// ignored here.
+func rateLimitFromBroker(b *brokerv1beta1.Broker) *config.RateLimit {
+	annotations := b.GetAnnotations()
+	eventsPerSecond := parsePositiveFloat(annotations[brokerv1beta1.IngressEventsPerSecondAnnotationKey])
+	bytesPerSecond := parsePositiveFloat(annotations[brokerv1beta1.IngressBytesPerSecondAnnotationKey])
+	if eventsPerSecond == 0 && bytesPerSecond == 0 {
+		return nil
+	}
+	return &config.RateLimit{
+		EventsPerSecond: eventsPerSecond,
+		EventsBurst:     burstOrDefault(annotations[brokerv1beta1.IngressEventsBurstAnnotationKey], eventsPerSecond),
+		BytesPerSecond:  bytesPerSecond,
+		BytesBurst:      burstOrDefault(annotations[brokerv1beta1.IngressBytesBurstAnnotationKey], bytesPerSecond),
+	}
+}
+
+func parsePositiveFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f <= 0 {
+		return 0
+	}
+	return f
+}
+
+// burstOrDefault parses the burst annotation value. If it is not set, the burst defaults to the
+// rate rounded up, so that a full second worth of traffic can be accepted at once.
+func burstOrDefault(s string, rate float64) int64 {
+	if rate == 0 {
+		return 0
+	}
+	if burst, err := strconv.ParseInt(s, 10, 64); err == nil && burst > 0 {
+		return burst
+	}
+	return int64(math.Ceil(rate))
+}
+
+// ingressAuthFromBroker returns the ingress authentication requirements set through the annotations
+// of the broker, or nil if requests to the broker don't need to be authenticated.
+func ingressAuthFromBroker(b *brokerv1beta1.Broker) *config.IngressAuth {
+	annotations := b.GetAnnotations()
+	audiences := brokerv1beta1.SplitAnnotationList(annotations[brokerv1beta1.IngressAllowedAudiencesAnnotationKey])
+	if len(audiences) == 0 {
+		return nil
+	}
+	return &config.IngressAuth{
+		Audiences: audiences,
+		Subjects:  brokerv1beta1.SplitAnnotationList(annotations[brokerv1beta1.IngressAllowedSubjectsAnnotationKey]),
+	}
+}
+
+// deadLetterFromBroker resolves the dead letter sink the retry pool delivers the events to once the
+// retries of the broker are exhausted. It returns nil if the broker has no such sink, either because
+// events are retried indefinitely or because Pub/Sub forwards them to a dead letter topic.
+func (r *Reconciler) deadLetterFromBroker(ctx context.Context, b *brokerv1beta1.Broker) (*config.DeadLetter, error) {
+	spec := b.Spec.Delivery
+	if spec == nil || spec.DeadLetterSink == nil || spec.Retry == nil || brokerv1beta1.IsPubsubDeadLetterSink(spec.DeadLetterSink) {
+		return nil, nil
+	}
+	uri, err := r.uriResolver.URIFromDestinationV1(ctx, *spec.DeadLetterSink, b)
+	if err != nil {
+		return nil, err
+	}
+	return &config.DeadLetter{
+		Address:             uri.String(),
+		MaxDeliveryAttempts: *spec.Retry,
+	}, nil
+}
+
+// filtersFromTrigger returns the Subscriptions API filters set through the annotations of the
+// trigger. Invalid annotation values are rejected by the webhook and are ignored here.
+func filtersFromTrigger(t *brokerv1beta1.Trigger) []*config.SubscriptionsAPIFilter {
+	filters, err := t.Filters()
+	if err != nil {
+		return nil
+	}
+	return filtersToConfig(filters)
+}
+
+func filtersToConfig(filters []brokerv1beta1.SubscriptionsAPIFilter) []*config.SubscriptionsAPIFilter {
+	if len(filters) == 0 {
+		return nil
+	}
+	out := make([]*config.SubscriptionsAPIFilter, 0, len(filters))
+	for i := range filters {
+		out = append(out, filterToConfig(&filters[i]))
+	}
+	return out
+}
+
+func filterToConfig(f *brokerv1beta1.SubscriptionsAPIFilter) *config.SubscriptionsAPIFilter {
+	out := &config.SubscriptionsAPIFilter{
+		All:    filtersToConfig(f.All),
+		Any:    filtersToConfig(f.Any),
+		Exact:  f.Exact,
+		Prefix: f.Prefix,
+		Suffix: f.Suffix,
+		Sql:    f.SQL,
+	}
+	if f.Not != nil {
+		out.Not = filterToConfig(f.Not)
+	}
+	return out
+}
+
+// deliveryLimitFromTrigger returns the delivery limits set through the annotations of the trigger,
+// or nil if the trigger has no delivery limit. Invalid annotation values are rejected by the webhook
+// and are ignored here.
+func deliveryLimitFromTrigger(t *brokerv1beta1.Trigger) *config.DeliveryLimit {
+	annotations := t.GetAnnotations()
+	var maxConcurrency int32
+	if i, err := strconv.ParseInt(annotations[brokerv1beta1.MaxConcurrencyAnnotationKey], 10, 32); err == nil && i > 0 {
+		maxConcurrency = int32(i)
+	}
+	maxRequestsPerSecond := parsePositiveFloat(annotations[brokerv1beta1.MaxRequestsPerSecondAnnotationKey])
+	if maxConcurrency == 0 && maxRequestsPerSecond == 0 {
+		return nil
+	}
+	return &config.DeliveryLimit{
+		MaxConcurrency:       maxConcurrency,
+		MaxRequestsPerSecond: maxRequestsPerSecond,
+	}
+}
+
+// subscriberAuthFromTrigger returns the authentication of the deliveries set through the annotations
+// of the trigger, or nil if the deliveries are not authenticated. Audiences that don't match the
+// resolved subscriber are reported by the trigger reconciler and no token is sent for them.
+func subscriberAuthFromTrigger(t *brokerv1beta1.Trigger) *config.SubscriberAuth {
+	audience, err := t.SubscriberAudience()
+	if err != nil || audience == "" {
+		return nil
+	}
+	return &config.SubscriberAuth{Audience: audience}
+}
+
+// batchPolicyFromTrigger returns the batching of the deliveries set through the annotations of the
+// trigger, or nil if events are delivered one at a time. Invalid annotation values are rejected by
+// the webhook and are ignored here.
+func batchPolicyFromTrigger(t *brokerv1beta1.Trigger) *config.BatchPolicy {
+	annotations := t.GetAnnotations()
+	maxCount, err := strconv.ParseInt(annotations[brokerv1beta1.BatchMaxCountAnnotationKey], 10, 32)
+	if err != nil || maxCount <= 0 {
+		return nil
+	}
+	policy := &config.BatchPolicy{MaxCount: int32(maxCount)}
+	if i, err := strconv.ParseInt(annotations[brokerv1beta1.BatchMaxBytesAnnotationKey], 10, 64); err == nil && i > 0 {
+		policy.MaxBytes = i
+	}
+	if d, err := time.ParseDuration(annotations[brokerv1beta1.BatchMaxLatencyAnnotationKey]); err == nil && d > 0 {
+		policy.MaxLatencyMillis = d.Milliseconds()
+	}
+	return policy
+}
+
+// replayFromTrigger returns the replay of the retained events of the broker to the trigger, or nil
+// if the trigger doesn't replay events. Events are only replayed once the trigger reconciler has
+// seeded the replay subscription.
+func replayFromTrigger(t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) *config.Replay {
+	if _, ok := t.GetAnnotations()[brokerv1beta1.ReplayFromAnnotationKey]; !ok {
+		return nil
+	}
+	_, until, ok := t.Status.ReplayWindow()
+	if !ok {
+		return nil
+	}
+	return &config.Replay{
+		Queue: &config.Queue{
+			Topic:        brokerresources.GenerateDecouplingTopicName(b),
+			Subscription: brokerresources.GenerateReplaySubscriptionName(t),
+		},
+		UntilMillis: until.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+// maxEventAgeFromTrigger returns the maximum age of the events delivered to the trigger in
+// milliseconds, set through the annotation of the trigger or else of its broker, or zero if events
+// don't expire.
+func maxEventAgeFromTrigger(t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) int64 {
+	for _, annotations := range []map[string]string{t.GetAnnotations(), b.GetAnnotations()} {
+		if d, err := time.ParseDuration(annotations[brokerv1beta1.MaxEventAgeAnnotationKey]); err == nil && d > 0 {
+			return d.Milliseconds()
+		}
+	}
+	return 0
+}
+
 //TODO all this stuff should be in a configmap variant of the config object
 func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) error {
-	desired, err := resources.MakeTargetsConfig(bc, brokerTargets)
+	desired, err := resources.MakeTargetsConfigs(bc, brokerTargets)
 	if err != nil {
 		return fmt.Errorf("error creating targets config: %w", err)
 	}
@@ -165,8 +396,58 @@ func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.Br
 		UpdateFunc: func(oldObj, newObj interface{}) { r.refreshPodVolume(ctx, bc) },
 		DeleteFunc: nil,
 	}
-	_, err = r.cmRec.ReconcileConfigMap(ctx, bc, desired, resources.TargetsConfigMapEqual, handlerFuncs)
-	return err
+	for _, cm := range desired {
+		if _, err := r.cmRec.ReconcileConfigMap(ctx, bc, cm, resources.TargetsConfigMapEqual, handlerFuncs); err != nil {
+			return err
+		}
+	}
+	if err := r.deleteStaleTargetsConfigs(ctx, bc, len(desired)); err != nil {
+		return err
+	}
+	r.publishTargetsConfig(ctx, bc, brokerTargets)
+	return nil
+}
+
+// publishTargetsConfig streams the targets config to the data plane, if enabled. The data plane
+// still gets the targets config from the ConfigMaps if it fails.
+func (r *Reconciler) publishTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) {
+	if r.targetsServer == nil {
+		return
+	}
+	data, err := brokerTargets.Bytes()
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to serialize targets config", zap.Error(err))
+		return
+	}
+	tc := &config.TargetsConfig{}
+	if err := proto.Unmarshal(data, tc); err != nil {
+		logging.FromContext(ctx).Error("Failed to deserialize targets config", zap.Error(err))
+		return
+	}
+	version, err := r.targetsServer.Publish(bc.Namespace, bc.Name, tc)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to publish targets config", zap.Error(err))
+		return
+	}
+	logging.FromContext(ctx).Debug("Published targets config", zap.String("version", version))
+}
+
+// deleteStaleTargetsConfigs deletes the shard ConfigMaps left over from a previous targets config
+// that was split into more shards than the current one. The data plane ignores them, since their
+// version doesn't match the first shard, so they are only deleted to avoid leaking them.
+func (r *Reconciler) deleteStaleTargetsConfigs(ctx context.Context, bc *intv1alpha1.BrokerCell, count int) error {
+	for i := count; i < config.MaxShards; i++ {
+		name := resources.TargetsConfigMapName(bc.Name, i)
+		if _, err := r.cmRec.Lister.ConfigMaps(bc.Namespace).Get(name); apierrs.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := r.KubeClientSet.CoreV1().ConfigMaps(bc.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return fmt.Errorf("error deleting stale targets config %s: %w", name, err)
+		}
+	}
+	return nil
 }
 
 func (r *Reconciler) refreshPodVolume(ctx context.Context, bc *intv1alpha1.BrokerCell) {
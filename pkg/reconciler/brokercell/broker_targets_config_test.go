@@ -0,0 +1,614 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
+
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestRateLimitFromBroker(t *testing.T) {
+	testCases := []struct {
+		name   string
+		broker *brokerv1beta1.Broker
+		want   *config.RateLimit
+	}{{
+		name:   "no annotations",
+		broker: NewBroker("broker", testNS),
+	}, {
+		name: "events per second with default burst",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressEventsPerSecondAnnotationKey, "10.5")),
+		want: &config.RateLimit{EventsPerSecond: 10.5, EventsBurst: 11},
+	}, {
+		name: "all annotations",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressEventsPerSecondAnnotationKey, "10"),
+			WithBrokerAnnotation(brokerv1beta1.IngressEventsBurstAnnotationKey, "100"),
+			WithBrokerAnnotation(brokerv1beta1.IngressBytesPerSecondAnnotationKey, "1000"),
+			WithBrokerAnnotation(brokerv1beta1.IngressBytesBurstAnnotationKey, "5000")),
+		want: &config.RateLimit{EventsPerSecond: 10, EventsBurst: 100, BytesPerSecond: 1000, BytesBurst: 5000},
+	}, {
+		name: "burst without rate is ignored",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressEventsBurstAnnotationKey, "100"),
+			WithBrokerAnnotation(brokerv1beta1.IngressBytesPerSecondAnnotationKey, "1000")),
+		want: &config.RateLimit{BytesPerSecond: 1000, BytesBurst: 1000},
+	}, {
+		name: "invalid values are ignored",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressEventsPerSecondAnnotationKey, "abc"),
+			WithBrokerAnnotation(brokerv1beta1.IngressBytesPerSecondAnnotationKey, "-1")),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rateLimitFromBroker(tc.broker)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("rateLimitFromBroker() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIngressAuthFromBroker(t *testing.T) {
+	testCases := []struct {
+		name   string
+		broker *brokerv1beta1.Broker
+		want   *config.IngressAuth
+	}{{
+		name:   "no annotations",
+		broker: NewBroker("broker", testNS),
+	}, {
+		name: "audiences",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressAllowedAudiencesAnnotationKey, "a, b")),
+		want: &config.IngressAuth{Audiences: []string{"a", "b"}},
+	}, {
+		name: "audiences and subjects",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressAllowedAudiencesAnnotationKey, "a"),
+			WithBrokerAnnotation(brokerv1beta1.IngressAllowedSubjectsAnnotationKey, "system:serviceaccount:ns:sa,sa@project.iam.gserviceaccount.com")),
+		want: &config.IngressAuth{
+			Audiences: []string{"a"},
+			Subjects:  []string{"system:serviceaccount:ns:sa", "sa@project.iam.gserviceaccount.com"},
+		},
+	}, {
+		name: "subjects without audiences are ignored",
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.IngressAllowedSubjectsAnnotationKey, "system:serviceaccount:ns:sa")),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ingressAuthFromBroker(tc.broker)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("ingressAuthFromBroker() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFiltersFromTrigger(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    []*config.SubscriptionsAPIFilter
+	}{{
+		name:    "no annotations",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+	}, {
+		name: "invalid annotation is ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.FiltersAnnotationKey, "not json")),
+	}, {
+		name: "nested filters",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.FiltersAnnotationKey,
+				`[{"prefix": {"type": "com.example."}}, {"not": {"any": [{"exact": {"source": "a"}}, {"suffix": {"subject": ".tmp"}}]}}, {"sql": "severity > 3"}]`)),
+		want: []*config.SubscriptionsAPIFilter{
+			{Prefix: map[string]string{"type": "com.example."}},
+			{Not: &config.SubscriptionsAPIFilter{Any: []*config.SubscriptionsAPIFilter{
+				{Exact: map[string]string{"source": "a"}},
+				{Suffix: map[string]string{"subject": ".tmp"}},
+			}}},
+			{Sql: "severity > 3"},
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := filtersFromTrigger(tc.trigger)
+			if len(got) != len(tc.want) {
+				t.Fatalf("filtersFromTrigger() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if !proto.Equal(tc.want[i], got[i]) {
+					t.Errorf("filtersFromTrigger()[%d] = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeliveryLimitFromTrigger(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    *config.DeliveryLimit
+	}{{
+		name:    "no annotations",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+	}, {
+		name: "max concurrency",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.MaxConcurrencyAnnotationKey, "10")),
+		want: &config.DeliveryLimit{MaxConcurrency: 10},
+	}, {
+		name: "all annotations",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.MaxConcurrencyAnnotationKey, "10"),
+			WithTriggerAnnotation(brokerv1beta1.MaxRequestsPerSecondAnnotationKey, "2.5")),
+		want: &config.DeliveryLimit{MaxConcurrency: 10, MaxRequestsPerSecond: 2.5},
+	}, {
+		name: "invalid values are ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.MaxConcurrencyAnnotationKey, "-1"),
+			WithTriggerAnnotation(brokerv1beta1.MaxRequestsPerSecondAnnotationKey, "fast")),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := deliveryLimitFromTrigger(tc.trigger)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("deliveryLimitFromTrigger() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubscriberAuthFromTrigger(t *testing.T) {
+	const subscriberURI = "https://subscriber.example.com/events"
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    *config.SubscriberAuth
+	}{{
+		name:    "no annotations",
+		trigger: NewTrigger("trigger", testNS, "broker", WithTriggerStatusSubscriberURI(subscriberURI)),
+	}, {
+		name: "audience",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerStatusSubscriberURI(subscriberURI),
+			WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "https://subscriber.example.com")),
+		want: &config.SubscriberAuth{Audience: "https://subscriber.example.com"},
+	}, {
+		name: "empty audience defaults to the subscriber origin",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerStatusSubscriberURI(subscriberURI),
+			WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "")),
+		want: &config.SubscriberAuth{Audience: "https://subscriber.example.com"},
+	}, {
+		name: "audience on another host is ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerStatusSubscriberURI(subscriberURI),
+			WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "https://victim.example.com")),
+	}, {
+		name: "audience of an unresolved subscriber is ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "https://subscriber.example.com")),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := subscriberAuthFromTrigger(tc.trigger)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("subscriberAuthFromTrigger() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBatchPolicyFromTrigger(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    *config.BatchPolicy
+	}{{
+		name:    "no annotations",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+	}, {
+		name: "count only",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxCountAnnotationKey, "100")),
+		want: &config.BatchPolicy{MaxCount: 100},
+	}, {
+		name: "all settings",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxCountAnnotationKey, "100"),
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxBytesAnnotationKey, "1000000"),
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxLatencyAnnotationKey, "1.5s")),
+		want: &config.BatchPolicy{MaxCount: 100, MaxBytes: 1000000, MaxLatencyMillis: 1500},
+	}, {
+		name: "invalid values are ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxCountAnnotationKey, "100"),
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxBytesAnnotationKey, "-1"),
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxLatencyAnnotationKey, "soon")),
+		want: &config.BatchPolicy{MaxCount: 100},
+	}, {
+		name: "no count",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.BatchMaxLatencyAnnotationKey, "1s")),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := batchPolicyFromTrigger(tc.trigger)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("batchPolicyFromTrigger() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMaxEventAgeFromTrigger(t *testing.T) {
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		broker  *brokerv1beta1.Broker
+		want    int64
+	}{{
+		name:    "no annotations",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+		broker:  NewBroker("broker", testNS),
+	}, {
+		name:    "broker max event age",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.MaxEventAgeAnnotationKey, "24h")),
+		want: 24 * 60 * 60 * 1000,
+	}, {
+		name: "trigger overrides broker",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.MaxEventAgeAnnotationKey, "90s")),
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.MaxEventAgeAnnotationKey, "24h")),
+		want: 90 * 1000,
+	}, {
+		name: "invalid trigger value is ignored",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.MaxEventAgeAnnotationKey, "2d")),
+		broker: NewBroker("broker", testNS,
+			WithBrokerAnnotation(brokerv1beta1.MaxEventAgeAnnotationKey, "1h")),
+		want: 60 * 60 * 1000,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := maxEventAgeFromTrigger(tc.trigger, tc.broker); got != tc.want {
+				t.Errorf("maxEventAgeFromTrigger() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplayFromTrigger(t *testing.T) {
+	from := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	until := from.Add(2 * time.Hour)
+	broker := NewBroker("broker", testNS, WithBrokerUID("broker-uid"))
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    *config.Replay
+	}{{
+		name:    "no replay",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+	}, {
+		name: "replay not ready",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, from.Format(time.RFC3339)),
+			WithTriggerReplayFailed("RetentionDisabled", "induced failure")),
+	}, {
+		name: "replay stopped",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerReplayReady(from, until)),
+	}, {
+		name: "replay ready",
+		trigger: NewTrigger("trigger", testNS, "broker",
+			WithTriggerUID("trigger-uid"),
+			WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, from.Format(time.RFC3339)),
+			WithTriggerReplayReady(from, until)),
+		want: &config.Replay{
+			Queue: &config.Queue{
+				Topic:        "cre-bkr_testnamespace_broker_broker-uid",
+				Subscription: "cre-tgr-rpl_testnamespace_trigger_trigger-uid",
+			},
+			UntilMillis: until.UnixNano() / int64(time.Millisecond),
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := replayFromTrigger(tc.trigger, broker)
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("replayFromTrigger() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTargetStateFromTrigger(t *testing.T) {
+	ready := []TriggerOption{
+		WithTriggerBrokerReady,
+		WithTriggerTopicReady,
+		WithTriggerSubscriptionReady,
+		WithTriggerSubscriberResolvedSucceeded,
+		WithTriggerFiltersReady,
+		WithTriggerDependencyReady,
+	}
+	suspended := WithTriggerAnnotation(brokerv1beta1.SuspendedAnnotationKey, "true")
+	testCases := []struct {
+		name    string
+		trigger *brokerv1beta1.Trigger
+		want    config.State
+	}{{
+		name:    "not ready",
+		trigger: NewTrigger("trigger", testNS, "broker"),
+		want:    config.State_UNKNOWN,
+	}, {
+		name:    "ready",
+		trigger: NewTrigger("trigger", testNS, "broker", ready...),
+		want:    config.State_READY,
+	}, {
+		name:    "ready before the data plane applied it",
+		trigger: NewTrigger("trigger", testNS, "broker", append(ready, WithTriggerDataPlaneUnknown("ConfigNotApplied", ""))...),
+		want:    config.State_READY,
+	}, {
+		name:    "suspended",
+		trigger: NewTrigger("trigger", testNS, "broker", append(ready, suspended)...),
+		want:    config.State_SUSPENDED,
+	}, {
+		name:    "suspended while not ready",
+		trigger: NewTrigger("trigger", testNS, "broker", suspended),
+		want:    config.State_SUSPENDED,
+	}, {
+		name:    "resumed",
+		trigger: NewTrigger("trigger", testNS, "broker", append(ready, WithTriggerAnnotation(brokerv1beta1.SuspendedAnnotationKey, "false"))...),
+		want:    config.State_READY,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := targetStateFromTrigger(tc.trigger); got != tc.want {
+				t.Errorf("targetStateFromTrigger() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddBrokerAndTriggersToConfigGenerations(t *testing.T) {
+	b := NewBroker("broker", testNS, WithBrokerGeneration(2), WithBrokerReady("broker.example.com"))
+	trigger := NewTrigger("trigger", testNS, "broker", WithTriggerGeneration(3))
+	targets := memory.NewEmptyTargets()
+	addBrokerAndTriggersToConfig(context.Background(), b, []*brokerv1beta1.Trigger{trigger}, nil, targets)
+
+	ct, ok := targets.GetCellTenantByKey(config.KeyFromBroker(b))
+	if !ok {
+		t.Fatal("Broker is missing from the targets")
+	}
+	if ct.Generation != 2 {
+		t.Errorf("Broker generation = %d, want 2", ct.Generation)
+	}
+	if got := ct.Targets["trigger"].GetGeneration(); got != 3 {
+		t.Errorf("Trigger generation = %d, want 3", got)
+	}
+}
+
+func TestAddBrokersAndTriggersToTargets(t *testing.T) {
+	bc := NewBrokerCell("cell", testNS)
+	objects := []runtime.Object{
+		bc,
+		NewBroker("bound", testNS, WithBrokerClass(brokerv1beta1.BrokerClass),
+			WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "cell")),
+		NewBroker("moving", testNS, WithBrokerClass(brokerv1beta1.BrokerClass),
+			WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "other"),
+			WithBrokerAddress("cell-brokercell-ingress."+testNS+".svc.cluster.local")),
+		NewBroker("other", testNS, WithBrokerClass(brokerv1beta1.BrokerClass),
+			WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "other")),
+		NewBroker("default", testNS, WithBrokerClass(brokerv1beta1.BrokerClass)),
+		NewTrigger("trigger", testNS, "bound"),
+	}
+	ls := NewListers(objects)
+	ctx, _ := SetupFakeContext(t)
+	r := &Reconciler{
+		listers: listers{
+			brokerLister:  ls.GetBrokerLister(),
+			triggerLister: ls.GetTriggerLister(),
+		},
+		uriResolver: resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {}),
+	}
+
+	targets := memory.NewEmptyTargets()
+	if err := r.addBrokersAndTriggersToTargets(ctx, bc, targets); err != nil {
+		t.Fatalf("addBrokersAndTriggersToTargets() = %v", err)
+	}
+	var got []string
+	targets.RangeCellTenants(func(ct *config.CellTenant) bool {
+		got = append(got, ct.Name)
+		return true
+	})
+	sort.Strings(got)
+	if want := []string{"bound", "moving"}; !cmp.Equal(got, want) {
+		t.Errorf("Brokers in the targets = %v, want %v", got, want)
+	}
+	if ct, ok := targets.GetCellTenantByKey(config.KeyFromBroker(NewBroker("bound", testNS))); !ok || ct.Targets["trigger"] == nil {
+		t.Errorf("Trigger is missing from the targets: %v", ct)
+	}
+}
+
+func TestAddBrokersAndTriggersToTargetsUnresolvedDeadLetter(t *testing.T) {
+	bc := NewBrokerCell("cell", testNS)
+	retry := int32(3)
+	objects := []runtime.Object{
+		bc,
+		NewBroker("broker", testNS, WithBrokerClass(brokerv1beta1.BrokerClass),
+			WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, "cell"),
+			WithBrokerDeliverySpec(&eventingduckv1beta1.DeliverySpec{
+				DeadLetterSink: &duckv1.Destination{
+					Ref: &duckv1.KReference{APIVersion: "serving.knative.dev/v1", Kind: "Service", Namespace: testNS, Name: "dls"},
+				},
+				Retry: &retry,
+			})),
+		NewTrigger("trigger", testNS, "broker"),
+	}
+	ls := NewListers(objects)
+	ctx, _ := SetupFakeContext(t)
+	r := &Reconciler{
+		listers: listers{
+			brokerLister:  ls.GetBrokerLister(),
+			triggerLister: ls.GetTriggerLister(),
+		},
+		uriResolver: resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {}),
+	}
+
+	targets := memory.NewEmptyTargets()
+	if err := r.addBrokersAndTriggersToTargets(ctx, bc, targets); !errors.Is(err, errDeadLetterNotResolved) {
+		t.Errorf("addBrokersAndTriggersToTargets() = %v, want %v", err, errDeadLetterNotResolved)
+	}
+	ct, ok := targets.GetCellTenantByKey(config.KeyFromBroker(NewBroker("broker", testNS)))
+	if !ok || ct.Targets["trigger"] == nil {
+		t.Fatalf("Broker or trigger is missing from the targets: %v", ct)
+	}
+	if ct.DeadLetter != nil {
+		t.Errorf("DeadLetter = %v, want nil until the sink is resolved", ct.DeadLetter)
+	}
+}
+
+func TestDeadLetterFromBroker(t *testing.T) {
+	retry := int32(3)
+	deliverySpec := func(dls *duckv1.Destination, retry *int32) *eventingduckv1beta1.DeliverySpec {
+		return &eventingduckv1beta1.DeliverySpec{DeadLetterSink: dls, Retry: retry}
+	}
+	httpSink := &duckv1.Destination{URI: apis.HTTP("dls.example.com")}
+	testCases := []struct {
+		name    string
+		broker  *brokerv1beta1.Broker
+		want    *config.DeadLetter
+		wantErr bool
+	}{{
+		name:   "no delivery spec",
+		broker: NewBroker("broker", testNS),
+	}, {
+		name: "pubsub dead letter topic",
+		broker: NewBroker("broker", testNS,
+			WithBrokerDeliverySpec(deliverySpec(&duckv1.Destination{URI: &apis.URL{Scheme: "pubsub", Host: "topic"}}, &retry))),
+	}, {
+		name:   "no retry",
+		broker: NewBroker("broker", testNS, WithBrokerDeliverySpec(deliverySpec(httpSink, nil))),
+	}, {
+		name:   "http dead letter sink",
+		broker: NewBroker("broker", testNS, WithBrokerDeliverySpec(deliverySpec(httpSink, &retry))),
+		want:   &config.DeadLetter{Address: "http://dls.example.com", MaxDeliveryAttempts: 3},
+	}, {
+		name: "unresolvable dead letter sink",
+		broker: NewBroker("broker", testNS,
+			WithBrokerDeliverySpec(deliverySpec(&duckv1.Destination{
+				Ref: &duckv1.KReference{APIVersion: "serving.knative.dev/v1", Kind: "Service", Namespace: testNS, Name: "dls"},
+			}, &retry))),
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := SetupFakeContext(t)
+			r := &Reconciler{uriResolver: resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})}
+			got, err := r.deadLetterFromBroker(ctx, tc.broker)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("deadLetterFromBroker() error = %v, want error %v", err, tc.wantErr)
+			}
+			if !proto.Equal(tc.want, got) {
+				t.Errorf("deadLetterFromBroker() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPublishTargetsConfig(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	bc := NewBrokerCell("cell", testNS)
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(config.KeyFromBroker(NewBroker("broker", testNS)), func(m config.CellTenantMutation) {
+		m.SetID("id").SetAddress("http://broker")
+	})
+
+	r := &Reconciler{targetsServer: stream.NewServer()}
+	r.publishTargetsConfig(ctx, bc, targets)
+
+	rec := httptest.NewRecorder()
+	r.targetsServer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, stream.TargetsPath(testNS, "cell"), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status, got %d, want %d", rec.Code, http.StatusOK)
+	}
+	upd := &config.TargetsConfigUpdate{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), upd); err != nil {
+		t.Fatalf("Failed to unmarshal the update: %v", err)
+	}
+	data, _ := targets.Bytes()
+	want := &config.TargetsConfig{}
+	if err := proto.Unmarshal(data, want); err != nil {
+		t.Fatalf("Failed to unmarshal targets: %v", err)
+	}
+	if !proto.Equal(upd.Config, want) {
+		t.Errorf("Published config = %v, want %v", upd.Config, want)
+	}
+}
+
+func TestTargetsConfigURL(t *testing.T) {
+	bc := NewBrokerCell("cell", testNS)
+	r := &Reconciler{}
+	if got := r.targetsConfigURL(bc); got != "" {
+		t.Errorf("targetsConfigURL() without server = %q, want empty", got)
+	}
+	r = &Reconciler{targetsServer: stream.NewServer(), env: envConfig{TargetsConfigPort: 8070}}
+	want := "http://controller.knative-testing.svc.cluster.local:8070/targets/" + testNS + "/cell"
+	if got := r.targetsConfigURL(bc); got != want {
+		t.Errorf("targetsConfigURL() = %q, want %q", got, want)
+	}
+}
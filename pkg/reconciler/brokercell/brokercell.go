@@ -18,7 +18,9 @@ package brokercell
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
@@ -29,18 +31,23 @@ import (
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	hpav2beta2listers "k8s.io/client-go/listers/autoscaling/v2beta2"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"knative.dev/pkg/network"
+	"knative.dev/pkg/system"
 
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
 
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	bcreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
@@ -54,6 +61,29 @@ type envConfig struct {
 	IngressPort            int    `envconfig:"INGRESS_PORT" default:"8080"`
 	MetricsPort            int    `envconfig:"METRICS_PORT" default:"9090"`
 	InternalMetricsEnabled bool   `envconfig:"INTERNAL_METRICS_ENABLED" default:"false"`
+	// TargetsConfigPort is the port the controller streams the targets config to the data plane on,
+	// through the controller Service. The data plane only loads the targets config from the
+	// ConfigMap volume if zero.
+	TargetsConfigPort int `envconfig:"TARGETS_CONFIG_PORT" default:"0"`
+	// ClaimCheckBucket is the GCS bucket the ingress offloads large event payloads to, and the fanout
+	// and retry read them back from. Payloads are not offloaded if empty.
+	ClaimCheckBucket string `envconfig:"CLAIM_CHECK_BUCKET"`
+	// TrustedTokenIssuers is the comma-separated list of issuers whose tokens are accepted by the
+	// ingress for the brokers that require authentication. The ingress trusts Google if empty.
+	TrustedTokenIssuers string `envconfig:"TRUSTED_TOKEN_ISSUERS"`
+	// MaxRetryDelay is the longest the retry pods hold an event after a throttled delivery before
+	// nacking it. The default of the retry pods is kept if unset.
+	MaxRetryDelay *time.Duration `envconfig:"MAX_RETRY_DELAY"`
+	// NonRetryableStatusCodes are the subscriber response status codes after which the retry pods
+	// send events to the dead letter sink without further retries.
+	NonRetryableStatusCodes []int `envconfig:"NON_RETRYABLE_STATUS_CODES"`
+	// DrainTimeout is how long the fanout and retry handlers wait for the events being processed
+	// on shutdown and config change. The default of the pods is kept if zero. It should be shorter
+	// than the termination grace period of the pods.
+	DrainTimeout time.Duration `envconfig:"DRAIN_TIMEOUT"`
+	// AdminPort is the port of the admin server of the fanout and retry pods, which serves their
+	// state as JSON. The admin server is disabled if zero.
+	AdminPort int `envconfig:"ADMIN_PORT"`
 }
 
 type listers struct {
@@ -112,6 +142,12 @@ type Reconciler struct {
 	deploymentRec *reconcilerutils.DeploymentReconciler
 	cmRec         *reconcilerutils.ConfigMapReconciler
 
+	// uriResolver resolves the addressable dead letter sinks of the brokers.
+	uriResolver *resolver.URIResolver
+
+	// targetsServer streams the targets config to the data plane, if enabled.
+	targetsServer *stream.Server
+
 	env envConfig
 }
 
@@ -135,9 +171,11 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 	bc.Status.InitializeConditions()
 
 	// Reconcile broker targets configmap first so that data plane pods are guaranteed to have the configmap volume
-	// mount available.
-	if err := r.reconcileConfig(ctx, bc); err != nil {
-		return err
+	// mount available. The config is updated even if the dead letter sinks of some brokers can't be
+	// resolved, and the error is returned once the rest of the BrokerCell is reconciled.
+	deadLetterErr := r.reconcileConfig(ctx, bc)
+	if deadLetterErr != nil && !errors.Is(deadLetterErr, errDeadLetterNotResolved) {
+		return deadLetterErr
 	}
 
 	authType, err := authcheck.GetAuthTypeForBrokerCell(ctx, r.serviceAccountLister, r.secretLister, authcheck.AuthTypeArgs{
@@ -241,13 +279,16 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 	}
 
 	bc.Status.ObservedGeneration = bc.Generation
+	if deadLetterErr != nil {
+		return deadLetterErr
+	}
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, "BrokerCellReconciled", "BrokerCell reconciled: \"%s/%s\"", bc.Namespace, bc.Name)
 }
 
 // shouldGC returns true if
 // 1. the brokercell was automatically created by GCP broker controller (with annotation
 // internal.events.cloud.google.com/creator: googlecloud), and
-// 2. there is no brokers pointing to it
+// 2. it serves no broker
 func (r *Reconciler) shouldGC(ctx context.Context, bc *intv1alpha1.BrokerCell) bool {
 	// TODO use the constants in #1132 once it's merged
 	// We only garbage collect brokercells that were automatically created by the GCP broker controller.
@@ -255,22 +296,28 @@ func (r *Reconciler) shouldGC(ctx context.Context, bc *intv1alpha1.BrokerCell) b
 		return false
 	}
 
-	// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
 	brokers, err := r.brokerLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list brokers, skipping garbage collection logic", zap.String("brokercell", bc.Name), zap.String("Namespace", bc.Namespace))
 		return false
 	}
 
-	return len(brokers) == 0
+	key := types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}
+	for _, b := range brokers {
+		if brokerresources.ServedByBrokerCell(b, key) {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *Reconciler) delete(ctx context.Context, bc *intv1alpha1.BrokerCell) pkgreconciler.Event {
 	if err := r.RunClientSet.InternalV1alpha1().BrokerCells(bc.Namespace).Delete(ctx, bc.Name, metav1.DeleteOptions{}); err != nil {
 		return fmt.Errorf("failed to garbage collect brokercell: %w", err)
 	}
+	if r.targetsServer != nil {
+		r.targetsServer.Delete(bc.Namespace, bc.Name)
+	}
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, "BrokerCellGarbageCollected", "BrokerCell garbage collected: \"%s/%s\"", bc.Namespace, bc.Name)
 }
 
@@ -289,8 +336,11 @@ func (r *Reconciler) makeIngressArgs(bc *intv1alpha1.BrokerCell, authType authch
 			MemoryLimit:        bc.Spec.Components.Ingress.MemoryLimit,
 			RolloutRestartTime: bc.GetAnnotations()[resources.IngressRestartTimeAnnotationKey],
 			AuthType:           authType,
+			TargetsConfigURL:   r.targetsConfigURL(bc),
+			ClaimCheckBucket:   r.env.ClaimCheckBucket,
 		},
-		Port: r.env.IngressPort,
+		Port:                r.env.IngressPort,
+		TrustedTokenIssuers: r.env.TrustedTokenIssuers,
 		// TODO(#1804): remove this arg when enabling the feature by default.
 		EnableIngressFilter: getIngressFilteringEnabled(bc),
 	}
@@ -331,7 +381,11 @@ func (r *Reconciler) makeFanoutArgs(bc *intv1alpha1.BrokerCell, authType authche
 			MemoryLimit:        bc.Spec.Components.Fanout.MemoryLimit,
 			RolloutRestartTime: bc.GetAnnotations()[resources.FanoutRestartTimeAnnotationKey],
 			AuthType:           authType,
+			TargetsConfigURL:   r.targetsConfigURL(bc),
+			ClaimCheckBucket:   r.env.ClaimCheckBucket,
 		},
+		DrainTimeout: r.env.DrainTimeout,
+		AdminPort:    r.env.AdminPort,
 	}
 }
 
@@ -361,7 +415,13 @@ func (r *Reconciler) makeRetryArgs(bc *intv1alpha1.BrokerCell, authType authchec
 			MemoryLimit:        bc.Spec.Components.Retry.MemoryLimit,
 			RolloutRestartTime: bc.GetAnnotations()[resources.RetryRestartTimeAnnotationKey],
 			AuthType:           authType,
+			TargetsConfigURL:   r.targetsConfigURL(bc),
+			ClaimCheckBucket:   r.env.ClaimCheckBucket,
 		},
+		MaxRetryDelay:           r.env.MaxRetryDelay,
+		NonRetryableStatusCodes: r.env.NonRetryableStatusCodes,
+		DrainTimeout:            r.env.DrainTimeout,
+		AdminPort:               r.env.AdminPort,
 	}
 }
 
@@ -404,3 +464,13 @@ func (r *Reconciler) reconcileAutoscaling(ctx context.Context, bc *intv1alpha1.B
 	}
 	return nil
 }
+
+// targetsConfigURL returns the URL the data plane of the BrokerCell streams the targets config from,
+// or an empty string if the targets config isn't streamed.
+func (r *Reconciler) targetsConfigURL(bc *intv1alpha1.BrokerCell) string {
+	if r.targetsServer == nil {
+		return ""
+	}
+	host := network.GetServiceHostname(controllerServiceName, system.Namespace())
+	return fmt.Sprintf("http://%s:%d%s", host, r.env.TargetsConfigPort, stream.TargetsPath(bc.Namespace, bc.Name))
+}
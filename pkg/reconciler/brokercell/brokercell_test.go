@@ -26,12 +26,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
 	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
 
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/proto"
@@ -58,6 +61,12 @@ var (
 	testKey     = fmt.Sprintf("%s/%s", testNS, brokerCellName)
 	testKeyAuth = fmt.Sprintf("%s/%s", authcheck.ControlPlaneNamespace, brokerCellName)
 
+	brokerServiceAccount = NewServiceAccount(authcheck.BrokerServiceAccountName, testNS,
+		WithServiceAccountAnnotation("broker@test-project-id.iam.gserviceaccount.com"))
+
+	// withTestBrokerCell binds a broker to the BrokerCell under test.
+	withTestBrokerCell = WithBrokerAnnotation(brokerv1beta1.BrokerCellAnnotationKey, brokerCellName)
+
 	creatorAnnotation       = map[string]string{"internal.events.cloud.google.com/creator": "googlecloud"}
 	restartedTimeAnnotation = map[string]string{
 		"events.cloud.google.com/ingressRestartRequestedAt": "2020-09-25T16:28:36-04:00",
@@ -119,6 +128,7 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell is being deleted",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS,
 					WithInitBrokerCellConditions,
 					WithBrokerCellDeletionTimestamp,
@@ -130,6 +140,7 @@ func TestAllCases(t *testing.T) {
 			Name: "ConfigMap.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 			},
 			WithReactors: []clientgotesting.ReactionFunc{InduceFailure("create", "configmaps")},
@@ -148,9 +159,10 @@ func TestAllCases(t *testing.T) {
 			Name: "ConfigMap.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+				NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults),
 			},
 			WithReactors: []clientgotesting.ReactionFunc{InduceFailure("update", "configmaps")},
 			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
@@ -163,7 +175,7 @@ func TestAllCases(t *testing.T) {
 			WantEvents: []string{configmapUpdateFailedEvent},
 			WantUpdates: []clientgotesting.UpdateActionImpl{{Object: testingdata.Config(t,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-				NewBroker("broker", testNS, WithBrokerSetDefaults))}},
+				NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults))}},
 			WantErr: true,
 		},
 		{
@@ -186,10 +198,31 @@ func TestAllCases(t *testing.T) {
 			WantEvents: []string{authTypeEvent},
 			WantErr:    true,
 		},
+		{
+			Name: "authType error, service account missing outside the control plane namespace",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewBrokerCell(brokerCellName, testNS,
+					WithInitBrokerCellConditions,
+					WithTargetsCofigReady(),
+					WithBrokerCellIngressUnknown(authcheck.AuthenticationCheckUnknownReason, `authentication is not configured, BrokerCells outside namespace events-system need Workload Identity, when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker`),
+					WithBrokerCellFanoutUnknown(authcheck.AuthenticationCheckUnknownReason, `authentication is not configured, BrokerCells outside namespace events-system need Workload Identity, when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker`),
+					WithBrokerCellRetryUnknown(authcheck.AuthenticationCheckUnknownReason, `authentication is not configured, BrokerCells outside namespace events-system need Workload Identity, when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker`),
+					WithBrokerCellSetDefaults,
+				),
+			}},
+			WantEvents: []string{Eventf(corev1.EventTypeWarning, "InternalError", `authentication is not configured, BrokerCells outside namespace events-system need Workload Identity, when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker`)},
+			WantErr:    true,
+		},
 		{
 			Name: "Ingress Deployment.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 			},
@@ -216,6 +249,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Ingress Deployment.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				// Create an deployment such that only the spec is different from expected deployment to trigger an update.
 				NewDeployment(brokerCellName+"-brokercell-ingress", testNS,
@@ -249,6 +283,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Ingress HorizontalPodAutoscaler.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -276,6 +311,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Ingress HorizontalPodAutoscaler.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -304,6 +340,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Ingress Service.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -334,6 +371,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Ingress Service.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -369,6 +407,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Fanout Deployment.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressHPA(t),
@@ -402,6 +441,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Fanout Deployment.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressHPA(t),
@@ -442,6 +482,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Fanout HorizontalPodAutoscaler.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressHPA(t),
@@ -476,6 +517,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Fanout HorizontalPodAutoscaler.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				testingdata.IngressHPA(t),
@@ -511,6 +553,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Retry Deployment.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -548,6 +591,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Retry Deployment.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -591,6 +635,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Retry HorizontalPodAutoscaler.Create error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -628,6 +673,7 @@ func TestAllCases(t *testing.T) {
 			Name: "Retry HorizontalPodAutoscaler.Update error",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -666,6 +712,7 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell created, resources created but some resource status not ready",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS),
 			},
@@ -709,9 +756,10 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell created, resources updated but some resource status not ready",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+				NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS),
 				NewDeployment(brokerCellName+"-brokercell-ingress", testNS,
@@ -744,7 +792,7 @@ func TestAllCases(t *testing.T) {
 			WantUpdates: []clientgotesting.UpdateActionImpl{
 				{Object: testingdata.Config(t,
 					NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-					NewBroker("broker", testNS, WithBrokerSetDefaults))},
+					NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults))},
 				{Object: testingdata.IngressDeployment(t)},
 				{Object: testingdata.IngressHPA(t)},
 				{Object: testingdata.IngressService(t)},
@@ -783,6 +831,7 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell created successfully but status update failed",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -814,6 +863,7 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell created successfully",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
@@ -842,6 +892,7 @@ func TestAllCases(t *testing.T) {
 			Name: "BrokerCell with ingress filtering created successfully",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
 					WithBrokerCellAnnotations(enableIngressFilteringAnnotation)),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
@@ -867,16 +918,59 @@ func TestAllCases(t *testing.T) {
 				brokerCellReconciledEvent,
 			},
 		},
+		{
+			Name: "Stale targets config shards are deleted",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				brokerServiceAccount,
+				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNS,
+					Name:      resources.TargetsConfigMapName(brokerCellName, 1),
+				}},
+				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
+					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
+				testingdata.IngressDeploymentWithStatus(t),
+				testingdata.IngressServiceWithStatus(t),
+				testingdata.FanoutDeploymentWithStatus(t),
+				testingdata.RetryDeploymentWithStatus(t),
+				testingdata.IngressHPA(t),
+				testingdata.FanoutHPA(t),
+				testingdata.RetryHPA(t),
+			},
+			WantDeletes: []clientgotesting.DeleteActionImpl{
+				{
+					Name: resources.TargetsConfigMapName(brokerCellName, 1),
+					ActionImpl: clientgotesting.ActionImpl{
+						Namespace: testNS,
+						Verb:      "delete",
+						Resource:  corev1.SchemeGroupVersion.WithResource("configmaps"),
+					},
+				},
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+				{Object: NewBrokerCell(brokerCellName, testNS,
+					WithBrokerCellReady,
+					WithIngressTemplate("http://test-brokercell-brokercell-ingress.testnamespace.svc.cluster.local/{namespace}/{name}"),
+					WithBrokerCellSetDefaults,
+				)},
+			},
+			WantEvents: []string{
+				brokerCellReconciledEvent,
+			},
+		},
 		{
 			Name: "googlecloud created BrokerCell shouldn't be gc'ed because there are brokers",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS,
 					WithBrokerCellAnnotations(creatorAnnotation),
 					WithBrokerCellSetDefaults),
 				testingdata.Config(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-					NewBroker("broker", testNS, WithBrokerSetDefaults)),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+					NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults)),
+				NewBroker("broker", testNS, withTestBrokerCell, WithBrokerSetDefaults),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
 					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -941,10 +1035,35 @@ func TestAllCases(t *testing.T) {
 			},
 			WantEvents: []string{brokerCellGCEvent},
 		},
+		{
+			Name: "googlecloud created BrokerCell is gc'ed if it serves no broker",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				brokerServiceAccount,
+				NewBrokerCell(brokerCellName, testNS,
+					WithBrokerCellAnnotations(creatorAnnotation),
+					WithBrokerCellSetDefaults,
+					WithInitBrokerCellConditions,
+				),
+				NewBroker("broker", testNS, WithBrokerSetDefaults),
+			},
+			WantDeletes: []clientgotesting.DeleteActionImpl{
+				{
+					Name: brokerCellName,
+					ActionImpl: clientgotesting.ActionImpl{
+						Namespace: testNS,
+						Verb:      "delete",
+						Resource:  intv1alpha1.SchemeGroupVersion.WithResource("brokercells"),
+					},
+				},
+			},
+			WantEvents: []string{brokerCellGCEvent},
+		},
 		{
 			Name: "Brokercell has restart time annotation, deployments are updated with restart time annotation successfully",
 			Key:  testKey,
 			Objects: []runtime.Object{
+				brokerServiceAccount,
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
 					WithBrokerCellAnnotations(restartedTimeAnnotation)),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
@@ -1000,6 +1119,7 @@ func TestAllCases(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to created BrokerCell reconciler: %v", err)
 		}
+		r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
 		return bcreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, testingListers.GetBrokerCellLister(), r.Recorder, r)
 	}))
 }
@@ -1022,7 +1142,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 	}{
 		{
 			name:   "reconcile config of one broker and its triggers",
-			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1beta1.BrokerClass)),
+			broker: NewBroker("broker", testNS, withTestBrokerCell, WithBrokerClass(brokerv1beta1.BrokerClass)),
 			triggers: []*brokerv1beta1.Trigger{
 				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
 				NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
@@ -1030,10 +1150,20 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 			bc:             NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 			expectEmptyMap: false,
 		},
+		{
+			name: "reconcile config of an ordered broker",
+			broker: NewBroker("broker", testNS, withTestBrokerCell, WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotationKey, "partitionkey")),
+			triggers: []*brokerv1beta1.Trigger{
+				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
+			},
+			bc:             NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+			expectEmptyMap: false,
+		},
 
 		{
 			name:   "reconcile config when the broker is not gcp broker",
-			broker: NewBroker("broker", testNS, WithBrokerClass("some-other-broker-class")),
+			broker: NewBroker("broker", testNS, withTestBrokerCell, WithBrokerClass("some-other-broker-class")),
 			triggers: []*brokerv1beta1.Trigger{
 				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
 				NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
@@ -1070,6 +1200,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
 			}
+			r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
 			// here we only want to test the functionality of the reconcileConfig that it should create a brokerTargets config successfully
 			r.reconcileConfig(ctx, bc)
 			var wantMap *corev1.ConfigMap
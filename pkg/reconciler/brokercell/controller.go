@@ -20,16 +20,19 @@ package brokercell
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/broker"
 	triggerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/trigger"
 	brokercellinformer "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell"
 	hpainformer "github.com/google/knative-gcp/pkg/client/injection/kube/informers/autoscaling/v2beta2/horizontalpodautoscaler"
 	v1alpha1brokercell "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
+	inteventslisters "github.com/google/knative-gcp/pkg/client/listers/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/reconciler"
@@ -39,6 +42,7 @@ import (
 	customresourceutil "github.com/google/knative-gcp/pkg/utils/customresource"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 
@@ -52,21 +56,25 @@ import (
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection"
 	systemnamespacesecretinformer "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret"
-	"knative.dev/pkg/system"
+	"knative.dev/pkg/resolver"
 )
 
 const (
 	// controllerAgentName is the string used by this controller to identify
 	// itself when creating events.
 	controllerAgentName = "brokercell-controller"
+	// controllerServiceName is the name of the Service of the controller, through which the data
+	// plane streams the targets config.
+	controllerServiceName = "controller"
 )
 
 type Constructor injection.ControllerConstructor
 
-// NewConstructor creates a constructor to make a BrokerCell controller.
-func NewConstructor() Constructor {
+// NewConstructor creates a constructor to make a BrokerCell controller. The targets config is streamed
+// to the data plane through the given Server if enabled.
+func NewConstructor(targetsServer *stream.Server) Constructor {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-		return NewController(ctx, cmw)
+		return NewController(ctx, cmw, targetsServer)
 	}
 }
 
@@ -74,6 +82,7 @@ func NewConstructor() Constructor {
 func NewController(
 	ctx context.Context,
 	cmw configmap.Watcher,
+	targetsServer *stream.Server,
 ) *controller.Impl {
 	brokerCellInformer := brokercellinformer.Get(ctx)
 
@@ -97,7 +106,30 @@ func NewController(
 	if err != nil {
 		logger.Fatal("Failed to create BrokerCell reconciler", zap.Error(err))
 	}
+	if r.env.TargetsConfigPort > 0 {
+		r.targetsServer = targetsServer
+		// Only the data plane pods of a BrokerCell get its targets config.
+		r.targetsServer.RequireAuthentication(
+			stream.NewTokenReviewAuthenticator(r.KubeClientSet.AuthenticationV1().TokenReviews(), stream.TokenAudience),
+			r.env.ServiceAccountName,
+		)
+		r.targetsServer.TrackPods(dataPlanePods{podLister: ls.podLister})
+		go func() {
+			if err := r.targetsServer.ListenAndServe(ctx, fmt.Sprintf(":%d", r.env.TargetsConfigPort)); err != nil {
+				logger.Error("Failed to serve the targets config", zap.Error(err))
+			}
+		}()
+	}
 	impl := v1alpha1brokercell.NewImpl(ctx, r)
+	brokerCellLister := brokerCellInformer.Lister()
+	r.uriResolver = resolver.NewURIResolver(ctx, func(key types.NamespacedName) {
+		// The dead letter sinks are tracked on behalf of their broker.
+		b, err := ls.brokerLister.Brokers(key.Namespace).Get(key.Name)
+		if err != nil {
+			return
+		}
+		enqueueBrokerCells(impl, brokerCellLister, b)
+	})
 
 	var latencyReporter *metrics.BrokerCellLatencyReporter
 	if r.env.InternalMetricsEnabled {
@@ -110,23 +142,30 @@ func NewController(
 	logger.Info("Setting up event handlers.")
 
 	brokerCellInformer.Informer().AddEventHandlerWithResyncPeriod(controller.HandleAll(impl.Enqueue), reconciler.DefaultResyncPeriod)
-	brokerCellLister := brokerCellInformer.Lister()
 
 	// Watch brokers and triggers to invoke configmap update immediately.
 	brokerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
 			if b, ok := obj.(*brokerv1beta1.Broker); ok {
-				// TODO(#866) Select the brokercell that's associated with the given broker.
-				impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName})
+				enqueueBrokerCells(impl, brokerCellLister, b)
 				reportLatency(ctx, b, latencyReporter, "Broker", b.Name, b.Namespace)
 			}
 		},
 	))
+	// A broker that moved to another brokercell must be removed from the config of the previous one.
+	brokerinformer.Get(ctx).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, _ interface{}) {
+			if b, ok := oldObj.(*brokerv1beta1.Broker); ok {
+				enqueueBrokerCells(impl, brokerCellLister, b)
+			}
+		},
+	})
 	triggerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
 			if t, ok := obj.(*brokerv1beta1.Trigger); ok {
-				// TODO(#866) Select the brokercell that's associated with the given broker.
-				impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName})
+				if b, err := ls.brokerLister.Brokers(t.Namespace).Get(t.Spec.Broker); err == nil {
+					enqueueBrokerCells(impl, brokerCellLister, b)
+				}
 				reportLatency(ctx, t, latencyReporter, "Trigger", t.Name, t.Namespace)
 			}
 		},
@@ -149,15 +188,33 @@ func NewController(
 		FilterFunc: filterWithNamespace(authcheck.ControlPlaneNamespace),
 		Handler:    authcheck.EnqueueBrokerCell(impl, brokerCellLister),
 	})
-	// 2. Watch broker data plane's k8s service account,
+	// 2. Watch broker data plane's k8s service account in every namespace that may hold brokercells,
 	// if the filtered k8s service account resource changes, enqueue brokercells from the same namespace.
 	serviceaccountinformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
-		FilterFunc: filterWithNamespace(authcheck.ControlPlaneNamespace),
+		FilterFunc: controller.FilterWithName(authcheck.BrokerServiceAccountName),
 		Handler:    authcheck.EnqueueBrokerCell(impl, brokerCellLister),
 	})
 	return impl
 }
 
+// enqueueBrokerCells enqueues the brokercell the given broker is bound to, along with the
+// brokercells that still serve it while it moves to another brokercell.
+func enqueueBrokerCells(impl *controller.Impl, brokerCellLister inteventslisters.BrokerCellLister, b *brokerv1beta1.Broker) {
+	impl.EnqueueKey(brokerresources.BrokerCellKey(b))
+	if b.Status.Address.URL == nil {
+		return
+	}
+	bcs, err := brokerCellLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, bc := range bcs {
+		if key := (types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}); brokerresources.ServedByBrokerCell(b, key) {
+			impl.EnqueueKey(key)
+		}
+	}
+}
+
 // handleResourceUpdate returns an event handler for resources created by brokercell such as the ingress deployment.
 func handleResourceUpdate(impl *controller.Impl) cache.ResourceEventHandler {
 	// Since resources created by brokercell live in the same namespace as the brokercell, we use an
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+// dataPlanePods lists the ingress, fanout and retry pods of the BrokerCells, whose reports tell the
+// targets config applied by the data plane.
+type dataPlanePods struct {
+	podLister corev1listers.PodLister
+}
+
+var _ stream.Pods = dataPlanePods{}
+
+// Pods implements stream.Pods.
+func (d dataPlanePods) Pods(namespace, name string) (map[string]bool, error) {
+	pods, err := d.podLister.Pods(namespace).List(labels.SelectorFromSet(resources.CommonLabels(name)))
+	if err != nil {
+		return nil, err
+	}
+	ready := make(map[string]bool, len(pods))
+	for _, p := range pods {
+		switch p.Labels["role"] {
+		case resources.IngressName, resources.FanoutName, resources.RetryName:
+		default:
+			continue
+		}
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		ready[p.Name] = isPodReady(p)
+	}
+	return ready, nil
+}
+
+func isPodReady(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
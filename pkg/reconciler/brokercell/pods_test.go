@@ -0,0 +1,65 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	testingListers "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestDataPlanePods(t *testing.T) {
+	pod := func(namespace, name, brokerCell, component string, ready corev1.ConditionStatus) runtime.Object {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels:    resources.Labels(brokerCell, component),
+			},
+		}
+		if ready != "" {
+			p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}
+		}
+		return p
+	}
+	deleting := pod("ns", "retry-deleting", "cell", resources.RetryName, corev1.ConditionTrue).(*corev1.Pod)
+	deleting.DeletionTimestamp = &metav1.Time{}
+
+	listers := testingListers.NewListers([]runtime.Object{
+		pod("ns", "ingress", "cell", resources.IngressName, corev1.ConditionTrue),
+		pod("ns", "fanout", "cell", resources.FanoutName, corev1.ConditionFalse),
+		pod("ns", "retry", "cell", resources.RetryName, ""),
+		deleting,
+		pod("ns", "other-component", "cell", "other", corev1.ConditionTrue),
+		pod("ns", "other-cell", "other", resources.IngressName, corev1.ConditionTrue),
+		pod("other", "other-namespace", "cell", resources.IngressName, corev1.ConditionTrue),
+	})
+	got, err := dataPlanePods{podLister: listers.GetPodLister()}.Pods("ns", "cell")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]bool{"ingress": true, "fanout": false, "retry": false}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected pods (-want,+got): %v", diff)
+	}
+}
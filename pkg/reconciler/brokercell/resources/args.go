@@ -18,6 +18,7 @@ package resources
 
 import (
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
 	"knative.dev/pkg/kmeta"
@@ -63,6 +64,10 @@ type Args struct {
 	MemoryLimit        string
 	RolloutRestartTime string
 	AuthType           authcheck.AuthType
+	// TargetsConfigURL is the URL the data plane streams the targets config from, if not empty.
+	TargetsConfigURL string
+	// ClaimCheckBucket is the GCS bucket of the offloaded event payloads, if not empty.
+	ClaimCheckBucket string
 }
 
 // IngressArgs are the arguments to create a Broker's ingress Deployment.
@@ -71,16 +76,32 @@ type IngressArgs struct {
 	Port int
 	// TODO(#1804): remove this field when enabling the feature by default.
 	EnableIngressFilter bool
+	// TrustedTokenIssuers is the comma-separated list of the issuers whose tokens are accepted by
+	// the ingress, if not empty.
+	TrustedTokenIssuers string
 }
 
 // FanoutArgs are the arguments to create a Broker's fanout Deployment.
 type FanoutArgs struct {
 	Args
+	// DrainTimeout is how long the handlers drain on shutdown and config change, if not zero.
+	DrainTimeout time.Duration
+	// AdminPort is the port of the admin server, which is disabled if zero.
+	AdminPort int
 }
 
 // RetryArgs are the arguments to create a Broker's retry Deployment.
 type RetryArgs struct {
 	Args
+	// MaxRetryDelay is the longest an event is held after a throttled delivery, if not nil.
+	MaxRetryDelay *time.Duration
+	// NonRetryableStatusCodes are the status codes after which events are sent to the dead letter
+	// sink without further retries.
+	NonRetryableStatusCodes []int
+	// DrainTimeout is how long the handlers drain on shutdown and config change, if not zero.
+	DrainTimeout time.Duration
+	// AdminPort is the port of the admin server, which is disabled if zero.
+	AdminPort int
 }
 
 // AutoscalingArgs are the arguments to create HPA for deployments.
@@ -33,46 +33,72 @@ const (
 	targetsCMKey  = "targets"
 )
 
-// TargetsConfigMapEqual compares the binary data contained in two TargetsConfig
+// TargetsConfigMapName returns the name of the ConfigMap holding the shard with the given index of
+// the targets config of the BrokerCell. The first shard is always present.
+func TargetsConfigMapName(brokerCellName string, index int) string {
+	if index == 0 {
+		return Name(brokerCellName, targetsCMName)
+	}
+	return Name(brokerCellName, fmt.Sprintf("%s-%d", targetsCMName, index))
+}
+
+// TargetsConfigMapEqual compares the shards contained in two TargetsConfig
 // ConfigMaps and returns true if and only if the inputs are valid and the
-// unmarshaled binary data are equal.
+// decoded shards are equal.
 func TargetsConfigMapEqual(cm1, cm2 *corev1.ConfigMap) bool {
-	// The broker targets ConfigMap BinaryData holds the serialized TargetsConfig
-	// proto, and therefore cannot be safely compared with equality.Semantic.DeepEqual.
-	// Instead, use proto.Equal to compare protos.
-	v1, ok := cm1.BinaryData[targetsCMKey]
-	if !ok {
+	// The broker targets ConfigMap BinaryData holds compressed, serialized
+	// TargetsConfigShard protos, and therefore cannot be safely compared with
+	// equality.Semantic.DeepEqual. Instead, use proto.Equal to compare protos.
+	if len(cm1.BinaryData) == 0 || len(cm1.BinaryData) != len(cm2.BinaryData) {
 		return false
 	}
-	v2, ok := cm2.BinaryData[targetsCMKey]
-	if !ok {
-		return false
+	for key, v1 := range cm1.BinaryData {
+		v2, ok := cm2.BinaryData[key]
+		if !ok {
+			return false
+		}
+		shard1, err := config.DecodeShard(v1)
+		if err != nil {
+			return false
+		}
+		shard2, err := config.DecodeShard(v2)
+		if err != nil {
+			return false
+		}
+		if !proto.Equal(shard1, shard2) {
+			return false
+		}
 	}
-	proto1 := &config.TargetsConfig{}
-	proto2 := &config.TargetsConfig{}
-	if err := proto.Unmarshal(v1, proto1); err != nil {
-		return false
-	}
-	if err := proto.Unmarshal(v2, proto2); err != nil {
-		return false
-	}
-	return proto.Equal(proto1, proto2)
+	return true
 }
 
-func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) (*corev1.ConfigMap, error) {
+// MakeTargetsConfigs returns the ConfigMaps holding the targets config of the BrokerCell. The
+// config is compressed and, if it's too large for a single ConfigMap, split into shards by
+// CellTenant. Each ConfigMap holds one shard, and the data plane only loads a complete set of shards.
+func MakeTargetsConfigs(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) ([]*corev1.ConfigMap, error) {
 	data, err := brokerTargets.Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("error serializing targets config: %w", err)
 	}
-	return &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:            Name(bc.Name, targetsCMName),
-			Namespace:       bc.Namespace,
-			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
-			Labels:          Labels(bc.Name, "broker-targets"),
-		},
-		BinaryData: map[string][]byte{targetsCMKey: data},
-		// Write out the text version for debugging purposes only
-		Data: map[string]string{"debugOnlyTargets.txt": brokerTargets.DebugString()},
-	}, nil
+	tc := &config.TargetsConfig{}
+	if err := proto.Unmarshal(data, tc); err != nil {
+		return nil, fmt.Errorf("error serializing targets config: %w", err)
+	}
+	shards, err := config.EncodeShards(tc, config.MaxShardBytes, config.MaxShards)
+	if err != nil {
+		return nil, fmt.Errorf("error sharding targets config: %w", err)
+	}
+	cms := make([]*corev1.ConfigMap, 0, len(shards))
+	for i, shard := range shards {
+		cms = append(cms, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            TargetsConfigMapName(bc.Name, i),
+				Namespace:       bc.Namespace,
+				OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
+				Labels:          Labels(bc.Name, "broker-targets"),
+			},
+			BinaryData: map[string][]byte{config.ShardName(targetsCMKey, i): shard},
+		})
+	}
+	return cms, nil
 }
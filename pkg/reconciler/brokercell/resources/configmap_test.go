@@ -17,7 +17,10 @@ limitations under the License.
 package resources
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -41,10 +44,10 @@ func testConfigMap(names []string, namespace string) *corev1.ConfigMap {
 	targets := &config.TargetsConfig{
 		CellTenants: brokerMap,
 	}
-	targetsBytes, _ := proto.Marshal(targets)
+	shards, _ := config.EncodeShards(targets, config.MaxShardBytes, config.MaxShards)
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: targetsCMName},
-		BinaryData: map[string][]byte{targetsCMKey: targetsBytes},
+		BinaryData: map[string][]byte{config.ShardName(targetsCMKey, 0): shards[0]},
 	}
 }
 
@@ -54,12 +57,16 @@ func TestTargetsConfigMapEqual(t *testing.T) {
 		targetsCm2            = testConfigMap([]string{"broker2"}, "ns")
 		invalidProtoTargetsCm = &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: targetsCMName},
-			BinaryData: map[string][]byte{targetsCMKey: {'b'}},
+			BinaryData: map[string][]byte{config.ShardName(targetsCMKey, 0): {'b'}},
 		}
 		notTargetsCm = &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: targetsCMName},
 			BinaryData: map[string][]byte{"some-key": nil},
 		}
+		otherKeyTargetsCm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: targetsCMName},
+			BinaryData: map[string][]byte{config.ShardName(targetsCMKey, 1): targetsCm1.BinaryData[config.ShardName(targetsCMKey, 0)]},
+		}
 		orderTargetsCm1 = testConfigMap([]string{"broker1", "broker2"}, "ns")
 		orderTargetsCm2 = testConfigMap([]string{"broker2", "broker1"}, "ns")
 	)
@@ -93,6 +100,12 @@ func TestTargetsConfigMapEqual(t *testing.T) {
 			cm2:    notTargetsCm,
 			wantEq: false,
 		},
+		{
+			name:   "different shard key",
+			cm1:    targetsCm1,
+			cm2:    otherKeyTargetsCm,
+			wantEq: false,
+		},
 		{
 			name:   "unequal bytes, same proto",
 			cm1:    orderTargetsCm1,
@@ -109,9 +122,58 @@ func TestTargetsConfigMapEqual(t *testing.T) {
 	}
 }
 
-func TestMakeTargetsConfig(t *testing.T) {
-	_, err := MakeTargetsConfig(NewBrokerCell("name", "ns"), memory.NewEmptyTargets())
+func TestMakeTargetsConfigs(t *testing.T) {
+	bc := NewBrokerCell("name", "ns")
+	cms, err := MakeTargetsConfigs(bc, memory.NewEmptyTargets())
 	if err != nil {
-		t.Errorf("Error making TargetsConfig: %v", err)
+		t.Fatalf("Error making TargetsConfig: %v", err)
+	}
+	if len(cms) != 1 {
+		t.Fatalf("Unexpected number of ConfigMaps, got %d, want 1", len(cms))
+	}
+	if got, want := cms[0].Name, "name-brokercell-broker-targets"; got != want {
+		t.Errorf("Unexpected ConfigMap name, got %q, want %q", got, want)
+	}
+}
+
+func TestMakeTargetsConfigsSharded(t *testing.T) {
+	bc := NewBrokerCell("name", "ns")
+	tc := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	// Random-looking, incompressible addresses make the config large enough to be sharded.
+	for i := 0; i < 20000; i++ {
+		sum := sha256.Sum256([]byte(strconv.Itoa(i)))
+		b := &config.CellTenant{
+			Id:        hex.EncodeToString(sum[:]),
+			Type:      config.CellTenantType_BROKER,
+			Name:      fmt.Sprintf("broker%d", i),
+			Namespace: "ns",
+			Address:   "http://" + hex.EncodeToString(sum[:]),
+		}
+		tc.CellTenants[b.Key().PersistenceString()] = b
+	}
+	cms, err := MakeTargetsConfigs(bc, memory.NewTargets(tc))
+	if err != nil {
+		t.Fatalf("Error making TargetsConfig: %v", err)
+	}
+	if len(cms) < 2 {
+		t.Fatalf("Expected the targets config to be sharded, got %d ConfigMaps", len(cms))
+	}
+	shards := make([][]byte, 0, len(cms))
+	for i, cm := range cms {
+		if got, want := cm.Name, TargetsConfigMapName(bc.Name, i); got != want {
+			t.Errorf("Unexpected ConfigMap name, got %q, want %q", got, want)
+		}
+		shard, ok := cm.BinaryData[config.ShardName(targetsCMKey, i)]
+		if !ok {
+			t.Fatalf("ConfigMap %s is missing shard %d", cm.Name, i)
+		}
+		shards = append(shards, shard)
+	}
+	got, err := config.DecodeShards(shards)
+	if err != nil {
+		t.Fatalf("Error decoding shards: %v", err)
+	}
+	if !proto.Equal(got, tc) {
+		t.Errorf("Decoded targets config doesn't match the original")
 	}
 }
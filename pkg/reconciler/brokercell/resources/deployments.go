@@ -18,7 +18,12 @@ package resources
 
 import (
 	"strconv"
+	"strings"
+	"time"
 
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/handler"
 	resourceutil "github.com/google/knative-gcp/pkg/utils/resource"
 	appsv1 "k8s.io/api/apps/v1"
@@ -30,6 +35,18 @@ import (
 	"knative.dev/pkg/system"
 )
 
+const (
+	deliveryCAVolumeName         = "delivery-ca"
+	deliveryCAMountPath          = "/var/run/events-system/tls/ca"
+	deliveryClientCertVolumeName = "delivery-client-cert"
+	deliveryClientCertMountPath  = "/var/run/events-system/tls/client"
+	targetsConfigTokenVolumeName = "targets-config-token"
+	targetsConfigTokenMountPath  = "/var/run/events-system/targets-config"
+	// targetsConfigTokenExpiration is the requested lifetime of the tokens the data plane streams
+	// the targets config with. The kubelet rotates them before they expire.
+	targetsConfigTokenExpiration = 3600
+)
+
 // MakeIngressDeployment creates the ingress Deployment object.
 func MakeIngressDeployment(args IngressArgs) *appsv1.Deployment {
 	container := containerTemplate(args.Args)
@@ -42,6 +59,12 @@ func MakeIngressDeployment(args IngressArgs) *appsv1.Deployment {
 		Name:  "ENABLE_INGRESS_EVENT_FILTERING",
 		Value: strconv.FormatBool(args.EnableIngressFilter),
 	})
+	if args.TrustedTokenIssuers != "" {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "TRUSTED_TOKEN_ISSUERS",
+			Value: args.TrustedTokenIssuers,
+		})
+	}
 
 	container.Ports = append(container.Ports, corev1.ContainerPort{Name: "http", ContainerPort: int32(args.Port)})
 	container.ReadinessProbe = &corev1.Probe{
@@ -89,6 +112,7 @@ func MakeFanoutDeployment(args FanoutArgs) *appsv1.Deployment {
 		Name:  "MAX_CONCURRENCY_PER_EVENT",
 		Value: "100",
 	})
+	container.Env = append(container.Env, handlerEnv(args.DrainTimeout, args.AdminPort)...)
 	container.LivenessProbe = &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
@@ -103,12 +127,29 @@ func MakeFanoutDeployment(args FanoutArgs) *appsv1.Deployment {
 		SuccessThreshold:    1,
 		TimeoutSeconds:      5,
 	}
-	return deploymentTemplate(args.Args, []corev1.Container{container})
+	return withDeliveryTLS(deploymentTemplate(args.Args, []corev1.Container{container}), args.BrokerCell.Spec.DeliveryTLS)
 }
 
 // MakeRetryDeployment creates the retry Deployment object.
 func MakeRetryDeployment(args RetryArgs) *appsv1.Deployment {
 	container := containerTemplate(args.Args)
+	container.Env = append(container.Env, handlerEnv(args.DrainTimeout, args.AdminPort)...)
+	if args.MaxRetryDelay != nil {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "MAX_RETRY_DELAY",
+			Value: args.MaxRetryDelay.String(),
+		})
+	}
+	if len(args.NonRetryableStatusCodes) > 0 {
+		codes := make([]string, 0, len(args.NonRetryableStatusCodes))
+		for _, code := range args.NonRetryableStatusCodes {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "NON_RETRYABLE_STATUS_CODES",
+			Value: strings.Join(codes, ","),
+		})
+	}
 	container.Resources = resourceutil.BuildResourceRequirements(args.CPURequest, args.CPULimit, args.MemoryRequest, args.MemoryLimit)
 	container.Ports = append(container.Ports,
 		corev1.ContainerPort{
@@ -130,7 +171,26 @@ func MakeRetryDeployment(args RetryArgs) *appsv1.Deployment {
 		SuccessThreshold:    1,
 		TimeoutSeconds:      5,
 	}
-	return deploymentTemplate(args.Args, []corev1.Container{container})
+	return withDeliveryTLS(deploymentTemplate(args.Args, []corev1.Container{container}), args.BrokerCell.Spec.DeliveryTLS)
+}
+
+// handlerEnv returns the env of the handlers of the fanout and retry containers. The defaults of
+// the containers are kept for the unset values.
+func handlerEnv(drainTimeout time.Duration, adminPort int) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if drainTimeout > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "DRAIN_TIMEOUT",
+			Value: drainTimeout.String(),
+		})
+	}
+	if adminPort > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "ADMIN_PORT",
+			Value: strconv.Itoa(adminPort),
+		})
+	}
+	return env
 }
 
 // deploymentTemplate creates a template for data plane deployments.
@@ -141,7 +201,7 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 	if args.RolloutRestartTime != "" {
 		annotation[RolloutRestartTimeAnnotationKey] = args.RolloutRestartTime
 	}
-	return &appsv1.Deployment{
+	d := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:       args.BrokerCell.Namespace,
 			Name:            Name(args.BrokerCell.Name, args.ComponentName),
@@ -167,7 +227,7 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 					Volumes: []corev1.Volume{
 						{
 							Name:         "broker-config",
-							VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: Name(args.BrokerCell.Name, targetsCMName)}}},
+							VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: targetsConfigProjections(args.BrokerCell.Name)}},
 						},
 						{
 							Name:         "google-broker-key",
@@ -180,11 +240,75 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 			},
 		},
 	}
+	if args.TargetsConfigURL != "" {
+		// The token the data plane authenticates to the targets config server with.
+		d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: targetsConfigTokenVolumeName,
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          stream.TokenAudience,
+						ExpirationSeconds: ptr.Int64(targetsConfigTokenExpiration),
+						Path:              "token",
+					},
+				}},
+			}},
+		})
+	}
+	return d
+}
+
+// withDeliveryTLS mounts the delivery TLS Secrets, if any, into the container of the deployment and
+// points the container to the certificate files. The whole Secrets are mounted, rather than the
+// files, so that the container sees the rotated certificates.
+func withDeliveryTLS(d *appsv1.Deployment, spec *intv1alpha1.DeliveryTLSSpec) *appsv1.Deployment {
+	if spec == nil {
+		return d
+	}
+	podSpec := &d.Spec.Template.Spec
+	container := &podSpec.Containers[0]
+	if spec.CASecretName != "" {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         deliveryCAVolumeName,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: spec.CASecretName}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      deliveryCAVolumeName,
+			MountPath: deliveryCAMountPath,
+			ReadOnly:  true,
+		})
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "DELIVERY_CA_FILE",
+			Value: deliveryCAMountPath + "/ca.crt",
+		})
+	}
+	if spec.ClientCertSecretName != "" {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         deliveryClientCertVolumeName,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: spec.ClientCertSecretName}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      deliveryClientCertVolumeName,
+			MountPath: deliveryClientCertMountPath,
+			ReadOnly:  true,
+		})
+		container.Env = append(container.Env,
+			corev1.EnvVar{
+				Name:  "DELIVERY_CERT_FILE",
+				Value: deliveryClientCertMountPath + "/" + corev1.TLSCertKey,
+			},
+			corev1.EnvVar{
+				Name:  "DELIVERY_KEY_FILE",
+				Value: deliveryClientCertMountPath + "/" + corev1.TLSPrivateKeyKey,
+			},
+		)
+	}
+	return d
 }
 
 // containerTemplate returns a common template for broker data plane containers.
 func containerTemplate(args Args) corev1.Container {
-	return corev1.Container{
+	container := corev1.Container{
 		Image: args.Image,
 		Name:  args.ComponentName,
 		Env: []corev1.EnvVar{
@@ -238,4 +362,44 @@ func containerTemplate(args Args) corev1.Container {
 			},
 		},
 	}
+	if args.TargetsConfigURL != "" {
+		container.Env = append(container.Env,
+			corev1.EnvVar{
+				Name:  "TARGETS_CONFIG_URL",
+				Value: args.TargetsConfigURL,
+			},
+			corev1.EnvVar{
+				Name:  "TARGETS_CONFIG_TOKEN_PATH",
+				Value: targetsConfigTokenMountPath + "/token",
+			},
+		)
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      targetsConfigTokenVolumeName,
+			MountPath: targetsConfigTokenMountPath,
+			ReadOnly:  true,
+		})
+	}
+	if args.ClaimCheckBucket != "" {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "CLAIM_CHECK_BUCKET",
+			Value: args.ClaimCheckBucket,
+		})
+	}
+	return container
+}
+
+// targetsConfigProjections returns the volume projections of all the ConfigMaps that may hold a
+// shard of the targets config. Only the first shard is required to exist.
+func targetsConfigProjections(brokerCellName string) []corev1.VolumeProjection {
+	projections := make([]corev1.VolumeProjection, 0, config.MaxShards)
+	for i := 0; i < config.MaxShards; i++ {
+		cm := &corev1.ConfigMapProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: TargetsConfigMapName(brokerCellName, i)},
+		}
+		if i > 0 {
+			cm.Optional = ptr.Bool(true)
+		}
+		projections = append(projections, corev1.VolumeProjection{ConfigMap: cm})
+	}
+	return projections
 }
@@ -0,0 +1,301 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_ "knative.dev/pkg/system/testing"
+
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+)
+
+func TestDeliveryTLSDeployments(t *testing.T) {
+	tests := []struct {
+		name        string
+		deliveryTLS *intv1alpha1.DeliveryTLSSpec
+		wantVolumes []corev1.Volume
+		wantMounts  []corev1.VolumeMount
+		wantEnv     []corev1.EnvVar
+	}{{
+		name: "no delivery TLS",
+	}, {
+		name:        "CA bundle",
+		deliveryTLS: &intv1alpha1.DeliveryTLSSpec{CASecretName: "subscribers-ca"},
+		wantVolumes: []corev1.Volume{{
+			Name:         "delivery-ca",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "subscribers-ca"}},
+		}},
+		wantMounts: []corev1.VolumeMount{{
+			Name:      "delivery-ca",
+			MountPath: "/var/run/events-system/tls/ca",
+			ReadOnly:  true,
+		}},
+		wantEnv: []corev1.EnvVar{{
+			Name:  "DELIVERY_CA_FILE",
+			Value: "/var/run/events-system/tls/ca/ca.crt",
+		}},
+	}, {
+		name: "CA bundle and client certificate",
+		deliveryTLS: &intv1alpha1.DeliveryTLSSpec{
+			CASecretName:         "subscribers-ca",
+			ClientCertSecretName: "broker-client-cert",
+		},
+		wantVolumes: []corev1.Volume{{
+			Name:         "delivery-ca",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "subscribers-ca"}},
+		}, {
+			Name:         "delivery-client-cert",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "broker-client-cert"}},
+		}},
+		wantMounts: []corev1.VolumeMount{{
+			Name:      "delivery-ca",
+			MountPath: "/var/run/events-system/tls/ca",
+			ReadOnly:  true,
+		}, {
+			Name:      "delivery-client-cert",
+			MountPath: "/var/run/events-system/tls/client",
+			ReadOnly:  true,
+		}},
+		wantEnv: []corev1.EnvVar{{
+			Name:  "DELIVERY_CA_FILE",
+			Value: "/var/run/events-system/tls/ca/ca.crt",
+		}, {
+			Name:  "DELIVERY_CERT_FILE",
+			Value: "/var/run/events-system/tls/client/tls.crt",
+		}, {
+			Name:  "DELIVERY_KEY_FILE",
+			Value: "/var/run/events-system/tls/client/tls.key",
+		}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := &intv1alpha1.BrokerCell{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"},
+				Spec:       intv1alpha1.BrokerCellSpec{DeliveryTLS: tc.deliveryTLS},
+			}
+			base := deploymentTemplate(Args{BrokerCell: bc}, []corev1.Container{containerTemplate(Args{})})
+			deployments := map[string]*appsv1.Deployment{
+				"fanout": MakeFanoutDeployment(FanoutArgs{Args: Args{ComponentName: FanoutName, BrokerCell: bc}}),
+				"retry":  MakeRetryDeployment(RetryArgs{Args: Args{ComponentName: RetryName, BrokerCell: bc}}),
+			}
+			for name, d := range deployments {
+				podSpec := d.Spec.Template.Spec
+				container := podSpec.Containers[0]
+				if diff := cmp.Diff(tc.wantVolumes, podSpec.Volumes[len(base.Spec.Template.Spec.Volumes):], cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("unexpected %s volumes (-want, +got): %v", name, diff)
+				}
+				if diff := cmp.Diff(tc.wantMounts, container.VolumeMounts[len(base.Spec.Template.Spec.Containers[0].VolumeMounts):], cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("unexpected %s volume mounts (-want, +got): %v", name, diff)
+				}
+				var gotEnv []corev1.EnvVar
+				for _, env := range container.Env {
+					if env.Name == "DELIVERY_CA_FILE" || env.Name == "DELIVERY_CERT_FILE" || env.Name == "DELIVERY_KEY_FILE" {
+						gotEnv = append(gotEnv, env)
+					}
+				}
+				if diff := cmp.Diff(tc.wantEnv, gotEnv); diff != "" {
+					t.Errorf("unexpected %s env (-want, +got): %v", name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestTargetsConfigURLDeployments(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	for _, url := range []string{"", "http://controller.events-system.svc.cluster.local:8070/targets/ns/default"} {
+		args := Args{BrokerCell: bc, TargetsConfigURL: url}
+		deployments := map[string]*appsv1.Deployment{
+			"ingress": MakeIngressDeployment(IngressArgs{Args: args}),
+			"fanout":  MakeFanoutDeployment(FanoutArgs{Args: args}),
+			"retry":   MakeRetryDeployment(RetryArgs{Args: args}),
+		}
+		for name, d := range deployments {
+			var got string
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "TARGETS_CONFIG_URL" {
+					got = env.Value
+				}
+			}
+			if got != url {
+				t.Errorf("unexpected %s TARGETS_CONFIG_URL, got %q, want %q", name, got, url)
+			}
+			// The config is streamed with a token of the targets config audience.
+			var audience string
+			for _, v := range d.Spec.Template.Spec.Volumes {
+				if v.Projected != nil && len(v.Projected.Sources) == 1 && v.Projected.Sources[0].ServiceAccountToken != nil {
+					audience = v.Projected.Sources[0].ServiceAccountToken.Audience
+				}
+			}
+			var tokenPath string
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "TARGETS_CONFIG_TOKEN_PATH" {
+					tokenPath = env.Value
+				}
+			}
+			if url == "" {
+				if audience != "" || tokenPath != "" {
+					t.Errorf("unexpected %s targets config token without URL, got audience %q and path %q", name, audience, tokenPath)
+				}
+				continue
+			}
+			if audience != stream.TokenAudience {
+				t.Errorf("unexpected %s targets config token audience, got %q, want %q", name, audience, stream.TokenAudience)
+			}
+			if want := targetsConfigTokenMountPath + "/token"; tokenPath != want {
+				t.Errorf("unexpected %s TARGETS_CONFIG_TOKEN_PATH, got %q, want %q", name, tokenPath, want)
+			}
+		}
+	}
+}
+
+func TestClaimCheckBucketDeployments(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	for _, bucket := range []string{"", "claim-checks"} {
+		args := Args{BrokerCell: bc, ClaimCheckBucket: bucket}
+		deployments := map[string]*appsv1.Deployment{
+			"ingress": MakeIngressDeployment(IngressArgs{Args: args}),
+			"fanout":  MakeFanoutDeployment(FanoutArgs{Args: args}),
+			"retry":   MakeRetryDeployment(RetryArgs{Args: args}),
+		}
+		for name, d := range deployments {
+			var got string
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "CLAIM_CHECK_BUCKET" {
+					got = env.Value
+				}
+			}
+			if got != bucket {
+				t.Errorf("unexpected %s CLAIM_CHECK_BUCKET, got %q, want %q", name, got, bucket)
+			}
+		}
+	}
+}
+
+func TestTrustedTokenIssuersIngressDeployment(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	for _, issuers := range []string{"", "https://accounts.google.com,https://kubernetes.default.svc"} {
+		d := MakeIngressDeployment(IngressArgs{Args: Args{BrokerCell: bc}, TrustedTokenIssuers: issuers})
+		var got string
+		for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == "TRUSTED_TOKEN_ISSUERS" {
+				got = env.Value
+			}
+		}
+		if got != issuers {
+			t.Errorf("unexpected TRUSTED_TOKEN_ISSUERS, got %q, want %q", got, issuers)
+		}
+	}
+}
+
+func TestRetryDelayDeployment(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	noDelay := time.Duration(0)
+	maxDelay := 2 * time.Minute
+	tests := []struct {
+		name string
+		args RetryArgs
+		want []corev1.EnvVar
+	}{{
+		name: "defaults",
+	}, {
+		name: "no delay",
+		args: RetryArgs{MaxRetryDelay: &noDelay},
+		want: []corev1.EnvVar{{Name: "MAX_RETRY_DELAY", Value: "0s"}},
+	}, {
+		name: "max delay and non-retryable status codes",
+		args: RetryArgs{MaxRetryDelay: &maxDelay, NonRetryableStatusCodes: []int{400, 413}},
+		want: []corev1.EnvVar{
+			{Name: "MAX_RETRY_DELAY", Value: "2m0s"},
+			{Name: "NON_RETRYABLE_STATUS_CODES", Value: "400,413"},
+		},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args.Args = Args{ComponentName: RetryName, BrokerCell: bc}
+			d := MakeRetryDeployment(tc.args)
+			var got []corev1.EnvVar
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "MAX_RETRY_DELAY" || env.Name == "NON_RETRYABLE_STATUS_CODES" {
+					got = append(got, env)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected retry env (-want, +got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestDrainTimeoutDeployments(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	for _, tc := range []struct {
+		drainTimeout time.Duration
+		want         string
+	}{{}, {drainTimeout: 40 * time.Second, want: "40s"}} {
+		args := Args{BrokerCell: bc}
+		deployments := map[string]*appsv1.Deployment{
+			"fanout": MakeFanoutDeployment(FanoutArgs{Args: args, DrainTimeout: tc.drainTimeout}),
+			"retry":  MakeRetryDeployment(RetryArgs{Args: args, DrainTimeout: tc.drainTimeout}),
+		}
+		for name, d := range deployments {
+			var got string
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "DRAIN_TIMEOUT" {
+					got = env.Value
+				}
+			}
+			if got != tc.want {
+				t.Errorf("unexpected %s DRAIN_TIMEOUT, got %q, want %q", name, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestAdminPortDeployments(t *testing.T) {
+	bc := &intv1alpha1.BrokerCell{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default"}}
+	for _, tc := range []struct {
+		adminPort int
+		want      string
+	}{{}, {adminPort: 8090, want: "8090"}} {
+		args := Args{BrokerCell: bc}
+		deployments := map[string]*appsv1.Deployment{
+			"fanout": MakeFanoutDeployment(FanoutArgs{Args: args, AdminPort: tc.adminPort}),
+			"retry":  MakeRetryDeployment(RetryArgs{Args: args, AdminPort: tc.adminPort}),
+		}
+		for name, d := range deployments {
+			var got string
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "ADMIN_PORT" {
+					got = env.Value
+				}
+			}
+			if got != tc.want {
+				t.Errorf("unexpected %s ADMIN_PORT, got %q, want %q", name, got, tc.want)
+			}
+		}
+	}
+}
@@ -29,8 +29,8 @@ import (
 )
 
 func EmptyConfig(t *testing.T, bc *intv1alpha1.BrokerCell) *corev1.ConfigMap {
-	cm, _ := resources.MakeTargetsConfig(bc, memory.NewEmptyTargets())
-	return cm
+	cms, _ := resources.MakeTargetsConfigs(bc, memory.NewEmptyTargets())
+	return cms[0]
 }
 
 func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Broker, triggers ...*brokerv1beta1.Trigger) *corev1.ConfigMap {
@@ -38,7 +38,7 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 	targets := make(map[string]*config.Target, len(triggers))
 	for _, t := range triggers {
 		state := config.State_UNKNOWN
-		if t.Status.IsReady() {
+		if t.Status.IsControlPlaneReady() {
 			state = config.State_READY
 		}
 		var filterAttributes map[string]string
@@ -58,6 +58,7 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 			},
 			State:            state,
 			FilterAttributes: filterAttributes,
+			Generation:       t.Generation,
 		}
 
 		targets[t.Name] = target
@@ -65,7 +66,7 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 
 	// construct broker config
 	state := config.State_UNKNOWN
-	if broker.Status.IsReady() {
+	if broker.Status.IsControlPlaneReady() {
 		state = config.State_READY
 	}
 	brokerQueueState := config.State_UNKNOWN
@@ -83,8 +84,10 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 			Subscription: brokerresources.GenerateDecouplingSubscriptionName(broker),
 			State:        brokerQueueState,
 		},
-		Targets: targets,
-		State:   state,
+		Targets:              targets,
+		State:                state,
+		OrderingKeyExtension: broker.GetAnnotations()[brokerv1beta1.OrderingKeyExtensionAnnotationKey],
+		Generation:           broker.Generation,
 	}
 	bt := &config.TargetsConfig{
 		CellTenants: map[string]*config.CellTenant{
@@ -92,6 +95,6 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 		},
 	}
 	brokerTargets := memory.NewTargets(bt)
-	cm, _ := resources.MakeTargetsConfig(bc, brokerTargets)
-	return cm
+	cms, _ := resources.MakeTargetsConfigs(bc, brokerTargets)
+	return cms[0]
 }
@@ -21,6 +21,7 @@ package celltenant
 import (
 	"context"
 	"fmt"
+	"time"
 
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 
@@ -38,8 +39,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/network"
-	"knative.dev/pkg/system"
 
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	inteventslisters "github.com/google/knative-gcp/pkg/client/listers/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/broker/resources"
@@ -141,6 +142,8 @@ func (r *Reconciler) reconcileDecouplingTopicAndSubscription(ctx context.Context
 	subConfig := pubsub.SubscriptionConfig{
 		Topic:  topic,
 		Labels: b.GetLabels(),
+		// Message ordering can only be set when the subscription is created.
+		EnableMessageOrdering: b.EnableMessageOrdering(),
 		//TODO(grantr): configure these settings?
 		// AckDeadline
 		// RetentionDuration
@@ -153,6 +156,24 @@ func (r *Reconciler) reconcileDecouplingTopicAndSubscription(ctx context.Context
 	//TODO uncomment when eventing webhook allows this
 	//b.Status.SubscriptionID = sub.ID()
 
+	// The events are retained for replay by a subscription that is never pulled, so that all of
+	// them are kept until they fall out of its retention duration.
+	retentionSubID := b.GetRetentionSubscriptionName()
+	retention := b.RetentionDuration()
+	if retention == 0 {
+		return pubsubReconciler.DeleteSubscription(ctx, retentionSubID, b.Object(), b.StatusUpdater())
+	}
+	retentionConfig := pubsub.SubscriptionConfig{
+		Topic:             topic,
+		Labels:            b.GetLabels(),
+		RetentionDuration: retention,
+		// The subscription must not expire although it's never pulled.
+		ExpirationPolicy: time.Duration(0),
+	}
+	if _, err := pubsubReconciler.ReconcileSubscription(ctx, retentionSubID, retentionConfig, b.Object(), b.StatusUpdater()); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -182,6 +203,8 @@ func (r *Reconciler) deleteDecouplingTopicAndSubscription(ctx context.Context, s
 	err = multierr.Append(nil, pubsubReconciler.DeleteTopic(ctx, topicID, s.Object(), s.StatusUpdater()))
 	subID := s.GetSubscriptionName()
 	err = multierr.Append(err, pubsubReconciler.DeleteSubscription(ctx, subID, s.Object(), s.StatusUpdater()))
+	retentionSubID := s.GetRetentionSubscriptionName()
+	err = multierr.Append(err, pubsubReconciler.DeleteSubscription(ctx, retentionSubID, s.Object(), s.StatusUpdater()))
 
 	return err
 }
@@ -212,9 +235,14 @@ func (r *Reconciler) getClientOrCreateNew(ctx context.Context, projectID string,
 func (r *Reconciler) ensureBrokerCellExists(ctx context.Context, s Statusable) error {
 	var bc *inteventsv1alpha1.BrokerCell
 	var err error
-	// TODO(#866) Get brokercell based on the label (or annotation) on the broker.
-	bcNS := system.Namespace()
-	bcName := resources.DefaultBrokerCellName
+	key := s.BrokerCellKey()
+	bcNS, bcName := key.Namespace, key.Name
+	// The webhook rejects the BrokerCells of other namespaces, but they may have been selected
+	// before it did.
+	if !brokerv1beta1.IsBrokerCellNamespaceAllowed(s.Key().Namespace(), bcNS) {
+		s.MarkBrokerCellFailed("BrokerCellNotAllowed", "BrokerCell %s/%s is neither in namespace %s nor in the system namespace", bcNS, bcName, s.Key().Namespace())
+		return fmt.Errorf("brokercell %s/%s is not allowed", bcNS, bcName)
+	}
 	bc, err = r.BrokerCellLister.BrokerCells(bcNS).Get(bcName)
 	if err != nil && !apierrs.IsNotFound(err) {
 		logging.FromContext(ctx).Error("Error getting BrokerCell", zap.String("namespace", bcNS), zap.String("brokerCell", bcName), zap.Error(err))
@@ -222,8 +250,14 @@ func (r *Reconciler) ensureBrokerCellExists(ctx context.Context, s Statusable) e
 		return err
 	}
 
+	if apierrs.IsNotFound(err) && bcNS != s.Key().Namespace() && key != resources.DefaultBrokerCellKey() {
+		// The BrokerCells of the system namespace, apart from the default one, are set up by the
+		// operator.
+		s.MarkBrokerCellFailed("BrokerCellNotFound", "BrokerCell %s/%s doesn't exist", bcNS, bcName)
+		return fmt.Errorf("brokercell %s/%s not found", bcNS, bcName)
+	}
 	if apierrs.IsNotFound(err) {
-		want := resources.CreateBrokerCell(nil)
+		want := resources.CreateBrokerCell(key)
 		bc, err = r.RunClientSet.InternalV1alpha1().BrokerCells(want.Namespace).Create(ctx, want, metav1.CreateOptions{})
 		if err != nil && !apierrs.IsAlreadyExists(err) {
 			logging.FromContext(ctx).Error("Error creating brokerCell", zap.String("namespace", want.Namespace), zap.String("brokerCell", want.Name), zap.Error(err))
@@ -254,11 +288,18 @@ func (r *Reconciler) ensureBrokerCellExists(ctx context.Context, s Statusable) e
 
 	//TODO(#1019) Use the IngressTemplate of brokercell.
 	ingressServiceName := brokercellresources.Name(bc.Name, brokercellresources.IngressName)
-	s.SetAddress(&apis.URL{
+	address := &apis.URL{
 		Scheme: "http",
 		Host:   network.GetServiceHostname(ingressServiceName, bc.Namespace),
 		Path:   "/" + s.Key().PersistenceString(),
-	})
+	}
+	// While the CellTenant moves to another BrokerCell, its address keeps pointing to the ingress of
+	// the previous BrokerCell until the new one is ready. The previous BrokerCell serves the
+	// CellTenant as long as its address points to it.
+	if current := s.GetAddress(); current != nil && current.Host != address.Host && !bc.Status.IsReady() {
+		return nil
+	}
+	s.SetAddress(address)
 
 	return nil
 }
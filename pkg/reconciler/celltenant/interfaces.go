@@ -18,6 +18,7 @@ package celltenant
 
 import (
 	"fmt"
+	"time"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/broker/config"
@@ -25,6 +26,7 @@ import (
 	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 	"knative.dev/pkg/apis"
 )
@@ -37,22 +39,46 @@ type Target interface {
 	GetSubscriptionName() string
 	GetLabels() map[string]string
 	DeliverySpec() *eventingduckv1beta1.DeliverySpec
+	// EnableMessageOrdering returns true if the retry subscription should be created with message
+	// ordering enabled.
+	EnableMessageOrdering() bool
 	SetStatusProjectID(projectID string)
+	// ReplayFrom returns the time from which the events retained by the CellTenant are replayed to
+	// the Target, if a replay is requested.
+	ReplayFrom() (time.Time, bool)
+	GetReplaySubscriptionName() string
+	GetReplaySnapshotName() string
+	// GetCellTenantTopicID returns the decoupling topic of the CellTenant.
+	GetCellTenantTopicID() string
+	// GetRetentionSubscriptionName returns the subscription retaining the events of the CellTenant,
+	// or an empty string if the CellTenant doesn't retain events.
+	GetRetentionSubscriptionName() string
+	ReplayStatusUpdater() ReplayStatusUpdater
+}
+
+// ReplayStatusUpdater reports the replay of the retained events to a Target.
+type ReplayStatusUpdater interface {
+	MarkReplayReady(from, until time.Time)
+	MarkReplayFailed(reason, format string, args ...interface{})
+	ClearReplay()
+	ReplayWindow() (from, until time.Time, ok bool)
+	GetCondition(t apis.ConditionType) *apis.Condition
 }
 
 var _ Target = (*targetForTrigger)(nil)
 
 type targetForTrigger struct {
-	trigger      *brokerv1beta1.Trigger
-	deliverySpec *eventingduckv1beta1.DeliverySpec
+	trigger *brokerv1beta1.Trigger
+	broker  *brokerv1beta1.Broker
 }
 
 // TargetFromTrigger creates a Target for the given Trigger and associated
-// Broker's deliverySpec.
-func TargetFromTrigger(t *brokerv1beta1.Trigger, deliverySpec *eventingduckv1beta1.DeliverySpec) Target {
+// Broker. The Broker may be nil if it is unknown, e.g. when the Trigger is
+// being finalized.
+func TargetFromTrigger(t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) Target {
 	return &targetForTrigger{
-		trigger:      t,
-		deliverySpec: deliverySpec,
+		trigger: t,
+		broker:  b,
 	}
 }
 
@@ -82,7 +108,14 @@ func (t *targetForTrigger) GetSubscriptionName() string {
 }
 
 func (t *targetForTrigger) DeliverySpec() *eventingduckv1beta1.DeliverySpec {
-	return t.deliverySpec
+	if t.broker == nil {
+		return nil
+	}
+	return t.broker.Spec.Delivery
+}
+
+func (t *targetForTrigger) EnableMessageOrdering() bool {
+	return t.broker != nil && orderingEnabled(t.broker)
 }
 
 func (t *targetForTrigger) SetStatusProjectID(_ string) {
@@ -90,6 +123,45 @@ func (t *targetForTrigger) SetStatusProjectID(_ string) {
 	// t.trigger.Status.ProjectID = projectID
 }
 
+func (t *targetForTrigger) ReplayFrom() (time.Time, bool) {
+	v, ok := t.trigger.GetAnnotations()[brokerv1beta1.ReplayFromAnnotationKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	// The annotation is validated by the webhook.
+	from, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return from, true
+}
+
+func (t *targetForTrigger) GetReplaySubscriptionName() string {
+	return resources.GenerateReplaySubscriptionName(t.trigger)
+}
+
+func (t *targetForTrigger) GetReplaySnapshotName() string {
+	return resources.GenerateReplaySnapshotName(t.trigger)
+}
+
+func (t *targetForTrigger) GetCellTenantTopicID() string {
+	if t.broker == nil {
+		return ""
+	}
+	return resources.GenerateDecouplingTopicName(t.broker)
+}
+
+func (t *targetForTrigger) GetRetentionSubscriptionName() string {
+	if t.broker == nil || retentionDuration(t.broker) == 0 {
+		return ""
+	}
+	return resources.GenerateRetentionSubscriptionName(t.broker)
+}
+
+func (t *targetForTrigger) ReplayStatusUpdater() ReplayStatusUpdater {
+	return &t.trigger.Status
+}
+
 var _ reconcilerutilspubsub.StatusUpdater = (*SubscriberStatus)(nil)
 
 type SubscriberStatus struct {
@@ -155,12 +227,23 @@ type Statusable interface {
 	MarkBrokerCellReady()
 	MarkBrokerCellUnknown(reason, format string, args ...interface{})
 	MarkBrokerCellFailed(reason, format string, args ...interface{})
+	// BrokerCellKey returns the namespace and name of the BrokerCell the CellTenant is bound to.
+	BrokerCellKey() types.NamespacedName
+	// GetAddress returns the current address of the CellTenant, or nil if it has none.
+	GetAddress() *apis.URL
 	SetAddress(*apis.URL)
 	Object() runtime.Object
 	StatusUpdater() reconcilerutilspubsub.StatusUpdater
 	GetLabels() map[string]string
 	GetTopicID() string
 	GetSubscriptionName() string
+	// EnableMessageOrdering returns true if the decoupling subscription should be created with
+	// message ordering enabled.
+	EnableMessageOrdering() bool
+	GetRetentionSubscriptionName() string
+	// RetentionDuration returns how long the events of the CellTenant are retained for replay, or
+	// zero if they are not retained.
+	RetentionDuration() time.Duration
 }
 
 var _ Statusable = (*statusableForBroker)(nil)
@@ -191,6 +274,14 @@ func (b *statusableForBroker) MarkBrokerCellFailed(reason, format string, args .
 	b.broker.Status.MarkBrokerCellFailed(reason, format, args...)
 }
 
+func (b *statusableForBroker) BrokerCellKey() types.NamespacedName {
+	return resources.BrokerCellKey(b.broker)
+}
+
+func (b *statusableForBroker) GetAddress() *apis.URL {
+	return b.broker.Status.Address.URL
+}
+
 func (b *statusableForBroker) SetAddress(url *apis.URL) {
 	b.broker.Status.SetAddress(url)
 }
@@ -220,3 +311,35 @@ func (b *statusableForBroker) GetTopicID() string {
 func (b *statusableForBroker) GetSubscriptionName() string {
 	return resources.GenerateDecouplingSubscriptionName(b.broker)
 }
+
+func (b *statusableForBroker) EnableMessageOrdering() bool {
+	return orderingEnabled(b.broker)
+}
+
+func (b *statusableForBroker) GetRetentionSubscriptionName() string {
+	return resources.GenerateRetentionSubscriptionName(b.broker)
+}
+
+func (b *statusableForBroker) RetentionDuration() time.Duration {
+	return retentionDuration(b.broker)
+}
+
+// orderingEnabled returns true if the Broker maps a CloudEvent extension to the Pub/Sub ordering
+// key.
+func orderingEnabled(b *brokerv1beta1.Broker) bool {
+	return b.GetAnnotations()[brokerv1beta1.OrderingKeyExtensionAnnotationKey] != ""
+}
+
+// retentionDuration returns how long the events sent to the Broker are retained for replay, or zero
+// if they are not retained. The annotation is validated by the webhook.
+func retentionDuration(b *brokerv1beta1.Broker) time.Duration {
+	v, ok := b.GetAnnotations()[brokerv1beta1.RetentionAnnotationKey]
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0
+	}
+	return d
+}
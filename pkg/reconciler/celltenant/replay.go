@@ -0,0 +1,207 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package celltenant
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/utils"
+)
+
+const (
+	// replayFromLabel is the label of the replay subscription recording the time from which it
+	// was seeded, in nanoseconds since the epoch.
+	replayFromLabel = "replay-from"
+
+	// Name of the corev1.Events emitted when replaying events to a Target.
+	replayStarted = "ReplayStarted"
+	replayStopped = "ReplayStopped"
+)
+
+// ReconcileReplay replays the events retained by the CellTenant to the Target if it requests a
+// replay. The replay subscription is created on the decoupling topic and seeded with a snapshot of
+// the retention subscription of the CellTenant, which holds all the retained events as it is never
+// pulled. It is then sought to the time from which the events are replayed. The data plane delivers
+// the events of the replay subscription published until the snapshot was taken, the later ones
+// being delivered by the decoupling subscription.
+func (r *TargetReconciler) ReconcileReplay(ctx context.Context, recorder record.EventRecorder, t Target) error {
+	from, ok := t.ReplayFrom()
+	if !ok {
+		if t.ReplayStatusUpdater().GetCondition(brokerv1beta1.TriggerConditionReplay) == nil {
+			// Replay was never requested, there is nothing to clean up.
+			return nil
+		}
+		if err := r.DeleteReplay(ctx, recorder, t); err != nil {
+			return err
+		}
+		t.ReplayStatusUpdater().ClearReplay()
+		return nil
+	}
+
+	status := t.ReplayStatusUpdater()
+	retentionSubID := t.GetRetentionSubscriptionName()
+	if retentionSubID == "" {
+		status.MarkReplayFailed("RetentionDisabled", "Events are not retained, set the %s annotation on the Broker to replay them", brokerv1beta1.RetentionAnnotationKey)
+		return r.DeleteReplay(ctx, recorder, t)
+	}
+
+	logger := logging.FromContext(ctx)
+	projectID, err := utils.ProjectIDOrDefault(r.ProjectID)
+	if err != nil {
+		logger.Error("Failed to find project id", zap.Error(err))
+		status.MarkReplayFailed("ProjectIdNotFound", "Failed to find project id: %v", err)
+		return err
+	}
+	client, err := r.getClientOrCreateNew(ctx, projectID, t.StatusUpdater())
+	if err != nil {
+		logger.Error("Failed to create Pub/Sub client", zap.Error(err))
+		return err
+	}
+
+	fromLabel := strconv.FormatInt(from.UnixNano(), 10)
+	sub := client.Subscription(t.GetReplaySubscriptionName())
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		logger.Error("Failed to verify replay subscription exists", zap.Error(err))
+		status.MarkReplayFailed("ReplaySubscriptionVerificationFailed", "Failed to verify replay subscription exists: %v", err)
+		return err
+	}
+	if exists {
+		config, err := sub.Config(ctx)
+		if err != nil {
+			logger.Error("Failed to get replay subscription config", zap.Error(err))
+			status.MarkReplayFailed("ReplaySubscriptionConfigUnknown", "Failed to get replay subscription config: %v", err)
+			return err
+		}
+		if _, _, ok := status.ReplayWindow(); ok && config.Labels[replayFromLabel] == fromLabel {
+			// The replay subscription was already seeded.
+			return nil
+		}
+		// The replay was restarted from another time, or its seeding didn't complete.
+		if err := sub.Delete(ctx); err != nil {
+			logger.Error("Failed to delete replay subscription", zap.Error(err))
+			status.MarkReplayFailed("ReplaySubscriptionDeletionFailed", "Failed to delete replay subscription: %v", err)
+			return err
+		}
+	}
+
+	// Events published from now on are delivered by the decoupling subscription.
+	until := time.Now()
+	snapshot, err := r.createReplaySnapshot(ctx, client, retentionSubID, t.GetReplaySnapshotName())
+	if err != nil {
+		logger.Error("Failed to snapshot retention subscription", zap.Error(err))
+		status.MarkReplayFailed("SnapshotCreationFailed", "Failed to snapshot the retained events: %v", err)
+		return err
+	}
+	defer func() {
+		if err := snapshot.Delete(ctx); err != nil {
+			logger.Warn("Failed to delete replay snapshot", zap.String("snapshot", snapshot.ID()), zap.Error(err))
+		}
+	}()
+
+	labels := t.GetLabels()
+	labels[replayFromLabel] = fromLabel
+	sub, err = client.CreateSubscription(ctx, t.GetReplaySubscriptionName(), pubsub.SubscriptionConfig{
+		Topic:                 client.Topic(t.GetCellTenantTopicID()),
+		Labels:                labels,
+		RetryPolicy:           getPubsubRetryPolicy(t.DeliverySpec()),
+		EnableMessageOrdering: t.EnableMessageOrdering(),
+	})
+	if err != nil {
+		logger.Error("Failed to create replay subscription", zap.Error(err))
+		status.MarkReplayFailed("ReplaySubscriptionCreationFailed", "Failed to create replay subscription: %v", err)
+		return err
+	}
+	if err := seekReplay(ctx, sub, snapshot.Snapshot, from); err != nil {
+		logger.Error("Failed to seek replay subscription", zap.Error(err))
+		status.MarkReplayFailed("ReplaySeekFailed", "Failed to seek replay subscription: %v", err)
+		// Seed the replay subscription again on the next reconciliation.
+		if err := sub.Delete(ctx); err != nil {
+			logger.Error("Failed to delete replay subscription", zap.Error(err))
+		}
+		return err
+	}
+
+	status.MarkReplayReady(from, until)
+	recorder.Eventf(t.Object(), corev1.EventTypeNormal, replayStarted, "Replaying the events published from %s", from.Format(time.RFC3339))
+	return nil
+}
+
+// createReplaySnapshot snapshots the retention subscription. A snapshot may be left over if the
+// controller stopped while seeding the replay subscription, in which case it is replaced.
+func (r *TargetReconciler) createReplaySnapshot(ctx context.Context, client *pubsub.Client, retentionSubID, snapshotID string) (*pubsub.SnapshotConfig, error) {
+	retention := client.Subscription(retentionSubID)
+	snapshot, err := retention.CreateSnapshot(ctx, snapshotID)
+	if grpcstatus.Code(err) != codes.AlreadyExists {
+		return snapshot, err
+	}
+	if err := client.Snapshot(snapshotID).Delete(ctx); err != nil {
+		return nil, err
+	}
+	return retention.CreateSnapshot(ctx, snapshotID)
+}
+
+// seekReplay restores the retained events of the snapshot in the replay subscription, and acks the
+// ones published before the replay starts.
+func seekReplay(ctx context.Context, sub *pubsub.Subscription, snapshot *pubsub.Snapshot, from time.Time) error {
+	if err := sub.SeekToSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+	return sub.SeekToTime(ctx, from)
+}
+
+// DeleteReplay deletes the replay subscription of the Target if it exists.
+func (r *TargetReconciler) DeleteReplay(ctx context.Context, recorder record.EventRecorder, t Target) error {
+	logger := logging.FromContext(ctx)
+	projectID, err := utils.ProjectIDOrDefault(r.ProjectID)
+	if err != nil {
+		logger.Error("Failed to find project id", zap.Error(err))
+		return err
+	}
+	client, err := r.getClientOrCreateNew(ctx, projectID, t.StatusUpdater())
+	if err != nil {
+		logger.Error("Failed to create Pub/Sub client", zap.Error(err))
+		return err
+	}
+	sub := client.Subscription(t.GetReplaySubscriptionName())
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		logger.Error("Failed to verify replay subscription exists", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if err := sub.Delete(ctx); err != nil {
+		logger.Error("Failed to delete replay subscription", zap.Error(err))
+		return err
+	}
+	logger.Info("Deleted replay subscription", zap.String("name", sub.ID()))
+	recorder.Eventf(t.Object(), corev1.EventTypeNormal, replayStopped, "Deleted replay subscription %q", sub.ID())
+	return nil
+}
@@ -33,6 +33,7 @@ import (
 	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 
 	"cloud.google.com/go/pubsub"
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
 	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	"github.com/google/knative-gcp/pkg/utils"
@@ -43,6 +44,9 @@ const (
 	// Default maximum backoff duration used in the backoff retry policy for
 	// pubsub subscriptions. 600 seconds is the longest supported time.
 	defaultMaximumBackoff = 600 * time.Second
+	// maxDeliveryAttempts is the largest number of delivery attempts supported
+	// in a pubsub dead letter policy. Retries beyond it are rejected by the webhook.
+	maxDeliveryAttempts = brokerv1beta1.MaxRetry
 )
 
 // TargetReconciler implements controller.Reconciler for CellTenant Targets.
@@ -103,7 +107,7 @@ func (r *TargetReconciler) ReconcileRetryTopicAndSubscription(ctx context.Contex
 	//trig.Status.TopicID = topic.ID()
 
 	retryPolicy := getPubsubRetryPolicy(t.DeliverySpec())
-	deadLetterPolicy := getPubsubDeadLetterPolicy(projectID, t.DeliverySpec())
+	deadLetterPolicy := getPubsubDeadLetterPolicy(projectID, topicID, t.DeliverySpec())
 
 	// Check if PullSub exists, and if not, create it.
 	subID := t.GetSubscriptionName()
@@ -112,6 +116,8 @@ func (r *TargetReconciler) ReconcileRetryTopicAndSubscription(ctx context.Contex
 		Labels:           t.GetLabels(),
 		RetryPolicy:      retryPolicy,
 		DeadLetterPolicy: deadLetterPolicy,
+		// Message ordering can only be set when the subscription is created.
+		EnableMessageOrdering: t.EnableMessageOrdering(),
 		//TODO(grantr): configure these settings?
 		// AckDeadline
 		// RetentionDuration
@@ -155,10 +161,22 @@ func getPubsubRetryPolicy(spec *eventingduckv1beta1.DeliverySpec) *pubsub.RetryP
 
 // getPubsubDeadLetterPolicy gets the eventing dead letter policy from the
 // Broker delivery spec and translates it to a pubsub dead letter policy.
-func getPubsubDeadLetterPolicy(projectID string, spec *eventingduckv1beta1.DeliverySpec) *pubsub.DeadLetterPolicy {
+func getPubsubDeadLetterPolicy(projectID, topicID string, spec *eventingduckv1beta1.DeliverySpec) *pubsub.DeadLetterPolicy {
 	if spec == nil || spec.DeadLetterSink == nil {
 		return nil
 	}
+	if !brokerv1beta1.IsPubsubDeadLetterSink(spec.DeadLetterSink) {
+		// Events are sent to other dead letter sinks by the retry pool once spec.Retry attempts
+		// are exhausted. Pub/Sub only tracks the delivery attempts of subscriptions with a dead
+		// letter policy, so point it back to the retry topic with the largest number of attempts.
+		if spec.Retry == nil {
+			return nil
+		}
+		return &pubsub.DeadLetterPolicy{
+			DeadLetterTopic:     fmt.Sprintf("projects/%s/topics/%s", projectID, topicID),
+			MaxDeliveryAttempts: maxDeliveryAttempts,
+		}
+	}
 	// Translate to the pubsub dead letter policy format.
 
 	dlp := &pubsub.DeadLetterPolicy{
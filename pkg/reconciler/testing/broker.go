@@ -118,6 +118,8 @@ func WithBrokerReadyURI(address *apis.URL) BrokerOption {
 		WithBrokerBrokerCellReady(b)
 		WithBrokerSubscriptionReady(b)
 		WithBrokerTopicReady(b)
+		WithBrokerDeadLetterSinkResolved(b)
+		WithBrokerDataPlaneReady(b)
 		WithBrokerAddressURI(address)(b)
 	}
 }
@@ -146,6 +148,34 @@ func WithBrokerTopicReady(b *brokerv1beta1.Broker) {
 	b.Status.MarkTopicReady()
 }
 
+func WithBrokerDeadLetterSinkResolved(b *brokerv1beta1.Broker) {
+	b.Status.MarkDeadLetterSinkResolved()
+}
+
+func WithBrokerDeadLetterSinkNotNeeded(b *brokerv1beta1.Broker) {
+	b.Status.MarkDeadLetterSinkNotNeeded()
+}
+
+func WithBrokerDeadLetterSinkFailed(reason, msg string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		b.Status.MarkDeadLetterSinkFailed(reason, msg)
+	}
+}
+
+func WithBrokerDataPlaneReady(b *brokerv1beta1.Broker) {
+	b.Status.MarkDataPlaneReady()
+}
+
+func WithBrokerDataPlaneUnknown(reason, msg string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		b.Status.MarkDataPlaneUnknown(reason, msg)
+	}
+}
+
+func WithBrokerDataPlaneNotTracked(b *brokerv1beta1.Broker) {
+	b.Status.MarkDataPlaneNotTracked()
+}
+
 func WithBrokerTopicUnknown(reason, msg string) BrokerOption {
 	return func(b *brokerv1beta1.Broker) {
 		b.Status.MarkTopicUnknown(reason, msg)
@@ -169,6 +199,17 @@ func WithBrokerClass(bc string) BrokerOption {
 	}
 }
 
+func WithBrokerAnnotation(key, value string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		annotations := b.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string, 1)
+		}
+		annotations[key] = value
+		b.SetAnnotations(annotations)
+	}
+}
+
 func WithBrokerSetDefaults(b *brokerv1beta1.Broker) {
 	b.SetDefaults(context.Background())
 }
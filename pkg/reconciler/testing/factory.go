@@ -26,6 +26,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/reconciler"
 
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
@@ -101,6 +102,12 @@ func MakeFactory(ctor Ctor) Factory {
 			return ValidateCreates(ctx, action)
 		})
 		client.PrependReactor("update", "*", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+			if action.GetSubresource() == "status" {
+				// Like the webhook, let resources tell status updates apart. The spec doesn't change
+				// in status updates, so the object is its own baseline.
+				obj := action.(ktesting.UpdateAction).GetObject()
+				return ValidateUpdates(apis.WithinSubResourceUpdate(ctx, obj, "status"), action)
+			}
 			return ValidateUpdates(ctx, action)
 		})
 
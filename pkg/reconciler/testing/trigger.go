@@ -142,6 +142,15 @@ func WithInjectionAnnotation(injectionAnnotation string) TriggerOption {
 	}
 }
 
+func WithTriggerAnnotation(key, value string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[key] = value
+	}
+}
+
 func WithDependencyAnnotation(dependencyAnnotation string) TriggerOption {
 	return func(t *brokerv1beta1.Trigger) {
 		if t.Annotations == nil {
@@ -203,6 +212,46 @@ func WithTriggerSubscriptionUnknown(reason, msg string) TriggerOption {
 	}
 }
 
+func WithTriggerFiltersReady(t *brokerv1beta1.Trigger) {
+	t.Status.MarkFiltersReady()
+}
+
+func WithTriggerFiltersFailed(reason, msg string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkFiltersFailed(reason, msg)
+	}
+}
+
+func WithTriggerDataPlaneReady(t *brokerv1beta1.Trigger) {
+	t.Status.MarkDataPlaneReady()
+}
+
+func WithTriggerDataPlaneUnknown(reason, msg string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkDataPlaneUnknown(reason, msg)
+	}
+}
+
+func WithTriggerDataPlaneNotTracked(t *brokerv1beta1.Trigger) {
+	t.Status.MarkDataPlaneNotTracked()
+}
+
+func WithTriggerReplayReady(from, until time.Time) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkReplayReady(from, until)
+	}
+}
+
+func WithTriggerReplayFailed(reason, msg string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkReplayFailed(reason, msg)
+	}
+}
+
+func WithTriggerSuspended(t *brokerv1beta1.Trigger) {
+	t.Status.MarkSuspended()
+}
+
 func WithTriggerDeletionTimestamp(t *brokerv1beta1.Trigger) {
 	deleteTime := metav1.NewTime(time.Unix(1e9, 0))
 	t.ObjectMeta.SetDeletionTimestamp(&deleteTime)
@@ -24,6 +24,7 @@ import (
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/google/knative-gcp/pkg/logging"
@@ -39,10 +40,12 @@ import (
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/broker"
 	triggerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/trigger"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"github.com/google/knative-gcp/pkg/utils"
 )
@@ -58,13 +61,13 @@ const (
 type Constructor injection.ControllerConstructor
 
 // NewConstructor creates a constructor to make a Trigger controller.
-func NewConstructor(dataresidencyss *dataresidency.StoreSingleton) Constructor {
+func NewConstructor(dataresidencyss *dataresidency.StoreSingleton, targetsServer *stream.Server) Constructor {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-		return newController(ctx, cmw, dataresidencyss.Store(ctx, cmw))
+		return newController(ctx, cmw, dataresidencyss.Store(ctx, cmw), targetsServer)
 	}
 }
 
-func newController(ctx context.Context, cmw configmap.Watcher, drs *dataresidency.Store) *controller.Impl {
+func newController(ctx context.Context, cmw configmap.Watcher, drs *dataresidency.Store, targetsServer *stream.Server) *controller.Impl {
 	triggerInformer := triggerinformer.Get(ctx)
 
 	var client *pubsub.Client
@@ -97,6 +100,7 @@ func newController(ctx context.Context, cmw configmap.Watcher, drs *dataresidenc
 			PubsubClient:       client,
 			DataresidencyStore: drs,
 		},
+		targetsServer: targetsServer,
 	}
 
 	impl := triggerreconciler.NewImpl(ctx, r, withAgentAndFinalizer)
@@ -128,6 +132,23 @@ func newController(ctx context.Context, cmw configmap.Watcher, drs *dataresidenc
 		},
 	)
 
+	// Watch the targets config applied by the data plane.
+	targetsServer.OnApplied(func(namespace, name string) {
+		tc, ok := targetsServer.Applied(namespace, name)
+		if !ok {
+			return
+		}
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		impl.FilteredGlobalResync(func(obj interface{}) bool {
+			t, ok := obj.(*brokerv1beta1.Trigger)
+			if !ok || tc.HasTriggerGeneration(t) == t.Status.GetCondition(brokerv1beta1.TriggerConditionDataPlane).IsTrue() {
+				return false
+			}
+			b, err := brokerinformer.Get(ctx).Lister().Brokers(t.Namespace).Get(t.Spec.Broker)
+			return err == nil && brokerresources.BrokerCellKey(b) == key
+		}, triggerInformer.Informer())
+	})
+
 	return impl
 }
 
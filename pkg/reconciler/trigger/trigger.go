@@ -33,9 +33,12 @@ import (
 	"knative.dev/pkg/resolver"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"knative.dev/eventing/pkg/apis/eventing/v1beta1"
 )
@@ -59,6 +62,10 @@ type Reconciler struct {
 	// Dynamic tracker to track AddressableTypes. It tracks Trigger subscribers.
 	addressableTracker duck.ListableTracker
 	uriResolver        *resolver.URIResolver
+
+	// targetsServer streams the targets config to the data plane, which reports the config it
+	// applied. It is nil if the data plane doesn't report it.
+	targetsServer *stream.Server
 }
 
 // Check that TriggerReconciler implements Interface
@@ -105,6 +112,8 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, t *brokerv1beta1.Trigger
 		// skip a trigger if it's not pointed to a gcp broker and doesn't have googlecloud finalizer string.
 		t.Status.MarkTopicReady()
 		t.Status.MarkSubscriptionReady()
+		t.Status.MarkFiltersReady()
+		t.Status.MarkDataPlaneNotTracked()
 		var reconcilerEvent *pkgreconciler.ReconcilerEvent
 		switch {
 		case event == nil:
@@ -123,6 +132,9 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, t *brokerv1beta1.Trigger
 func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) pkgreconciler.Event {
 	t.Status.InitializeConditions()
 	t.Status.PropagateBrokerStatus(&b.Status)
+	checkFilters(t)
+	checkSuspended(t)
+	r.checkDataPlane(t, b)
 
 	if err := r.resolveSubscriber(ctx, t, b); err != nil {
 		return err
@@ -132,10 +144,13 @@ func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1beta1.Trigger, b
 		b.SetDefaults(ctx)
 	}
 
-	ct := celltenant.TargetFromTrigger(t, b.Spec.Delivery)
+	ct := celltenant.TargetFromTrigger(t, b)
 	if err := r.targetReconciler.ReconcileRetryTopicAndSubscription(ctx, r.Recorder, ct); err != nil {
 		return err
 	}
+	if err := r.targetReconciler.ReconcileReplay(ctx, r.Recorder, ct); err != nil {
+		return err
+	}
 
 	if err := r.checkDependencyAnnotation(ctx, t); err != nil {
 		return err
@@ -144,6 +159,55 @@ func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1beta1.Trigger, b
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, triggerReconciled, "Trigger reconciled: \"%s/%s\"", t.Namespace, t.Name)
 }
 
+// checkFilters reports whether the filters of the trigger are valid. Invalid filters are normally
+// rejected by the webhook, but they may have been created before it validated them.
+func checkFilters(t *brokerv1beta1.Trigger) {
+	if err := t.ValidateFilters(); err != nil {
+		t.Status.MarkFiltersFailed("InvalidFilters", "%v", err)
+		return
+	}
+	t.Status.MarkFiltersReady()
+}
+
+// checkSuspended reports whether the delivery to the subscriber of the trigger is suspended.
+func checkSuspended(t *brokerv1beta1.Trigger) {
+	if t.IsSuspended() {
+		t.Status.MarkSuspended()
+		return
+	}
+	t.Status.MarkResumed()
+}
+
+// checkDataPlane reports whether the data plane applied the targets config with the latest spec of
+// the trigger.
+func (r *Reconciler) checkDataPlane(t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) {
+	if r.targetsServer == nil || !r.targetsServer.Serving() {
+		t.Status.MarkDataPlaneNotTracked()
+		return
+	}
+	bc := brokerresources.BrokerCellKey(b)
+	tc, ok := r.targetsServer.Applied(bc.Namespace, bc.Name)
+	if !ok {
+		// Keep the condition until the data plane reports the config it applied again, e.g. after
+		// the controller restarted, so that the ready triggers don't flap.
+		if !t.Status.GetCondition(brokerv1beta1.TriggerConditionDataPlane).IsTrue() {
+			t.Status.MarkDataPlaneUnknown("DataPlaneNotReported", "The data plane didn't report the targets config it applied")
+		}
+		return
+	}
+	checkAppliedConfig(t, tc)
+}
+
+// checkAppliedConfig reports whether the targets config applied by the data plane has the trigger at
+// its latest generation.
+func checkAppliedConfig(t *brokerv1beta1.Trigger, tc *config.TargetsConfig) {
+	if !tc.HasTriggerGeneration(t) {
+		t.Status.MarkDataPlaneUnknown("ConfigNotApplied", "The data plane didn't apply the generation %d of the Trigger yet", t.Generation)
+		return
+	}
+	t.Status.MarkDataPlaneReady()
+}
+
 // FinalizeKind frees GCP Broker related resources for this Trigger if applicable. It's called when:
 // 1) the Trigger is being deleted;
 // 2) the Broker of this Trigger is deleted;
@@ -157,6 +221,9 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, t *brokerv1beta1.Trigger)
 		return nil
 	}
 	ct := celltenant.TargetFromTrigger(t, nil)
+	if err := r.targetReconciler.DeleteReplay(ctx, r.Recorder, ct); err != nil {
+		return err
+	}
 	if err := r.targetReconciler.DeleteRetryTopicAndSubscription(ctx, r.Recorder, ct); err != nil {
 		return err
 	}
@@ -178,6 +245,12 @@ func (r *Reconciler) resolveSubscriber(ctx context.Context, t *brokerv1beta1.Tri
 		return err
 	}
 	t.Status.SubscriberURI = subscriberURI
+	// The data plane sends no ID token for an audience that doesn't match the subscriber, so the
+	// Trigger reports it rather than its deliveries failing to authenticate.
+	if _, err := t.SubscriberAudience(); err != nil {
+		t.Status.MarkSubscriberResolvedFailed("InvalidSubscriberAudience", "%v", err)
+		return nil
+	}
 	t.Status.MarkSubscriberResolvedSucceeded()
 
 	return nil
@@ -43,12 +43,16 @@ import (
 	"knative.dev/pkg/controller"
 	. "knative.dev/pkg/reconciler/testing"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/client/injection/ducks/duck/v1alpha1/resource"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	. "github.com/google/knative-gcp/pkg/reconciler/testing"
 )
 
@@ -65,6 +69,8 @@ const (
 	subscriberName    = "subscriber-name"
 	subscriberGroup   = "serving.knative.dev"
 	subscriberVersion = "v1"
+
+	invalidFilters = `[{"sql": "type LIKE"}]`
 )
 
 var (
@@ -101,6 +107,20 @@ var (
 			},
 		},
 	}
+	brokerDeliverySpecWithHTTPDeadLetterSink = &eventingduckv1beta1.DeliverySpec{
+		BackoffDelay:  &backoffDelay,
+		BackoffPolicy: &backoffPolicy,
+		Retry:         &retry,
+		DeadLetterSink: &duckv1.Destination{
+			URI: &apis.URL{
+				Scheme: "http",
+				Host:   "dls.example.com",
+			},
+		},
+	}
+	replayFrom  = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	replayUntil = replayFrom.Add(2 * time.Hour)
+
 	brokerDeliverySpecWithoutRetry = &eventingduckv1beta1.DeliverySpec{
 		BackoffDelay:  &backoffDelay,
 		BackoffPolicy: &backoffPolicy,
@@ -281,6 +301,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSetDefaults,
 					WithInitTriggerConditions,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerSubscriptionReady,
 				),
 			}},
@@ -308,6 +330,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerUnknown("Broker/", ""),
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -354,6 +378,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
 					WithInitTriggerConditions,
 					WithTriggerBrokerReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerSubscriberResolvedFailed("Unable to get the Subscriber's URI", `services.serving.knative.dev "subscriber-name" not found`),
 					WithTriggerSetDefaults,
 				),
@@ -391,6 +417,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -431,6 +459,384 @@ func TestAllCasesTrigger(t *testing.T) {
 				}),
 			},
 		},
+		{
+			Name: "Trigger created, broker has an addressable dead letter sink",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpecWithHTTPDeadLetterSink),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+				SubscriptionHasDeadLetterPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.DeadLetterPolicy{
+						MaxDeliveryAttempts: 100,
+						DeadLetterTopic:     "projects/test-project-id/topics/cre-tgr_testnamespace_test-trigger_abc123",
+					}),
+			},
+		},
+		{
+			Name: "Trigger with invalid filters",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.FiltersAnnotationKey, invalidFilters),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.FiltersAnnotationKey, invalidFilters),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersFailed("InvalidFilters", invalidFiltersMessage()),
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger with a subscriber audience on another host",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "https://victim.example.com"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.SubscriberAudienceAnnotationKey, "https://victim.example.com"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedFailed("InvalidSubscriberAudience", `audience "https://victim.example.com" is not on the host example.com of the subscriber`),
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger suspended",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.SuspendedAnnotationKey, "true"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.SuspendedAnnotationKey, "true"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerSuspended,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger replay, broker doesn't retain events",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, replayFrom.Format(time.RFC3339)),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, replayFrom.Format(time.RFC3339)),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerReplayFailed("RetentionDisabled", "Events are not retained, set the events.cloud.google.com/retention annotation on the Broker to replay them"),
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger replay, replay subscription already seeded",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.RetentionAnnotationKey, "24h"),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, replayFrom.Format(time.RFC3339)),
+					WithTriggerReplayReady(replayFrom, replayUntil),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1beta1.ReplayFromAnnotationKey, replayFrom.Format(time.RFC3339)),
+					WithTriggerReplayReady(replayFrom, replayUntil),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					Topic("cre-bkr_testnamespace_test-broker_"),
+					replaySubscription("cre-tgr-rpl_testnamespace_test-trigger_abc123", "cre-bkr_testnamespace_test-broker_", replayFrom),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123", "cre-tgr-rpl_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger replay stopped, replay subscription is deleted",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.RetentionAnnotationKey, "24h"),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerReplayReady(replayFrom, replayUntil),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				Eventf(corev1.EventTypeNormal, "ReplayStopped", `Deleted replay subscription "cre-tgr-rpl_testnamespace_test-trigger_abc123"`),
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					Topic("cre-bkr_testnamespace_test-broker_"),
+					replaySubscription("cre-tgr-rpl_testnamespace_test-trigger_abc123", "cre-bkr_testnamespace_test-broker_", replayFrom),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
 		{
 			Name: "Sub already exists, update config",
 			Key:  testKey,
@@ -455,6 +861,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -514,6 +922,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -581,6 +991,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -649,6 +1061,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerDependencyReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
@@ -716,6 +1130,8 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerBrokerReady,
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
+					WithTriggerFiltersReady,
+					WithTriggerDataPlaneNotTracked,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
@@ -817,6 +1233,26 @@ func makeSubscriberAddressableAsUnstructured() *unstructured.Unstructured {
 }
 
 // TODO Move to a util package so all reconciler tests can use.
+// invalidFiltersMessage returns the message of the FiltersReady condition of a trigger with
+// invalidFilters.
+// replaySubscription creates a replay subscription seeded from the given time.
+func replaySubscription(id, tid string, from time.Time) PubsubAction {
+	return func(ctx context.Context, t *testing.T, c *pubsub.Client) {
+		_, err := c.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
+			Topic:  c.Topic(tid),
+			Labels: map[string]string{"replay-from": fmt.Sprint(from.UnixNano())},
+		})
+		if err != nil {
+			t.Fatalf("Error creating subscription %q: %v", id, err)
+		}
+	}
+}
+
+func invalidFiltersMessage() string {
+	t := NewTrigger(triggerName, testNS, brokerName, WithTriggerAnnotation(brokerv1beta1.FiltersAnnotationKey, invalidFilters))
+	return t.ValidateFilters().Error()
+}
+
 func patchFinalizers(namespace, name, finalizer string) clientgotesting.PatchActionImpl {
 	action := clientgotesting.PatchActionImpl{}
 	action.Name = name
@@ -835,3 +1271,112 @@ func patchRemoveFinalizers(namespace, name string) clientgotesting.PatchActionIm
 	action.Patch = []byte(patch)
 	return action
 }
+
+func TestCheckDataPlane(t *testing.T) {
+	newTrigger := func(opts ...TriggerOption) *brokerv1beta1.Trigger {
+		t := NewTrigger(triggerName, testNS, brokerName, append([]TriggerOption{WithTriggerUID(testUID), WithTriggerGeneration(2)}, opts...)...)
+		t.Status.InitializeConditions()
+		return t
+	}
+	applied := func(generation int64) *config.TargetsConfig {
+		return &config.TargetsConfig{CellTenants: map[string]*config.CellTenant{
+			config.TestOnlyBrokerKey(testNS, brokerName).PersistenceString(): {
+				Type:      config.CellTenantType_BROKER,
+				Namespace: testNS,
+				Name:      brokerName,
+				Targets: map[string]*config.Target{
+					triggerName: {
+						Id:         testUID,
+						Namespace:  testNS,
+						Name:       triggerName,
+						Generation: generation,
+					},
+				},
+			},
+		}}
+	}
+
+	testCases := []struct {
+		name       string
+		notServing bool
+		applied    *config.TargetsConfig
+		trigger    *brokerv1beta1.Trigger
+		// brokerCell is the brokerCell annotation of the broker.
+		brokerCell string
+		// appliedBy is the brokercell that applied the config, the brokercell of the broker if empty.
+		appliedBy  *types.NamespacedName
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{{
+		name:       "data plane not tracked",
+		notServing: true,
+		trigger:    newTrigger(),
+		wantStatus: corev1.ConditionTrue,
+		wantReason: "NotTracked",
+	}, {
+		name:       "data plane not reported",
+		trigger:    newTrigger(),
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "DataPlaneNotReported",
+	}, {
+		name:       "data plane ready until reported",
+		trigger:    newTrigger(WithTriggerDataPlaneReady),
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "older generation applied",
+		applied:    applied(1),
+		trigger:    newTrigger(WithTriggerDataPlaneReady),
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "ConfigNotApplied",
+	}, {
+		name:       "latest generation applied",
+		applied:    applied(2),
+		trigger:    newTrigger(),
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "latest generation applied by the brokercell of the broker",
+		applied:    applied(2),
+		trigger:    newTrigger(),
+		brokerCell: "team-a",
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "latest generation applied by another brokercell",
+		applied:    applied(2),
+		trigger:    newTrigger(),
+		brokerCell: "team-a",
+		appliedBy:  &types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName},
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "DataPlaneNotReported",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := stream.NewLocalServer(time.Second)
+			defer s.Close()
+			r := &Reconciler{targetsServer: s.Server}
+			if tc.notServing {
+				r.targetsServer = stream.NewServer()
+			}
+			b := NewBroker(brokerName, testNS)
+			if tc.brokerCell != "" {
+				b.SetAnnotations(map[string]string{brokerv1beta1.BrokerCellAnnotationKey: tc.brokerCell})
+			}
+			if tc.applied != nil {
+				bc := brokerresources.BrokerCellKey(b)
+				if tc.appliedBy != nil {
+					bc = *tc.appliedBy
+				}
+				version, err := s.Publish(bc.Namespace, bc.Name, tc.applied)
+				if err != nil {
+					t.Fatalf("Failed to publish config: %v", err)
+				}
+				s.Report(bc.Namespace, bc.Name, "pod", version)
+			}
+
+			r.checkDataPlane(tc.trigger, b)
+			got := tc.trigger.Status.GetCondition(brokerv1beta1.TriggerConditionDataPlane)
+			if got.Status != tc.wantStatus || got.Reason != tc.wantReason {
+				t.Errorf("Unexpected data plane condition, got %v, want status %v and reason %q", got, tc.wantStatus, tc.wantReason)
+			}
+		})
+	}
+}
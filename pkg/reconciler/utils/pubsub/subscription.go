@@ -66,12 +66,15 @@ func (r *Reconciler) ReconcileSubscription(ctx context.Context, id string, subCo
 			}
 			return r.createSubscription(ctx, id, subConfig, obj, updater)
 		}
-		// Update the subscription config in case the retry or dead letter policy changed. A nil policy indicates no change.
+		// Update the subscription config in case the retry or dead letter policy, or the retention
+		// duration changed. A nil policy or a zero duration indicates no change.
 		if (subConfig.RetryPolicy != nil && !equality.Semantic.DeepEqual(config.RetryPolicy, subConfig.RetryPolicy)) ||
-			(subConfig.DeadLetterPolicy != nil && !equality.Semantic.DeepEqual(config.DeadLetterPolicy, subConfig.DeadLetterPolicy)) {
+			(subConfig.DeadLetterPolicy != nil && !equality.Semantic.DeepEqual(config.DeadLetterPolicy, subConfig.DeadLetterPolicy)) ||
+			(subConfig.RetentionDuration != 0 && config.RetentionDuration != subConfig.RetentionDuration) {
 			updateSubConfig := pubsub.SubscriptionConfigToUpdate{
-				RetryPolicy:      subConfig.RetryPolicy,
-				DeadLetterPolicy: subConfig.DeadLetterPolicy,
+				RetryPolicy:       subConfig.RetryPolicy,
+				DeadLetterPolicy:  subConfig.DeadLetterPolicy,
+				RetentionDuration: subConfig.RetentionDuration,
 			}
 			if _, err := sub.Update(ctx, updateSubConfig); err != nil {
 				updater.MarkSubscriptionFailed("SubscriptionConfigUpdateFailed", "Failed to update Pub/Sub subscription config: %v", err)
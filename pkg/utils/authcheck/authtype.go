@@ -81,6 +81,12 @@ func GetAuthTypeForBrokerCell(ctx context.Context, serviceAccountLister corev1li
 	if workloadIdentityErr == nil {
 		return authTypeForWorkloadIdentity, nil
 	}
+	if args.Namespace != ControlPlaneNamespace {
+		// Controller doesn't have the permission to check the existence of a secret in namespaces
+		// other than the control plane's namespace, so BrokerCells there must use Workload Identity.
+		return "", fmt.Errorf("authentication is not configured, BrokerCells outside namespace %s need Workload Identity, when checking Kubernetes Service Account %s, got error: %s",
+			ControlPlaneNamespace, args.ServiceAccountName, workloadIdentityErr.Error())
+	}
 	authTypeForSecret, secretErr := getAuthTypeForSecret(ctx, secretLister, args)
 	if secretErr == nil {
 		return authTypeForSecret, nil
@@ -138,12 +144,7 @@ func getAuthTypeForWorkloadIdentity(ctx context.Context, serviceAccountLister co
 }
 
 func getAuthTypeForSecret(ctx context.Context, secretLister corev1listers.SecretLister, args AuthTypeArgs) (AuthType, error) {
-	// Controller doesn't have the permission to check the existence of a secret in namespaces
-	// other than the control plane's namespace.
-	if args.Namespace != ControlPlaneNamespace {
-		return Secret, nil
-	}
-	// If current namespace is control plane's namespace, check the existence of the secret and its key.
+	// Check the existence of the secret and its key.
 	secret, err := secretLister.Secrets(args.Namespace).Get(args.Secret.Name)
 	if err != nil {
 		if apierrs.IsNotFound(err) {
@@ -216,6 +216,37 @@ func TestGetAuthTypeForBrokerCell(t *testing.T) {
 				"when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker, " +
 				"when checking Kubernetes Secret google-broker-key, got error: the Kubernetes Secret google-broker-key does not have required key key.json"),
 		},
+		{
+			name: "error get authType, service account doesn't exist outside broker namespace",
+			objects: []runtime.Object{
+				pkgtesting.NewSecret(brokerSecretName, testNS,
+					pkgtesting.WithData(map[string][]byte{
+						"key.json": make([]byte, 5, 5),
+					})),
+			},
+			args: AuthTypeArgs{
+				Namespace:          testNS,
+				ServiceAccountName: brokerServiceAccountName,
+				Secret:             brokerArgs.Secret,
+			},
+			wantAuthType: "",
+			wantError: fmt.Errorf("authentication is not configured, BrokerCells outside namespace events-system need Workload Identity, " +
+				"when checking Kubernetes Service Account broker, got error: can't find Kubernetes Service Account broker"),
+		},
+		{
+			name: "successfully get authType outside broker namespace",
+			objects: []runtime.Object{
+				pkgtesting.NewServiceAccount(brokerServiceAccountName, testNS,
+					pkgtesting.WithServiceAccountAnnotation("test123@test123.iam.gserviceaccount.com")),
+			},
+			args: AuthTypeArgs{
+				Namespace:          testNS,
+				ServiceAccountName: brokerServiceAccountName,
+				Secret:             brokerArgs.Secret,
+			},
+			wantAuthType: WorkloadIdentityGSA,
+			wantError:    nil,
+		},
 		{
 			name: "successfully get authType in broker namespace",
 			objects: []runtime.Object{
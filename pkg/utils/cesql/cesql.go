@@ -0,0 +1,130 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cesql parses and evaluates CloudEvents SQL (CESQL) expressions, such as
+// `type LIKE 'com.example.%' AND severity > 3`.
+//
+// Expressions have the three CESQL types: 32-bit signed integers, strings, and booleans. Operands
+// are cast to the type an operator expects, e.g. the string attribute `severity` is cast to an
+// integer in `severity > 3`. Operators follow the usual SQL precedence, from lowest to highest:
+// OR, XOR, AND, NOT, comparisons (including LIKE and IN), + and -, *, / and %, and unary minus.
+package cesql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+var (
+	// ErrMissingAttribute is the error when an expression references an attribute that the event
+	// doesn't have.
+	ErrMissingAttribute = errors.New("missing attribute")
+	// ErrCast is the error when a value can't be cast to the type required by an operator or
+	// function.
+	ErrCast = errors.New("invalid cast")
+	// ErrMath is the error when an arithmetic operation is undefined, such as a division by zero.
+	ErrMath = errors.New("math error")
+)
+
+// ParseError is the error when an expression can't be parsed.
+type ParseError struct {
+	// Pos is the byte offset in the expression where the error was found.
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cesql: %s at position %d", e.Msg, e.Pos)
+}
+
+// Expression is a compiled CESQL expression. It is safe for concurrent use.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse compiles a CESQL expression.
+func Parse(s string) (*Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{source: s, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression against the event. The result is an int32, a string, or a
+// bool.
+func (e *Expression) Evaluate(event *event.Event) (interface{}, error) {
+	return e.root.eval(attributesOf(event))
+}
+
+// Match evaluates the expression against the event and casts the result to a boolean. An event
+// that causes an evaluation error doesn't match.
+func (e *Expression) Match(event *event.Event) (bool, error) {
+	v, err := e.Evaluate(event)
+	if err != nil {
+		return false, err
+	}
+	return castToBool(v)
+}
+
+// attributesOf returns the context attributes and extensions of the event that are set.
+// Extensions keep their integer or boolean type, all other values are strings.
+func attributesOf(event *event.Event) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"specversion": event.SpecVersion(),
+		"type":        event.Type(),
+		"source":      event.Source(),
+		"id":          event.ID(),
+	}
+	optional := map[string]string{
+		"subject":         event.Subject(),
+		"dataschema":      event.DataSchema(),
+		"datacontenttype": event.DataContentType(),
+	}
+	for k, v := range optional {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	if !event.Time().IsZero() {
+		attrs["time"] = cetypes.FormatTime(event.Time())
+	}
+	for k, v := range event.Extensions() {
+		switch v := v.(type) {
+		case int32, bool:
+			attrs[k] = v
+		default:
+			if s, err := cetypes.Format(v); err == nil {
+				attrs[k] = s
+			}
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func testEvent() *event.Event {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("https://example.com/orders")
+	e.SetType("com.example.orders.created")
+	e.SetTime(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	e.SetExtension("severity", "5")
+	e.SetExtension("priority", 3)
+	e.SetExtension("urgent", true)
+	e.SetExtension("region", "us-central1")
+	return &e
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    interface{}
+		wantErr error
+	}{
+		{expr: "TRUE", want: true},
+		{expr: `'it\'s'`, want: "it's"},
+		{expr: `"a \"quoted\" string"`, want: `a "quoted" string`},
+		{expr: "-2147483648", want: int32(math.MinInt32)},
+		{expr: "1 + 2 * 3", want: int32(7)},
+		{expr: "(1 + 2) * 3", want: int32(9)},
+		{expr: "7 / 2", want: int32(3)},
+		{expr: "7 % 2", want: int32(1)},
+		{expr: "- (1 - 3)", want: int32(2)},
+		{expr: "1 / 0", wantErr: ErrMath},
+		{expr: "type", want: "com.example.orders.created"},
+		{expr: "priority", want: int32(3)},
+		{expr: "time", want: "2021-01-02T03:04:05Z"},
+		{expr: "subject", wantErr: ErrMissingAttribute},
+		{expr: "EXISTS subject", want: false},
+		{expr: "EXISTS region", want: true},
+		{expr: "type LIKE 'com.example.%' AND severity > 3", want: true},
+		{expr: "type LIKE 'com.example.%' AND severity > 5", want: false},
+		{expr: "type LIKE 'com.example.orders.create_'", want: true},
+		{expr: "type NOT LIKE 'com.example.%'", want: false},
+		{expr: `'50%' LIKE '50\%'`, want: true},
+		{expr: `'500' LIKE '50\%'`, want: false},
+		{expr: "region IN ('europe-west1', 'us-central1')", want: true},
+		{expr: "region NOT IN ('europe-west1', 'us-central1')", want: false},
+		{expr: "priority IN (1, 2, 3)", want: true},
+		{expr: "severity = 5", want: true},
+		{expr: "5 = severity", want: true},
+		{expr: "priority = '3'", want: true},
+		{expr: "priority <> 3", want: false},
+		{expr: "urgent = 'TRUE'", want: true},
+		{expr: "region = 5", want: false},
+		{expr: "region > 5", wantErr: ErrCast},
+		{expr: "urgent AND severity >= 5", want: true},
+		{expr: "NOT urgent OR TRUE", want: true},
+		{expr: "FALSE OR TRUE AND FALSE", want: false},
+		{expr: "TRUE XOR TRUE", want: false},
+		{expr: "TRUE OR subject = 'a'", want: true},
+		{expr: "FALSE AND subject = 'a'", want: false},
+		{expr: "NOT EXISTS subject OR subject = 'a'", want: true},
+		{expr: "LENGTH(region)", want: int32(11)},
+		{expr: "CONCAT(type, '/', id)", want: "com.example.orders.created/id"},
+		{expr: "CONCAT_WS('-', 'a', 1, TRUE)", want: "a-1-true"},
+		{expr: "UPPER(LEFT(region, 2))", want: "US"},
+		{expr: "LOWER(RIGHT('ABC', 10))", want: "abc"},
+		{expr: "TRIM('  a ')", want: "a"},
+		{expr: "ABS(-3)", want: int32(3)},
+		{expr: "INT('42') + 1", want: int32(43)},
+		{expr: "BOOL('false')", want: false},
+		{expr: "STRING(42)", want: "42"},
+		{expr: "IS_INT(region)", want: false},
+		{expr: "IS_BOOL(urgent)", want: true},
+		{expr: "LEFT(region, -1)", wantErr: ErrMath},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			got, err := expr.Evaluate(testEvent())
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Evaluate() error = %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("Evaluate() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"type =",
+		"type = 'a' AND",
+		"(type = 'a'",
+		"type = 'a')",
+		"'unterminated",
+		"Type = 'a'",
+		"type == 'a'",
+		"type LIKE region",
+		"type IN ()",
+		"type NOT 'a'",
+		"EXISTS 'a'",
+		"UNKNOWN(type)",
+		"LENGTH()",
+		"LEFT('a')",
+		"2147483648",
+		"a < b < c",
+		"type ; 'a'",
+		"0 LIKE'\xef'",
+	}
+	for _, s := range tests {
+		t.Run(s, func(t *testing.T) {
+			_, err := Parse(s)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Errorf("Parse() error = %v, want a ParseError", err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "type LIKE 'com.example.%'", want: true},
+		{expr: "region", wantErr: true},
+		{expr: "'true'", want: true},
+		{expr: "subject = 'a'", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			got, err := expr.Match(testEvent())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Match() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Match() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// node is a node of the syntax tree of an expression.
+type node interface {
+	// eval evaluates the node with the given event attributes. The result is an int32, a string,
+	// or a bool.
+	eval(attrs map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type attributeNode struct {
+	name string
+}
+
+func (n *attributeNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	v, ok := attrs[n.name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrMissingAttribute, n.name)
+	}
+	return v, nil
+}
+
+type existsNode struct {
+	name string
+}
+
+func (n *existsNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	_, ok := attrs[n.name]
+	return ok, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	b, err := evalBool(n.operand, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	i, err := evalInt(n.operand, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return -i, nil
+}
+
+// logicNode is AND, OR, or XOR. AND and OR don't evaluate their right operand if the left
+// operand determines the result.
+type logicNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if (n.op == "AND" && !left) || (n.op == "OR" && left) {
+		return left, nil
+	}
+	right, err := evalBool(n.right, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "XOR" {
+		return left != right, nil
+	}
+	return right, nil
+}
+
+type arithmeticNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithmeticNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	left, err := evalInt(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalInt(n.right, attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	}
+	if right == 0 {
+		return nil, fmt.Errorf("%w: division by zero", ErrMath)
+	}
+	if n.op == "/" {
+		return left / right, nil
+	}
+	return left % right, nil
+}
+
+// comparisonNode compares two values. The relational operators compare integers. The equality
+// operators cast the right operand to the type of the left operand.
+type comparisonNode struct {
+	op          string
+	left, right node
+}
+
+func (n *comparisonNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	switch n.op {
+	case "=":
+		return evalEqual(n.left, n.right, attrs)
+	case "!=", "<>":
+		eq, err := evalEqual(n.left, n.right, attrs)
+		if err != nil {
+			return nil, err
+		}
+		return !eq, nil
+	}
+	left, err := evalInt(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalInt(n.right, attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return left < right, nil
+	case "<=":
+		return left <= right, nil
+	case ">":
+		return left > right, nil
+	default:
+		return left >= right, nil
+	}
+}
+
+type likeNode struct {
+	operand node
+	pattern *regexp.Regexp
+	negated bool
+}
+
+func (n *likeNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return n.pattern.MatchString(castToString(v)) != n.negated, nil
+}
+
+type inNode struct {
+	operand node
+	set     []node
+	negated bool
+}
+
+func (n *inNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range n.set {
+		e, err := s.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		if eq, err := equal(v, e); err == nil && eq {
+			return !n.negated, nil
+		}
+	}
+	return n.negated, nil
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(attrs map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, a := range n.args {
+		v, err := a.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+func evalBool(n node, attrs map[string]interface{}) (bool, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	return castToBool(v)
+}
+
+func evalInt(n node, attrs map[string]interface{}) (int32, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return 0, err
+	}
+	return castToInt(v)
+}
+
+func evalEqual(left, right node, attrs map[string]interface{}) (bool, error) {
+	l, err := left.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	r, err := right.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	return equal(l, r)
+}
+
+// equal compares two values after casting the right value to the type of the left value.
+func equal(left, right interface{}) (bool, error) {
+	switch l := left.(type) {
+	case int32:
+		r, err := castToInt(right)
+		return err == nil && l == r, err
+	case bool:
+		r, err := castToBool(right)
+		return err == nil && l == r, err
+	default:
+		return left == castToString(right), nil
+	}
+}
+
+func castToInt(v interface{}) (int32, error) {
+	switch v := v.(type) {
+	case int32:
+		return v, nil
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is not an integer", ErrCast, v)
+		}
+		return int32(i), nil
+	default:
+		return 0, fmt.Errorf("%w: %v is not an integer", ErrCast, v)
+	}
+}
+
+func castToBool(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %q is not a boolean", ErrCast, v)
+	default:
+		return false, fmt.Errorf("%w: %v is not a boolean", ErrCast, v)
+	}
+}
+
+func castToString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// function is a built-in function.
+type function struct {
+	minArgs int
+	// maxArgs is -1 for variadic functions.
+	maxArgs int
+	call    func(args []interface{}) (interface{}, error)
+}
+
+// functions are the built-in functions of CESQL, by name.
+var functions = map[string]function{
+	"LENGTH": {1, 1, func(args []interface{}) (interface{}, error) {
+		return int32(utf8.RuneCountInString(castToString(args[0]))), nil
+	}},
+	"CONCAT": {0, -1, func(args []interface{}) (interface{}, error) {
+		return strings.Join(castAllToString(args), ""), nil
+	}},
+	"CONCAT_WS": {1, -1, func(args []interface{}) (interface{}, error) {
+		return strings.Join(castAllToString(args[1:]), castToString(args[0])), nil
+	}},
+	"LOWER": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(castToString(args[0])), nil
+	}},
+	"UPPER": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(castToString(args[0])), nil
+	}},
+	"TRIM": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(castToString(args[0])), nil
+	}},
+	"LEFT": {2, 2, func(args []interface{}) (interface{}, error) {
+		s, n, err := stringAndLength(args)
+		if err != nil {
+			return nil, err
+		}
+		return string(s[:n]), nil
+	}},
+	"RIGHT": {2, 2, func(args []interface{}) (interface{}, error) {
+		s, n, err := stringAndLength(args)
+		if err != nil {
+			return nil, err
+		}
+		return string(s[len(s)-n:]), nil
+	}},
+	"ABS": {1, 1, func(args []interface{}) (interface{}, error) {
+		i, err := castToInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if i == math.MinInt32 {
+			return nil, fmt.Errorf("%w: integer overflow", ErrMath)
+		}
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}},
+	"INT": {1, 1, func(args []interface{}) (interface{}, error) {
+		return castToInt(args[0])
+	}},
+	"BOOL": {1, 1, func(args []interface{}) (interface{}, error) {
+		return castToBool(args[0])
+	}},
+	"STRING": {1, 1, func(args []interface{}) (interface{}, error) {
+		return castToString(args[0]), nil
+	}},
+	"IS_INT": {1, 1, func(args []interface{}) (interface{}, error) {
+		_, err := castToInt(args[0])
+		return err == nil, nil
+	}},
+	"IS_BOOL": {1, 1, func(args []interface{}) (interface{}, error) {
+		_, err := castToBool(args[0])
+		return err == nil, nil
+	}},
+}
+
+func castAllToString(args []interface{}) []string {
+	s := make([]string, 0, len(args))
+	for _, a := range args {
+		s = append(s, castToString(a))
+	}
+	return s
+}
+
+// stringAndLength returns the runes of the first argument and the second argument, capped to the
+// number of runes.
+func stringAndLength(args []interface{}) ([]rune, int, error) {
+	s := []rune(castToString(args[0]))
+	n, err := castToInt(args[1])
+	if err != nil {
+		return nil, 0, err
+	}
+	if n < 0 {
+		return nil, 0, fmt.Errorf("%w: negative length %d", ErrMath, n)
+	}
+	if int(n) > len(s) {
+		return s, len(s), nil
+	}
+	return s, int(n), nil
+}
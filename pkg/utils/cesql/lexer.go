@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenIdentifier is a lower case attribute name.
+	tokenIdentifier
+	// tokenKeyword is an upper case keyword or function name.
+	tokenKeyword
+	tokenInteger
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the expression.
+	pos int
+}
+
+// operators are the operators and punctuation of the language. Two character operators come
+// first so that they are matched before their one character prefixes.
+var operators = []string{"!=", "<>", "<=", ">=", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ","}
+
+// tokenize splits the expression into tokens. The returned tokens always end with a tokenEOF.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			text, n, err := scanString(s[i:])
+			if err != nil {
+				return nil, &ParseError{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i += n
+		case isWordChar(c):
+			j := i
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			kind, err := classifyWord(s[i:j])
+			if err != nil {
+				return nil, &ParseError{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: kind, text: s[i:j], pos: i})
+			i = j
+		default:
+			op := matchOperator(s[i:])
+			if op == "" {
+				return nil, &ParseError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// scanString scans a single or double quoted string literal at the beginning of s. A backslash
+// escapes the quote character, other backslashes are kept as is, e.g. for LIKE patterns. It
+// returns the unquoted value and the length of the literal in s.
+func scanString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case s[i] == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+func isWordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+}
+
+// classifyWord tells integers, attribute names, and keywords apart. Attribute names are lower
+// case, as required by the CloudEvents spec, while keywords and function names are upper case.
+func classifyWord(w string) (tokenKind, error) {
+	digits, lower, upper := true, true, true
+	for i := 0; i < len(w); i++ {
+		c := w[i]
+		isDigit := c >= '0' && c <= '9'
+		digits = digits && isDigit
+		lower = lower && (isDigit || (c >= 'a' && c <= 'z'))
+		upper = upper && (isDigit || (c >= 'A' && c <= 'Z') || c == '_')
+	}
+	switch {
+	case digits:
+		return tokenInteger, nil
+	case lower:
+		return tokenIdentifier, nil
+	case upper:
+		return tokenKeyword, nil
+	default:
+		return tokenEOF, fmt.Errorf("invalid identifier %q: attribute names must consist of lower-case letters or digits", w)
+	}
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
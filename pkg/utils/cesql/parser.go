@@ -0,0 +1,362 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive descent parser with one method per precedence level.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given keyword or operator.
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if !p.accept(kind, text) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return &ParseError{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseXor()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenKeyword, "OR") {
+		right, err := p.parseXor()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseXor() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenKeyword, "XOR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{op: "XOR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenKeyword, "AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept(tokenKeyword, "NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses the non associative comparison operators, LIKE, and IN.
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokenOperator && isComparisonOperator(t.text):
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &comparisonNode{op: t.text, left: left, right: right}, nil
+	case t.kind == tokenKeyword && t.text == "NOT":
+		p.next()
+		if p.accept(tokenKeyword, "LIKE") {
+			return p.parseLike(left, true)
+		}
+		if p.accept(tokenKeyword, "IN") {
+			return p.parseIn(left, true)
+		}
+		return nil, p.unexpected(p.peek())
+	case t.kind == tokenKeyword && t.text == "LIKE":
+		p.next()
+		return p.parseLike(left, false)
+	case t.kind == tokenKeyword && t.text == "IN":
+		p.next()
+		return p.parseIn(left, false)
+	}
+	return left, nil
+}
+
+func isComparisonOperator(op string) bool {
+	switch op {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// parseLike parses the pattern of a LIKE expression, which must be a string literal.
+func (p *parser) parseLike(operand node, negated bool) (node, error) {
+	t := p.next()
+	if t.kind != tokenString {
+		return nil, &ParseError{Pos: t.pos, Msg: "expected a string literal pattern after LIKE"}
+	}
+	pattern, err := likePattern(t.text)
+	if err != nil {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("invalid LIKE pattern: %v", err)}
+	}
+	return &likeNode{operand: operand, pattern: pattern, negated: negated}, nil
+}
+
+// likePattern converts a LIKE pattern to a regular expression. '%' matches any sequence of
+// characters, '_' matches any single character, and a backslash escapes the next character. The
+// pattern may not compile, e.g. if it isn't valid UTF-8.
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func (p *parser) parseIn(operand node, negated bool) (node, error) {
+	if err := p.expect(tokenOperator, "("); err != nil {
+		return nil, err
+	}
+	set, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(set) == 0 {
+		return nil, &ParseError{Pos: p.tokens[p.pos-1].pos, Msg: "expected at least one value after IN"}
+	}
+	return &inNode{operand: operand, set: set, negated: negated}, nil
+}
+
+// parseList parses a comma separated list of expressions, after the opening parenthesis.
+func (p *parser) parseList() ([]node, error) {
+	var list []node
+	if p.accept(tokenOperator, ")") {
+		return list, nil
+	}
+	for {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+		if p.accept(tokenOperator, ")") {
+			return list, nil
+		}
+		if err := p.expect(tokenOperator, ","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || (t.text != "*" && t.text != "/" && t.text != "%") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if !p.accept(tokenOperator, "-") {
+		return p.parsePrimary()
+	}
+	// Negative integer literals are parsed directly, so that the smallest int32 can be written.
+	if t := p.peek(); t.kind == tokenInteger {
+		p.next()
+		return parseInteger(t, "-")
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &negateNode{operand: operand}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenInteger:
+		return parseInteger(t, "")
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdentifier:
+		return &attributeNode{name: t.text}, nil
+	case tokenOperator:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenOperator, ")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	case tokenKeyword:
+		switch t.text {
+		case "TRUE":
+			return &literalNode{value: true}, nil
+		case "FALSE":
+			return &literalNode{value: false}, nil
+		case "EXISTS":
+			name := p.next()
+			if name.kind != tokenIdentifier {
+				return nil, &ParseError{Pos: name.pos, Msg: "expected an attribute name after EXISTS"}
+			}
+			return &existsNode{name: name.text}, nil
+		}
+		if p.accept(tokenOperator, "(") {
+			return p.parseCall(t)
+		}
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, &ParseError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %s", name.text)}
+	}
+	args, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, &ParseError{Pos: name.pos, Msg: fmt.Sprintf("wrong number of arguments for %s: %d", name.text, len(args))}
+	}
+	return &callNode{name: name.text, fn: fn, args: args}, nil
+}
+
+func parseInteger(t token, sign string) (node, error) {
+	i, err := strconv.ParseInt(sign+t.text, 10, 32)
+	if err != nil {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("invalid integer %s%s", sign, t.text)}
+	}
+	return &literalNode{value: int32(i)}, nil
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
+)
+
+// GoogleIssuers are the issuer claims of Google-signed ID tokens.
+var GoogleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// oidcConfig is the configuration of the go-oidc verifiers. The audience is checked by the caller,
+// which may allow several audiences.
+var oidcConfig = &oidc.Config{
+	SkipClientIDCheck:    true,
+	SupportedSigningAlgs: []string{oidc.RS256, oidc.ES256},
+}
+
+type googleIssuer struct {
+	validator *idtoken.Validator
+}
+
+// NewGoogleIssuer creates an Issuer of Google-signed ID tokens. Google's keys are fetched with the
+// client.
+func NewGoogleIssuer(client *http.Client) (Issuer, error) {
+	validator, err := idtoken.NewValidator(context.Background(), option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	return &googleIssuer{validator: validator}, nil
+}
+
+// Verify implements Issuer.
+func (g *googleIssuer) Verify(ctx context.Context, token string) (*Claims, error) {
+	payload, err := g.validator.Validate(ctx, token, "")
+	if err != nil {
+		return nil, err
+	}
+	// The idtoken package doesn't check the issuer claim.
+	if payload.Issuer != GoogleIssuers[0] && payload.Issuer != GoogleIssuers[1] {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownIssuer, payload.Issuer)
+	}
+	return &Claims{
+		Issuer:   payload.Issuer,
+		Subject:  payload.Subject,
+		Audience: []string{payload.Audience},
+		Email:    verifiedEmail(payload.Claims),
+	}, nil
+}
+
+// verifiedEmail returns the email claim of a Google-signed ID token, or an empty string if Google
+// didn't verify the email.
+func verifiedEmail(claims map[string]interface{}) string {
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		return ""
+	}
+	email, _ := claims["email"].(string)
+	return email
+}
+
+type oidcIssuer struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewRemoteIssuer creates an Issuer whose tokens are signed by the keys of the JSON Web Key Set at
+// jwksURL. The keys are fetched with the client.
+func NewRemoteIssuer(issuer, jwksURL string, client *http.Client) Issuer {
+	// The key set fetches its keys with the context, so it must outlive the requests.
+	keySet := oidc.NewRemoteKeySet(oidc.ClientContext(context.Background(), client), jwksURL)
+	return &oidcIssuer{verifier: oidc.NewVerifier(issuer, keySet, oidcConfig)}
+}
+
+// Verify implements Issuer.
+func (o *oidcIssuer) Verify(ctx context.Context, token string) (*Claims, error) {
+	return verifyIDToken(ctx, o.verifier, token)
+}
+
+const (
+	// discoveryTimeout is the timeout of the OpenID Connect Discovery of an issuer.
+	discoveryTimeout = 10 * time.Second
+	// minDiscoveryBackoff and maxDiscoveryBackoff bound the delay before a failed discovery is
+	// tried again. The delay doubles with each consecutive failure.
+	minDiscoveryBackoff = time.Second
+	maxDiscoveryBackoff = time.Minute
+)
+
+type discoveredIssuer struct {
+	issuer string
+	client *http.Client
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+	// discovering is closed once the discovery in progress, if any, is done.
+	discovering chan struct{}
+	// err is the error of the last discovery, returned until retryAt.
+	err     error
+	retryAt time.Time
+	backoff time.Duration
+}
+
+// NewDiscoveredIssuer creates an Issuer whose JSON Web Key Set is discovered through OpenID Connect
+// Discovery when its first token is verified. The discovery document and keys are fetched with the
+// client.
+func NewDiscoveredIssuer(issuer string, client *http.Client) Issuer {
+	return &discoveredIssuer{issuer: issuer, client: client}
+}
+
+// Verify implements Issuer.
+func (d *discoveredIssuer) Verify(ctx context.Context, token string) (*Claims, error) {
+	verifier, err := d.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return verifyIDToken(ctx, verifier, token)
+}
+
+// discover returns the verifier of the issuer, discovering it if it wasn't yet. Concurrent calls
+// wait for the same discovery. After a failed discovery, the error is returned without trying
+// again until the backoff delay has passed.
+func (d *discoveredIssuer) discover(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	d.mu.Lock()
+	for d.discovering != nil {
+		discovering := d.discovering
+		d.mu.Unlock()
+		select {
+		case <-discovering:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		d.mu.Lock()
+	}
+	if d.verifier != nil || time.Now().Before(d.retryAt) {
+		defer d.mu.Unlock()
+		return d.verifier, d.err
+	}
+	discovering := make(chan struct{})
+	d.discovering = discovering
+	d.mu.Unlock()
+
+	verifier, err := d.newVerifier()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.verifier, d.err = verifier, err
+	if err != nil {
+		d.backoff *= 2
+		if d.backoff < minDiscoveryBackoff {
+			d.backoff = minDiscoveryBackoff
+		} else if d.backoff > maxDiscoveryBackoff {
+			d.backoff = maxDiscoveryBackoff
+		}
+		d.retryAt = time.Now().Add(d.backoff)
+	}
+	d.discovering = nil
+	close(discovering)
+	return verifier, err
+}
+
+// newVerifier discovers the key set of the issuer and returns a verifier of its tokens.
+func (d *discoveredIssuer) newVerifier() (*oidc.IDTokenVerifier, error) {
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(context.Background(), d.client), discoveryTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, d.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover issuer %q: %w", d.issuer, err)
+	}
+	var discovery struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover issuer %q: %w", d.issuer, err)
+	}
+	// The key set fetches its keys with the context, so it must outlive the discovery.
+	keySet := oidc.NewRemoteKeySet(oidc.ClientContext(context.Background(), d.client), discovery.JWKSURL)
+	return oidc.NewVerifier(d.issuer, keySet, oidcConfig), nil
+}
+
+func verifyIDToken(ctx context.Context, verifier *oidc.IDTokenVerifier, token string) (*Claims, error) {
+	t, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	// The email claim is ignored, as other issuers may not verify it.
+	return &Claims{
+		Issuer:   t.Issuer,
+		Subject:  t.Subject,
+		Audience: t.Audience,
+	}, nil
+}
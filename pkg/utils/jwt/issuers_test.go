@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   string
+	}{{
+		name:   "verified",
+		claims: map[string]interface{}{"email": "sa@project.iam.gserviceaccount.com", "email_verified": true},
+		want:   "sa@project.iam.gserviceaccount.com",
+	}, {
+		name:   "not verified",
+		claims: map[string]interface{}{"email": "sa@project.iam.gserviceaccount.com", "email_verified": false},
+	}, {
+		name:   "verification missing",
+		claims: map[string]interface{}{"email": "sa@project.iam.gserviceaccount.com"},
+	}, {
+		name:   "verification not a boolean",
+		claims: map[string]interface{}{"email": "sa@project.iam.gserviceaccount.com", "email_verified": "true"},
+	}, {
+		name:   "no email",
+		claims: map[string]interface{}{"email_verified": true},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := verifiedEmail(tc.claims); got != tc.want {
+				t.Errorf("verifiedEmail() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiscoveryBackoff(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	d := NewDiscoveredIssuer(srv.URL, srv.Client()).(*discoveredIssuer)
+
+	if _, err := d.discover(context.Background()); err == nil {
+		t.Fatal("discover() succeeded, want error")
+	}
+	// The failure is returned without discovering again until the backoff delay has passed.
+	if _, err := d.discover(context.Background()); err == nil {
+		t.Fatal("discover() succeeded, want error")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("discovery requests got=%d, want=1", got)
+	}
+	if d.backoff != minDiscoveryBackoff {
+		t.Errorf("backoff got=%v, want=%v", d.backoff, minDiscoveryBackoff)
+	}
+
+	d.retryAt = time.Now()
+	if _, err := d.discover(context.Background()); err == nil {
+		t.Fatal("discover() succeeded, want error")
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("discovery requests got=%d, want=2", got)
+	}
+	if want := 2 * minDiscoveryBackoff; d.backoff != want {
+		t.Errorf("backoff got=%v, want=%v", d.backoff, want)
+	}
+}
+
+func TestDiscoveryWaitCancelled(t *testing.T) {
+	d := NewDiscoveredIssuer("https://issuer.example.com", http.DefaultClient).(*discoveredIssuer)
+	// Simulate a discovery in progress.
+	d.discovering = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.discover(ctx); err != context.Canceled {
+		t.Errorf("discover() error = %v, want %v", err, context.Canceled)
+	}
+}
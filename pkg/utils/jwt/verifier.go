@@ -0,0 +1,80 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jwt verifies JSON Web Tokens, such as Kubernetes projected service account tokens and
+// Google-signed ID tokens. Google-signed ID tokens are verified with the idtoken package of the
+// Google API client, and the tokens of other OpenID Connect issuers with go-oidc.
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrMalformed is the error when a token can't be parsed.
+	ErrMalformed = errors.New("malformed token")
+	// ErrUnknownIssuer is the error when a token is issued by an issuer that is not trusted.
+	ErrUnknownIssuer = errors.New("unknown issuer")
+)
+
+// Claims are the claims of a verified token that are used for authentication.
+type Claims struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	// Email is the verified email of Google-signed ID tokens, e.g. of service accounts. It is empty
+	// for the tokens of other issuers.
+	Email string
+}
+
+// Issuer verifies the tokens of a trusted issuer.
+type Issuer interface {
+	// Verify verifies the token's signature and time claims, and returns its claims. The audience
+	// and subject are not checked, as they are up to the caller.
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// Verifier verifies tokens issued by a set of trusted issuers.
+type Verifier struct {
+	issuers map[string]Issuer
+}
+
+// NewVerifier creates a Verifier that trusts the given issuers, keyed by their issuer claim.
+func NewVerifier(issuers map[string]Issuer) *Verifier {
+	return &Verifier{issuers: issuers}
+}
+
+// Verify verifies the token with the issuer named by its issuer claim, and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parsed, err := josejwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	// The unverified issuer claim only selects the issuer that verifies the token.
+	var unverified josejwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	issuer, ok := v.issuers[unverified.Issuer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownIssuer, unverified.Issuer)
+	}
+	return issuer.Verify(ctx, token)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/utils/jwt"
+	jwttesting "github.com/google/knative-gcp/pkg/utils/jwt/testing"
+)
+
+func TestVerify(t *testing.T) {
+	iss := jwttesting.NewIssuer(t)
+	other := jwttesting.NewIssuer(t)
+	v := jwt.NewVerifier(map[string]jwt.Issuer{
+		iss.URL: iss.Remote(),
+		// The other issuer is trusted but its key set is discovered.
+		other.URL: jwt.NewDiscoveredIssuer(other.URL, other.Client()),
+	})
+	now := time.Now()
+	valid := jwttesting.Claims{
+		Subject:  "system:serviceaccount:ns:sa",
+		Audience: []string{"broker"},
+		Expiry:   now.Add(time.Hour).Unix(),
+		IssuedAt: now.Unix(),
+		Email:    "sa@project.iam.gserviceaccount.com",
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+		// wantErr is the error wrapped by the expected error, or errAny if it isn't exported.
+		wantErr error
+	}{{
+		name:  "valid",
+		token: func() string { return iss.Sign(t, valid) },
+	}, {
+		name: "valid from discovered issuer",
+		token: func() string {
+			c := valid
+			c.Issuer = other.URL
+			return other.Sign(t, c)
+		},
+	}, {
+		name:    "malformed",
+		token:   func() string { return "not.a-token" },
+		wantErr: jwt.ErrMalformed,
+	}, {
+		name: "unknown issuer",
+		token: func() string {
+			c := valid
+			c.Issuer = "https://untrusted.example.com"
+			return iss.Sign(t, c)
+		},
+		wantErr: jwt.ErrUnknownIssuer,
+	}, {
+		name: "signed by another issuer's key",
+		token: func() string {
+			c := valid
+			c.Issuer = iss.URL
+			return other.Sign(t, c)
+		},
+		wantErr: errAny,
+	}, {
+		name: "tampered claims",
+		token: func() string {
+			parts := strings.Split(iss.Sign(t, valid), ".")
+			c := valid
+			c.Subject = "system:serviceaccount:ns:admin"
+			forged := strings.Split(iss.Sign(t, c), ".")
+			return parts[0] + "." + forged[1] + "." + parts[2]
+		},
+		wantErr: errAny,
+	}, {
+		name: "expired",
+		token: func() string {
+			c := valid
+			c.Expiry = now.Add(-time.Hour).Unix()
+			return iss.Sign(t, c)
+		},
+		wantErr: errAny,
+	}, {
+		name: "no expiry",
+		token: func() string {
+			c := valid
+			c.Expiry = 0
+			return iss.Sign(t, c)
+		},
+		wantErr: errAny,
+	}, {
+		name: "not valid yet",
+		token: func() string {
+			c := valid
+			c.NotBefore = now.Add(time.Hour).Unix()
+			return iss.Sign(t, c)
+		},
+		wantErr: errAny,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tc.token())
+			switch {
+			case tc.wantErr == errAny:
+				if err == nil {
+					t.Fatal("Verify() succeeded, want error")
+				}
+				return
+			case !errors.Is(err, tc.wantErr):
+				t.Fatalf("Verify() error = %v, want %v", err, tc.wantErr)
+			case err != nil:
+				return
+			}
+			want := &jwt.Claims{
+				Issuer:   claims.Issuer,
+				Subject:  valid.Subject,
+				Audience: valid.Audience,
+				// The email of tokens not signed by Google is ignored.
+			}
+			if diff := cmp.Diff(want, claims); diff != "" {
+				t.Errorf("unexpected claims (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+// errAny stands for any error in the test cases.
+var errAny = errors.New("any error")